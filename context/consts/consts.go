@@ -1,8 +1,24 @@
 package consts
 
 const USER_KEY_FOR_GIN_CONTEXT = "user"
+const DEVICE_ID_KEY_FOR_GIN_CONTEXT = "deviceId"
+const ROUTE_GROUP_KEY_FOR_GIN_CONTEXT = "routeGroup"
+const ROUTE_KEY_FOR_GIN_CONTEXT = "route"
+const REQUEST_ID_KEY_FOR_GIN_CONTEXT = "uuid"
+const MICROSERVICE_CLIENT_KEY_FOR_GIN_CONTEXT = "microserviceClient"
 const GOCMS_HEADER_USER_CONTEXT_KEY = "X-GOCMS-USER-CONTEXT"
 const GOCMS_HEADER_TIMEZONE_KEY = "X-GOCMS-TIMEZONE"
 const GOCMS_HEADER_MICROSERVICE_SECRET = "X-GOCMS-MICROSERVICE-SECRET"
+const GOCMS_HEADER_PLUGIN_ID = "X-GOCMS-PLUGIN-ID"
+const GOCMS_HEADER_PLUGIN_SECRET = "X-GOCMS-PLUGIN-SECRET"
+const GOCMS_HEADER_REQUEST_ID = "X-Request-Id"
 
 const GOCMS_MIDDLEWARE_URL_SEGMENT = "middleware"
+
+// environment variables set on local plugins so they can reach the internal api
+const GOCMS_ENV_PLUGIN_ID = "GOCMS_PLUGIN_ID"
+const GOCMS_ENV_INTERNAL_API_URL = "GOCMS_INTERNAL_API_URL"
+const GOCMS_ENV_PLUGIN_SECRET = "GOCMS_PLUGIN_SECRET"
+const GOCMS_ENV_INTERNAL_CA_FILE = "GOCMS_INTERNAL_CA_FILE"
+const GOCMS_ENV_CLIENT_CERT_FILE = "GOCMS_CLIENT_CERT_FILE"
+const GOCMS_ENV_CLIENT_KEY_FILE = "GOCMS_CLIENT_KEY_FILE"
@@ -2,9 +2,12 @@ package context
 
 import (
 	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/tlsUtil"
+	"github.com/gocms-io/gocms/utility/trace"
 	_ "github.com/joho/godotenv/autoload"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -24,11 +27,75 @@ func init() {
 	}
 	log.LogLevel = logLevel
 
+	// structured output, per subsystem levels and file rotation
+	logConfig := &log.Config{
+		Format: os.Getenv("LOG_FORMAT"),
+		File:   os.Getenv("LOG_FILE"),
+	}
+	logConfig.MaxSize, err = strconv.ParseInt(os.Getenv("LOG_FILE_MAX_SIZE"), 10, 64)
+	if err != nil {
+		logConfig.MaxSize = 100
+	}
+	logConfig.MaxBackups, err = strconv.ParseInt(os.Getenv("LOG_FILE_MAX_BACKUPS"), 10, 64)
+	if err != nil {
+		logConfig.MaxBackups = 5
+	}
+	logConfig.Levels, err = log.ParseSubsystemLevels(os.Getenv("LOG_LEVELS"))
+	if err != nil {
+		log.Errorf("Error parsing LOG_LEVELS: %v\n", err.Error())
+	}
+	if err = log.Configure(logConfig); err != nil {
+		log.Errorf("Error configuring logging, using defaults: %v\n", err.Error())
+	}
+
+	// tracing uses the standard opentelemetry environment variables
+	traceConfig := &trace.Config{
+		Exporter:    os.Getenv("OTEL_TRACES_EXPORTER"),
+		ServiceName: os.Getenv("OTEL_SERVICE_NAME"),
+		Endpoint:    os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"),
+		Headers:     make(map[string]string),
+	}
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); traceConfig.Endpoint == "" && endpoint != "" {
+		traceConfig.Endpoint = strings.TrimSuffix(endpoint, "/") + "/v1/traces"
+	}
+	for _, header := range strings.Split(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"), ",") {
+		if parts := strings.SplitN(header, "=", 2); len(parts) == 2 {
+			traceConfig.Headers[strings.TrimSpace(parts[0])] = strings.TrimSpace(parts[1])
+		}
+	}
+	traceConfig.SampleRatio, err = strconv.ParseFloat(os.Getenv("OTEL_TRACES_SAMPLER_ARG"), 64)
+	if err != nil {
+		traceConfig.SampleRatio = 1
+	}
+	if err = trace.Configure(traceConfig); err != nil {
+		log.Errorf("Error configuring tracing, spans won't be exported: %v\n", err.Error())
+	}
+
 	devMode, err := strconv.ParseBool(os.Getenv("DEV_MODE"))
 	if err != nil {
 		devMode = false
 	}
 
+	// how long to wait for requests and plugins to finish when stopping
+	shutdownTimeout, err := strconv.ParseInt(os.Getenv("SHUTDOWN_TIMEOUT"), 10, 64)
+	if err != nil || shutdownTimeout <= 0 {
+		shutdownTimeout = 30
+	}
+
+	// native tls. both engines share the version and cipher settings.
+	tlsMinVersion, err := tlsUtil.ParseVersion(os.Getenv("TLS_MIN_VERSION"))
+	if err != nil {
+		log.Fatalf("Error parsing TLS_MIN_VERSION: %v\n", err.Error())
+	}
+	tlsCiphers, err := tlsUtil.ParseCipherSuites(os.Getenv("TLS_CIPHERS"))
+	if err != nil {
+		log.Fatalf("Error parsing TLS_CIPHERS: %v\n", err.Error())
+	}
+	internalTlsRequireClientCert, err := strconv.ParseBool(os.Getenv("INTERNAL_TLS_REQUIRE_CLIENT_CERT"))
+	if err != nil {
+		internalTlsRequireClientCert = false
+	}
+
 	// set config
 	config := Context{
 
@@ -38,7 +105,23 @@ func init() {
 			DbPassword: GetEnvVarOrFail("DB_PASSWORD"),
 			DbServer:   GetEnvVarOrFail("DB_SERVER"),
 			LogLevel:   logLevel,
+			LogFormat:  logConfig.Format,
+			LogFile:    logConfig.File,
 			DevMode:    devMode,
+
+			ShutdownTimeout: time.Duration(shutdownTimeout) * time.Second,
+
+			TlsCertFile:                  os.Getenv("TLS_CERT_FILE"),
+			TlsKeyFile:                   os.Getenv("TLS_KEY_FILE"),
+			TlsMinVersion:                tlsMinVersion,
+			TlsCipherSuites:              tlsCiphers,
+			TlsRedirectPort:              os.Getenv("TLS_REDIRECT_PORT"),
+			InternalTlsCertFile:          os.Getenv("INTERNAL_TLS_CERT_FILE"),
+			InternalTlsKeyFile:           os.Getenv("INTERNAL_TLS_KEY_FILE"),
+			InternalTlsCAFile:            os.Getenv("INTERNAL_TLS_CA_FILE"),
+			InternalTlsClientCAFile:      os.Getenv("INTERNAL_TLS_CLIENT_CA_FILE"),
+			InternalTlsClientCAKeyFile:   os.Getenv("INTERNAL_TLS_CLIENT_CA_KEY_FILE"),
+			InternalTlsRequireClientCert: internalTlsRequireClientCert,
 		},
 		DbVars: &dbVars{},
 	}
@@ -50,6 +133,7 @@ func init() {
 		idCount: 0,
 		tickers: make(map[int]*time.Ticker),
 		timers:  make(map[int]*time.Timer),
+		done:    make(chan struct{}),
 	}
 	Schedule = &schedule
 }
@@ -10,37 +10,50 @@ import (
 func GetEnvVarOrFail(envVar string) string {
 	is := os.Getenv(envVar)
 	if is == "" {
-		log.Criticalf("Error retrieving envVar: " + envVar)
-		os.Exit(1)
+		log.Fatalf("Error retrieving envVar: %v\n", envVar)
 	}
 	return is
 }
 
-func GetIntOrFail(s string, settings map[string]setting_model.Setting) int64 {
-	is := settings[s].Value
-	i, err := strconv.ParseInt(is, 10, 34)
+// GetSettingOrDefault returns the value of the setting if it is valid for its schema.
+// Invalid or missing values are logged and the schema default is returned instead.
+func GetSettingOrDefault(s string, settings map[string]setting_model.Setting) string {
+	value := settings[s].Value
+
+	schema, ok := setting_model.GetSchema(s)
+	if !ok {
+		return value
+	}
+
+	if _, exists := settings[s]; !exists {
+		log.Errorf("Setting %v is missing. Using default: %v\n", s, schema.Default)
+		return schema.Default
+	}
+
+	if err := schema.Validate(value); err != nil {
+		log.Errorf("Invalid setting: %v. Using default: %v\n", err.Error(), schema.Default)
+		return schema.Default
+	}
+
+	return value
+}
+
+func GetInt(s string, settings map[string]setting_model.Setting) int64 {
+	i, err := strconv.ParseInt(GetSettingOrDefault(s, settings), 10, 64)
 	if err != nil {
-		log.Criticalf("Error parsing string: " + s + " into int: " + err.Error())
-		os.Exit(0)
+		log.Errorf("Error parsing setting %v into int: %v\n", s, err.Error())
 	}
 	return i
 }
 
-func GetStringOrFail(s string, settings map[string]setting_model.Setting) string {
-
-	ss := settings[s].Value
-	if ss == "" {
-		log.Criticalf("Error retrieving string: " + s)
-		os.Exit(1)
-	}
-	return ss
+func GetString(s string, settings map[string]setting_model.Setting) string {
+	return GetSettingOrDefault(s, settings)
 }
-func GetBoolOrFail(s string, settings map[string]setting_model.Setting) bool {
-	bs := settings[s].Value
-	b, err := strconv.ParseBool(bs)
+
+func GetBool(s string, settings map[string]setting_model.Setting) bool {
+	b, err := strconv.ParseBool(GetSettingOrDefault(s, settings))
 	if err != nil {
-		log.Criticalf("Error parsing bool: " + s + " into bool: " + err.Error())
-		os.Exit(0)
+		log.Errorf("Error parsing setting %v into bool: %v\n", s, err.Error())
 	}
 	return b
 }
@@ -1,6 +1,9 @@
 package context
 
 import (
+	"github.com/gocms-io/gocms/utility/log"
+	"runtime/debug"
+	"sync"
 	"time"
 )
 
@@ -10,18 +13,37 @@ type Scheduler struct {
 	idCount int
 	tickers map[int]*time.Ticker
 	timers  map[int]*time.Timer
+	mu      sync.Mutex
+	running sync.WaitGroup
+	done    chan struct{}
+	stopped bool
 }
 
 func (s *Scheduler) AddTicker(d time.Duration, f func()) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	// increment id count and assign
 	s.idCount += 1
 	id := s.idCount
 
+	// nothing new runs once we are shutting down
+	if s.stopped {
+		return id
+	}
+
 	// create ticker and start it
 	ticker := time.NewTicker(d)
+	s.running.Add(1)
 	go func() {
-		for range ticker.C {
-			f()
+		defer s.running.Done()
+		for {
+			select {
+			case <-ticker.C:
+				runJob(f)
+			case <-s.done:
+				return
+			}
 		}
 	}()
 
@@ -30,3 +52,35 @@ func (s *Scheduler) AddTicker(d time.Duration, f func()) int {
 
 	return id
 }
+
+// runJob keeps a panicking job from taking the whole process down. The ticker keeps running.
+func runJob(f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Errorf("Scheduled job panicked: %v\n%s", r, debug.Stack())
+		}
+	}()
+	f()
+}
+
+// Stop stops every ticker and waits for any tick that is already running to finish
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if s.stopped {
+		s.mu.Unlock()
+		return
+	}
+	s.stopped = true
+	for id, ticker := range s.tickers {
+		ticker.Stop()
+		delete(s.tickers, id)
+	}
+	for id, timer := range s.timers {
+		timer.Stop()
+		delete(s.timers, id)
+	}
+	close(s.done)
+	s.mu.Unlock()
+
+	s.running.Wait()
+}
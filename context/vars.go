@@ -5,6 +5,9 @@ import (
 	"crypto/rsa"
 	"github.com/gocms-io/gocms/utility/log"
 	"github.com/dgrijalva/jwt-go"
+	"os"
+	"strings"
+	"time"
 )
 
 type envVars struct {
@@ -17,6 +20,24 @@ type envVars struct {
 	// Dev & Debug
 	DevMode bool
 	LogLevel   int64
+	LogFormat  string
+	LogFile    string
+
+	// Lifecycle
+	ShutdownTimeout time.Duration
+
+	// TLS (tls is off unless a cert and key are set)
+	TlsCertFile                  string
+	TlsKeyFile                   string
+	TlsMinVersion                uint16
+	TlsCipherSuites              []uint16
+	TlsRedirectPort              string
+	InternalTlsCertFile          string
+	InternalTlsKeyFile           string
+	InternalTlsCAFile            string
+	InternalTlsClientCAFile      string
+	InternalTlsClientCAKeyFile   string
+	InternalTlsRequireClientCert bool
 }
 
 type dbVars struct {
@@ -42,11 +63,35 @@ type dbVars struct {
 	EmailActivationTimeout int64
 	DeviceAuthTimeout      int64
 	TwoFactorCodeTimeout   int64
+	InvitationTimeout      int64
 	UseTwoFactor           bool
+	AcceptLegacyTokens     bool
+	TokenIssuer            string
 	PasswordComplexity     int64
+	PasswordMinLength      int64
+	PasswordHistory        int64
+	PasswordMaxAge         int64
+	PasswordBreachList     string
 	PermissionsCacheLife   int64
 	MicroserviceSecret	string
 
+	// Signing Keys
+	SigningKeyRotation    int64
+	SigningKeyGracePeriod int64
+
+	// Magic Link
+	MagicLinkEnabled bool
+	MagicLinkTimeout int64
+	MagicLinkUrl     string
+
+	// WebAuthn
+	WebauthnRpId             string
+	WebauthnRpName           string
+	WebauthnOrigins          []string
+	WebauthnTimeout          int64
+	WebauthnUserVerification string
+	WebauthnLoginEnabled     bool
+
 	// rsa
 	rsaPriv             *rsa.PrivateKey
 	RSAPub              *rsa.PublicKey
@@ -65,66 +110,144 @@ type dbVars struct {
 	LoginTitle            string
 	LoginSuccessRedirect  string
 	DisableDocumentationDisplay   bool
+
+	// User Data
+	UserDeletionGracePeriod int64
+
+	// Rate Limiting
+	RateLimitStore          string
+	RateLimitPublicRequests int64
+	RateLimitPublicWindow   int64
+	RateLimitAuthRequests   int64
+	RateLimitAuthWindow     int64
+
+	// Plugin Middleware
+	PluginMaxBodySize     int64
+	PluginBodyMemoryLimit int64
+
+	// loaded is set after the first load
+	loaded bool
 }
 
 func (dbVars *dbVars) LoadDbVars(settings map[string]setting_model.Setting) {
 	log.Debugf("Refresh GoCMS Settings\n")
 
 	// Debug
-	dbVars.Debug = GetBoolOrFail("DEBUG", settings)
-	dbVars.DebugSecurity = GetBoolOrFail("DEBUG_SECURITY", settings)
+	dbVars.Debug = GetBool("DEBUG", settings)
+	dbVars.DebugSecurity = GetBool("DEBUG_SECURITY", settings)
 
 	// App Config
-	dbVars.Port = GetStringOrFail("PORT", settings)
-	dbVars.MsPort = GetStringOrFail("MS_PORT", settings)
-	dbVars.PublicApiUrl = GetStringOrFail("PUBLIC_API_URL", settings)
-	dbVars.RedirectRootUrl = GetStringOrFail("REDIRECT_ROOT_URL", settings)
-	dbVars.CorsHost = GetStringOrFail("CORS_HOST", settings)
-	dbVars.SettingsRefreshRate = GetIntOrFail("SETTINGS_REFRESH_RATE", settings)
+	dbVars.Port = GetString("PORT", settings)
+	dbVars.MsPort = GetString("MS_PORT", settings)
+	dbVars.PublicApiUrl = GetString("PUBLIC_API_URL", settings)
+	dbVars.RedirectRootUrl = GetString("REDIRECT_ROOT_URL", settings)
+	dbVars.CorsHost = GetString("CORS_HOST", settings)
+	dbVars.SettingsRefreshRate = GetInt("SETTINGS_REFRESH_RATE", settings)
 
 	// Authentication
-	dbVars.UserAuthTimeout = GetIntOrFail("USER_AUTHENTICATION_TIMEOUT", settings)
-	dbVars.PasswordResetTimeout = GetIntOrFail("PASSWORD_RESET_TIMEOUT", settings)
-	dbVars.DeviceAuthTimeout = GetIntOrFail("DEVICE_AUTHENTICATION_TIMEOUT", settings)
-	dbVars.TwoFactorCodeTimeout = GetIntOrFail("TWO_FACTOR_CODE_TIMEOUT", settings)
-	dbVars.EmailActivationTimeout = GetIntOrFail("EMAIL_ACTIVATION_TIMEOUT", settings)
-	dbVars.UseTwoFactor = GetBoolOrFail("USE_TWO_FACTOR", settings)
-	dbVars.PasswordComplexity = GetIntOrFail("PASSWORD_COMPLEXITY", settings)
-	dbVars.OpenRegistration = GetBoolOrFail("OPEN_REGISTRATION", settings)
-	dbVars.PermissionsCacheLife = GetIntOrFail("PERMISSIONS_CACHE_LIFE", settings)
-	dbVars.MicroserviceSecret = GetStringOrFail("MS_SECRET_KEY", settings)
+	dbVars.UserAuthTimeout = GetInt("USER_AUTHENTICATION_TIMEOUT", settings)
+	dbVars.PasswordResetTimeout = GetInt("PASSWORD_RESET_TIMEOUT", settings)
+	dbVars.DeviceAuthTimeout = GetInt("DEVICE_AUTHENTICATION_TIMEOUT", settings)
+	dbVars.TwoFactorCodeTimeout = GetInt("TWO_FACTOR_CODE_TIMEOUT", settings)
+	dbVars.EmailActivationTimeout = GetInt("EMAIL_ACTIVATION_TIMEOUT", settings)
+	dbVars.InvitationTimeout = GetInt("INVITATION_TIMEOUT", settings)
+	// routes are built for two factor at startup so a change only applies after a restart
+	if !dbVars.loaded {
+		dbVars.UseTwoFactor = GetBool("USE_TWO_FACTOR", settings)
+	}
+	dbVars.AcceptLegacyTokens = GetBool("ACCEPT_LEGACY_TOKENS", settings)
+	dbVars.TokenIssuer = GetString("TOKEN_ISSUER", settings)
+	dbVars.PasswordComplexity = GetInt("PASSWORD_COMPLEXITY", settings)
+	dbVars.PasswordMinLength = GetInt("PASSWORD_MIN_LENGTH", settings)
+	dbVars.PasswordHistory = GetInt("PASSWORD_HISTORY", settings)
+	dbVars.PasswordMaxAge = GetInt("PASSWORD_MAX_AGE", settings)
+	dbVars.PasswordBreachList = GetString("PASSWORD_BREACH_LIST", settings)
+	if list := dbVars.PasswordBreachList; list != "" && !strings.HasPrefix(list, "http://") && !strings.HasPrefix(list, "https://") {
+		if _, err := os.Stat(list); err != nil {
+			log.Errorf("PASSWORD_BREACH_LIST can't be read so new passwords will be refused: %v\n", err.Error())
+		}
+	}
+	dbVars.OpenRegistration = GetBool("OPEN_REGISTRATION", settings)
+	dbVars.PermissionsCacheLife = GetInt("PERMISSIONS_CACHE_LIFE", settings)
+	dbVars.MicroserviceSecret = GetString("MS_SECRET_KEY", settings)
+
+	// Signing Keys
+	dbVars.SigningKeyRotation = GetInt("SIGNING_KEY_ROTATION", settings)
+	dbVars.SigningKeyGracePeriod = GetInt("SIGNING_KEY_GRACE_PERIOD", settings)
+
+	// Magic Link
+	dbVars.MagicLinkEnabled = GetBool("MAGIC_LINK_ENABLED", settings)
+	dbVars.MagicLinkTimeout = GetInt("MAGIC_LINK_TIMEOUT", settings)
+	dbVars.MagicLinkUrl = GetString("MAGIC_LINK_URL", settings)
+
+	// WebAuthn
+	dbVars.WebauthnRpId = GetString("WEBAUTHN_RP_ID", settings)
+	dbVars.WebauthnRpName = GetString("WEBAUTHN_RP_NAME", settings)
+	dbVars.WebauthnOrigins = nil
+	for _, origin := range strings.Split(GetString("WEBAUTHN_ORIGINS", settings), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			dbVars.WebauthnOrigins = append(dbVars.WebauthnOrigins, origin)
+		}
+	}
+	dbVars.WebauthnTimeout = GetInt("WEBAUTHN_TIMEOUT", settings)
+	dbVars.WebauthnUserVerification = GetString("WEBAUTHN_USER_VERIFICATION", settings)
+	dbVars.WebauthnLoginEnabled = GetBool("WEBAUTHN_LOGIN_ENABLED", settings)
 
 	// RSA
+	// the keys are required at startup. a bad value after that keeps the keys already loaded.
+	logRsaErr := log.Errorf
+	if !dbVars.loaded {
+		logRsaErr = log.Fatalf
+	}
+
 	// rsa priv privKey
-	rsaPrivStr := GetStringOrFail("RSA_PRIV", settings)
+	rsaPrivStr := GetString("RSA_PRIV", settings)
 	privKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(rsaPrivStr))
 	if err !=nil {
-		log.Criticalf("Can't parse rsa privKey: %v\n", err.Error())
+		logRsaErr("Can't parse rsa privKey: %v\n", err.Error())
+	} else {
+		dbVars.rsaPriv = privKey
 	}
-	dbVars.rsaPriv = privKey
 
 	// rsa pub privKey
-	rsaPubStr := GetStringOrFail("RSA_PUB", settings)
+	rsaPubStr := GetString("RSA_PUB", settings)
 	pubKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(rsaPubStr))
 	if err !=nil {
-		log.Criticalf("Can't parse rsa pubKey: %v\n", err.Error())
+		logRsaErr("Can't parse rsa pubKey: %v\n", err.Error())
+	} else {
+		dbVars.RSAPub = pubKey
 	}
-	dbVars.RSAPub = pubKey
 
 	// SMTP
-	dbVars.SMTPServer = GetStringOrFail("SMTP_SERVER", settings)
-	dbVars.SMTPPort = GetIntOrFail("SMTP_PORT", settings)
-	dbVars.SMTPUser = GetStringOrFail("SMTP_USER", settings)
-	dbVars.SMTPPassword = GetStringOrFail("SMTP_PASSWORD", settings)
-	dbVars.SMTPFromAddress = GetStringOrFail("SMTP_FROM_ADDRESS", settings)
-	dbVars.SMTPSimulate = GetBoolOrFail("SMTP_SIMULATE", settings)
+	dbVars.SMTPServer = GetString("SMTP_SERVER", settings)
+	dbVars.SMTPPort = GetInt("SMTP_PORT", settings)
+	dbVars.SMTPUser = GetString("SMTP_USER", settings)
+	dbVars.SMTPPassword = GetString("SMTP_PASSWORD", settings)
+	dbVars.SMTPFromAddress = GetString("SMTP_FROM_ADDRESS", settings)
+	dbVars.SMTPSimulate = GetBool("SMTP_SIMULATE", settings)
 
 	// GoCMS
-	dbVars.ActiveTheme = GetStringOrFail("ACTIVE_THEME", settings)
-	dbVars.ActiveThemeAssetsBase = GetStringOrFail("ACTIVE_THEME_ASSETS_BASE", settings)
-	dbVars.LoginTitle = GetStringOrFail("GOCMS_LOGIN_TITLE", settings)
-	dbVars.LoginSuccessRedirect = GetStringOrFail("GOCMS_LOGIN_SUCCESS_REDIRECT", settings)
-	dbVars.DisableDocumentationDisplay = GetBoolOrFail("DISABLE_DOCUMENTATION_DISPLAY", settings)
+	dbVars.ActiveTheme = GetString("ACTIVE_THEME", settings)
+	dbVars.ActiveThemeAssetsBase = GetString("ACTIVE_THEME_ASSETS_BASE", settings)
+	dbVars.LoginTitle = GetString("GOCMS_LOGIN_TITLE", settings)
+	dbVars.LoginSuccessRedirect = GetString("GOCMS_LOGIN_SUCCESS_REDIRECT", settings)
+	dbVars.DisableDocumentationDisplay = GetBool("DISABLE_DOCUMENTATION_DISPLAY", settings)
+
+	// User Data
+	dbVars.UserDeletionGracePeriod = GetInt("USER_DELETION_GRACE_PERIOD", settings)
+
+	// Rate Limiting
+	dbVars.RateLimitStore = GetString("RATE_LIMIT_STORE", settings)
+	dbVars.RateLimitPublicRequests = GetInt("RATE_LIMIT_PUBLIC_REQUESTS", settings)
+	dbVars.RateLimitPublicWindow = GetInt("RATE_LIMIT_PUBLIC_WINDOW", settings)
+	dbVars.RateLimitAuthRequests = GetInt("RATE_LIMIT_AUTH_REQUESTS", settings)
+	dbVars.RateLimitAuthWindow = GetInt("RATE_LIMIT_AUTH_WINDOW", settings)
+
+	// Plugin Middleware
+	dbVars.PluginMaxBodySize = GetInt("PLUGIN_MAX_BODY_SIZE", settings)
+	dbVars.PluginBodyMemoryLimit = GetInt("PLUGIN_BODY_MEMORY_LIMIT", settings)
+
+	dbVars.loaded = true
 
 }
 
@@ -134,4 +257,13 @@ func (dbVars *dbVars) GetRsaPrivateKey(iWillBeSecure bool) *rsa.PrivateKey {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+// TlsEnabled is true when the public engine serves https
+func (envVars *envVars) TlsEnabled() bool {
+	return envVars.TlsCertFile != "" && envVars.TlsKeyFile != ""
+}
+
+// InternalTlsEnabled is true when the internal engine serves https
+func (envVars *envVars) InternalTlsEnabled() bool {
+	return envVars.InternalTlsCertFile != "" && envVars.InternalTlsKeyFile != ""
+}
@@ -36,7 +36,7 @@ func (as *AclService) RefreshPermissionsCache() error {
 	// get all permissions
 	permissions, err := as.RepositoriesGroup.PermissionsRepository.GetAll()
 	if err != nil {
-		log.Criticalf("Fatal - Error caching permissions: %s\n", err.Error())
+		log.Errorf("Error caching permissions: %s\n", err.Error())
 		return err
 	}
 
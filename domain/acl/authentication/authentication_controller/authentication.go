@@ -1,13 +1,10 @@
 package authentication_controller
 
 import (
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_middleware"
 	"github.com/gocms-io/gocms/init/service"
 	"github.com/gocms-io/gocms/routes"
-	"github.com/gocms-io/gocms/utility"
-	"time"
-	"github.com/gocms-io/gocms/utility/log"
 )
 
 const (
@@ -45,33 +42,20 @@ func (ac *AuthController) Default() {
 	ac.routes.Public.POST("/login/google", ac.loginGoogle)
 	ac.routes.Public.POST("/reset-password", ac.resetPassword)
 	ac.routes.Public.PUT("/reset-password", ac.setPassword)
+	ac.routes.Public.POST("/invitation", ac.acceptInvitation)
+	ac.routes.Public.POST("/login/webauthn/options", requireWebauthnLogin, ac.getWebauthnLoginOptions)
+	ac.routes.Public.POST("/login/webauthn", requireWebauthnLogin, ac.loginWebauthn)
+	ac.routes.Public.POST("/login/magic-link/send", requireMagicLink, rate_limit_middleware.RateLimit(ac.ServicesGroup.RateLimitService, RATE_LIMIT_MAGIC_LINK, magicLinkRateLimit), ac.sendMagicLink)
+	ac.routes.Public.GET("/login/magic-link", requireMagicLink, ac.getMagicLinkLogin)
+	ac.routes.Public.POST("/login/magic-link", requireMagicLink, ac.postMagicLinkLogin)
 	ac.routes.Auth.GET("/verify", ac.verifyUser)
+	ac.routes.Auth.GET("/webauthn/register", ac.getWebauthnRegistrationOptions)
+	ac.routes.Auth.POST("/webauthn/register", ac.registerWebauthn)
 
 	if context.Config.DbVars.UseTwoFactor {
 		ac.routes.PreTwofactor.GET("/verify-device", ac.getDeviceCode)
 		ac.routes.PreTwofactor.POST("/verify-device", ac.verifyDevice)
+		ac.routes.PreTwofactor.GET("/verify-device/webauthn", ac.getWebauthnDeviceOptions)
+		ac.routes.PreTwofactor.POST("/verify-device/webauthn", ac.verifyDeviceWebauthn)
 	}
 }
-
-type MyCustomClaims struct {
-	userId string `json:"userId"`
-	jwt.StandardClaims
-}
-
-func (ac *AuthController) createToken(userId int64) (string, error) {
-	expire := time.Now().Add(time.Minute * utility.GetTimeout(context.Config.DbVars.UserAuthTimeout))
-
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
-		"userId": userId,
-		"iat": time.Now().Unix(),
-		"exp": expire.Unix() * 1000, // get milliseconds,
-
-	})
-	tokenString, err := token.SignedString(context.Config.DbVars.GetRsaPrivateKey(true))
-	if err != nil {
-		log.Errorf("Error signing token for account %v: %v\n", userId, err.Error())
-		return "", err
-	}
-
-	return tokenString, nil
-}
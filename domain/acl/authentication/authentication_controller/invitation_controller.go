@@ -0,0 +1,60 @@
+package authentication_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/acl/authentication/authentication_model"
+	"github.com/gocms-io/gocms/domain/user/user_service"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+)
+
+/**
+* @api {post} /invitation Accept Invitation
+* @apiDescription Accept an invitation sent by an admin. Sets the users password, verifies their email and enables the account. Invitations can only be used once.
+* @apiName AcceptInvitation
+* @apiGroup Authentication
+*
+* @apiUse AcceptInvitationInput
+* @apiUse UserDisplay
+* @apiUse AuthHeaderResponse
+ */
+func (ac *AuthController) acceptInvitation(c *gin.Context) {
+
+	var acceptInvitationInput authentication_model.AcceptInvitationInput
+	err := c.BindJSON(&acceptInvitationInput)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, errors.ApiError_Json, err)
+		return
+	}
+
+	user, err := ac.ServicesGroup.UserService.AcceptInvitation(acceptInvitationInput.Email, acceptInvitationInput.Code, acceptInvitationInput.Password)
+	if err == user_service.ErrInvalidCode {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Invitation is not valid or has expired.", REDIRECT_LOGIN)
+		return
+	}
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't accept invitation.", err)
+		return
+	}
+
+	// let the invitee set their name if the admin didn't
+	if acceptInvitationInput.FullName != "" {
+		user.FullName = acceptInvitationInput.FullName
+		err = ac.ServicesGroup.UserService.Update(user.Id, user)
+		if err != nil {
+			errors.Response(c, http.StatusInternalServerError, errors.ApiError_Server, err)
+			return
+		}
+	}
+
+	// create token
+	tokenString, err := ac.ServicesGroup.AuthService.CreateUserToken(user.Id)
+	if err != nil {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error generating token.", REDIRECT_LOGIN)
+		return
+	}
+
+	c.Header("X-AUTH-TOKEN", tokenString)
+
+	c.JSON(http.StatusOK, user.GetUserDisplay())
+}
@@ -47,7 +47,7 @@ func (ac *AuthController) login(c *gin.Context) {
 	}
 
 	// create token
-	tokenString, err := ac.createToken(user.Id)
+	tokenString, err := ac.ServicesGroup.AuthService.CreateUserToken(user.Id)
 	if err != nil {
 		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error generating token.", REDIRECT_LOGIN)
 		return
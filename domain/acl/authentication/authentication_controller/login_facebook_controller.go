@@ -137,7 +137,7 @@ func (ac *AuthController) loginFacebook(c *gin.Context) {
 	}
 
 	// create token
-	tokenString, err := ac.createToken(user.Id)
+	tokenString, err := ac.ServicesGroup.AuthService.CreateUserToken(user.Id)
 	if err != nil {
 		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error generating token.", REDIRECT_LOGIN)
 		return
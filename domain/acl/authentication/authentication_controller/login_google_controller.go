@@ -134,7 +134,7 @@ func (ac *AuthController) loginGoogle(c *gin.Context) {
 	}
 
 	// create token
-	tokenString, err := ac.createToken(user.Id)
+	tokenString, err := ac.ServicesGroup.AuthService.CreateUserToken(user.Id)
 	if err != nil {
 		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error generating token.", REDIRECT_LOGIN)
 		return
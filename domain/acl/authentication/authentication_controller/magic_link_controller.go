@@ -0,0 +1,126 @@
+package authentication_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/authentication/authentication_model"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_model"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"net/http"
+)
+
+const RATE_LIMIT_MAGIC_LINK = "MagicLink"
+
+// login links that can be requested from one ip
+var magicLinkRateLimit = &rate_limit_model.RateLimit{
+	Requests: 5,
+	Window:   900,
+	By:       rate_limit_model.RATE_LIMIT_BY_IP,
+}
+
+// requireMagicLink hides the magic link routes while the setting is off
+func requireMagicLink(c *gin.Context) {
+	if !context.Config.DbVars.MagicLinkEnabled {
+		errors.Response(c, http.StatusNotFound, "Login links are disabled.", nil)
+		return
+	}
+	c.Next()
+}
+
+/**
+* @api {post} /login/magic-link/send Send Login Link
+* @apiDescription Email a single use login link to the account. Only available when MAGIC_LINK_ENABLED is set.
+* @apiName SendMagicLink
+* @apiGroup Authentication
+*
+* @apiUse MagicLinkRequestInput
+ */
+func (ac *AuthController) sendMagicLink(c *gin.Context) {
+
+	var magicLinkRequest authentication_model.MagicLinkRequestInput
+	err := c.BindJSON(&magicLinkRequest)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Missing Fields", err)
+		return
+	}
+
+	err = ac.ServicesGroup.AuthService.SendMagicLink(magicLinkRequest.Email)
+	if err != nil {
+		log.Errorf("Error sending login link: %s", err.Error())
+		//return nothing for security.
+	}
+
+	// respond as everything after this doesn't matter to the requester
+	c.String(http.StatusOK, "Email will be sent to the account provided.")
+}
+
+/**
+* @api {get} /login/magic-link Check Login Link
+* @apiDescription Check a login link without using it. Email clients prefetch links, so the page MAGIC_LINK_URL points at should
+* check the link here and log in by posting the code to /login/magic-link once the user asks to.
+* @apiName GetMagicLinkLogin
+* @apiGroup Authentication
+*
+* @apiParam (Query) {string} email
+* @apiParam (Query) {string} code
+ */
+func (ac *AuthController) getMagicLinkLogin(c *gin.Context) {
+	email, code := c.Query("email"), c.Query("code")
+	if email == "" || code == "" || !ac.ServicesGroup.AuthService.CheckMagicLink(email, code) {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Login link is invalid or has expired.", REDIRECT_LOGIN)
+		return
+	}
+
+	c.String(http.StatusOK, "Login link is valid.")
+}
+
+/**
+* @api {post} /login/magic-link Login With Link Code
+* @apiDescription Login with the code from a login link. Links can only be used once.
+* @apiName MagicLinkLogin
+* @apiGroup Authentication
+*
+* @apiUse MagicLinkInput
+* @apiUse UserDisplay
+* @apiUse AuthHeaderResponse
+ */
+func (ac *AuthController) postMagicLinkLogin(c *gin.Context) {
+	var magicLinkInput authentication_model.MagicLinkInput
+	if c.BindJSON(&magicLinkInput) != nil {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Missing Email or Code", REDIRECT_LOGIN)
+		return
+	}
+
+	ac.magicLinkLogin(c, &magicLinkInput)
+}
+
+func (ac *AuthController) magicLinkLogin(c *gin.Context, magicLinkInput *authentication_model.MagicLinkInput) {
+	if magicLinkInput.Email == "" || magicLinkInput.Code == "" {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Missing Email or Code", REDIRECT_LOGIN)
+		return
+	}
+
+	user, ok := ac.ServicesGroup.AuthService.VerifyMagicLink(magicLinkInput.Email, magicLinkInput.Code)
+	if !ok {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Login link is invalid or has expired.", REDIRECT_LOGIN)
+		return
+	}
+
+	// verify user is enabled
+	if !user.Enabled {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, errors.ApiError_User_Disabled, REDIRECT_LOGIN)
+		return
+	}
+
+	// create token
+	tokenString, err := ac.ServicesGroup.AuthService.CreateUserToken(user.Id)
+	if err != nil {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error generating token.", REDIRECT_LOGIN)
+		return
+	}
+
+	c.Header("X-AUTH-TOKEN", tokenString)
+
+	c.JSON(http.StatusOK, user.GetUserDisplay())
+}
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"github.com/gocms-io/gocms/domain/acl/authentication/authentication_model"
 	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/domain/user/user_service"
 )
 
 /**
@@ -52,27 +53,12 @@ func (ac *AuthController) setPassword(c *gin.Context) {
 		return
 	}
 
-	// verify password complexity
-	if !ac.ServicesGroup.AuthService.PasswordIsComplex(resetPassword.Password) {
-		errors.Response(c, http.StatusBadRequest, "Password is not complex enough.", err)
-		return
-	}
-
-	// get user
-	user, err := ac.ServicesGroup.UserService.GetByEmail(resetPassword.Email)
-	if err != nil {
-		errors.Response(c, http.StatusBadRequest, "Couldn't reset password.", err)
-		return
-	}
-
-	// verify code
-	if ok := ac.ServicesGroup.AuthService.VerifyPasswordResetCode(user.Id, resetPassword.ResetCode); !ok {
+	// reset password. the code is verified before the password policy.
+	err = ac.ServicesGroup.UserService.ResetPassword(resetPassword.Email, resetPassword.ResetCode, resetPassword.Password)
+	if err == user_service.ErrInvalidCode {
 		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error resetting password.", REDIRECT_LOGIN)
 		return
 	}
-
-	// reset password
-	err = ac.ServicesGroup.UserService.UpdatePassword(user.Id, resetPassword.Password)
 	if err != nil {
 		errors.Response(c, http.StatusBadRequest, "Couldn't reset password.", err)
 		return
@@ -1,12 +1,10 @@
 package authentication_controller
 
 import (
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"net/http"
-	"time"
 
-	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/user/user_model"
 	"github.com/gocms-io/gocms/utility/api_utility"
 	"github.com/gocms-io/gocms/utility"
 	"github.com/gocms-io/gocms/utility/errors"
@@ -15,6 +13,7 @@ import (
 // Verify device form structure
 type VerifyDeviceDisplay struct {
 	DeviceCode string `json:"deviceCode" binding:"required"`
+	DeviceName string `json:"deviceName"`
 }
 
 // getDeviceToken
@@ -53,19 +52,32 @@ func (ac *AuthController) verifyDevice(c *gin.Context) {
 		return
 	}
 
-	// generate device token
-	expire := time.Now().Add(time.Minute * utility.GetTimeout(context.Config.DbVars.DeviceAuthTimeout))
-	deviceToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
-		"iat": time.Now().Unix(),
-		"exp": expire.Unix() * 1000, // get milliseconds,
-	})
-	deviceTokenString, err := deviceToken.SignedString(context.Config.DbVars.GetRsaPrivateKey(true))
+	// trust device
+	err := ac.trustDevice(c, user, verifyDeviceDisplay.DeviceName)
 	if err != nil {
 		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error generating device token.", REDIRECT_LOGIN)
 		return
 	}
 
-	c.Header("X-DEVICE-TOKEN", deviceTokenString)
-
 	c.String(http.StatusOK, "ok")
 }
+
+// trustDevice records the device making the request as trusted and returns its device token in the X-DEVICE-TOKEN header.
+func (ac *AuthController) trustDevice(c *gin.Context, user *user_model.User, deviceName string) error {
+	deviceId, err := utility.GenerateRandomString(16)
+	if err != nil {
+		return err
+	}
+	deviceTokenString, err := ac.ServicesGroup.AuthService.CreateDeviceToken(user.Id, deviceId)
+	if err != nil {
+		return err
+	}
+
+	_, err = ac.ServicesGroup.TrustedDeviceService.Trust(user, deviceId, deviceName, c.Request.UserAgent(), c.ClientIP())
+	if err != nil {
+		return err
+	}
+
+	c.Header("X-DEVICE-TOKEN", deviceTokenString)
+	return nil
+}
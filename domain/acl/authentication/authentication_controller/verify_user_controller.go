@@ -33,7 +33,7 @@ func (ac *AuthController) verifyUser(c *gin.Context) {
 	// if refresh requested, do it
 	if refreshToken {
 		// create token
-		tokenString, err := ac.createToken(authUser.Id)
+		tokenString, err := ac.ServicesGroup.AuthService.CreateUserToken(authUser.Id)
 		if err != nil {
 			errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error generating token.", REDIRECT_LOGIN)
 			return
@@ -0,0 +1,208 @@
+package authentication_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/webauthn/webauthn_model"
+	"github.com/gocms-io/gocms/utility/api_utility"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+)
+
+/**
+* @api {get} /webauthn/register Get Passkey Registration Options
+* @apiDescription Start registering a passkey or security key. Pass the options to navigator.credentials.create().
+* @apiName GetWebauthnRegistrationOptions
+* @apiGroup Authentication
+*
+* @apiUse AuthHeader
+* @apiUse WebauthnCreationOptions
+* @apiPermission Authenticated
+ */
+func (ac *AuthController) getWebauthnRegistrationOptions(c *gin.Context) {
+	user, _ := api_utility.GetUserFromContext(c)
+
+	creationOptions, err := ac.ServicesGroup.WebauthnService.BeginRegistration(user)
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, errors.ApiError_Server, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, creationOptions)
+}
+
+/**
+* @api {post} /webauthn/register Register Passkey
+* @apiDescription Finish registering a passkey with the credential returned by navigator.credentials.create().
+* @apiName RegisterWebauthn
+* @apiGroup Authentication
+*
+* @apiUse AuthHeader
+* @apiUse WebauthnRegistrationInput
+* @apiUse WebauthnCredentialDisplay
+* @apiPermission Authenticated
+ */
+func (ac *AuthController) registerWebauthn(c *gin.Context) {
+	user, _ := api_utility.GetUserFromContext(c)
+
+	var registrationInput webauthn_model.RegistrationInput
+	if c.BindJSON(&registrationInput) != nil {
+		errors.Response(c, http.StatusBadRequest, errors.ApiError_Json, nil)
+		return
+	}
+
+	credential, err := ac.ServicesGroup.WebauthnService.FinishRegistration(user, &registrationInput)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Passkey couldn't be registered.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credential.GetWebauthnCredentialDisplay())
+}
+
+// requireWebauthnLogin hides the passwordless login routes while the setting is off
+func requireWebauthnLogin(c *gin.Context) {
+	if !context.Config.DbVars.WebauthnLoginEnabled {
+		errors.Response(c, http.StatusNotFound, "Passkey login is disabled.", nil)
+		return
+	}
+	c.Next()
+}
+
+/**
+* @api {post} /login/webauthn/options Get Passkey Login Options
+* @apiDescription Start a passwordless login with a discoverable passkey. Pass the options to navigator.credentials.get(). allowCredentials
+* is always empty so the options can't be used to find accounts. Only available when WEBAUTHN_LOGIN_ENABLED is set.
+* @apiName GetWebauthnLoginOptions
+* @apiGroup Authentication
+*
+* @apiUse WebauthnRequestOptions
+ */
+func (ac *AuthController) getWebauthnLoginOptions(c *gin.Context) {
+	requestOptions, err := ac.ServicesGroup.WebauthnService.BeginLogin()
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, errors.ApiError_Server, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, requestOptions)
+}
+
+/**
+* @api {post} /login/webauthn Login With Passkey
+* @apiDescription Finish a passwordless login with the credential returned by navigator.credentials.get(). When two-factor is enabled
+* and the authenticator verified the user a device token is also returned.
+* @apiName LoginWebauthn
+* @apiGroup Authentication
+*
+* @apiUse WebauthnAssertionInput
+* @apiUse UserDisplay
+* @apiUse AuthHeaderResponse
+* @apiSuccess (Response-Header) {string} [x-device-token]
+ */
+func (ac *AuthController) loginWebauthn(c *gin.Context) {
+	var assertionInput webauthn_model.AssertionInput
+	if c.BindJSON(&assertionInput) != nil {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, errors.ApiError_Webauthn, REDIRECT_LOGIN)
+		return
+	}
+
+	assertionResult, err := ac.ServicesGroup.WebauthnService.FinishLogin(&assertionInput)
+	if err != nil {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, errors.ApiError_Webauthn, REDIRECT_LOGIN)
+		return
+	}
+
+	user, err := ac.ServicesGroup.UserService.Get(assertionResult.UserId)
+	if err != nil || !user.Enabled {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, errors.ApiError_Webauthn, REDIRECT_LOGIN)
+		return
+	}
+
+	// verify user has activated email
+	if !user.Verified {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Your primary email has not yet been verified. A new verification email will be sent.", REDIRECT_LOGIN)
+		ac.ServicesGroup.EmailService.SendEmailActivationCode(user.Email)
+		return
+	}
+
+	// create token
+	tokenString, err := ac.ServicesGroup.AuthService.CreateUserToken(user.Id)
+	if err != nil {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error generating token.", REDIRECT_LOGIN)
+		return
+	}
+	c.Header("X-AUTH-TOKEN", tokenString)
+
+	// a user verified passkey is already two factors
+	if context.Config.DbVars.UseTwoFactor && assertionResult.UserVerified {
+		err = ac.trustDevice(c, user, assertionInput.DeviceName)
+		if err != nil {
+			errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error generating device token.", REDIRECT_VERIFY_DEVICE)
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, user.GetUserDisplay())
+}
+
+/**
+* @api {get} /verify-device/webauthn Get Passkey Two-Factor Options
+* @apiDescription Start verifying the device with a passkey instead of an emailed code. Pass the options to navigator.credentials.get().
+* @apiName GetWebauthnDeviceOptions
+* @apiGroup Authentication
+*
+* @apiUse UserAuthHeader
+* @apiUse WebauthnRequestOptions
+* @apiPermission Authenticated
+ */
+func (ac *AuthController) getWebauthnDeviceOptions(c *gin.Context) {
+	user, _ := api_utility.GetUserFromContext(c)
+
+	requestOptions, err := ac.ServicesGroup.WebauthnService.BeginTwoFactor(user)
+	if err != nil {
+		errors.ResponseWithSoftRedirect(c, http.StatusInternalServerError, errors.ApiError_Server, REDIRECT_VERIFY_DEVICE)
+		return
+	}
+	if len(requestOptions.AllowCredentials) == 0 {
+		errors.ResponseWithSoftRedirect(c, http.StatusNotFound, "No passkeys are registered.", REDIRECT_VERIFY_DEVICE)
+		return
+	}
+
+	c.JSON(http.StatusOK, requestOptions)
+}
+
+/**
+* @api {post} /verify-device/webauthn Verify Device With Passkey
+* @apiDescription Finish verifying the device with the credential returned by navigator.credentials.get().
+* @apiName VerifyDeviceWebauthn
+* @apiGroup Authentication
+*
+* @apiUse UserAuthHeader
+* @apiUse WebauthnAssertionInput
+* @apiSuccess (Response-Header) {string} x-device-token
+* @apiPermission Authenticated
+ */
+func (ac *AuthController) verifyDeviceWebauthn(c *gin.Context) {
+	user, _ := api_utility.GetUserFromContext(c)
+
+	var assertionInput webauthn_model.AssertionInput
+	if c.BindJSON(&assertionInput) != nil {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, errors.ApiError_Webauthn, REDIRECT_VERIFY_DEVICE)
+		return
+	}
+
+	_, err := ac.ServicesGroup.WebauthnService.FinishTwoFactor(user, &assertionInput)
+	if err != nil {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, errors.ApiError_Webauthn, REDIRECT_VERIFY_DEVICE)
+		return
+	}
+
+	err = ac.trustDevice(c, user, assertionInput.DeviceName)
+	if err != nil {
+		errors.ResponseWithSoftRedirect(c, http.StatusUnauthorized, "Error generating device token.", REDIRECT_LOGIN)
+		return
+	}
+
+	c.String(http.StatusOK, "ok")
+}
@@ -1,7 +1,6 @@
 package authentication_middleware
 
 import (
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/gocms-io/gocms/context"
 	"github.com/gocms-io/gocms/context/consts"
@@ -13,6 +12,13 @@ import (
 	"github.com/gocms-io/gocms/utility/log"
 )
 
+const (
+	REDIRECT_CHANGE_PASSWORD = "changePassword"
+	// routes still available while a password is expired, relative to the auth routes
+	PASSWORD_CHANGE_PATH = "/user/changePassword"
+	USER_PATH            = "/user"
+)
+
 type AuthMiddleware struct {
 	ServicesGroup *service.ServicesGroup
 }
@@ -29,10 +35,12 @@ func DefaultAuthMiddleware(sg *service.ServicesGroup) *AuthMiddleware {
 func (am *AuthMiddleware) ApplyAuthToRoutes(routes *routes.Routes) {
 	log.Debugf("Adding Authentication Middleware\n")
 	routes.Auth.Use(am.RequireAuthenticatedUser())
-	routes.PreTwofactor = routes.Auth
+	// branch before the device check so pre two-factor routes don't require a device
+	routes.PreTwofactor = routes.Auth.Group("")
 	if context.Config.DbVars.UseTwoFactor {
 		routes.Auth.Use(am.RequireAuthenticatedDevice())
 	}
+	routes.Auth.Use(am.RequireCurrentPassword(routes.Auth.BasePath()))
 }
 
 // middleware
@@ -46,6 +54,15 @@ func (am *AuthMiddleware) RequireAuthenticatedUser() gin.HandlerFunc {
 func (am *AuthMiddleware) RequireAuthenticatedDevice() gin.HandlerFunc {
 	return am.requireAuthedDevice
 }
+func (am *AuthMiddleware) RequireCurrentPassword(basePath string) gin.HandlerFunc {
+	allowed := map[string]string{
+		basePath + PASSWORD_CHANGE_PATH: http.MethodPut,
+		basePath + USER_PATH:            http.MethodGet,
+	}
+	return func(c *gin.Context) {
+		am.requireCurrentPassword(c, allowed)
+	}
+}
 
 // getAuthedUserIfPresent
 func (am *AuthMiddleware) addUserToContextIfValidToken(c *gin.Context) {
@@ -58,18 +75,18 @@ func (am *AuthMiddleware) addUserToContextIfValidToken(c *gin.Context) {
 		return
 	} else {
 		// parse token
-		token, err := am.verifyToken(authHeader)
+		claims, err := am.ServicesGroup.AuthService.ParseUserToken(authHeader)
 		if err != nil {
 			c.Next()
 			return
 		} else {
-			userId, ok := token.Claims.(jwt.MapClaims)["userId"].(float64)
-			if !ok {
+			userId, err := claims.GetUserId()
+			if err != nil {
 				c.Next()
 				return
 			} else {
 				// get user
-				user, err := am.ServicesGroup.UserService.Get(int64(userId))
+				user, err := am.ServicesGroup.UserService.Get(userId)
 				if err != nil {
 					c.Next()
 					return
@@ -118,37 +135,45 @@ func (am *AuthMiddleware) requireAuthedDevice(c *gin.Context) {
 		return
 	}
 
+	// device tokens are only valid for the user they were issued to
+	user, ok := api_utility.GetUserFromContext(c)
+	if !ok || user == nil {
+		errors.Response(c, http.StatusUnauthorized, errors.ApiError_UserToken, nil)
+		return
+	}
+
 	// parse token
-	_, err := am.verifyToken(authDeviceHeader)
+	claims, err := am.ServicesGroup.AuthService.ParseDeviceToken(authDeviceHeader, user.Id)
 	if err != nil {
 		errors.Response(c, http.StatusUnauthorized, errors.ApiError_DeviceToken, err)
 		return
 	}
 
+	// every device token must belong to a device that is still trusted
+	if !am.ServicesGroup.TrustedDeviceService.Verify(user.Id, claims.DeviceId, c.ClientIP()) {
+		errors.Response(c, http.StatusUnauthorized, errors.ApiError_DeviceToken, nil)
+		return
+	}
+	c.Set(consts.DEVICE_ID_KEY_FOR_GIN_CONTEXT, claims.DeviceId)
+
 	// continue
 	c.Next()
 
 }
 
-// verifyToken
-func (am *AuthMiddleware) verifyToken(authHeader string) (*jwt.Token, error) {
-	token, err := jwt.Parse(authHeader, func(token *jwt.Token) (interface{}, error) {
-		if jwt.SigningMethodRS256 != token.Method {
-			return nil, errors.New("Token signing method does not match.")
-		}
-
-		return context.Config.DbVars.RSAPub, nil
-	})
+// requireCurrentPassword blocks users with an expired password from everything except the allowed path and method pairs
+func (am *AuthMiddleware) requireCurrentPassword(c *gin.Context, allowed map[string]string) {
 
-	// check for parsing error
-	if err != nil {
-		return nil, err
+	user, ok := api_utility.GetUserFromContext(c)
+	if !ok || !am.ServicesGroup.AuthService.PasswordIsExpired(user) {
+		c.Next()
+		return
 	}
 
-	// check if token is valid
-	if !token.Valid {
-		return nil, err
+	if method, ok := allowed[c.Request.URL.Path]; ok && method == c.Request.Method {
+		c.Next()
+		return
 	}
 
-	return token, nil
+	errors.ResponseWithSoftRedirect(c, http.StatusForbidden, errors.ApiError_PasswordExpired, REDIRECT_CHANGE_PASSWORD)
 }
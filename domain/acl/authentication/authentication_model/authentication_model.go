@@ -45,6 +45,20 @@ type UserRegisterInput struct {
 	Email2   string `json:"email2,omitempty"`
 }
 
+/**
+* @apiDefine AcceptInvitationInput
+* @apiParam (Request) {string} email
+* @apiParam (Request) {string} code The invitation code sent to the email address.
+* @apiParam (Request) {string} password
+* @apiParam (Request) {string} [fullName]
+ */
+type AcceptInvitationInput struct {
+	Email    string `json:"email" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+	Password string `json:"password" binding:"required"`
+	FullName string `json:"fullName,omitempty"`
+}
+
 /**
 * @apiDefine ResetPasswordRequestInput
 * @apiParam (Request) {string} email
@@ -53,6 +67,24 @@ type ResetPasswordRequestInput struct {
 	Email string `json:"email" binding:"required"`
 }
 
+/**
+* @apiDefine MagicLinkRequestInput
+* @apiParam (Request) {string} email
+ */
+type MagicLinkRequestInput struct {
+	Email string `json:"email" binding:"required"`
+}
+
+/**
+* @apiDefine MagicLinkInput
+* @apiParam (Request) {string} email
+* @apiParam (Request) {string} code The code from the login link.
+ */
+type MagicLinkInput struct {
+	Email string `json:"email" form:"email" binding:"required"`
+	Code  string `json:"code" form:"code" binding:"required"`
+}
+
 /**
 * @apiDefine ResetPasswordInput
 * @apiParam (Request) {string} email
@@ -0,0 +1,31 @@
+package authentication_model
+
+import (
+	"github.com/dgrijalva/jwt-go"
+	"strconv"
+)
+
+const (
+	TOKEN_AUDIENCE_USER   = "gocms:user"
+	TOKEN_AUDIENCE_DEVICE = "gocms:device"
+)
+
+// TokenClaims are the claims of user and device tokens. sub is the user id and device tokens also carry the device id in did.
+type TokenClaims struct {
+	DeviceId string `json:"did,omitempty"`
+	// LegacyUserId is only set on tokens issued before standard claims were used.
+	LegacyUserId int64 `json:"userId,omitempty"`
+	jwt.StandardClaims
+}
+
+// IsLegacy is true for tokens issued before standard claims were used. Their exp is in milliseconds.
+func (tc *TokenClaims) IsLegacy() bool {
+	return tc.Audience == ""
+}
+
+func (tc *TokenClaims) GetUserId() (int64, error) {
+	if tc.IsLegacy() {
+		return tc.LegacyUserId, nil
+	}
+	return strconv.ParseInt(tc.Subject, 10, 64)
+}
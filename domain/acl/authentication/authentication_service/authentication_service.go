@@ -3,13 +3,14 @@ package authentication_service
 import (
 	"fmt"
 	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/authentication/authentication_model"
+	"github.com/gocms-io/gocms/domain/acl/signing_key/signing_key_service"
 	"github.com/gocms-io/gocms/domain/mail/mail_service"
 	"github.com/gocms-io/gocms/domain/secure_code/security_code_model"
 	"github.com/gocms-io/gocms/domain/user/user_model"
 	"github.com/gocms-io/gocms/init/repository"
 	"github.com/gocms-io/gocms/utility"
 	"github.com/gocms-io/gocms/utility/log"
-	"github.com/nbutton23/zxcvbn-go"
 	"golang.org/x/crypto/bcrypt"
 	"time"
 )
@@ -19,24 +20,39 @@ type IAuthService interface {
 	HashPassword(string) (string, error)
 	SendPasswordResetCode(string) error
 	VerifyPassword(string, string) bool
+	CheckPasswordResetCode(int64, string) bool
 	VerifyPasswordResetCode(int64, string) bool
 	SendTwoFactorCode(*user_model.User) error
 	VerifyTwoFactorCode(int64, string) bool
+	SendMagicLink(string) error
+	CheckMagicLink(string, string) bool
+	VerifyMagicLink(string, string) (*user_model.User, bool)
 	PasswordIsComplex(string) bool
+	ValidatePassword(string, *user_model.User) error
+	PasswordIsExpired(*user_model.User) bool
 	GetRandomCode(int64) (string, string, error)
+	CreateUserToken(userId int64) (string, error)
+	CreateDeviceToken(userId int64, deviceId string) (string, error)
+	ParseUserToken(string) (*authentication_model.TokenClaims, error)
+	ParseDeviceToken(tokenString string, userId int64) (*authentication_model.TokenClaims, error)
 }
 
 type AuthService struct {
 	MailService       mail_service.IMailService
+	SigningKeyService signing_key_service.ISigningKeyService
 	RepositoriesGroup *repository.RepositoriesGroup
 }
 
-func DefaultAuthService(rg *repository.RepositoriesGroup, mailService *mail_service.MailService) *AuthService {
+func DefaultAuthService(rg *repository.RepositoriesGroup, mailService *mail_service.MailService, signingKeyService signing_key_service.ISigningKeyService) *AuthService {
 	authService := &AuthService{
 		MailService:       mailService,
+		SigningKeyService: signingKeyService,
 		RepositoriesGroup: rg,
 	}
 
+	// clean up codes that can no longer be used
+	context.Schedule.AddTicker(time.Hour, authService.deleteExpiredCodes)
+
 	return authService
 
 }
@@ -48,7 +64,7 @@ func (as *AuthService) AuthUser(email string, password string) (*user_model.User
 	dbUser, err = as.RepositoriesGroup.UsersRepository.GetByEmail(email)
 
 	if err != nil {
-		log.Errorf("Error authing user: " + err.Error())
+		log.Errorf("Error authing user: %v\n", err.Error())
 		return nil, false
 	}
 
@@ -70,21 +86,17 @@ func (as *AuthService) VerifyPassword(passwordHash string, password string) bool
 	return true
 }
 
-func (as *AuthService) VerifyPasswordResetCode(id int64, code string) bool {
-
-	// get code
-	secureCode, err := as.RepositoriesGroup.SecureCodeRepository.GetLatestForUserByType(id, security_code_model.Code_ResetPassword)
-	if err != nil {
-		log.Errorf("error getting latest password reset code: %s", err.Error())
-		return false
-	}
+// CheckPasswordResetCode reports whether the reset code is valid without using it up.
+func (as *AuthService) CheckPasswordResetCode(id int64, code string) bool {
+	_, ok := as.checkPasswordResetCode(id, code)
+	return ok
+}
 
-	if ok := as.VerifyPassword(secureCode.Code, code); !ok {
-		return false
-	}
+// VerifyPasswordResetCode checks the reset code, uses it up and activates the primary email if it wasn't already.
+func (as *AuthService) VerifyPasswordResetCode(id int64, code string) bool {
 
-	// check within time
-	if time.Since(secureCode.Created) > (time.Minute * time.Duration(context.Config.DbVars.PasswordResetTimeout)) {
+	secureCode, ok := as.checkPasswordResetCode(id, code)
+	if !ok {
 		return false
 	}
 
@@ -121,6 +133,27 @@ func (as *AuthService) VerifyPasswordResetCode(id int64, code string) bool {
 	return true
 }
 
+func (as *AuthService) checkPasswordResetCode(id int64, code string) (*security_code_model.SecureCode, bool) {
+
+	// get code
+	secureCode, err := as.RepositoriesGroup.SecureCodeRepository.GetLatestForUserByType(id, security_code_model.Code_ResetPassword)
+	if err != nil {
+		log.Errorf("error getting latest password reset code: %s", err.Error())
+		return nil, false
+	}
+
+	if ok := as.VerifyPassword(secureCode.Code, code); !ok {
+		return nil, false
+	}
+
+	// check within time
+	if time.Since(secureCode.Created) > (time.Minute * time.Duration(context.Config.DbVars.PasswordResetTimeout)) {
+		return nil, false
+	}
+
+	return secureCode, true
+}
+
 func (as *AuthService) SendPasswordResetCode(email string) error {
 
 	// get user
@@ -157,7 +190,7 @@ func (as *AuthService) SendPasswordResetCode(email string) error {
 		BodyHTML: fmt.Sprintf("<h1>Password Reset</h1><p>To reset your password enter the code below into the app:</p><h3>%v</h3><p>The code will expire at: <b>%v</b></p>", code, expireTimeStr),
 	})
 	if err != nil {
-		log.Errorf("Error sending mail: " + err.Error())
+		log.Errorf("Error sending mail: %v\n", err.Error())
 	}
 
 	return nil
@@ -191,7 +224,7 @@ func (as *AuthService) SendTwoFactorCode(user *user_model.User) error {
 		BodyHTML: fmt.Sprintf("<h1>Verification Code</h1><p>Your verification code is: </p><h3>%v</h3><p>The code will expire at: <b>%v</b></p>", code, expireTimeStr),
 	})
 	if err != nil {
-		log.Errorf("Error sending mail: " + err.Error())
+		log.Errorf("Error sending mail: %v\n", err.Error())
 	}
 
 	return nil
@@ -249,11 +282,23 @@ func (as *AuthService) GetRandomCode(length int64) (string, string, error) {
 	return code, hashedCode, nil
 }
 
-func (as *AuthService) PasswordIsComplex(password string) bool {
-	userInputs := []string{}
-	score := zxcvbn.PasswordStrength(password, userInputs)
-	if score.Score < int(context.Config.DbVars.PasswordComplexity) {
-		return false
+// deleteExpiredCodes removes codes older than the timeout of their type. Invitations are kept so they can be resent.
+func (as *AuthService) deleteExpiredCodes() {
+	// activation codes are checked against the password reset timeout so keep them for the longer of the two
+	verifyEmailTimeout := context.Config.DbVars.EmailActivationTimeout
+	if context.Config.DbVars.PasswordResetTimeout > verifyEmailTimeout {
+		verifyEmailTimeout = context.Config.DbVars.PasswordResetTimeout
+	}
+
+	timeouts := map[security_code_model.SecureCodeType]int64{
+		security_code_model.Code_VerifyEmail:   verifyEmailTimeout,
+		security_code_model.Code_VerifyDevice:  context.Config.DbVars.TwoFactorCodeTimeout,
+		security_code_model.Code_ResetPassword: context.Config.DbVars.PasswordResetTimeout,
+		security_code_model.Code_MagicLink:     context.Config.DbVars.MagicLinkTimeout,
+	}
+
+	now := time.Now()
+	for codeType, timeout := range timeouts {
+		as.RepositoriesGroup.SecureCodeRepository.DeleteExpiredByType(codeType, now.Add(-time.Minute*utility.GetTimeout(timeout)))
 	}
-	return true
 }
@@ -0,0 +1,132 @@
+package authentication_service
+
+import (
+	"fmt"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/mail/mail_service"
+	"github.com/gocms-io/gocms/domain/secure_code/security_code_model"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"github.com/gocms-io/gocms/utility/log"
+	"net/url"
+	"time"
+)
+
+// a new login link isn't sent while the last one is younger than this
+const magicLinkCooldown = time.Minute
+
+// SendMagicLink emails a single use login link to the primary email of the account. Issuing a new link invalidates older ones.
+func (as *AuthService) SendMagicLink(email string) error {
+
+	// get user
+	user, err := as.RepositoriesGroup.UsersRepository.GetByEmail(email)
+	if err != nil {
+		return err
+	}
+	if !user.Enabled {
+		return nil
+	}
+
+	// don't flood the inbox
+	if latest, err := as.RepositoriesGroup.SecureCodeRepository.GetLatestForUserByType(user.Id, security_code_model.Code_MagicLink); err == nil {
+		if time.Since(latest.Created) < magicLinkCooldown {
+			return nil
+		}
+	}
+
+	// create code
+	code, hashedCode, err := as.GetRandomCode(32)
+	if err != nil {
+		return err
+	}
+
+	err = as.RepositoriesGroup.SecureCodeRepository.Add(&security_code_model.SecureCode{
+		UserId: user.Id,
+		Type:   security_code_model.Code_MagicLink,
+		Code:   hashedCode,
+	})
+	if err != nil {
+		return err
+	}
+
+	expireTimeStr := time.Now().Add(time.Minute * time.Duration(context.Config.DbVars.MagicLinkTimeout)).Format("03:04 pm")
+	loginLink := fmt.Sprintf("%v?email=%v&code=%v", context.Config.DbVars.MagicLinkUrl, url.QueryEscape(user.Email), url.QueryEscape(code))
+
+	// send email
+	err = as.MailService.Send(&mail_service.Mail{
+		To:      user.Email,
+		Subject: "Login Link",
+		Body: "Click on the link below to login:\n" +
+			loginLink + "\n\nThe link can only be used once and will expire at: " +
+			expireTimeStr + ".\n\nIf you didn't ask to login you can ignore this email.",
+		BodyHTML: fmt.Sprintf("<h1>Login Link</h1><h2>Click on the link below to login:</h2><p><a href='%v'>Login</a></p><p>The link can only be used once and will expire at: <b>%v</b></p><p>If you didn't ask to login you can ignore this email.</p>", loginLink, expireTimeStr),
+	})
+	if err != nil {
+		log.Errorf("Error sending mail: %v\n", err.Error())
+	}
+
+	return nil
+}
+
+// CheckMagicLink reports whether a login link is valid without using it up, so pages that are prefetched can check it safely.
+func (as *AuthService) CheckMagicLink(email string, code string) bool {
+	_, _, ok := as.checkMagicLink(email, code)
+	return ok
+}
+
+// VerifyMagicLink checks a login link and uses it up. Only the newest link sent to the account is valid.
+func (as *AuthService) VerifyMagicLink(email string, code string) (*user_model.User, bool) {
+	user, secureCode, ok := as.checkMagicLink(email, code)
+	if !ok {
+		return nil, false
+	}
+
+	// only the first request to use the link logs in
+	consumed, err := as.RepositoriesGroup.SecureCodeRepository.Consume(secureCode.Id)
+	if err != nil || !consumed {
+		return nil, false
+	}
+
+	// the link was sent to the primary email so following it verifies the address
+	if !user.Verified {
+		primaryEmail, err := as.RepositoriesGroup.EmailRepository.GetPrimaryByUserId(user.Id)
+		if err == nil {
+			primaryEmail.IsVerified = true
+			err = as.RepositoriesGroup.EmailRepository.Update(primaryEmail)
+		}
+		if err != nil {
+			log.Errorf("Verify magic link, error setting primary email to verified: %v\n", err.Error())
+		} else {
+			user.Verified = true
+		}
+	}
+
+	return user, true
+}
+
+// checkMagicLink finds the account and the newest link sent to it and checks the code and expiry.
+func (as *AuthService) checkMagicLink(email string, code string) (*user_model.User, *security_code_model.SecureCode, bool) {
+
+	// get user
+	user, err := as.RepositoriesGroup.UsersRepository.GetByEmail(email)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	// get code from db
+	secureCode, err := as.RepositoriesGroup.SecureCodeRepository.GetLatestForUserByType(user.Id, security_code_model.Code_MagicLink)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	// check code
+	if ok := as.VerifyPassword(secureCode.Code, code); !ok {
+		return nil, nil, false
+	}
+
+	// check within time
+	if time.Since(secureCode.Created) > (time.Minute * time.Duration(context.Config.DbVars.MagicLinkTimeout)) {
+		return nil, nil, false
+	}
+
+	return user, secureCode, true
+}
@@ -0,0 +1,96 @@
+package authentication_service
+
+import (
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/security"
+	"github.com/nbutton23/zxcvbn-go"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidatePassword checks a new password against the password policy. user may be nil when there is no account yet.
+// The user's name and email count against the strength score and their recent passwords can't be reused.
+func (as *AuthService) ValidatePassword(password string, user *user_model.User) error {
+
+	// length
+	if int64(utf8.RuneCountInString(password)) < context.Config.DbVars.PasswordMinLength {
+		return errors.NewToUser("Password is too short.")
+	}
+
+	// strength
+	score := zxcvbn.PasswordStrength(password, passwordUserInputs(user))
+	if score.Score < int(context.Config.DbVars.PasswordComplexity) {
+		return errors.NewToUser("Password is not complex enough.")
+	}
+
+	// reuse
+	if user != nil && user.Id != 0 && context.Config.DbVars.PasswordHistory > 0 {
+		previous := []string{}
+		if user.Password != "" {
+			previous = append(previous, user.Password)
+		}
+		passwordHistory, err := as.RepositoriesGroup.PasswordHistoryRepository.GetRecent(user.Id, context.Config.DbVars.PasswordHistory-1)
+		if err != nil {
+			return err
+		}
+		for _, entry := range passwordHistory {
+			previous = append(previous, entry.Password)
+		}
+		for _, hash := range previous {
+			if as.VerifyPassword(hash, password) {
+				return errors.NewToUser("Password was used recently.")
+			}
+		}
+	}
+
+	// breached
+	if context.Config.DbVars.PasswordBreachList != "" {
+		breached, err := security.PasswordIsBreached(context.Config.DbVars.PasswordBreachList, password)
+		if err != nil {
+			// the check is configured so an unreadable list refuses the password instead of skipping it
+			log.Errorf("Error checking breached password list, password refused: %s\n", err.Error())
+			return errors.NewToUser("Password couldn't be checked against breached passwords. Please try again later.")
+		}
+		if breached {
+			return errors.NewToUser("Password has appeared in a data breach.")
+		}
+	}
+
+	return nil
+}
+
+// PasswordIsComplex checks a password against the policy without any user context.
+func (as *AuthService) PasswordIsComplex(password string) bool {
+	return as.ValidatePassword(password, nil) == nil
+}
+
+// PasswordIsExpired is true when the user must change their password before doing anything else. Users who never chose
+// a password sign in another way and are not asked to change it.
+func (as *AuthService) PasswordIsExpired(user *user_model.User) bool {
+	if context.Config.DbVars.PasswordMaxAge <= 0 || user.PasswordChanged == nil {
+		return false
+	}
+	maxAge := time.Duration(context.Config.DbVars.PasswordMaxAge) * 24 * time.Hour
+	return time.Since(*user.PasswordChanged) > maxAge
+}
+
+func passwordUserInputs(user *user_model.User) []string {
+	userInputs := []string{}
+	if user == nil {
+		return userInputs
+	}
+
+	userInputs = append(userInputs, strings.Fields(user.FullName)...)
+	if user.Email != "" {
+		userInputs = append(userInputs, user.Email)
+		if at := strings.Index(user.Email, "@"); at > 0 {
+			userInputs = append(userInputs, user.Email[:at])
+		}
+	}
+
+	return userInputs
+}
@@ -0,0 +1,130 @@
+package authentication_service
+
+import (
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/authentication/authentication_model"
+	"github.com/gocms-io/gocms/utility"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"strconv"
+	"time"
+)
+
+// CreateUserToken creates a token for the user that expires after USER_AUTHENTICATION_TIMEOUT minutes.
+func (as *AuthService) CreateUserToken(userId int64) (string, error) {
+	claims, err := newTokenClaims(userId, authentication_model.TOKEN_AUDIENCE_USER, context.Config.DbVars.UserAuthTimeout)
+	if err != nil {
+		return "", err
+	}
+
+	tokenString, err := as.SigningKeyService.Sign(claims)
+	if err != nil {
+		log.Errorf("Error signing token for account %v: %v\n", userId, err.Error())
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// CreateDeviceToken creates a token for a device verified by the user that expires after DEVICE_AUTHENTICATION_TIMEOUT minutes.
+func (as *AuthService) CreateDeviceToken(userId int64, deviceId string) (string, error) {
+	claims, err := newTokenClaims(userId, authentication_model.TOKEN_AUDIENCE_DEVICE, context.Config.DbVars.DeviceAuthTimeout)
+	if err != nil {
+		return "", err
+	}
+	claims.DeviceId = deviceId
+
+	tokenString, err := as.SigningKeyService.Sign(claims)
+	if err != nil {
+		log.Errorf("Error signing device token for account %v: %v\n", userId, err.Error())
+		return "", err
+	}
+
+	return tokenString, nil
+}
+
+// ParseUserToken verifies a user token and returns its claims.
+func (as *AuthService) ParseUserToken(tokenString string) (*authentication_model.TokenClaims, error) {
+	claims, err := as.parseToken(tokenString, authentication_model.TOKEN_AUDIENCE_USER)
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.IsLegacy() && claims.LegacyUserId == 0 {
+		return nil, errors.New("Token has no user.")
+	}
+
+	return claims, nil
+}
+
+// ParseDeviceToken verifies a device token and that it was issued to the user.
+func (as *AuthService) ParseDeviceToken(tokenString string, userId int64) (*authentication_model.TokenClaims, error) {
+	claims, err := as.parseToken(tokenString, authentication_model.TOKEN_AUDIENCE_DEVICE)
+	if err != nil {
+		return nil, err
+	}
+
+	// legacy device tokens can't be told apart from legacy user tokens so the device must be verified again
+	if claims.IsLegacy() {
+		return nil, errors.New("Legacy device tokens are no longer accepted.")
+	}
+
+	if claims.Subject != strconv.FormatInt(userId, 10) {
+		return nil, errors.New("Device token was issued to another user.")
+	}
+	if claims.DeviceId == "" {
+		return nil, errors.New("Device token has no device.")
+	}
+
+	return claims, nil
+}
+
+func (as *AuthService) parseToken(tokenString string, audience string) (*authentication_model.TokenClaims, error) {
+	var claims authentication_model.TokenClaims
+	_, err := as.SigningKeyService.Parse(tokenString, &claims)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().Unix()
+	if claims.IsLegacy() {
+		if !context.Config.DbVars.AcceptLegacyTokens {
+			return nil, errors.New("Legacy tokens are no longer accepted.")
+		}
+		// legacy tokens set exp in milliseconds
+		if claims.ExpiresAt/1000 < now {
+			return nil, errors.New("Token is expired.")
+		}
+		return &claims, nil
+	}
+
+	if !claims.VerifyAudience(audience, true) {
+		return nil, errors.New("Token audience does not match.")
+	}
+	if !claims.VerifyIssuer(context.Config.DbVars.TokenIssuer, true) {
+		return nil, errors.New("Token issuer does not match.")
+	}
+	if !claims.VerifyExpiresAt(now, true) {
+		return nil, errors.New("Token is expired or has no expiry.")
+	}
+
+	return &claims, nil
+}
+
+func newTokenClaims(userId int64, audience string, timeoutMinutes int64) (*authentication_model.TokenClaims, error) {
+	jti, err := utility.GenerateRandomString(16)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	claims := &authentication_model.TokenClaims{}
+	claims.Subject = strconv.FormatInt(userId, 10)
+	claims.Issuer = context.Config.DbVars.TokenIssuer
+	claims.Audience = audience
+	claims.Id = jti
+	claims.IssuedAt = now.Unix()
+	claims.ExpiresAt = now.Add(time.Minute * utility.GetTimeout(timeoutMinutes)).Unix()
+
+	return claims, nil
+}
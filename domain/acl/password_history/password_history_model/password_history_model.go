@@ -0,0 +1,11 @@
+package password_history_model
+
+import "time"
+
+// PasswordHistory is the hash of a password a user used before. Kept so passwords can't be reused.
+type PasswordHistory struct {
+	Id       int64     `db:"id"`
+	UserId   int64     `db:"userId"`
+	Password string    `db:"password"`
+	Created  time.Time `db:"created"`
+}
@@ -0,0 +1,72 @@
+package password_history_repository
+
+import (
+	"github.com/gocms-io/gocms/domain/acl/password_history/password_history_model"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IPasswordHistoryRepository interface {
+	GetRecent(userId int64, limit int64) ([]*password_history_model.PasswordHistory, error)
+	Add(*password_history_model.PasswordHistory) error
+	Prune(userId int64, keep int64) error
+}
+
+type PasswordHistoryRepository struct {
+	database *sqlx.DB
+}
+
+func DefaultPasswordHistoryRepository(dbx *sqlx.DB) *PasswordHistoryRepository {
+	passwordHistoryRepository := &PasswordHistoryRepository{
+		database: dbx,
+	}
+
+	return passwordHistoryRepository
+}
+
+// get the most recent previous passwords for a user newest first
+func (phr *PasswordHistoryRepository) GetRecent(userId int64, limit int64) ([]*password_history_model.PasswordHistory, error) {
+	passwordHistory := []*password_history_model.PasswordHistory{}
+	err := phr.database.Select(&passwordHistory, `
+	SELECT * FROM gocms_password_history WHERE userId=? ORDER BY created DESC, id DESC LIMIT ?
+	`, userId, limit)
+	if err != nil {
+		log.Errorf("Error getting password history from database: %s", err.Error())
+		return nil, err
+	}
+
+	return passwordHistory, nil
+}
+
+func (phr *PasswordHistoryRepository) Add(passwordHistory *password_history_model.PasswordHistory) error {
+	passwordHistory.Created = time.Now()
+	result, err := phr.database.NamedExec(`
+	INSERT INTO gocms_password_history (userId, password, created) VALUES (:userId, :password, :created)
+	`, passwordHistory)
+	if err != nil {
+		log.Errorf("Error adding password history to database: %s", err.Error())
+		return err
+	}
+	id, _ := result.LastInsertId()
+	passwordHistory.Id = id
+
+	return nil
+}
+
+// delete all but the newest keep entries for a user
+func (phr *PasswordHistoryRepository) Prune(userId int64, keep int64) error {
+	_, err := phr.database.Exec(`
+	DELETE FROM gocms_password_history WHERE userId=? AND id NOT IN (
+		SELECT id FROM (
+			SELECT id FROM gocms_password_history WHERE userId=? ORDER BY created DESC, id DESC LIMIT ?
+		) AS recent
+	)
+	`, userId, userId, keep)
+	if err != nil {
+		log.Errorf("Error pruning password history in database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
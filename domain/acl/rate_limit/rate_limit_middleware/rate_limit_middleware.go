@@ -0,0 +1,84 @@
+package rate_limit_middleware
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_model"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_service"
+	"github.com/gocms-io/gocms/routes"
+	"github.com/gocms-io/gocms/utility/api_utility"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"net/http"
+	"time"
+)
+
+// PublicRateLimit applies the Public route group limit configured in settings. Requests are counted per client ip.
+// Settings are read on each request so a settings refresh takes effect without a restart.
+func PublicRateLimit(rateLimitService rate_limit_service.IRateLimitService) gin.HandlerFunc {
+	log.Debugf("Adding Rate Limit Middleware for %v routes\n", routes.PUBLIC)
+	return func(c *gin.Context) {
+		take(c, rateLimitService, routes.PUBLIC, &rate_limit_model.RateLimit{
+			Requests: context.Config.DbVars.RateLimitPublicRequests,
+			Window:   context.Config.DbVars.RateLimitPublicWindow,
+			By:       rate_limit_model.RATE_LIMIT_BY_IP,
+		})
+	}
+}
+
+// AuthRateLimit applies the Auth route group limit configured in settings. Requests are counted per user.
+func AuthRateLimit(rateLimitService rate_limit_service.IRateLimitService) gin.HandlerFunc {
+	log.Debugf("Adding Rate Limit Middleware for %v routes\n", routes.AUTH)
+	return func(c *gin.Context) {
+		take(c, rateLimitService, routes.AUTH, &rate_limit_model.RateLimit{
+			Requests: context.Config.DbVars.RateLimitAuthRequests,
+			Window:   context.Config.DbVars.RateLimitAuthWindow,
+			By:       rate_limit_model.RATE_LIMIT_BY_USER,
+		})
+	}
+}
+
+// RateLimit applies a fixed limit. name must be unique to the route(s) being limited.
+func RateLimit(rateLimitService rate_limit_service.IRateLimitService, name string, limit *rate_limit_model.RateLimit) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		take(c, rateLimitService, name, limit)
+	}
+}
+
+func take(c *gin.Context, rateLimitService rate_limit_service.IRateLimitService, name string, limit *rate_limit_model.RateLimit) {
+	if !limit.Enabled() {
+		c.Next()
+		return
+	}
+
+	status, err := rateLimitService.Take(name, requestKey(c, limit), limit)
+	if err != nil {
+		// don't block requests when the counter store is unavailable
+		log.Errorf("Error applying rate limit %v: %s\n", name, err.Error())
+		c.Next()
+		return
+	}
+
+	c.Header("X-RateLimit-Limit", fmt.Sprint(status.Limit))
+	c.Header("X-RateLimit-Remaining", fmt.Sprint(status.Remaining))
+	c.Header("X-RateLimit-Reset", fmt.Sprint(status.Reset.Unix()))
+
+	if !status.Allowed {
+		retryAfter := int64(status.Reset.Sub(time.Now()).Seconds()) + 1
+		c.Header("Retry-After", fmt.Sprint(retryAfter))
+		errors.Response(c, http.StatusTooManyRequests, errors.ApiError_RateLimit, nil)
+		return
+	}
+
+	c.Next()
+}
+
+func requestKey(c *gin.Context, limit *rate_limit_model.RateLimit) string {
+	if limit.By == rate_limit_model.RATE_LIMIT_BY_USER {
+		if authUser, ok := api_utility.GetUserFromContext(c); ok {
+			return fmt.Sprintf("user:%v", authUser.Id)
+		}
+	}
+	return fmt.Sprintf("ip:%v", c.ClientIP())
+}
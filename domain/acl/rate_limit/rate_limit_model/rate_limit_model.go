@@ -0,0 +1,42 @@
+package rate_limit_model
+
+import "time"
+
+const (
+	// key requests on the client ip address
+	RATE_LIMIT_BY_IP = "ip"
+	// key requests on the authenticated user. Falls back to the client ip when no user is on the context.
+	RATE_LIMIT_BY_USER = "user"
+
+	STORE_MEMORY   = "memory"
+	STORE_DATABASE = "database"
+)
+
+// RateLimit describes a fixed window quota that can be applied to a route group or plugin route.
+type RateLimit struct {
+	// Requests allowed within each window. A value of 0 disables the limit.
+	Requests int64 `json:"requests"`
+	// Window length in seconds.
+	Window int64 `json:"window"`
+	// By the value requests are counted against. Either "ip" or "user". Defaults to "ip".
+	By string `json:"by,omitempty"`
+}
+
+func (rl *RateLimit) Enabled() bool {
+	return rl != nil && rl.Requests > 0 && rl.Window > 0
+}
+
+// RateLimitStatus is the result of counting a request against a RateLimit.
+type RateLimitStatus struct {
+	Limit     int64
+	Remaining int64
+	Reset     time.Time
+	Allowed   bool
+}
+
+type RateLimitCounter struct {
+	RateKey     string    `db:"rateKey"`
+	WindowStart time.Time `db:"windowStart"`
+	Count       int64     `db:"count"`
+	Expires     time.Time `db:"expires"`
+}
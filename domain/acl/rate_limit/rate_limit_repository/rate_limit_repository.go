@@ -0,0 +1,74 @@
+package rate_limit_repository
+
+import (
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IRateLimitRepository interface {
+	Increment(rateKey string, windowStart time.Time, expires time.Time) (int64, error)
+	DeleteExpired() error
+}
+
+type RateLimitRepository struct {
+	database *sqlx.DB
+}
+
+func DefaultRateLimitRepository(dbx *sqlx.DB) *RateLimitRepository {
+	rateLimitRepository := &RateLimitRepository{
+		database: dbx,
+	}
+
+	return rateLimitRepository
+}
+
+// increment the counter for the key and window and return the new count
+func (rlr *RateLimitRepository) Increment(rateKey string, windowStart time.Time, expires time.Time) (int64, error) {
+	tx, err := rlr.database.Beginx()
+	if err != nil {
+		log.Errorf("Error starting rate limit transaction: %s", err.Error())
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+	INSERT INTO gocms_rate_limits (rateKey, windowStart, count, expires) VALUES (?, ?, 1, ?)
+	ON DUPLICATE KEY UPDATE count = count + 1
+	`, rateKey, windowStart, expires)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("Error incrementing rate limit counter: %s", err.Error())
+		return 0, err
+	}
+
+	var count int64
+	err = tx.Get(&count, `
+	SELECT count FROM gocms_rate_limits WHERE rateKey=? AND windowStart=?
+	`, rateKey, windowStart)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("Error getting rate limit counter: %s", err.Error())
+		return 0, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Errorf("Error committing rate limit counter: %s", err.Error())
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// remove all counters whose window has passed
+func (rlr *RateLimitRepository) DeleteExpired() error {
+	_, err := rlr.database.Exec(`
+	DELETE FROM gocms_rate_limits WHERE expires < ?
+	`, time.Now())
+	if err != nil {
+		log.Errorf("Error deleting expired rate limit counters: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package rate_limit_service
+
+import (
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_repository"
+	"sync"
+	"time"
+)
+
+// ICounterStore counts requests for a key within a fixed window.
+type ICounterStore interface {
+	Increment(key string, windowStart time.Time, window time.Duration) (int64, error)
+	Cleanup()
+}
+
+// memory store. counters only apply to this instance of GoCMS.
+type memoryCounter struct {
+	windowStart time.Time
+	expires     time.Time
+	count       int64
+}
+
+type MemoryCounterStore struct {
+	mu       sync.Mutex
+	counters map[string]*memoryCounter
+}
+
+func NewMemoryCounterStore() *MemoryCounterStore {
+	return &MemoryCounterStore{
+		counters: make(map[string]*memoryCounter),
+	}
+}
+
+func (ms *MemoryCounterStore) Increment(key string, windowStart time.Time, window time.Duration) (int64, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	counter, ok := ms.counters[key]
+	if !ok || !counter.windowStart.Equal(windowStart) {
+		counter = &memoryCounter{
+			windowStart: windowStart,
+			expires:     windowStart.Add(window),
+		}
+		ms.counters[key] = counter
+	}
+	counter.count++
+
+	return counter.count, nil
+}
+
+func (ms *MemoryCounterStore) Cleanup() {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	for key, counter := range ms.counters {
+		if counter.expires.Before(now) {
+			delete(ms.counters, key)
+		}
+	}
+}
+
+// database store. counters are shared between every GoCMS instance using the same database.
+type DatabaseCounterStore struct {
+	repository rate_limit_repository.IRateLimitRepository
+}
+
+func NewDatabaseCounterStore(repository rate_limit_repository.IRateLimitRepository) *DatabaseCounterStore {
+	return &DatabaseCounterStore{
+		repository: repository,
+	}
+}
+
+func (ds *DatabaseCounterStore) Increment(key string, windowStart time.Time, window time.Duration) (int64, error) {
+	return ds.repository.Increment(key, windowStart, windowStart.Add(window))
+}
+
+func (ds *DatabaseCounterStore) Cleanup() {
+	ds.repository.DeleteExpired()
+}
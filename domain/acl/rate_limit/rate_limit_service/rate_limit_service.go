@@ -0,0 +1,75 @@
+package rate_limit_service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_model"
+	"github.com/gocms-io/gocms/init/repository"
+	"github.com/gocms-io/gocms/utility/log"
+	"time"
+)
+
+type IRateLimitService interface {
+	Take(name string, key string, limit *rate_limit_model.RateLimit) (*rate_limit_model.RateLimitStatus, error)
+}
+
+type RateLimitService struct {
+	store ICounterStore
+}
+
+func DefaultRateLimitService(rg *repository.RepositoriesGroup) *RateLimitService {
+
+	var store ICounterStore
+	switch context.Config.DbVars.RateLimitStore {
+	case rate_limit_model.STORE_DATABASE:
+		store = NewDatabaseCounterStore(rg.RateLimitRepository)
+	case rate_limit_model.STORE_MEMORY:
+		store = NewMemoryCounterStore()
+	default:
+		log.Warningf("Unknown rate limit store '%v'. Using %v store.\n", context.Config.DbVars.RateLimitStore, rate_limit_model.STORE_MEMORY)
+		store = NewMemoryCounterStore()
+	}
+
+	rateLimitService := &RateLimitService{
+		store: store,
+	}
+
+	// remove expired counters
+	context.Schedule.AddTicker(time.Minute, store.Cleanup)
+
+	return rateLimitService
+}
+
+// count a request for key against the named limit and report if it is allowed
+func (rls *RateLimitService) Take(name string, key string, limit *rate_limit_model.RateLimit) (*rate_limit_model.RateLimitStatus, error) {
+	window := time.Duration(limit.Window) * time.Second
+	windowStart := time.Now().Truncate(window)
+
+	// keep keys within the size of the database column
+	rateKey := fmt.Sprintf("%v:%v", name, key)
+	if len(rateKey) > 255 {
+		sum := sha256.Sum256([]byte(rateKey))
+		rateKey = hex.EncodeToString(sum[:])
+	}
+
+	count, err := rls.store.Increment(rateKey, windowStart, window)
+	if err != nil {
+		return nil, err
+	}
+
+	remaining := limit.Requests - count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	status := &rate_limit_model.RateLimitStatus{
+		Limit:     limit.Requests,
+		Remaining: remaining,
+		Reset:     windowStart.Add(window),
+		Allowed:   count <= limit.Requests,
+	}
+
+	return status, nil
+}
@@ -0,0 +1,89 @@
+package signing_key_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/acl/access_control/access_control_middleware"
+	"github.com/gocms-io/gocms/domain/acl/permissions"
+	"github.com/gocms-io/gocms/init/service"
+	"github.com/gocms-io/gocms/routes"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+)
+
+type SigningKeyController struct {
+	routes        *routes.Routes
+	ServicesGroup *service.ServicesGroup
+	adminRoutes   *gin.RouterGroup
+}
+
+func DefaultSigningKeyController(routes *routes.Routes, sg *service.ServicesGroup) *SigningKeyController {
+	signingKeyController := &SigningKeyController{
+		routes:        routes,
+		ServicesGroup: sg,
+	}
+
+	// add acl rules to route
+	signingKeyController.adminRoutes = routes.Auth.Group("/admin", access_control_middleware.RequirePermission(sg.AclService, permissions.SUPER_ADMIN))
+
+	signingKeyController.Default()
+	return signingKeyController
+}
+
+func (skc *SigningKeyController) Default() {
+	skc.routes.Root.GET("/.well-known/jwks.json", skc.getJWKS)
+	skc.adminRoutes.GET("/signing-keys", skc.getAll)
+	skc.adminRoutes.POST("/signing-keys/rotate", skc.rotate)
+}
+
+/**
+* @api {get} /.well-known/jwks.json Get Token Signing Keys
+* @apiDescription Public keys for verifying user and device tokens. Tokens name their key in the kid header. Retired keys are listed
+* until tokens signed with them expire.
+* @apiName GetJWKS
+* @apiGroup Authentication
+*
+* @apiSuccess (Response) {Object[]} keys JSON Web Keys as described in RFC 7517.
+ */
+func (skc *SigningKeyController) getJWKS(c *gin.Context) {
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, skc.ServicesGroup.SigningKeyService.GetJWKS())
+}
+
+/**
+* @api {get} /admin/signing-keys Get Signing Keys
+* @apiName GetSigningKeys
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiUse SigningKeyDisplay
+* @apiPermission Admin
+ */
+func (skc *SigningKeyController) getAll(c *gin.Context) {
+	signingKeys, err := skc.ServicesGroup.SigningKeyService.GetKeys()
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, "Couldn't get signing keys.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, signingKeys)
+}
+
+/**
+* @api {post} /admin/signing-keys/rotate Rotate Signing Key
+* @apiDescription Create a new token signing key and retire the current one. Tokens signed with the retired key stay valid until they expire.
+* @apiName RotateSigningKey
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiUse SigningKeyDisplay
+* @apiPermission Admin
+ */
+func (skc *SigningKeyController) rotate(c *gin.Context) {
+	err := skc.ServicesGroup.SigningKeyService.Rotate()
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, "Couldn't rotate signing key.", err)
+		return
+	}
+
+	skc.getAll(c)
+}
@@ -0,0 +1,78 @@
+package signing_key_model
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"math/big"
+	"time"
+)
+
+const ALGORITHM_RS256 = "RS256"
+
+// SigningKey is a key pair used to sign user and device tokens. Only the newest key that hasn't been retired signs new tokens.
+// Retired keys still verify tokens until they expire.
+type SigningKey struct {
+	Kid        string     `db:"kid"`
+	Algorithm  string     `db:"algorithm"`
+	PrivateKey string     `db:"privateKey"`
+	PublicKey  string     `db:"publicKey"`
+	Created    time.Time  `db:"created"`
+	Retired    *time.Time `db:"retired"`
+	Expires    *time.Time `db:"expires"`
+}
+
+/**
+* @apiDefine SigningKeyDisplay
+* @apiSuccess (Response) {string} kid Key id sent in the kid header of tokens signed with this key.
+* @apiSuccess (Response) {string} algorithm
+* @apiSuccess (Response) {bool} active True for the key signing new tokens.
+* @apiSuccess (Response) {Date} created
+* @apiSuccess (Response) {Date} [retired]
+* @apiSuccess (Response) {Date} [expires] Tokens signed with this key are rejected after this time.
+ */
+type SigningKeyDisplay struct {
+	Kid       string     `json:"kid"`
+	Algorithm string     `json:"algorithm"`
+	Active    bool       `json:"active"`
+	Created   time.Time  `json:"created"`
+	Retired   *time.Time `json:"retired,omitempty"`
+	Expires   *time.Time `json:"expires,omitempty"`
+}
+
+// JWK is the public half of a signing key as described in RFC 7517.
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func (sk *SigningKey) GetSigningKeyDisplay(active bool) *SigningKeyDisplay {
+	signingKeyDisplay := SigningKeyDisplay{
+		Kid:       sk.Kid,
+		Algorithm: sk.Algorithm,
+		Active:    active,
+		Created:   sk.Created,
+		Retired:   sk.Retired,
+		Expires:   sk.Expires,
+	}
+	return &signingKeyDisplay
+}
+
+func NewRsaJWK(kid string, publicKey *rsa.PublicKey) *JWK {
+	jwk := JWK{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: ALGORITHM_RS256,
+		Kid: kid,
+		N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	}
+	return &jwk
+}
@@ -0,0 +1,125 @@
+package signing_key_repository
+
+import (
+	"github.com/gocms-io/gocms/domain/acl/signing_key/signing_key_model"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type ISigningKeyRepository interface {
+	GetAll() ([]*signing_key_model.SigningKey, error)
+	AddIfNoActiveKey(*signing_key_model.SigningKey) (bool, error)
+	Rotate(activeKid string, newKey *signing_key_model.SigningKey, retired time.Time, expires time.Time) (bool, error)
+	DeleteExpired(time.Time) error
+}
+
+type SigningKeyRepository struct {
+	database *sqlx.DB
+}
+
+func DefaultSigningKeyRepository(dbx *sqlx.DB) *SigningKeyRepository {
+	signingKeyRepository := &SigningKeyRepository{
+		database: dbx,
+	}
+
+	return signingKeyRepository
+}
+
+// get all signing keys newest first
+func (skr *SigningKeyRepository) GetAll() ([]*signing_key_model.SigningKey, error) {
+	var signingKeys []*signing_key_model.SigningKey
+	err := skr.database.Select(&signingKeys, `
+	SELECT * FROM gocms_signing_keys ORDER BY created DESC
+	`)
+	if err != nil {
+		log.Errorf("Error getting signing keys from database: %s", err.Error())
+		return nil, err
+	}
+
+	return signingKeys, nil
+}
+
+// AddIfNoActiveKey adds the key unless there is already an active key, ie. one another instance just added. It reports
+// whether the key was added.
+func (skr *SigningKeyRepository) AddIfNoActiveKey(signingKey *signing_key_model.SigningKey) (bool, error) {
+	result, err := skr.database.NamedExec(`
+	INSERT INTO gocms_signing_keys (kid, algorithm, privateKey, publicKey, created)
+	SELECT :kid, :algorithm, :privateKey, :publicKey, :created FROM DUAL
+	WHERE NOT EXISTS (SELECT 1 FROM gocms_signing_keys WHERE retired IS NULL)
+	`, signingKey)
+	if err != nil {
+		log.Errorf("Error adding signing key to database: %s", err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// Rotate adds the new key and retires every other active key in one transaction, but only while activeKid is still the
+// active key. It reports false without changing anything when another instance rotated first.
+func (skr *SigningKeyRepository) Rotate(activeKid string, newKey *signing_key_model.SigningKey, retired time.Time, expires time.Time) (bool, error) {
+	tx, err := skr.database.Beginx()
+	if err != nil {
+		log.Errorf("Error starting signing key rotation: %s", err.Error())
+		return false, err
+	}
+
+	// lock the active keys so concurrent rotations wait for this one and then see it
+	var activeKids []string
+	err = tx.Select(&activeKids, `
+	SELECT kid FROM gocms_signing_keys WHERE retired IS NULL ORDER BY created DESC FOR UPDATE
+	`)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("Error locking active signing keys: %s", err.Error())
+		return false, err
+	}
+	if len(activeKids) == 0 || activeKids[0] != activeKid {
+		tx.Rollback()
+		return false, nil
+	}
+
+	_, err = tx.NamedExec(`
+	INSERT INTO gocms_signing_keys (kid, algorithm, privateKey, publicKey, created) VALUES (:kid, :algorithm, :privateKey, :publicKey, :created)
+	`, newKey)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("Error adding signing key to database: %s", err.Error())
+		return false, err
+	}
+
+	_, err = tx.Exec(`
+	UPDATE gocms_signing_keys SET retired=?, expires=? WHERE retired IS NULL AND kid<>?
+	`, retired, expires, newKey.Kid)
+	if err != nil {
+		tx.Rollback()
+		log.Errorf("Error retiring signing keys in database: %s", err.Error())
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		log.Errorf("Error committing signing key rotation: %s", err.Error())
+		return false, err
+	}
+
+	return true, nil
+}
+
+// delete retired keys that expired before t
+func (skr *SigningKeyRepository) DeleteExpired(t time.Time) error {
+	_, err := skr.database.Exec(`
+	DELETE FROM gocms_signing_keys WHERE expires IS NOT NULL AND expires<?
+	`, t)
+	if err != nil {
+		log.Errorf("Error deleting expired signing keys from database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
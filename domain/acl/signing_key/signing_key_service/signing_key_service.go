@@ -0,0 +1,379 @@
+package signing_key_service
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/signing_key/signing_key_model"
+	"github.com/gocms-io/gocms/init/repository"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/security"
+	"github.com/gocms-io/gocms/utility/sqlUtl"
+	"sync"
+	"time"
+)
+
+const rsaKeySize = 2048
+
+// an unknown kid reloads the keys at most this often so a key rotated by another instance is picked up before the ticker
+const kidMissRefreshInterval = 10 * time.Second
+
+type ISigningKeyService interface {
+	Sign(claims jwt.Claims) (string, error)
+	Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error)
+	GetJWKS() *signing_key_model.JWKS
+	GetKeys() ([]*signing_key_model.SigningKeyDisplay, error)
+	Rotate() error
+	RefreshKeys() error
+}
+
+type SigningKeyService struct {
+	RepositoriesGroup *repository.RepositoriesGroup
+	mu                sync.RWMutex
+	signingKid        string
+	signingKey        *rsa.PrivateKey
+	verifyKeys        map[string]*rsa.PublicKey
+	legacyKid         string
+	jwks              *signing_key_model.JWKS
+	kidMissMu         sync.Mutex
+	lastKidMissReload time.Time
+}
+
+func DefaultSigningKeyService(rg *repository.RepositoriesGroup) *SigningKeyService {
+	signingKeyService := &SigningKeyService{
+		RepositoriesGroup: rg,
+		verifyKeys:        make(map[string]*rsa.PublicKey),
+		jwks:              &signing_key_model.JWKS{Keys: []signing_key_model.JWK{}},
+	}
+
+	// tokens issued before signing keys were rotated have no kid and were signed with RSA_PUB
+	if context.Config.DbVars.RSAPub != nil {
+		signingKeyService.legacyKid = kidForPublicKey(context.Config.DbVars.RSAPub)
+	}
+
+	err := signingKeyService.RefreshKeys()
+	if err != nil {
+		log.Fatalf("Error loading signing keys: %s\n", err.Error())
+	}
+
+	// check for rotation and pick up keys rotated by other instances
+	context.Schedule.AddTicker(time.Hour, func() {
+		if err := signingKeyService.rotateIfDue(); err != nil {
+			log.Errorf("Error rotating signing keys: %s\n", err.Error())
+		}
+		if err := signingKeyService.RefreshKeys(); err != nil {
+			log.Errorf("Error refreshing signing keys: %s\n", err.Error())
+		}
+	})
+
+	return signingKeyService
+}
+
+// Sign signs the claims with the active key and adds its kid to the token header.
+func (sks *SigningKeyService) Sign(claims jwt.Claims) (string, error) {
+	sks.mu.RLock()
+	kid, key := sks.signingKid, sks.signingKey
+	sks.mu.RUnlock()
+
+	if key == nil {
+		return "", errors.New("No signing key available.")
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+// Parse verifies the token with the key named by its kid header. Tokens without a kid are verified with the legacy RSA_PUB key.
+func (sks *SigningKeyService) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if jwt.SigningMethodRS256 != token.Method {
+			return nil, errors.New("Token signing method does not match.")
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			kid = sks.legacyKid
+		}
+
+		sks.mu.RLock()
+		key, ok := sks.verifyKeys[kid]
+		sks.mu.RUnlock()
+		if !ok && kid != "" {
+			key, ok = sks.reloadForUnknownKid(kid)
+		}
+		if !ok {
+			return nil, errors.New("Token signing key is unknown or expired.")
+		}
+		return key, nil
+	})
+
+	// check for parsing error
+	if err != nil {
+		return nil, err
+	}
+
+	// check if token is valid
+	if !token.Valid {
+		return nil, errors.New("Token is not valid.")
+	}
+
+	return token, nil
+}
+
+func (sks *SigningKeyService) GetJWKS() *signing_key_model.JWKS {
+	sks.mu.RLock()
+	defer sks.mu.RUnlock()
+
+	return sks.jwks
+}
+
+func (sks *SigningKeyService) GetKeys() ([]*signing_key_model.SigningKeyDisplay, error) {
+	signingKeys, err := sks.RepositoriesGroup.SigningKeyRepository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	sks.mu.RLock()
+	signingKid := sks.signingKid
+	sks.mu.RUnlock()
+
+	displays := make([]*signing_key_model.SigningKeyDisplay, len(signingKeys))
+	for i, signingKey := range signingKeys {
+		displays[i] = signingKey.GetSigningKeyDisplay(signingKey.Kid == signingKid)
+	}
+	return displays, nil
+}
+
+// Rotate creates a new signing key and retires the current one. Retired keys verify tokens until the grace period ends.
+func (sks *SigningKeyService) Rotate() error {
+	signingKeys, err := sks.RepositoriesGroup.SigningKeyRepository.GetAll()
+	if err != nil {
+		return err
+	}
+
+	return sks.rotate(activeKid(signingKeys))
+}
+
+// RefreshKeys loads the signing keys from the database. The first key is created if none exist.
+func (sks *SigningKeyService) RefreshKeys() error {
+	repo := sks.RepositoriesGroup.SigningKeyRepository
+
+	err := repo.DeleteExpired(time.Now())
+	if err != nil {
+		return err
+	}
+
+	signingKeys, err := repo.GetAll()
+	if err != nil {
+		return err
+	}
+
+	if len(signingKeys) == 0 {
+		if err := sks.addInitialKey(); err != nil {
+			return err
+		}
+		signingKeys, err = repo.GetAll()
+		if err != nil {
+			return err
+		}
+	}
+
+	var signingKid string
+	var signingKey *rsa.PrivateKey
+	verifyKeys := make(map[string]*rsa.PublicKey, len(signingKeys))
+	jwks := &signing_key_model.JWKS{Keys: []signing_key_model.JWK{}}
+
+	for _, sk := range signingKeys {
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(sk.PublicKey))
+		if err != nil {
+			log.Errorf("Error parsing public signing key %v: %s\n", sk.Kid, err.Error())
+			continue
+		}
+		verifyKeys[sk.Kid] = publicKey
+		jwks.Keys = append(jwks.Keys, *signing_key_model.NewRsaJWK(sk.Kid, publicKey))
+
+		// keys are newest first so the first active key signs
+		if sk.Retired == nil && signingKey == nil {
+			privatePem, err := security.DecryptSecret(security.SigningKeySecretName(sk.Kid), sk.PrivateKey)
+			if err != nil {
+				log.Errorf("Error decrypting signing key %v: %s\n", sk.Kid, err.Error())
+				continue
+			}
+			privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(privatePem))
+			if err != nil {
+				log.Errorf("Error parsing private signing key %v: %s\n", sk.Kid, err.Error())
+				continue
+			}
+			signingKid, signingKey = sk.Kid, privateKey
+		}
+	}
+
+	if signingKey == nil {
+		return errors.New("No active signing key could be loaded.")
+	}
+
+	sks.mu.Lock()
+	sks.signingKid = signingKid
+	sks.signingKey = signingKey
+	sks.verifyKeys = verifyKeys
+	sks.jwks = jwks
+	sks.mu.Unlock()
+
+	return nil
+}
+
+func (sks *SigningKeyService) rotateIfDue() error {
+	rotationDays := context.Config.DbVars.SigningKeyRotation
+	if rotationDays <= 0 {
+		return nil
+	}
+
+	signingKeys, err := sks.RepositoriesGroup.SigningKeyRepository.GetAll()
+	if err != nil {
+		return err
+	}
+
+	for _, signingKey := range signingKeys {
+		if signingKey.Retired == nil {
+			if time.Since(signingKey.Created) < time.Duration(rotationDays)*24*time.Hour {
+				return nil
+			}
+			break
+		}
+	}
+
+	return sks.rotate(activeKid(signingKeys))
+}
+
+// rotate replaces the active key. If another instance already replaced it nothing changes and its key is loaded instead.
+func (sks *SigningKeyService) rotate(activeKid string) error {
+	newKey, err := sks.generateKey()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	rotated, err := sks.RepositoriesGroup.SigningKeyRepository.Rotate(activeKid, newKey, now, now.Add(sks.gracePeriod()))
+	if err != nil {
+		return err
+	}
+
+	if rotated {
+		log.Infof("Signing key rotated. New kid: %v\n", newKey.Kid)
+	} else {
+		log.Infof("Signing key was already rotated by another instance.\n")
+	}
+	return sks.RefreshKeys()
+}
+
+// reloadForUnknownKid reloads the keys, at most once per kidMissRefreshInterval, and looks the kid up again.
+func (sks *SigningKeyService) reloadForUnknownKid(kid string) (*rsa.PublicKey, bool) {
+	sks.kidMissMu.Lock()
+	if time.Since(sks.lastKidMissReload) >= kidMissRefreshInterval {
+		sks.lastKidMissReload = time.Now()
+		if err := sks.RefreshKeys(); err != nil {
+			log.Errorf("Error refreshing signing keys for kid %v: %s\n", kid, err.Error())
+		}
+	}
+	sks.kidMissMu.Unlock()
+
+	sks.mu.RLock()
+	defer sks.mu.RUnlock()
+	key, ok := sks.verifyKeys[kid]
+	return key, ok
+}
+
+// gracePeriod is never shorter than the longest token timeout so rotation doesn't log anyone out.
+func (sks *SigningKeyService) gracePeriod() time.Duration {
+	grace := time.Duration(context.Config.DbVars.SigningKeyGracePeriod) * 24 * time.Hour
+	for _, timeout := range []int64{context.Config.DbVars.UserAuthTimeout, context.Config.DbVars.DeviceAuthTimeout} {
+		if t := time.Duration(timeout) * time.Minute; t > grace {
+			grace = t
+		}
+	}
+	return grace
+}
+
+// addInitialKey stores the existing RSA_PRIV key as the first signing key so existing tokens stay valid. Instances starting
+// together may race to add it; whichever key lands first is used by all of them.
+func (sks *SigningKeyService) addInitialKey() error {
+	var signingKey *signing_key_model.SigningKey
+	var err error
+	if privateKey := context.Config.DbVars.GetRsaPrivateKey(true); privateKey != nil {
+		signingKey, err = sks.newSigningKey(privateKey)
+	} else {
+		signingKey, err = sks.generateKey()
+	}
+	if err != nil {
+		return err
+	}
+
+	// RSA_PRIV gives every instance the same kid, so a duplicate means another instance added it first
+	_, err = sks.RepositoriesGroup.SigningKeyRepository.AddIfNoActiveKey(signingKey)
+	if err != nil && !sqlUtl.ErrDupEtry(err) {
+		return err
+	}
+	return nil
+}
+
+func (sks *SigningKeyService) generateKey() (*signing_key_model.SigningKey, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, rsaKeySize)
+	if err != nil {
+		log.Errorf("Error generating signing key: %s\n", err.Error())
+		return nil, err
+	}
+	return sks.newSigningKey(privateKey)
+}
+
+// newSigningKey builds the stored form of the key. The private key is encrypted under the master key.
+func (sks *SigningKeyService) newSigningKey(privateKey *rsa.PrivateKey) (*signing_key_model.SigningKey, error) {
+	publicKeyData, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	kid := kidForPublicKey(&privateKey.PublicKey)
+	privatePem := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(privateKey),
+	})
+	encryptedPrivatePem, err := security.EncryptSecret(security.SigningKeySecretName(kid), string(privatePem))
+	if err != nil {
+		return nil, err
+	}
+
+	return &signing_key_model.SigningKey{
+		Kid:        kid,
+		Algorithm:  signing_key_model.ALGORITHM_RS256,
+		PrivateKey: encryptedPrivatePem,
+		PublicKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyData})),
+		Created:    time.Now(),
+	}, nil
+}
+
+// activeKid is the newest key that isn't retired. keys are newest first.
+func activeKid(signingKeys []*signing_key_model.SigningKey) string {
+	for _, signingKey := range signingKeys {
+		if signingKey.Retired == nil {
+			return signingKey.Kid
+		}
+	}
+	return ""
+}
+
+// kidForPublicKey is the first 16 hex characters of the sha256 of the public key
+func kidForPublicKey(publicKey *rsa.PublicKey) string {
+	data, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
@@ -0,0 +1,98 @@
+package trusted_device_model
+
+import (
+	"strings"
+	"time"
+)
+
+// TrustedDevice is a device that has passed two-factor verification. Device tokens are only accepted while their device is trusted and
+// the device expires with its token.
+type TrustedDevice struct {
+	Id        string    `db:"id"`
+	UserId    int64     `db:"userId"`
+	Name      string    `db:"name"`
+	UserAgent string    `db:"userAgent"`
+	Ip        string    `db:"ip"`
+	FirstSeen time.Time `db:"firstSeen"`
+	LastSeen  time.Time `db:"lastSeen"`
+	Expires   time.Time `db:"expires"`
+}
+
+/**
+* @apiDefine TrustedDeviceDisplay
+* @apiSuccess (Response) {string} id
+* @apiSuccess (Response) {string} name Name given when the device was verified or guessed from the user agent.
+* @apiSuccess (Response) {string} userAgent
+* @apiSuccess (Response) {string} ip Address the device was last seen from.
+* @apiSuccess (Response) {Date} firstSeen
+* @apiSuccess (Response) {Date} lastSeen
+* @apiSuccess (Response) {Date} expires The device must be verified again after this time.
+* @apiSuccess (Response) {bool} current True for the device making the request.
+ */
+type TrustedDeviceDisplay struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	UserAgent string    `json:"userAgent"`
+	Ip        string    `json:"ip"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Expires   time.Time `json:"expires"`
+	Current   bool      `json:"current"`
+}
+
+func (td *TrustedDevice) GetTrustedDeviceDisplay() *TrustedDeviceDisplay {
+	return &TrustedDeviceDisplay{
+		Id:        td.Id,
+		Name:      td.Name,
+		UserAgent: td.UserAgent,
+		Ip:        td.Ip,
+		FirstSeen: td.FirstSeen,
+		LastSeen:  td.LastSeen,
+		Expires:   td.Expires,
+	}
+}
+
+var browsers = []struct{ token, name string }{
+	{"Edge/", "Edge"},
+	{"Edg/", "Edge"},
+	{"OPR/", "Opera"},
+	{"Firefox/", "Firefox"},
+	{"Chrome/", "Chrome"},
+	{"Safari/", "Safari"},
+}
+
+var platforms = []struct{ token, name string }{
+	{"iPhone", "iPhone"},
+	{"iPad", "iPad"},
+	{"Android", "Android"},
+	{"Windows", "Windows"},
+	{"Mac OS X", "Mac"},
+	{"Linux", "Linux"},
+}
+
+// DeviceNameFromUserAgent guesses a readable name such as "Chrome on Windows" for devices that weren't given one.
+func DeviceNameFromUserAgent(userAgent string) string {
+	browser, platform := "", ""
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, p := range platforms {
+		if strings.Contains(userAgent, p.token) {
+			platform = p.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && platform != "":
+		return browser + " on " + platform
+	case browser != "":
+		return browser
+	case platform != "":
+		return platform
+	}
+	return "Unknown device"
+}
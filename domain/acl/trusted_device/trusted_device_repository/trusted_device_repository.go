@@ -0,0 +1,111 @@
+package trusted_device_repository
+
+import (
+	"database/sql"
+	"github.com/gocms-io/gocms/domain/acl/trusted_device/trusted_device_model"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type ITrustedDeviceRepository interface {
+	Get(userId int64, id string) (*trusted_device_model.TrustedDevice, error)
+	GetByUser(userId int64) ([]*trusted_device_model.TrustedDevice, error)
+	Add(*trusted_device_model.TrustedDevice) error
+	UpdateLastSeen(id string, ip string, lastSeen time.Time) error
+	Delete(userId int64, id string) error
+	DeleteExpired(time.Time) error
+}
+
+type TrustedDeviceRepository struct {
+	database *sqlx.DB
+}
+
+func DefaultTrustedDeviceRepository(dbx *sqlx.DB) *TrustedDeviceRepository {
+	trustedDeviceRepository := &TrustedDeviceRepository{
+		database: dbx,
+	}
+
+	return trustedDeviceRepository
+}
+
+// get a trusted device belonging to a user
+func (tdr *TrustedDeviceRepository) Get(userId int64, id string) (*trusted_device_model.TrustedDevice, error) {
+	var trustedDevice trusted_device_model.TrustedDevice
+	err := tdr.database.Get(&trustedDevice, `
+	SELECT * FROM gocms_trusted_devices WHERE id=? AND userId=?
+	`, id, userId)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Errorf("Error getting trusted device from database: %s", err.Error())
+		}
+		return nil, err
+	}
+
+	return &trustedDevice, nil
+}
+
+// get all trusted devices for a user most recently seen first
+func (tdr *TrustedDeviceRepository) GetByUser(userId int64) ([]*trusted_device_model.TrustedDevice, error) {
+	trustedDevices := []*trusted_device_model.TrustedDevice{}
+	err := tdr.database.Select(&trustedDevices, `
+	SELECT * FROM gocms_trusted_devices WHERE userId=? ORDER BY lastSeen DESC
+	`, userId)
+	if err != nil {
+		log.Errorf("Error getting trusted devices from database: %s", err.Error())
+		return nil, err
+	}
+
+	return trustedDevices, nil
+}
+
+func (tdr *TrustedDeviceRepository) Add(trustedDevice *trusted_device_model.TrustedDevice) error {
+	_, err := tdr.database.NamedExec(`
+	INSERT INTO gocms_trusted_devices (id, userId, name, userAgent, ip, firstSeen, lastSeen, expires) VALUES (:id, :userId, :name, :userAgent, :ip, :firstSeen, :lastSeen, :expires)
+	`, trustedDevice)
+	if err != nil {
+		log.Errorf("Error adding trusted device to database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (tdr *TrustedDeviceRepository) UpdateLastSeen(id string, ip string, lastSeen time.Time) error {
+	_, err := tdr.database.Exec(`
+	UPDATE gocms_trusted_devices SET ip=?, lastSeen=? WHERE id=?
+	`, ip, lastSeen, id)
+	if err != nil {
+		log.Errorf("Error updating trusted device in database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (tdr *TrustedDeviceRepository) Delete(userId int64, id string) error {
+	result, err := tdr.database.Exec(`
+	DELETE FROM gocms_trusted_devices WHERE id=? AND userId=?
+	`, id, userId)
+	if err != nil {
+		log.Errorf("Error deleting trusted device from database: %s", err.Error())
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (tdr *TrustedDeviceRepository) DeleteExpired(t time.Time) error {
+	_, err := tdr.database.Exec(`
+	DELETE FROM gocms_trusted_devices WHERE expires<?
+	`, t)
+	if err != nil {
+		log.Errorf("Error deleting expired trusted devices from database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
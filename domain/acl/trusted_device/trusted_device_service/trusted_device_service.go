@@ -0,0 +1,147 @@
+package trusted_device_service
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/trusted_device/trusted_device_model"
+	"github.com/gocms-io/gocms/domain/mail/mail_service"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"github.com/gocms-io/gocms/init/repository"
+	"github.com/gocms-io/gocms/utility"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"html"
+	"time"
+)
+
+// last seen is only written once per interval so authenticated requests don't each cost a write
+const lastSeenInterval = 5 * time.Minute
+
+const maxUserAgentLength = 512
+const maxNameLength = 255
+
+type ITrustedDeviceService interface {
+	Trust(user *user_model.User, deviceId string, name string, userAgent string, ip string) (*trusted_device_model.TrustedDevice, error)
+	Verify(userId int64, deviceId string, ip string) bool
+	GetDevices(userId int64, currentDeviceId string) ([]*trusted_device_model.TrustedDeviceDisplay, error)
+	Revoke(userId int64, deviceId string) error
+	ExportUserDevices(user *user_model.User) (interface{}, error)
+}
+
+type TrustedDeviceService struct {
+	RepositoriesGroup *repository.RepositoriesGroup
+	MailService       mail_service.IMailService
+}
+
+func DefaultTrustedDeviceService(rg *repository.RepositoriesGroup, ms mail_service.IMailService) *TrustedDeviceService {
+	trustedDeviceService := &TrustedDeviceService{
+		RepositoriesGroup: rg,
+		MailService:       ms,
+	}
+
+	// clean up devices whose device token has expired
+	context.Schedule.AddTicker(time.Hour, func() {
+		rg.TrustedDeviceRepository.DeleteExpired(time.Now())
+	})
+
+	return trustedDeviceService
+}
+
+// Trust records a device that passed two-factor verification and lets the user know by email.
+func (tds *TrustedDeviceService) Trust(user *user_model.User, deviceId string, name string, userAgent string, ip string) (*trusted_device_model.TrustedDevice, error) {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	if name == "" {
+		name = trusted_device_model.DeviceNameFromUserAgent(userAgent)
+	}
+	if len(name) > maxNameLength {
+		name = name[:maxNameLength]
+	}
+
+	now := time.Now()
+	trustedDevice := &trusted_device_model.TrustedDevice{
+		Id:        deviceId,
+		UserId:    user.Id,
+		Name:      name,
+		UserAgent: userAgent,
+		Ip:        ip,
+		FirstSeen: now,
+		LastSeen:  now,
+		Expires:   now.Add(time.Minute * utility.GetTimeout(context.Config.DbVars.DeviceAuthTimeout)),
+	}
+	err := tds.RepositoriesGroup.TrustedDeviceRepository.Add(trustedDevice)
+	if err != nil {
+		return nil, err
+	}
+
+	trustedStr := now.Format("01/02/2006 03:04 pm")
+	err = tds.MailService.Send(&mail_service.Mail{
+		To:      user.Email,
+		Subject: "New Device Trusted",
+		Body: fmt.Sprintf("A new device was verified for your account.\n\nDevice: %v\nIP Address: %v\nTime: %v\n\n", name, ip, trustedStr) +
+			"If this wasn't you change your password and remove the device from your account right away.",
+		BodyHTML: fmt.Sprintf("<h1>New Device Trusted</h1><p>A new device was verified for your account.</p><p>Device: <b>%v</b><br/>IP Address: <b>%v</b><br/>Time: <b>%v</b></p><p>If this wasn't you change your password and remove the device from your account right away.</p>",
+			html.EscapeString(name), html.EscapeString(ip), trustedStr),
+	})
+	if err != nil {
+		log.Errorf("Error sending new device email to user %v: %s\n", user.Id, err.Error())
+	}
+
+	return trustedDevice, nil
+}
+
+// Verify checks the device is still trusted by the user and records that it was seen.
+func (tds *TrustedDeviceService) Verify(userId int64, deviceId string, ip string) bool {
+	if deviceId == "" {
+		return false
+	}
+
+	trustedDevice, err := tds.RepositoriesGroup.TrustedDeviceRepository.Get(userId, deviceId)
+	if err != nil {
+		return false
+	}
+
+	now := time.Now()
+	if now.After(trustedDevice.Expires) {
+		return false
+	}
+
+	if now.Sub(trustedDevice.LastSeen) > lastSeenInterval || trustedDevice.Ip != ip {
+		// a failed update shouldn't lock the user out
+		tds.RepositoriesGroup.TrustedDeviceRepository.UpdateLastSeen(deviceId, ip, now)
+	}
+
+	return true
+}
+
+func (tds *TrustedDeviceService) GetDevices(userId int64, currentDeviceId string) ([]*trusted_device_model.TrustedDeviceDisplay, error) {
+	trustedDevices, err := tds.RepositoriesGroup.TrustedDeviceRepository.GetByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	displays := make([]*trusted_device_model.TrustedDeviceDisplay, 0, len(trustedDevices))
+	for _, trustedDevice := range trustedDevices {
+		display := trustedDevice.GetTrustedDeviceDisplay()
+		display.Current = currentDeviceId != "" && trustedDevice.Id == currentDeviceId
+		displays = append(displays, display)
+	}
+
+	return displays, nil
+}
+
+// Revoke removes a trusted device. Its device token stops working immediately.
+func (tds *TrustedDeviceService) Revoke(userId int64, deviceId string) error {
+	err := tds.RepositoriesGroup.TrustedDeviceRepository.Delete(userId, deviceId)
+	if err == sql.ErrNoRows {
+		return errors.NewToUser("Device not found.")
+	}
+
+	return err
+}
+
+func (tds *TrustedDeviceService) ExportUserDevices(user *user_model.User) (interface{}, error) {
+	return tds.GetDevices(user.Id, "")
+}
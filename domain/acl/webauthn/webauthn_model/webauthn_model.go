@@ -0,0 +1,201 @@
+package webauthn_model
+
+import (
+	"github.com/gocms-io/gocms/utility/cose"
+	"time"
+)
+
+// ceremonies a challenge can be used for
+const (
+	CEREMONY_REGISTER   = "register"
+	CEREMONY_LOGIN      = "login"
+	CEREMONY_TWO_FACTOR = "twoFactor"
+)
+
+// COSE algorithm identifiers supported for credential keys
+const (
+	COSE_ALG_ES256 = cose.ALG_ES256
+	COSE_ALG_EDDSA = cose.ALG_EDDSA
+	COSE_ALG_RS256 = cose.ALG_RS256
+)
+
+const (
+	USER_VERIFICATION_REQUIRED    = "required"
+	USER_VERIFICATION_PREFERRED   = "preferred"
+	USER_VERIFICATION_DISCOURAGED = "discouraged"
+)
+
+// WebauthnCredential is a passkey or security key registered to a user. PublicKey is the COSE encoded key. Binary values are stored base64url encoded.
+type WebauthnCredential struct {
+	Id           int64      `db:"id"`
+	UserId       int64      `db:"userId"`
+	CredentialId string     `db:"credentialId"`
+	PublicKey    string     `db:"publicKey"`
+	Algorithm    int64      `db:"algorithm"`
+	SignCount    int64      `db:"signCount"`
+	Aaguid       string     `db:"aaguid"`
+	Name         string     `db:"name"`
+	Created      time.Time  `db:"created"`
+	LastUsed     *time.Time `db:"lastUsed"`
+}
+
+/**
+* @apiDefine WebauthnCredentialDisplay
+* @apiSuccess (Response) {number} id
+* @apiSuccess (Response) {string} credentialId base64url credential id.
+* @apiSuccess (Response) {string} name
+* @apiSuccess (Response) {number} signCount Signature counter reported by the authenticator. Used to detect cloned authenticators.
+* @apiSuccess (Response) {string} aaguid Authenticator model. Blank when the authenticator doesn't share it.
+* @apiSuccess (Response) {Date} created
+* @apiSuccess (Response) {Date} [lastUsed]
+ */
+type WebauthnCredentialDisplay struct {
+	Id           int64      `json:"id"`
+	CredentialId string     `json:"credentialId"`
+	Name         string     `json:"name"`
+	SignCount    int64      `json:"signCount"`
+	Aaguid       string     `json:"aaguid"`
+	Created      time.Time  `json:"created"`
+	LastUsed     *time.Time `json:"lastUsed,omitempty"`
+}
+
+func (wc *WebauthnCredential) GetWebauthnCredentialDisplay() *WebauthnCredentialDisplay {
+	return &WebauthnCredentialDisplay{
+		Id:           wc.Id,
+		CredentialId: wc.CredentialId,
+		Name:         wc.Name,
+		SignCount:    wc.SignCount,
+		Aaguid:       wc.Aaguid,
+		Created:      wc.Created,
+		LastUsed:     wc.LastUsed,
+	}
+}
+
+// WebauthnChallenge is a single use challenge for one ceremony. UserId is 0 for passwordless logins that don't name a user.
+type WebauthnChallenge struct {
+	Challenge string    `db:"challenge"`
+	Ceremony  string    `db:"ceremony"`
+	UserId    int64     `db:"userId"`
+	Expires   time.Time `db:"expires"`
+}
+
+type RelyingParty struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type UserEntity struct {
+	Id          string `json:"id"`
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+type CredentialDescriptor struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+type AuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+/**
+* @apiDefine WebauthnCreationOptions
+* @apiDescription Options for navigator.credentials.create(). Binary values are base64url encoded.
+* @apiSuccess (Response) {string} challenge
+* @apiSuccess (Response) {Object} rp
+* @apiSuccess (Response) {Object} user
+* @apiSuccess (Response) {Object[]} pubKeyCredParams
+* @apiSuccess (Response) {number} timeout Milliseconds.
+* @apiSuccess (Response) {Object[]} excludeCredentials Credentials already registered to the user.
+* @apiSuccess (Response) {Object} authenticatorSelection
+* @apiSuccess (Response) {string} attestation
+ */
+type CreationOptions struct {
+	Challenge              string                 `json:"challenge"`
+	Rp                     RelyingParty           `json:"rp"`
+	User                   UserEntity             `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection AuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                 `json:"attestation"`
+}
+
+/**
+* @apiDefine WebauthnRequestOptions
+* @apiDescription Options for navigator.credentials.get(). Binary values are base64url encoded.
+* @apiSuccess (Response) {string} challenge
+* @apiSuccess (Response) {number} timeout Milliseconds.
+* @apiSuccess (Response) {string} rpId
+* @apiSuccess (Response) {Object[]} allowCredentials Empty when any passkey for the site may be used.
+* @apiSuccess (Response) {string} userVerification
+ */
+type RequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RpId             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+/**
+* @apiDefine WebauthnRegistrationInput
+* @apiDescription The PublicKeyCredential returned by navigator.credentials.create(). Binary values are base64url encoded.
+* @apiParam (Request) {string} id
+* @apiParam (Request) {Object} response
+* @apiParam (Request) {string} response.clientDataJSON
+* @apiParam (Request) {string} response.attestationObject
+* @apiParam (Request) {string} [name] Friendly name for the credential.
+ */
+type RegistrationInput struct {
+	Id       string `json:"id" binding:"required"`
+	Name     string `json:"name"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AttestationObject string `json:"attestationObject"`
+	} `json:"response"`
+}
+
+/**
+* @apiDefine WebauthnAssertionInput
+* @apiDescription The PublicKeyCredential returned by navigator.credentials.get(). Binary values are base64url encoded.
+* @apiParam (Request) {string} id
+* @apiParam (Request) {Object} response
+* @apiParam (Request) {string} response.clientDataJSON
+* @apiParam (Request) {string} response.authenticatorData
+* @apiParam (Request) {string} response.signature
+* @apiParam (Request) {string} [response.userHandle]
+* @apiParam (Request) {string} [deviceName] Name for the trusted device when the assertion completes two-factor.
+ */
+type AssertionInput struct {
+	Id         string `json:"id" binding:"required"`
+	DeviceName string `json:"deviceName"`
+	Response   struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+		UserHandle        string `json:"userHandle"`
+	} `json:"response"`
+}
+
+/**
+* @apiDefine WebauthnCredentialUpdateInput
+* @apiParam (Request) {string} name
+ */
+type CredentialUpdateInput struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// AssertionResult is a verified assertion. UserVerified is set when the authenticator checked a pin or biometric.
+type AssertionResult struct {
+	UserId       int64
+	Credential   *WebauthnCredential
+	UserVerified bool
+}
@@ -0,0 +1,190 @@
+package webauthn_repository
+
+import (
+	"database/sql"
+	"github.com/gocms-io/gocms/domain/acl/webauthn/webauthn_model"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IWebauthnRepository interface {
+	GetCredential(userId int64, id int64) (*webauthn_model.WebauthnCredential, error)
+	GetCredentialByCredentialId(credentialId string) (*webauthn_model.WebauthnCredential, error)
+	GetCredentials(userId int64) ([]*webauthn_model.WebauthnCredential, error)
+	AddCredential(*webauthn_model.WebauthnCredential) error
+	UpdateCredentialName(userId int64, id int64, name string) error
+	UpdateCredentialUse(id int64, signCount int64, lastUsed time.Time) error
+	DeleteCredential(userId int64, id int64) error
+	AddChallenge(*webauthn_model.WebauthnChallenge) error
+	ConsumeChallenge(challenge string, ceremony string) (*webauthn_model.WebauthnChallenge, error)
+	DeleteExpiredChallenges(time.Time) error
+}
+
+type WebauthnRepository struct {
+	database *sqlx.DB
+}
+
+func DefaultWebauthnRepository(dbx *sqlx.DB) *WebauthnRepository {
+	webauthnRepository := &WebauthnRepository{
+		database: dbx,
+	}
+
+	return webauthnRepository
+}
+
+// get a credential belonging to a user
+func (wr *WebauthnRepository) GetCredential(userId int64, id int64) (*webauthn_model.WebauthnCredential, error) {
+	var credential webauthn_model.WebauthnCredential
+	err := wr.database.Get(&credential, `
+	SELECT * FROM gocms_webauthn_credentials WHERE id=? AND userId=?
+	`, id, userId)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Errorf("Error getting webauthn credential from database: %s", err.Error())
+		}
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+// get a credential by the id the authenticator gave it
+func (wr *WebauthnRepository) GetCredentialByCredentialId(credentialId string) (*webauthn_model.WebauthnCredential, error) {
+	var credential webauthn_model.WebauthnCredential
+	err := wr.database.Get(&credential, `
+	SELECT * FROM gocms_webauthn_credentials WHERE credentialId=?
+	`, credentialId)
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Errorf("Error getting webauthn credential from database: %s", err.Error())
+		}
+		return nil, err
+	}
+
+	return &credential, nil
+}
+
+func (wr *WebauthnRepository) GetCredentials(userId int64) ([]*webauthn_model.WebauthnCredential, error) {
+	credentials := []*webauthn_model.WebauthnCredential{}
+	err := wr.database.Select(&credentials, `
+	SELECT * FROM gocms_webauthn_credentials WHERE userId=? ORDER BY created
+	`, userId)
+	if err != nil {
+		log.Errorf("Error getting webauthn credentials from database: %s", err.Error())
+		return nil, err
+	}
+
+	return credentials, nil
+}
+
+func (wr *WebauthnRepository) AddCredential(credential *webauthn_model.WebauthnCredential) error {
+	result, err := wr.database.NamedExec(`
+	INSERT INTO gocms_webauthn_credentials (userId, credentialId, publicKey, algorithm, signCount, aaguid, name, created) VALUES (:userId, :credentialId, :publicKey, :algorithm, :signCount, :aaguid, :name, :created)
+	`, credential)
+	if err != nil {
+		log.Errorf("Error adding webauthn credential to database: %s", err.Error())
+		return err
+	}
+	id, _ := result.LastInsertId()
+	credential.Id = id
+
+	return nil
+}
+
+func (wr *WebauthnRepository) UpdateCredentialName(userId int64, id int64, name string) error {
+	result, err := wr.database.Exec(`
+	UPDATE gocms_webauthn_credentials SET name=? WHERE id=? AND userId=?
+	`, name, id, userId)
+	if err != nil {
+		log.Errorf("Error updating webauthn credential in database: %s", err.Error())
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		// unchanged names also affect no rows
+		if _, err := wr.GetCredential(userId, id); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (wr *WebauthnRepository) UpdateCredentialUse(id int64, signCount int64, lastUsed time.Time) error {
+	_, err := wr.database.Exec(`
+	UPDATE gocms_webauthn_credentials SET signCount=?, lastUsed=? WHERE id=?
+	`, signCount, lastUsed, id)
+	if err != nil {
+		log.Errorf("Error updating webauthn credential in database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (wr *WebauthnRepository) DeleteCredential(userId int64, id int64) error {
+	result, err := wr.database.Exec(`
+	DELETE FROM gocms_webauthn_credentials WHERE id=? AND userId=?
+	`, id, userId)
+	if err != nil {
+		log.Errorf("Error deleting webauthn credential from database: %s", err.Error())
+		return err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return sql.ErrNoRows
+	}
+
+	return nil
+}
+
+func (wr *WebauthnRepository) AddChallenge(challenge *webauthn_model.WebauthnChallenge) error {
+	_, err := wr.database.NamedExec(`
+	INSERT INTO gocms_webauthn_challenges (challenge, ceremony, userId, expires) VALUES (:challenge, :ceremony, :userId, :expires)
+	`, challenge)
+	if err != nil {
+		log.Errorf("Error adding webauthn challenge to database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// ConsumeChallenge returns an unexpired challenge and deletes it so it can only be used once.
+func (wr *WebauthnRepository) ConsumeChallenge(challenge string, ceremony string) (*webauthn_model.WebauthnChallenge, error) {
+	var webauthnChallenge webauthn_model.WebauthnChallenge
+	err := wr.database.Get(&webauthnChallenge, `
+	SELECT * FROM gocms_webauthn_challenges WHERE challenge=? AND ceremony=? AND expires>?
+	`, challenge, ceremony, time.Now())
+	if err != nil {
+		if err != sql.ErrNoRows {
+			log.Errorf("Error getting webauthn challenge from database: %s", err.Error())
+		}
+		return nil, err
+	}
+
+	// only the request that deletes the challenge gets to use it
+	result, err := wr.database.Exec(`
+	DELETE FROM gocms_webauthn_challenges WHERE challenge=?
+	`, challenge)
+	if err != nil {
+		log.Errorf("Error deleting webauthn challenge from database: %s", err.Error())
+		return nil, err
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return nil, sql.ErrNoRows
+	}
+
+	return &webauthnChallenge, nil
+}
+
+func (wr *WebauthnRepository) DeleteExpiredChallenges(t time.Time) error {
+	_, err := wr.database.Exec(`
+	DELETE FROM gocms_webauthn_challenges WHERE expires<?
+	`, t)
+	if err != nil {
+		log.Errorf("Error deleting expired webauthn challenges from database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package webauthn_service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/webauthn/webauthn_model"
+	"github.com/gocms-io/gocms/utility/cbor"
+	"github.com/gocms-io/gocms/utility/errors"
+	"strings"
+)
+
+// authenticator data flags
+const (
+	flagUserPresent        = 0x01
+	flagUserVerified       = 0x04
+	flagAttestedCredential = 0x40
+)
+
+type clientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+type authenticatorData struct {
+	rpIdHash     []byte
+	flags        byte
+	signCount    uint32
+	aaguid       []byte
+	credentialId []byte
+	publicKey    []byte
+}
+
+func decodeBase64Url(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func encodeBase64Url(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// parseClientData checks the type and origin of the client data. The challenge is checked by the caller.
+func parseClientData(raw []byte, ceremonyType string) (*clientData, error) {
+	var cd clientData
+	err := json.Unmarshal(raw, &cd)
+	if err != nil {
+		return nil, errors.New("Client data isn't valid json.")
+	}
+	if cd.Type != ceremonyType {
+		return nil, errors.New("Client data is for the wrong ceremony.")
+	}
+
+	for _, origin := range context.Config.DbVars.WebauthnOrigins {
+		if cd.Origin == origin {
+			return &cd, nil
+		}
+	}
+	return nil, errors.New("Client data origin " + cd.Origin + " isn't allowed.")
+}
+
+func parseAuthenticatorData(data []byte) (*authenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("Authenticator data is too short.")
+	}
+
+	ad := &authenticatorData{
+		rpIdHash:  data[:32],
+		flags:     data[32],
+		signCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if ad.flags&flagAttestedCredential != 0 {
+		rest := data[37:]
+		if len(rest) < 18 {
+			return nil, errors.New("Attested credential data is too short.")
+		}
+		ad.aaguid = rest[:16]
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return nil, errors.New("Attested credential id is too short.")
+		}
+		ad.credentialId = rest[:idLength]
+		rest = rest[idLength:]
+
+		// the key is followed by extensions so only take the bytes it used
+		_, after, err := cbor.DecodeFirst(rest)
+		if err != nil {
+			return nil, errors.New("Attested credential key isn't valid cbor.")
+		}
+		ad.publicKey = rest[:len(rest)-len(after)]
+	}
+
+	return ad, nil
+}
+
+// checkAuthenticatorData verifies the relying party and the user presence and verification flags.
+func checkAuthenticatorData(ad *authenticatorData) error {
+	rpIdHash := sha256.Sum256([]byte(context.Config.DbVars.WebauthnRpId))
+	if !bytes.Equal(ad.rpIdHash, rpIdHash[:]) {
+		return errors.New("Authenticator data is for another relying party.")
+	}
+	if ad.flags&flagUserPresent == 0 {
+		return errors.New("User wasn't present.")
+	}
+	if context.Config.DbVars.WebauthnUserVerification == webauthn_model.USER_VERIFICATION_REQUIRED && ad.flags&flagUserVerified == 0 {
+		return errors.New("User wasn't verified.")
+	}
+	return nil
+}
+
+// formatAaguid formats the authenticator model id as a uuid. Authenticators that don't share it send zeros.
+func formatAaguid(aaguid []byte) string {
+	if len(aaguid) != 16 || bytes.Equal(aaguid, make([]byte, 16)) {
+		return ""
+	}
+	h := hex.EncodeToString(aaguid)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
@@ -0,0 +1,409 @@
+package webauthn_service
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/acl/webauthn/webauthn_model"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"github.com/gocms-io/gocms/init/repository"
+	"github.com/gocms-io/gocms/utility"
+	"github.com/gocms-io/gocms/utility/cbor"
+	"github.com/gocms-io/gocms/utility/cose"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"strconv"
+	"time"
+)
+
+const challengeBytes = 32
+const maxNameLength = 255
+const defaultCredentialName = "Passkey"
+const credentialType = "public-key"
+
+type IWebauthnService interface {
+	BeginRegistration(user *user_model.User) (*webauthn_model.CreationOptions, error)
+	FinishRegistration(user *user_model.User, input *webauthn_model.RegistrationInput) (*webauthn_model.WebauthnCredential, error)
+	BeginLogin() (*webauthn_model.RequestOptions, error)
+	FinishLogin(input *webauthn_model.AssertionInput) (*webauthn_model.AssertionResult, error)
+	BeginTwoFactor(user *user_model.User) (*webauthn_model.RequestOptions, error)
+	FinishTwoFactor(user *user_model.User, input *webauthn_model.AssertionInput) (*webauthn_model.AssertionResult, error)
+	GetCredentials(userId int64) ([]*webauthn_model.WebauthnCredentialDisplay, error)
+	UpdateCredentialName(userId int64, id int64, name string) error
+	DeleteCredential(userId int64, id int64) error
+	ExportUserCredentials(user *user_model.User) (interface{}, error)
+}
+
+type WebauthnService struct {
+	RepositoriesGroup *repository.RepositoriesGroup
+}
+
+func DefaultWebauthnService(rg *repository.RepositoriesGroup) *WebauthnService {
+	webauthnService := &WebauthnService{
+		RepositoriesGroup: rg,
+	}
+
+	// clean up challenges that were never finished
+	context.Schedule.AddTicker(time.Hour, func() {
+		rg.WebauthnRepository.DeleteExpiredChallenges(time.Now())
+	})
+
+	return webauthnService
+}
+
+// BeginRegistration creates the options for registering a new passkey to the user.
+func (ws *WebauthnService) BeginRegistration(user *user_model.User) (*webauthn_model.CreationOptions, error) {
+	challenge, err := ws.newChallenge(webauthn_model.CEREMONY_REGISTER, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	excludeCredentials, err := ws.getCredentialDescriptors(user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	creationOptions := &webauthn_model.CreationOptions{
+		Challenge: challenge,
+		Rp: webauthn_model.RelyingParty{
+			Id:   context.Config.DbVars.WebauthnRpId,
+			Name: context.Config.DbVars.WebauthnRpName,
+		},
+		User: webauthn_model.UserEntity{
+			Id:          userHandle(user.Id),
+			Name:        user.Email,
+			DisplayName: user.FullName,
+		},
+		PubKeyCredParams: []webauthn_model.CredentialParameter{
+			{Type: credentialType, Alg: webauthn_model.COSE_ALG_ES256},
+			{Type: credentialType, Alg: webauthn_model.COSE_ALG_EDDSA},
+			{Type: credentialType, Alg: webauthn_model.COSE_ALG_RS256},
+		},
+		Timeout:            ceremonyTimeout().Nanoseconds() / int64(time.Millisecond),
+		ExcludeCredentials: excludeCredentials,
+		AuthenticatorSelection: webauthn_model.AuthenticatorSelection{
+			ResidentKey:      "preferred",
+			UserVerification: context.Config.DbVars.WebauthnUserVerification,
+		},
+		Attestation: "none",
+	}
+
+	return creationOptions, nil
+}
+
+// FinishRegistration verifies the new credential and stores it. Attestation statements aren't checked since none is requested.
+func (ws *WebauthnService) FinishRegistration(user *user_model.User, input *webauthn_model.RegistrationInput) (*webauthn_model.WebauthnCredential, error) {
+	rawClientData, err := decodeBase64Url(input.Response.ClientDataJSON)
+	if err != nil {
+		return nil, errors.New("Client data isn't base64url.")
+	}
+	clientData, err := parseClientData(rawClientData, "webauthn.create")
+	if err != nil {
+		return nil, err
+	}
+	_, err = ws.consumeChallenge(clientData.Challenge, webauthn_model.CEREMONY_REGISTER, user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	rawAttestation, err := decodeBase64Url(input.Response.AttestationObject)
+	if err != nil {
+		return nil, errors.New("Attestation object isn't base64url.")
+	}
+	decoded, err := cbor.Decode(rawAttestation)
+	if err != nil {
+		return nil, errors.New("Attestation object isn't valid cbor.")
+	}
+	attestation, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, errors.New("Attestation object isn't a map.")
+	}
+	rawAuthData, ok := attestation["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("Attestation object has no authenticator data.")
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	err = checkAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.publicKey == nil {
+		return nil, errors.New("Authenticator data has no credential.")
+	}
+	if encodeBase64Url(authData.credentialId) != canonicalCredentialId(input.Id) {
+		return nil, errors.New("Credential id doesn't match the authenticator data.")
+	}
+	_, alg, err := cose.ParseKey(authData.publicKey)
+	if err != nil {
+		return nil, err
+	}
+
+	credentialId := encodeBase64Url(authData.credentialId)
+	if _, err := ws.RepositoriesGroup.WebauthnRepository.GetCredentialByCredentialId(credentialId); err == nil {
+		return nil, errors.NewToUser("Passkey is already registered.")
+	}
+
+	credential := &webauthn_model.WebauthnCredential{
+		UserId:       user.Id,
+		CredentialId: credentialId,
+		PublicKey:    encodeBase64Url(authData.publicKey),
+		Algorithm:    alg,
+		SignCount:    int64(authData.signCount),
+		Aaguid:       formatAaguid(authData.aaguid),
+		Name:         credentialName(input.Name),
+		Created:      time.Now(),
+	}
+	err = ws.RepositoriesGroup.WebauthnRepository.AddCredential(credential)
+	if err != nil {
+		return nil, err
+	}
+
+	return credential, nil
+}
+
+// BeginLogin creates the options for a passwordless login with any discoverable passkey. Credentials are never listed so the options
+// don't reveal whether an account exists or has passkeys.
+func (ws *WebauthnService) BeginLogin() (*webauthn_model.RequestOptions, error) {
+	return ws.beginAssertion(webauthn_model.CEREMONY_LOGIN, 0)
+}
+
+// FinishLogin verifies a passwordless login.
+func (ws *WebauthnService) FinishLogin(input *webauthn_model.AssertionInput) (*webauthn_model.AssertionResult, error) {
+	return ws.finishAssertion(webauthn_model.CEREMONY_LOGIN, 0, input)
+}
+
+// BeginTwoFactor creates the options for using a passkey as the second factor of a user that already logged in.
+func (ws *WebauthnService) BeginTwoFactor(user *user_model.User) (*webauthn_model.RequestOptions, error) {
+	return ws.beginAssertion(webauthn_model.CEREMONY_TWO_FACTOR, user.Id)
+}
+
+// FinishTwoFactor verifies the second factor. The passkey must belong to the user.
+func (ws *WebauthnService) FinishTwoFactor(user *user_model.User, input *webauthn_model.AssertionInput) (*webauthn_model.AssertionResult, error) {
+	return ws.finishAssertion(webauthn_model.CEREMONY_TWO_FACTOR, user.Id, input)
+}
+
+func (ws *WebauthnService) GetCredentials(userId int64) ([]*webauthn_model.WebauthnCredentialDisplay, error) {
+	credentials, err := ws.RepositoriesGroup.WebauthnRepository.GetCredentials(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	displays := make([]*webauthn_model.WebauthnCredentialDisplay, 0, len(credentials))
+	for _, credential := range credentials {
+		displays = append(displays, credential.GetWebauthnCredentialDisplay())
+	}
+
+	return displays, nil
+}
+
+func (ws *WebauthnService) UpdateCredentialName(userId int64, id int64, name string) error {
+	err := ws.RepositoriesGroup.WebauthnRepository.UpdateCredentialName(userId, id, credentialName(name))
+	if err == sql.ErrNoRows {
+		return errors.NewToUser("Passkey not found.")
+	}
+
+	return err
+}
+
+func (ws *WebauthnService) DeleteCredential(userId int64, id int64) error {
+	err := ws.RepositoriesGroup.WebauthnRepository.DeleteCredential(userId, id)
+	if err == sql.ErrNoRows {
+		return errors.NewToUser("Passkey not found.")
+	}
+
+	return err
+}
+
+func (ws *WebauthnService) ExportUserCredentials(user *user_model.User) (interface{}, error) {
+	return ws.GetCredentials(user.Id)
+}
+
+func (ws *WebauthnService) beginAssertion(ceremony string, userId int64) (*webauthn_model.RequestOptions, error) {
+	challenge, err := ws.newChallenge(ceremony, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	allowCredentials := []webauthn_model.CredentialDescriptor{}
+	if userId != 0 {
+		allowCredentials, err = ws.getCredentialDescriptors(userId)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	requestOptions := &webauthn_model.RequestOptions{
+		Challenge:        challenge,
+		Timeout:          ceremonyTimeout().Nanoseconds() / int64(time.Millisecond),
+		RpId:             context.Config.DbVars.WebauthnRpId,
+		AllowCredentials: allowCredentials,
+		UserVerification: context.Config.DbVars.WebauthnUserVerification,
+	}
+
+	return requestOptions, nil
+}
+
+// finishAssertion verifies an assertion. When userId is set the challenge and credential must belong to that user.
+func (ws *WebauthnService) finishAssertion(ceremony string, userId int64, input *webauthn_model.AssertionInput) (*webauthn_model.AssertionResult, error) {
+	rawClientData, err := decodeBase64Url(input.Response.ClientDataJSON)
+	if err != nil {
+		return nil, errors.New("Client data isn't base64url.")
+	}
+	clientData, err := parseClientData(rawClientData, "webauthn.get")
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := ws.consumeChallenge(clientData.Challenge, ceremony, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := ws.RepositoriesGroup.WebauthnRepository.GetCredentialByCredentialId(canonicalCredentialId(input.Id))
+	if err != nil {
+		return nil, errors.New("Credential isn't registered.")
+	}
+	if challenge.UserId != 0 && credential.UserId != challenge.UserId {
+		return nil, errors.New("Credential belongs to another user.")
+	}
+	if input.Response.UserHandle != "" {
+		handle, err := decodeBase64Url(input.Response.UserHandle)
+		if err != nil || encodeBase64Url(handle) != userHandle(credential.UserId) {
+			return nil, errors.New("User handle doesn't match the credential.")
+		}
+	}
+
+	rawAuthData, err := decodeBase64Url(input.Response.AuthenticatorData)
+	if err != nil {
+		return nil, errors.New("Authenticator data isn't base64url.")
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	err = checkAuthenticatorData(authData)
+	if err != nil {
+		return nil, err
+	}
+
+	// signature is over the authenticator data and the hash of the client data
+	rawPublicKey, err := decodeBase64Url(credential.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	publicKey, _, err := cose.ParseKey(rawPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := decodeBase64Url(input.Response.Signature)
+	if err != nil {
+		return nil, errors.New("Signature isn't base64url.")
+	}
+	clientDataHash := sha256.Sum256(rawClientData)
+	signed := append(append([]byte{}, rawAuthData...), clientDataHash[:]...)
+	if !cose.Verify(publicKey, signed, signature) {
+		return nil, errors.New("Signature doesn't match the credential.")
+	}
+
+	// a counter that doesn't increase means the authenticator may have been cloned
+	signCount := int64(authData.signCount)
+	if (signCount != 0 || credential.SignCount != 0) && signCount <= credential.SignCount {
+		log.Warningf("Webauthn credential %v for user %v reused sign count %v. The authenticator may be cloned.\n", credential.Id, credential.UserId, signCount)
+		return nil, errors.New("Credential sign count didn't increase.")
+	}
+
+	now := time.Now()
+	err = ws.RepositoriesGroup.WebauthnRepository.UpdateCredentialUse(credential.Id, signCount, now)
+	if err != nil {
+		return nil, err
+	}
+	credential.SignCount = signCount
+	credential.LastUsed = &now
+
+	assertionResult := &webauthn_model.AssertionResult{
+		UserId:       credential.UserId,
+		Credential:   credential,
+		UserVerified: authData.flags&flagUserVerified != 0,
+	}
+
+	return assertionResult, nil
+}
+
+func (ws *WebauthnService) newChallenge(ceremony string, userId int64) (string, error) {
+	// encoded from raw bytes so it survives the browser decoding and re-encoding it
+	b, err := utility.GenerateRandomBytes(challengeBytes)
+	if err != nil {
+		return "", err
+	}
+	challenge := encodeBase64Url(b)
+
+	err = ws.RepositoriesGroup.WebauthnRepository.AddChallenge(&webauthn_model.WebauthnChallenge{
+		Challenge: challenge,
+		Ceremony:  ceremony,
+		UserId:    userId,
+		Expires:   time.Now().Add(ceremonyTimeout()),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return challenge, nil
+}
+
+// consumeChallenge uses up the challenge. Challenges issued to a user can't be used by anyone else.
+func (ws *WebauthnService) consumeChallenge(challenge string, ceremony string, userId int64) (*webauthn_model.WebauthnChallenge, error) {
+	webauthnChallenge, err := ws.RepositoriesGroup.WebauthnRepository.ConsumeChallenge(challenge, ceremony)
+	if err != nil {
+		return nil, errors.New("Challenge is invalid or has expired.")
+	}
+	if userId != 0 && webauthnChallenge.UserId != userId {
+		return nil, errors.New("Challenge was issued to another user.")
+	}
+
+	return webauthnChallenge, nil
+}
+
+func (ws *WebauthnService) getCredentialDescriptors(userId int64) ([]webauthn_model.CredentialDescriptor, error) {
+	credentials, err := ws.RepositoriesGroup.WebauthnRepository.GetCredentials(userId)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptors := make([]webauthn_model.CredentialDescriptor, 0, len(credentials))
+	for _, credential := range credentials {
+		descriptors = append(descriptors, webauthn_model.CredentialDescriptor{Type: credentialType, Id: credential.CredentialId})
+	}
+
+	return descriptors, nil
+}
+
+func ceremonyTimeout() time.Duration {
+	return time.Duration(context.Config.DbVars.WebauthnTimeout) * time.Minute
+}
+
+// userHandle is the opaque id authenticators store for the user
+func userHandle(userId int64) string {
+	return encodeBase64Url([]byte(strconv.FormatInt(userId, 10)))
+}
+
+// canonicalCredentialId strips padding so ids match however the client encoded them
+func canonicalCredentialId(id string) string {
+	raw, err := decodeBase64Url(id)
+	if err != nil {
+		return ""
+	}
+	return encodeBase64Url(raw)
+}
+
+func credentialName(name string) string {
+	if name == "" {
+		return defaultCredentialName
+	}
+	if len(name) > maxNameLength {
+		return name[:maxNameLength]
+	}
+	return name
+}
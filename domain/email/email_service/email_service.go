@@ -139,7 +139,7 @@ func (es *EmailService) SendEmailActivationCode(emailAddress string) error {
 		BodyHTML: fmt.Sprintf("<h1>Account Verification Required</h1><h2>Click on the link below to activate your account:</h2><p><a href='%v'>Activate Link</a></h3></p><p>The link will expire at: <b>%v</b></p>", activationLink, expTimeStr),
 	})
 	if err != nil {
-		log.Errorf("Error sending email activation code, sending mail: " + err.Error())
+		log.Errorf("Error sending email activation code, sending mail: %v\n", err.Error())
 	}
 
 	return nil
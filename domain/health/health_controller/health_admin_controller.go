@@ -0,0 +1,62 @@
+package health_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/acl/access_control/access_control_middleware"
+	"github.com/gocms-io/gocms/domain/acl/permissions"
+	"github.com/gocms-io/gocms/init/service"
+	"github.com/gocms-io/gocms/routes"
+	"net/http"
+)
+
+type HealthAdminController struct {
+	routes       *routes.Routes
+	serviceGroup *service.ServicesGroup
+	adminRoutes  *gin.RouterGroup
+}
+
+func DefaultHealthAdminController(routes *routes.Routes, serviceGroup *service.ServicesGroup) *HealthAdminController {
+	hac := &HealthAdminController{
+		routes:       routes,
+		serviceGroup: serviceGroup,
+	}
+
+	// add acl rules to route
+	hac.adminRoutes = routes.Auth.Group("/admin", access_control_middleware.RequirePermission(serviceGroup.AclService, permissions.SUPER_ADMIN))
+
+	hac.Default()
+	return hac
+}
+
+func (hac *HealthAdminController) Default() {
+	hac.adminRoutes.GET("/health", hac.report)
+	hac.adminRoutes.GET("/health/events", hac.events)
+}
+
+/**
+* @api {get} /admin/health Detailed Health Report
+* @apiDescription Status of the database, migrations, mail transport and each active plugin along with recent status changes.
+* @apiName GetHealthReport
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiUse HealthReport
+* @apiPermission Admin
+ */
+func (hac *HealthAdminController) report(c *gin.Context) {
+	c.JSON(http.StatusOK, hac.serviceGroup.HealthService.GetHealthReport())
+}
+
+/**
+* @api {get} /admin/health/events Health Events
+* @apiDescription Server sent event stream. The first "health" event is the full report, each one after is a status change. A "heartbeat" event is sent every 30 seconds.
+* @apiName GetHealthEvents
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiUse HealthEvent
+* @apiPermission Admin
+ */
+func (hac *HealthAdminController) events(c *gin.Context) {
+	streamHealthEvents(c, hac.serviceGroup.HealthService)
+}
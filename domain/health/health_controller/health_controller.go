@@ -26,11 +26,13 @@ func DefaultHealthController(routes *routes.Routes, serviceGroup *service.Servic
 
 func (hc *HealthController) Default() {
 	hc.routes.Public.GET("/healthy", hc.health)
+	hc.routes.Public.GET("/healthy/live", hc.live)
+	hc.routes.Public.GET("/healthy/ready", hc.ready)
 }
 
 /**
 * @api {get} /healthy Service Health Status
-* @apiDescription Used to verify that the services are up and running.
+* @apiDescription Used to verify that the services are up and running. Fails when the database, migrations or a plugin are down.
 * @apiName GetHealthy
 * @apiGroup Utility
  */
@@ -39,19 +41,35 @@ func (hc *HealthController) health(c *gin.Context) {
 	ok, _ := hc.serviceGroup.HealthService.GetHealthStatus()
 
 	if !ok {
+		errors.Response(c, http.StatusInternalServerError, "Service is having health issues", nil)
+		return
+	}
 
-		msg := "Service is having health issues"
+	c.Status(http.StatusOK)
+}
 
-		// todo add a admin key to get more detailed reports
-		//for i, issue := range context {
-		//	if i == 0 {
-		//		msg = fmt.Sprintf("%v %v", msg, issue)
-		//	} else {
-		//		msg = fmt.Sprintf("%v, %v", msg, issue)
-		//	}
-		//}
+/**
+* @api {get} /healthy/live Service Liveness
+* @apiDescription Used to verify that the process is running and serving requests. It doesn't check any dependencies.
+* @apiName GetLive
+* @apiGroup Utility
+ */
+func (hc *HealthController) live(c *gin.Context) {
+	c.Status(http.StatusOK)
+}
 
-		errors.Response(c, http.StatusInternalServerError, msg, nil)
+/**
+* @api {get} /healthy/ready Service Readiness
+* @apiDescription Used to verify that the service can take traffic: the database is reachable and all migrations are applied. Plugins and mail don't affect readiness.
+* @apiName GetReady
+* @apiGroup Utility
+ */
+func (hc *HealthController) ready(c *gin.Context) {
+
+	ok, _ := hc.serviceGroup.HealthService.IsReady()
+
+	if !ok {
+		errors.Response(c, http.StatusServiceUnavailable, "Service is not ready", nil)
 		return
 	}
 
@@ -0,0 +1,46 @@
+package health_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/health/health_service"
+	"net/http"
+	"time"
+)
+
+const (
+	EVENT_HEALTH    = "health"
+	EVENT_HEARTBEAT = "heartbeat"
+	// keeps proxies from closing an idle stream
+	heartbeatInterval = 30 * time.Second
+)
+
+// streamHealthEvents writes status changes as server sent events until the client goes away
+func streamHealthEvents(c *gin.Context, healthService health_service.IHealthService) {
+	events, unsubscribe := healthService.Subscribe()
+	defer unsubscribe()
+
+	heartbeat := time.NewTicker(heartbeatInterval)
+	defer heartbeat.Stop()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// start with the current state so clients don't have to make a second request
+	c.SSEvent(EVENT_HEALTH, healthService.GetHealthReport())
+	c.Writer.Flush()
+
+	done := c.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return
+		case event := <-events:
+			c.SSEvent(EVENT_HEALTH, event)
+		case t := <-heartbeat.C:
+			c.SSEvent(EVENT_HEARTBEAT, t.UTC())
+		}
+		c.Writer.Flush()
+	}
+}
@@ -25,6 +25,8 @@ func DefaultInternalHealthController(iRoutes *routes.InternalRoutes, serviceGrou
 
 func (ihc *InternalHealthController) DefaultInternal() {
 	ihc.internalRoutes.InternalRoot.GET("/healthy", ihc.internalHealth)
+	ihc.internalRoutes.InternalRoot.GET("/health", ihc.internalReport)
+	ihc.internalRoutes.InternalRoot.GET("/health/events", ihc.internalEvents)
 }
 
 /**
@@ -46,3 +48,25 @@ func (hc *InternalHealthController) internalHealth(c *gin.Context) {
 
 	c.Status(http.StatusOK)
 }
+
+/**
+* @api {get} (internal)/health (Internal) Detailed Health Report
+* @apiDescription (Internal) Status of the database, migrations, mail transport and each active plugin along with recent status changes.
+* @apiName Internal-GetHealthReport
+* @apiGroup (Internal) Utility
+* @apiUse HealthReport
+ */
+func (hc *InternalHealthController) internalReport(c *gin.Context) {
+	c.JSON(http.StatusOK, hc.serviceGroup.HealthService.GetHealthReport())
+}
+
+/**
+* @api {get} (internal)/health/events (Internal) Health Events
+* @apiDescription (Internal) Server sent event stream. The first "health" event is the full report, each one after is a status change.
+* @apiName Internal-GetHealthEvents
+* @apiGroup (Internal) Utility
+* @apiUse HealthEvent
+ */
+func (hc *InternalHealthController) internalEvents(c *gin.Context) {
+	streamHealthEvents(c, hc.serviceGroup.HealthService)
+}
@@ -1,6 +1,68 @@
 package health_model
 
-type HealthMonitor struct {
-	Database bool
-	Plugin map[string]bool
+import "time"
+
+const (
+	STATUS_UP       = "up"
+	STATUS_DEGRADED = "degraded"
+	STATUS_DOWN     = "down"
+	STATUS_UNKNOWN  = "unknown"
+)
+
+// component names used in reports and events. plugins are reported as plugin:<pluginId>
+const (
+	COMPONENT_DATABASE   = "database"
+	COMPONENT_MAIL       = "mail"
+	COMPONENT_MIGRATIONS = "migrations"
+	COMPONENT_PLUGIN     = "plugin"
+)
+
+/**
+* @apiDefine ComponentHealth
+* @apiSuccess (Response) {string} name database, mail, migrations or plugin:<pluginId>.
+* @apiSuccess (Response) {string} status up, down or unknown before the first check.
+* @apiSuccess (Response) {Date} [lastCheck]
+* @apiSuccess (Response) {number} [latencyMs] How long the last check took.
+* @apiSuccess (Response) {string} [error] Why the last check failed.
+* @apiSuccess (Response) {string} [detail] ie. the number of pending migrations or that mail is simulated.
+* @apiSuccess (Response) {string} [breaker] Plugins only. closed, open or half-open.
+ */
+type ComponentHealth struct {
+	Name      string     `json:"name"`
+	Status    string     `json:"status"`
+	LastCheck *time.Time `json:"lastCheck,omitempty"`
+	LatencyMs float64    `json:"latencyMs,omitempty"`
+	Error     string     `json:"error,omitempty"`
+	Detail    string     `json:"detail,omitempty"`
+	Breaker   string     `json:"breaker,omitempty"`
+}
+
+/**
+* @apiDefine HealthEvent
+* @apiSuccess (Response) {string} component
+* @apiSuccess (Response) {string} status The new status.
+* @apiSuccess (Response) {string} previousStatus
+* @apiSuccess (Response) {string} [error]
+* @apiSuccess (Response) {Date} time
+ */
+type HealthEvent struct {
+	Component      string    `json:"component"`
+	Status         string    `json:"status"`
+	PreviousStatus string    `json:"previousStatus"`
+	Error          string    `json:"error,omitempty"`
+	Time           time.Time `json:"time"`
+}
+
+/**
+* @apiDefine HealthReport
+* @apiSuccess (Response) {string} status up when everything is up, degraded when a plugin or mail is down or a plugin's breaker isn't closed and down when the cms can't serve requests.
+* @apiSuccess (Response) {bool} ready
+* @apiSuccess (Response) {Object[]} components See ComponentHealth.
+* @apiSuccess (Response) {Object[]} events Recent status changes, newest last. See HealthEvent.
+ */
+type HealthReport struct {
+	Status     string            `json:"status"`
+	Ready      bool              `json:"ready"`
+	Components []ComponentHealth `json:"components"`
+	Events     []HealthEvent     `json:"events"`
 }
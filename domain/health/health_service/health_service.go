@@ -1,25 +1,50 @@
 package health_service
 
 import (
+	"crypto/tls"
 	"fmt"
 	"github.com/gocms-io/gocms/context"
 	"github.com/gocms-io/gocms/domain/health/health_model"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_model"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_circuit_breaker"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_services"
 	"github.com/gocms-io/gocms/init/database"
 	"github.com/gocms-io/gocms/utility/log"
-	"github.com/gocms-io/gocms/utility/rest"
+	"net"
 	"net/http"
+	"net/smtp"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 )
 
+const (
+	// how many status changes are kept for reports
+	eventHistorySize = 100
+	// status changes are dropped for subscribers that fall this far behind
+	subscriberBufferSize = 16
+	checkTimeout         = 5 * time.Second
+)
+
 type IHealthService interface {
 	GetHealthStatus() (ok bool, context []string)
+	IsReady() (ok bool, context []string)
+	GetHealthReport() *health_model.HealthReport
+	GetHealthEvents() []health_model.HealthEvent
+	Subscribe() (events <-chan health_model.HealthEvent, unsubscribe func())
 }
 
 type HealthService struct {
 	db            *database.Database
 	pluginService plugin_services.IPluginsService
-	health        *health_model.HealthMonitor
+	client        *http.Client
+
+	mu          sync.RWMutex
+	components  map[string]*health_model.ComponentHealth
+	events      []health_model.HealthEvent
+	subscribers map[chan health_model.HealthEvent]struct{}
 }
 
 func DefaultHealthService(db *database.Database, pluginService plugin_services.IPluginsService) *HealthService {
@@ -27,88 +52,342 @@ func DefaultHealthService(db *database.Database, pluginService plugin_services.I
 	healthService := &HealthService{
 		db:            db,
 		pluginService: pluginService,
-		// default good until bad
-		health: &health_model.HealthMonitor{
-			Database: true,
-			Plugin:   make(map[string]bool),
-		},
+		client:        &http.Client{Timeout: checkTimeout},
+		components:    make(map[string]*health_model.ComponentHealth),
+		subscribers:   make(map[chan health_model.HealthEvent]struct{}),
 	}
 
+	// the database is already connected so these are quick and readiness is known right away
+	healthService.checkDatabaseHealth()
+	healthService.checkMigrations()
+
+	// mail and plugins can take a while to answer, don't hold up startup
+	healthService.setStatus(health_model.COMPONENT_MAIL, health_model.STATUS_UNKNOWN, 0, nil, "")
+	go healthService.checkMailHealth()
+
 	// add health checks
 	context.Schedule.AddTicker(15*time.Second, healthService.checkDatabaseHealth)
 	context.Schedule.AddTicker(10*time.Second, healthService.checkActivePluginHealth)
+	context.Schedule.AddTicker(60*time.Second, healthService.checkMailHealth)
+	context.Schedule.AddTicker(5*time.Minute, healthService.checkMigrations)
 
 	return healthService
 
 }
 
+// GetHealthStatus is ok when the database, migrations and every plugin are up.
+// Mail isn't included so an unreachable smtp server doesn't fail basic health checks.
 func (healthService *HealthService) GetHealthStatus() (ok bool, context []string) {
 	// set ok until something is wrong
 	ok = true
 
-	// if database is not healthy
-	if !healthService.health.Database {
-		ok = false
-		context = append(context, "Database connection lost")
+	healthService.mu.RLock()
+	defer healthService.mu.RUnlock()
+
+	for _, name := range healthService.componentNames() {
+		component := healthService.components[name]
+		if name == health_model.COMPONENT_MAIL {
+			continue
+		}
+		if component.Status == health_model.STATUS_DOWN {
+			ok = false
+			context = append(context, describe(component))
+		}
 	}
 
-	// check plugins status
-	for pluginId, isRunning := range healthService.health.Plugin {
-		if !isRunning {
+	return ok, context
+}
+
+// IsReady is ok when the cms can serve requests: the database is reachable and fully migrated.
+// Plugins and mail only degrade the service.
+func (healthService *HealthService) IsReady() (ok bool, context []string) {
+	ok = true
+
+	healthService.mu.RLock()
+	defer healthService.mu.RUnlock()
+
+	for _, name := range []string{health_model.COMPONENT_DATABASE, health_model.COMPONENT_MIGRATIONS} {
+		component, found := healthService.components[name]
+		if !found || component.Status != health_model.STATUS_UP {
 			ok = false
-			context = append(context, fmt.Sprintf("Plugin %v, failed to start or is no longer running", pluginId))
+			if found {
+				context = append(context, describe(component))
+			} else {
+				context = append(context, fmt.Sprintf("%v has not been checked", name))
+			}
 		}
 	}
 
 	return ok, context
+}
+
+func (healthService *HealthService) GetHealthReport() *health_model.HealthReport {
+	ready, _ := healthService.IsReady()
+
+	healthService.mu.RLock()
+	defer healthService.mu.RUnlock()
+
+	report := &health_model.HealthReport{
+		Status:     health_model.STATUS_UP,
+		Ready:      ready,
+		Components: make([]health_model.ComponentHealth, 0, len(healthService.components)),
+		Events:     append([]health_model.HealthEvent{}, healthService.events...),
+	}
+
+	if !ready {
+		report.Status = health_model.STATUS_DOWN
+	}
+	for _, name := range healthService.componentNames() {
+		component := *healthService.components[name]
+
+		// breaker state changes between checks so it is read as the report is made
+		if strings.HasPrefix(name, health_model.COMPONENT_PLUGIN+":") {
+			if breaker, ok := plugin_circuit_breaker.Get(strings.TrimPrefix(name, health_model.COMPONENT_PLUGIN+":")); ok {
+				component.Breaker = breaker.Status().State
+			}
+		}
+
+		report.Components = append(report.Components, component)
+		degraded := component.Status == health_model.STATUS_DOWN || (component.Breaker != "" && component.Breaker != plugin_circuit_breaker.STATE_CLOSED)
+		if degraded && report.Status == health_model.STATUS_UP {
+			report.Status = health_model.STATUS_DEGRADED
+		}
+	}
+
+	return report
+}
 
+func (healthService *HealthService) GetHealthEvents() []health_model.HealthEvent {
+	healthService.mu.RLock()
+	defer healthService.mu.RUnlock()
+	return append([]health_model.HealthEvent{}, healthService.events...)
+}
+
+// Subscribe returns a channel that receives every status change until unsubscribe is called
+func (healthService *HealthService) Subscribe() (<-chan health_model.HealthEvent, func()) {
+	events := make(chan health_model.HealthEvent, subscriberBufferSize)
+
+	healthService.mu.Lock()
+	healthService.subscribers[events] = struct{}{}
+	healthService.mu.Unlock()
+
+	var once sync.Once
+	unsubscribe := func() {
+		once.Do(func() {
+			healthService.mu.Lock()
+			delete(healthService.subscribers, events)
+			healthService.mu.Unlock()
+		})
+	}
+	return events, unsubscribe
 }
 
 func (healthService *HealthService) checkActivePluginHealth() {
-	go func() {
-		for _, plugin := range healthService.pluginService.GetActivePlugins() {
-			// if plugin is not running and it is not external
-			if !plugin.Running && !plugin.IsExternal {
-				log.Errorf("[Health Service] - Plugin %v, failed to start or is no longer running\n", plugin.Manifest.Id)
-				healthService.health.Plugin[plugin.Manifest.Id] = false
-			} else {
-				// if health checks are not enabled we are good, and done!
-				if !plugin.Manifest.Services.HealthCheck {
-					healthService.health.Plugin[plugin.Manifest.Id] = true
-					return
-				}
-
-				// otherwise we need make a health check request first
-				healthUrl := fmt.Sprintf("%v://%v:%v/api/healthy", plugin.RoutesProxy.Schema, plugin.RoutesProxy.Host, plugin.RoutesProxy.Port)
-				request := rest.Request{
-					Url: healthUrl,
-				}
-				response, err := request.Get()
-				if err != nil {
-					log.Warningf("Error making plugin %v health request%v\n", plugin.Manifest.Id, err.Error())
-					healthService.health.Plugin[plugin.Manifest.Id] = false
-
-				} else if response.StatusCode != http.StatusOK {
-					log.Warningf("Plugin %v health request came back bad %v\n", plugin.Manifest.Id, response.StatusCode)
-					healthService.health.Plugin[plugin.Manifest.Id] = false
-				} else {
-					healthService.health.Plugin[plugin.Manifest.Id] = true
-				}
-			}
+	activePlugins := healthService.pluginService.GetActivePlugins()
+
+	for _, plugin := range activePlugins {
+		healthService.checkPluginHealth(plugin)
+	}
+
+	// forget plugins that have been deactivated
+	healthService.mu.Lock()
+	for name := range healthService.components {
+		if !strings.HasPrefix(name, health_model.COMPONENT_PLUGIN+":") {
+			continue
+		}
+		if _, ok := activePlugins[strings.TrimPrefix(name, health_model.COMPONENT_PLUGIN+":")]; !ok {
+			delete(healthService.components, name)
 		}
-	}()
+	}
+	healthService.mu.Unlock()
+}
+
+func (healthService *HealthService) checkPluginHealth(plugin *plugin_model.Plugin) {
+	name := pluginComponent(plugin.Manifest.Id)
+	defer healthService.recoverCheck(name)
+	start := time.Now()
+
+	// if plugin is not running and it is not external
+	if !plugin.IsRunning() && !plugin.IsExternal {
+		healthService.setStatus(name, health_model.STATUS_DOWN, 0, fmt.Errorf("failed to start or is no longer running"), "")
+		return
+	}
+
+	// if health checks are not enabled we are good
+	if !plugin.Manifest.Services.HealthCheck {
+		healthService.setStatus(name, health_model.STATUS_UP, 0, nil, "health checks not enabled")
+		return
+	}
+
+	// otherwise we need make a health check request first
+	healthUrl := fmt.Sprintf("%v://%v:%v/api/healthy", plugin.RoutesProxy.Schema, plugin.RoutesProxy.Host, plugin.RoutesProxy.Port)
+	client := healthService.client
+	if plugin.Transport != nil {
+		// use the plugin's tls settings but not its breaker, health checks have to reach it while the breaker is open
+		client = &http.Client{Timeout: checkTimeout, Transport: plugin.Transport}
+	}
+	response, err := client.Get(healthUrl)
+	if err != nil {
+		healthService.setStatus(name, health_model.STATUS_DOWN, time.Since(start), err, "")
+		return
+	}
+	response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		healthService.setStatus(name, health_model.STATUS_DOWN, time.Since(start), fmt.Errorf("health request came back %v", response.StatusCode), "")
+	} else {
+		healthService.setStatus(name, health_model.STATUS_UP, time.Since(start), nil, "")
+	}
 }
 
 func (healthService *HealthService) checkDatabaseHealth() {
-	go func() {
-		//log.Debugf("[Health Service] - ping database for active connection\n")
-		// check for database connectivity
-		err := healthService.db.SQL.Dbx.Ping()
-		if err != nil { // no connectivity
-			log.Errorf("[Health Service] - Database connection lost: %v\n", err.Error())
-			healthService.health.Database = false
-		} else { // good connectivity
-			healthService.health.Database = true
+	defer healthService.recoverCheck(health_model.COMPONENT_DATABASE)
+	start := time.Now()
+	err := healthService.db.SQL.Dbx.Ping()
+	if err != nil { // no connectivity
+		healthService.setStatus(health_model.COMPONENT_DATABASE, health_model.STATUS_DOWN, time.Since(start), err, "")
+	} else { // good connectivity
+		healthService.setStatus(health_model.COMPONENT_DATABASE, health_model.STATUS_UP, time.Since(start), nil, "")
+	}
+}
+
+func (healthService *HealthService) checkMigrations() {
+	defer healthService.recoverCheck(health_model.COMPONENT_MIGRATIONS)
+	start := time.Now()
+	pending, err := healthService.db.SQL.PendingMigrations()
+	if err != nil {
+		healthService.setStatus(health_model.COMPONENT_MIGRATIONS, health_model.STATUS_DOWN, time.Since(start), err, "")
+		return
+	}
+	if len(pending) > 0 {
+		healthService.setStatus(health_model.COMPONENT_MIGRATIONS, health_model.STATUS_DOWN, time.Since(start),
+			fmt.Errorf("%d migrations pending", len(pending)), strings.Join(pending, ", "))
+		return
+	}
+	healthService.setStatus(health_model.COMPONENT_MIGRATIONS, health_model.STATUS_UP, time.Since(start), nil, "up to date")
+}
+
+// checkMailHealth connects to the smtp server and waits for its greeting. Nothing is sent.
+func (healthService *HealthService) checkMailHealth() {
+	defer healthService.recoverCheck(health_model.COMPONENT_MAIL)
+	if context.Config.DbVars.SMTPSimulate {
+		healthService.setStatus(health_model.COMPONENT_MAIL, health_model.STATUS_UP, 0, nil, "mail is simulated")
+		return
+	}
+
+	start := time.Now()
+	host := context.Config.DbVars.SMTPServer
+	address := net.JoinHostPort(host, fmt.Sprint(context.Config.DbVars.SMTPPort))
+
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: checkTimeout}
+	// port 465 expects tls from the start, the same as the mail dialer
+	if context.Config.DbVars.SMTPPort == 465 {
+		conn, err = tls.DialWithDialer(dialer, "tcp", address, &tls.Config{ServerName: host})
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+	if err != nil {
+		healthService.setStatus(health_model.COMPONENT_MAIL, health_model.STATUS_DOWN, time.Since(start), err, address)
+		return
+	}
+	conn.SetDeadline(time.Now().Add(checkTimeout))
+
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		healthService.setStatus(health_model.COMPONENT_MAIL, health_model.STATUS_DOWN, time.Since(start), err, address)
+		return
+	}
+	client.Quit()
+	client.Close()
+
+	healthService.setStatus(health_model.COMPONENT_MAIL, health_model.STATUS_UP, time.Since(start), nil, address)
+}
+
+// recoverCheck marks a component down when its check panics instead of letting the panic take down the process
+func (healthService *HealthService) recoverCheck(name string) {
+	if r := recover(); r != nil {
+		log.Errorf("Health check for %v panicked: %v\n%s", name, r, debug.Stack())
+		healthService.setStatus(name, health_model.STATUS_DOWN, 0, fmt.Errorf("health check panicked: %v", r), "")
+	}
+}
+
+// setStatus records the result of a check and publishes an event when the status changed
+func (healthService *HealthService) setStatus(name string, status string, latency time.Duration, err error, detail string) {
+	now := time.Now()
+	component := health_model.ComponentHealth{
+		Name:      name,
+		Status:    status,
+		LatencyMs: float64(latency) / float64(time.Millisecond),
+		Detail:    detail,
+	}
+	if status != health_model.STATUS_UNKNOWN {
+		component.LastCheck = &now
+	}
+	if err != nil {
+		component.Error = err.Error()
+	}
+
+	healthService.mu.Lock()
+	defer healthService.mu.Unlock()
+
+	previous := health_model.STATUS_UNKNOWN
+	if existing, ok := healthService.components[name]; ok {
+		previous = existing.Status
+	}
+	healthService.components[name] = &component
+
+	if previous == status {
+		return
+	}
+
+	event := health_model.HealthEvent{
+		Component:      name,
+		Status:         status,
+		PreviousStatus: previous,
+		Error:          component.Error,
+		Time:           now,
+	}
+
+	if status == health_model.STATUS_DOWN {
+		log.Errorf("[Health Service] - %v is down: %v\n", name, component.Error)
+	} else if previous != health_model.STATUS_UNKNOWN {
+		log.Infof("[Health Service] - %v is %v\n", name, status)
+	}
+
+	healthService.events = append(healthService.events, event)
+	if len(healthService.events) > eventHistorySize {
+		healthService.events = healthService.events[len(healthService.events)-eventHistorySize:]
+	}
+
+	// never block a check on a slow subscriber
+	for subscriber := range healthService.subscribers {
+		select {
+		case subscriber <- event:
+		default:
 		}
-	}()
+	}
+}
+
+// componentNames returns component names in a stable order. Callers must hold the lock.
+func (healthService *HealthService) componentNames() []string {
+	names := make([]string, 0, len(healthService.components))
+	for name := range healthService.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func pluginComponent(pluginId string) string {
+	return fmt.Sprintf("%v:%v", health_model.COMPONENT_PLUGIN, pluginId)
+}
+
+func describe(component *health_model.ComponentHealth) string {
+	if component.Error == "" {
+		return fmt.Sprintf("%v is %v", component.Name, component.Status)
+	}
+	return fmt.Sprintf("%v is %v: %v", component.Name, component.Status, component.Error)
 }
@@ -2,15 +2,23 @@ package mail_service
 
 import (
 	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/setting/setting_model"
 	"gopkg.in/gomail.v2"
 	"io"
 	"path/filepath"
 	"text/template"
 	"time"
 	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/metrics"
+	"github.com/gocms-io/gocms/utility/trace"
 	"fmt"
+	"sync"
 )
 
+var mailSent = metrics.NewCounterVec("gocms_mail_sent_total",
+	"Mail send attempts by outcome: sent, failed or simulated.",
+	"result")
+
 type IMailService interface {
 	Send(*Mail) error
 }
@@ -19,6 +27,7 @@ type MailService struct {
 	Dialer          *gomail.Dialer
 	From            string
 	DefaultTemplate *template.Template
+	mu              sync.RWMutex
 }
 
 type Mail struct {
@@ -42,6 +51,15 @@ func DefaultMailService() *MailService {
 
 }
 
+// rebuild the smtp dialer after the settings are refreshed
+func (ms *MailService) RefreshSettings(settings map[string]setting_model.Setting) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	ms.Dialer = gomail.NewDialer(context.Config.DbVars.SMTPServer, int(context.Config.DbVars.SMTPPort), context.Config.DbVars.SMTPUser, context.Config.DbVars.SMTPPassword)
+	ms.From = context.Config.DbVars.SMTPFromAddress
+}
+
 func (ms *MailService) Send(mail *Mail) error {
 
 	if mail.BodyHTML == "" {
@@ -55,8 +73,12 @@ func (ms *MailService) Send(mail *Mail) error {
 		"headerImage": fmt.Sprintf("http://static.gocms.io/default_assets/default_email_img.jpg"),
 	}
 
+	ms.mu.RLock()
+	dialer, from := ms.Dialer, ms.From
+	ms.mu.RUnlock()
+
 	m := gomail.NewMessage()
-	m.SetHeader("From", ms.From)
+	m.SetHeader("From", from)
 	m.SetHeader("To", mail.To)
 	m.SetHeader("Subject", mail.Subject)
 	m.SetBody("text/plain", mail.Body)
@@ -70,12 +92,20 @@ func (ms *MailService) Send(mail *Mail) error {
 
 	// Send the email
 	if !context.Config.DbVars.SMTPSimulate {
-		err := ms.Dialer.DialAndSend(m)
+		span := trace.StartRoot("mail send", trace.KIND_CLIENT)
+		span.SetAttribute("smtp.server", dialer.Host)
+		err := dialer.DialAndSend(m)
+		span.SetError(err)
+		span.End()
 		if err != nil {
-			log.Errorf("Error sending mail: " + err.Error())
+			mailSent.Inc("failed")
+			log.Errorf("Error sending mail: %v\n", err.Error())
+		} else {
+			mailSent.Inc("sent")
 		}
 	} else {
-		log.Debugf("Email simulated: " + mail.Body)
+		mailSent.Inc("simulated")
+		log.Debugf("Email simulated: %v\n", mail.Body)
 	}
 
 	return nil
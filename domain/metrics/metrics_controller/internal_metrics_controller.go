@@ -0,0 +1,47 @@
+package metrics_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/init/service"
+	"github.com/gocms-io/gocms/routes"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/metrics"
+	"net/http"
+)
+
+// METRICS_ROUTE is the only internal route that accepts the microservice secret as a bearer token
+const METRICS_ROUTE = "/metrics"
+
+type InternalMetricsController struct {
+	internalRoutes *routes.InternalRoutes
+	serviceGroup   *service.ServicesGroup
+}
+
+func DefaultInternalMetricsController(iRoutes *routes.InternalRoutes, serviceGroup *service.ServicesGroup) *InternalMetricsController {
+	imc := &InternalMetricsController{
+		internalRoutes: iRoutes,
+		serviceGroup:   serviceGroup,
+	}
+
+	imc.DefaultInternal()
+	return imc
+}
+
+func (imc *InternalMetricsController) DefaultInternal() {
+	imc.internalRoutes.InternalRoot.GET(METRICS_ROUTE, imc.metrics)
+}
+
+/**
+* @api {get} (internal)/metrics (Internal) Metrics
+* @apiDescription (Internal) Prometheus metrics for http requests, the database pool, plugins, mail and settings. The microservice secret may be sent as a bearer token so scrapers can authenticate. Other internal routes don't accept a bearer token.
+* @apiName Internal-GetMetrics
+* @apiGroup (Internal) Utility
+* @apiHeader {String} X-GOCMS-MICROSERVICE-SECRET Microservice secret. Or "Authorization: Bearer <secret>".
+ */
+func (imc *InternalMetricsController) metrics(c *gin.Context) {
+	c.Header("Content-Type", metrics.ContentType)
+	c.Status(http.StatusOK)
+	if err := metrics.Write(c.Writer); err != nil {
+		log.Errorf("Error writing metrics: %v\n", err.Error())
+	}
+}
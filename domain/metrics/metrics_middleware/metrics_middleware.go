@@ -0,0 +1,85 @@
+package metrics_middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/context/consts"
+	"github.com/gocms-io/gocms/routes"
+	"github.com/gocms-io/gocms/utility/api_utility"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/metrics"
+	"strconv"
+	"time"
+)
+
+// requests that never reach a route group (404s, the react catch all, aborted by top level middleware)
+// share one label so random urls can't blow up the number of series
+const UNMATCHED = "unmatched"
+
+var (
+	httpRequests = metrics.NewCounterVec("gocms_http_requests_total",
+		"Total HTTP requests by route, route group and status.",
+		"method", "route", "group", "status")
+	httpRequestDuration = metrics.NewHistogramVec("gocms_http_request_duration_seconds",
+		"HTTP request latency by route and route group.",
+		metrics.DefaultBuckets,
+		"method", "route", "group")
+)
+
+// HttpMetrics times every request. It should be the first middleware on an engine so latency covers the whole chain.
+func HttpMetrics() gin.HandlerFunc {
+	log.Debugf("Adding HTTP Metrics Middleware\n")
+	return httpMetricsMiddleware
+}
+
+// each group has its own function so the route table can tell which group a route was registered on
+var routeGroups = map[string]gin.HandlerFunc{
+	routes.ROOT:           func(c *gin.Context) { labelRouteGroup(c, routes.ROOT) },
+	routes.PUBLIC:         func(c *gin.Context) { labelRouteGroup(c, routes.PUBLIC) },
+	routes.PRE_TWO_FACTOR: func(c *gin.Context) { labelRouteGroup(c, routes.PRE_TWO_FACTOR) },
+	routes.AUTH:           func(c *gin.Context) { labelRouteGroup(c, routes.AUTH) },
+}
+
+// RouteGroup labels requests handled by routes on the group. Apply it to the group before registering routes.
+func RouteGroup(name string) gin.HandlerFunc {
+	if handler, ok := routeGroups[name]; ok {
+		return handler
+	}
+	return func(c *gin.Context) {
+		labelRouteGroup(c, name)
+	}
+}
+
+func labelRouteGroup(c *gin.Context, name string) {
+	c.Set(consts.ROUTE_GROUP_KEY_FOR_GIN_CONTEXT, name)
+	c.Next()
+}
+
+// ApplyRouteGroups labels each group. Call it after auth is applied so PreTwofactor is its own group.
+func ApplyRouteGroups(r *routes.Routes) {
+	for name, handler := range routeGroups {
+		api_utility.AddRouteGroupHandler(name, handler)
+	}
+
+	r.Root.Use(RouteGroup(routes.ROOT))
+	r.Public.Use(RouteGroup(routes.PUBLIC))
+	r.PreTwofactor.Use(RouteGroup(routes.PRE_TWO_FACTOR))
+	r.Auth.Use(RouteGroup(routes.AUTH))
+}
+
+func httpMetricsMiddleware(c *gin.Context) {
+	start := time.Now()
+
+	c.Next()
+
+	group, route := UNMATCHED, UNMATCHED
+	if g, exists := c.Get(consts.ROUTE_GROUP_KEY_FOR_GIN_CONTEXT); exists {
+		group = g.(string)
+		if r, ok := api_utility.GetRouteFromContext(c); ok {
+			route = r
+		}
+	}
+
+	method := c.Request.Method
+	httpRequests.Inc(method, route, group, strconv.Itoa(c.Writer.Status()))
+	httpRequestDuration.Observe(time.Since(start).Seconds(), method, route, group)
+}
@@ -0,0 +1,116 @@
+package metrics_middleware
+
+import (
+	"bytes"
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/routes"
+	"github.com/gocms-io/gocms/utility/api_utility"
+	"github.com/gocms-io/gocms/utility/metrics"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func testEngine() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(api_utility.MatchRoute(r))
+	r.Use(HttpMetrics())
+
+	api := r.Group("/test-api")
+	api.Use(RouteGroup("testGroup"))
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	api.GET("/user", ok)
+	api.GET("/user/:userId/devices/:deviceId", ok)
+	api.GET("/files/*path", ok)
+	api.POST("/user/:userId", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	return r
+}
+
+func scrape(t *testing.T) string {
+	var buf bytes.Buffer
+	if err := metrics.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestHttpMetricsLabelsRegisteredRoute(t *testing.T) {
+	r := testEngine()
+
+	requests := []struct {
+		method string
+		path   string
+		label  string
+	}{
+		// param values that match other segments must not change the route
+		{"GET", "/test-api/user/user/devices/devices", `method="GET",route="/test-api/user/:userId/devices/:deviceId",group="testGroup",status="200"`},
+		{"GET", "/test-api/user", `method="GET",route="/test-api/user",group="testGroup",status="200"`},
+		{"GET", "/test-api/files/a/b/c", `method="GET",route="/test-api/files/*path",group="testGroup",status="200"`},
+		{"POST", "/test-api/user/42", `method="POST",route="/test-api/user/:userId",group="testGroup",status="201"`},
+		// unknown urls share one series
+		{"GET", "/test-api/nothing/here", `method="GET",route="unmatched",group="unmatched",status="404"`},
+	}
+
+	for _, request := range requests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(request.method, request.path, nil))
+	}
+
+	got := scrape(t)
+	for _, request := range requests {
+		if !strings.Contains(got, "gocms_http_requests_total{"+request.label+"} 1\n") {
+			t.Errorf("%v %v: missing %v in\n%s", request.method, request.path, request.label, got)
+		}
+		if !strings.Contains(got, "gocms_http_request_duration_seconds_count{"+request.label[:strings.Index(request.label, ",status=")]+"} 1\n") {
+			t.Errorf("%v %v: missing duration for %v", request.method, request.path, request.label)
+		}
+	}
+	if strings.Contains(got, "/test-api/nothing/here") {
+		t.Error("unmatched paths should not be used as labels")
+	}
+}
+
+func TestLookupRouteFindsRouteGroup(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	ok := func(c *gin.Context) { c.Status(http.StatusOK) }
+	requireUser := func(c *gin.Context) { c.Next() }
+
+	groups := &routes.Routes{
+		Root:   r.Group("/"),
+		Public: r.Group("/api"),
+		Auth:   r.Group("/api", requireUser),
+	}
+	groups.PreTwofactor = groups.Auth.Group("")
+	ApplyRouteGroups(groups)
+
+	groups.Root.GET("/content/:pluginId/*filepath", ok)
+	groups.Public.POST("/login", ok)
+	groups.PreTwofactor.GET("/verify-device", ok)
+	groups.Auth.GET("/user", ok)
+	r.GET("/ungrouped", ok)
+
+	lookups := []struct {
+		method string
+		path   string
+		route  string
+		group  string
+		found  bool
+	}{
+		{"GET", "/content/contact/main.js", "/content/:pluginId/*filepath", routes.ROOT, true},
+		{"POST", "/api/login", "/api/login", routes.PUBLIC, true},
+		{"GET", "/api/verify-device", "/api/verify-device", routes.PRE_TWO_FACTOR, true},
+		{"GET", "/api/user", "/api/user", routes.AUTH, true},
+		{"GET", "/ungrouped", "/ungrouped", "", true},
+		{"GET", "/api/login", "", "", false},
+	}
+
+	for _, lookup := range lookups {
+		route, group, found := api_utility.LookupRoute(r, lookup.method, lookup.path)
+		if route != lookup.route || group != lookup.group || found != lookup.found {
+			t.Errorf("%v %v: got %q %q %v, want %q %q %v", lookup.method, lookup.path, route, group, found, lookup.route, lookup.group, lookup.found)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package plugin_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/context/consts"
+	"github.com/gocms-io/gocms/init/service"
+	"github.com/gocms-io/gocms/routes"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+)
+
+type InternalPluginController struct {
+	internalRoutes *routes.InternalRoutes
+	servicesGroup  *service.ServicesGroup
+}
+
+func DefaultInternalPluginController(iRoutes *routes.InternalRoutes, sg *service.ServicesGroup) *InternalPluginController {
+	internalPluginController := &InternalPluginController{
+		internalRoutes: iRoutes,
+		servicesGroup:  sg,
+	}
+	internalPluginController.InternalDefault()
+	return internalPluginController
+}
+
+func (ipc *InternalPluginController) InternalDefault() {
+	ipc.internalRoutes.InternalRoot.GET("/plugins/:pluginId/settings", ipc.getSettings)
+}
+
+/**
+* @api {get} (internal)/plugins/:pluginId/settings (Internal) Get Plugin Settings
+* @apiName GetPluginSettingValues
+* @apiGroup (Internal) Plugins
+* @apiDescription (Internal) get the value of every setting declared in the plugin manifest, including secrets. Unset settings use their default.
+* Only the plugin itself can get its settings. It must send its id and plugin secret or a client certificate with its id as the common name.
+* @apiHeader {String} X-GOCMS-PLUGIN-ID Id of the calling plugin.
+* @apiHeader {String} X-GOCMS-PLUGIN-SECRET Secret of the calling plugin.
+* @apiSuccess (Response) {Object} settings Setting names mapped to values.
+ */
+func (ipc *InternalPluginController) getSettings(c *gin.Context) {
+	pluginId := c.Param("pluginId")
+
+	// settings include secrets so a plugin can only get its own
+	client, _ := c.Get(consts.MICROSERVICE_CLIENT_KEY_FOR_GIN_CONTEXT)
+	if clientId, ok := client.(string); !ok || clientId != pluginId {
+		errors.Response(c, http.StatusForbidden, "Plugins can only get their own settings.", nil)
+		return
+	}
+
+	settings, err := ipc.servicesGroup.PluginsService.GetPluginSettings(pluginId)
+	if err != nil {
+		errors.Response(c, http.StatusNotFound, "Couldn't get plugin settings.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
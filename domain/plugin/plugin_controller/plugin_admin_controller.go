@@ -0,0 +1,115 @@
+package plugin_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/acl/access_control/access_control_middleware"
+	"github.com/gocms-io/gocms/domain/acl/permissions"
+	"github.com/gocms-io/gocms/init/service"
+	"github.com/gocms-io/gocms/routes"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+)
+
+type PluginAdminController struct {
+	routes        *routes.Routes
+	ServicesGroup *service.ServicesGroup
+	adminRoutes   *gin.RouterGroup
+}
+
+func DefaultPluginAdminController(routes *routes.Routes, sg *service.ServicesGroup) *PluginAdminController {
+	pluginAdminController := &PluginAdminController{
+		routes:        routes,
+		ServicesGroup: sg,
+	}
+
+	// add acl rules to route
+	pluginAdminController.adminRoutes = routes.Auth.Group("/admin", access_control_middleware.RequirePermission(sg.AclService, permissions.SUPER_ADMIN))
+
+	pluginAdminController.Default()
+	return pluginAdminController
+}
+
+func (pac *PluginAdminController) Default() {
+	pac.adminRoutes.GET("/plugins/:pluginId/settings", pac.getSettings)
+	pac.adminRoutes.PUT("/plugins/:pluginId/settings", pac.updateSettings)
+	pac.adminRoutes.GET("/plugin-middleware", pac.getMiddlewareChain)
+}
+
+/**
+* @api {get} /admin/plugins/:pluginId/settings Get Plugin Settings
+* @apiDescription Get every setting declared in the plugin manifest. Secret values are never returned.
+* @apiName GetPluginSettings
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiParam {string} pluginId
+* @apiUse SettingDisplay
+* @apiPermission Admin
+ */
+func (pac *PluginAdminController) getSettings(c *gin.Context) {
+	settings, err := pac.ServicesGroup.PluginsService.GetPluginSettingDisplays(c.Param("pluginId"))
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't get plugin settings.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+/**
+* @api {put} /admin/plugins/:pluginId/settings Update Plugin Settings
+* @apiDescription Update one or more plugin settings. All values are validated before any are saved. The plugin is notified
+* through its settingsChanged hook.
+* @apiName UpdatePluginSettings
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiParam {string} pluginId
+* @apiParam {Object} body Setting names mapped to their new values. e.g. {"API_KEY": "abc"}
+* @apiUse SettingsUpdateResult
+* @apiPermission Admin
+ */
+func (pac *PluginAdminController) updateSettings(c *gin.Context) {
+	var values map[string]string
+	err := c.BindJSON(&values)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, errors.ApiError_Json, err)
+		return
+	}
+
+	result, err := pac.ServicesGroup.PluginsService.UpdatePluginSettings(c.Param("pluginId"), values)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't update plugin settings.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+/**
+* @api {get} /admin/plugin-middleware Get Plugin Middleware Chain
+* @apiDescription Show which plugin middleware would be called for a request and in what order.
+* @apiName GetPluginMiddlewareChain
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiParam {string} path ie. /api/contact
+* @apiParam {string} [method=GET]
+* @apiUse MiddlewareChain
+* @apiPermission Admin
+ */
+func (pac *PluginAdminController) getMiddlewareChain(c *gin.Context) {
+	path := c.Query("path")
+	if path == "" {
+		errors.Response(c, http.StatusBadRequest, "A path is required.", nil)
+		return
+	}
+	method := c.Query("method")
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	// the route and group come from the router so the chain is the one the request would really get
+	route, group, _ := pac.routes.LookupRoute(method, path)
+	c.JSON(http.StatusOK, pac.ServicesGroup.PluginsService.GetMiddlewareChain(method, path, route, group))
+}
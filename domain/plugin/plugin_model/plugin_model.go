@@ -1,11 +1,14 @@
 package plugin_model
 
 import (
+	"net/http"
 	"os/exec"
+	"sync/atomic"
 	"time"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_routes_proxy"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_middleware_proxy"
 	"database/sql"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_model"
 )
 
 // Plugin is the default plugin object used by GoCMS. For a default plugin look at:
@@ -18,12 +21,38 @@ type Plugin struct {
 	RoutesProxy       *plugin_routes_proxy.PluginRoutesProxy
 	MiddlewareProxies []*plugin_middleware_proxy.PluginMiddlewareProxy
 	Cmd               *exec.Cmd
-	Running           bool
+	// running and stopping are set by the goroutine watching the process and read by health checks and shutdown
+	running           atomic.Bool
+	stopping          atomic.Bool
+	// Exited is closed when the current process exits
+	Exited            chan struct{}
 	Database          *PluginDatabaseRecord
 	IsExternal     bool           `db:"isExternal"`
 	ExternalSchema sql.NullString `db:"externalSchema"`
 	ExternalHost   sql.NullString `db:"externalHost"`
 	ExternalPort   sql.NullInt64 `db:"externalPort"`
+	ExternalTls    *PluginTls
+	ProxySettings  *PluginProxySettings
+	// Transport is used for every call to the plugin. Proxies wrap it with retries and the circuit breaker.
+	Transport http.RoundTripper
+}
+
+// IsRunning is true while the local plugin process is running
+func (p *Plugin) IsRunning() bool {
+	return p.running.Load()
+}
+
+func (p *Plugin) SetRunning(running bool) {
+	p.running.Store(running)
+}
+
+// IsStopping is true when gocms stopped the plugin on purpose so it isn't restarted
+func (p *Plugin) IsStopping() bool {
+	return p.stopping.Load()
+}
+
+func (p *Plugin) SetStopping(stopping bool) {
+	p.stopping.Store(stopping)
 }
 
 // PluginManifest is the root manifest object.
@@ -48,6 +77,8 @@ type PluginManifest struct {
 	Services PluginServices `json:"services"`
 	// Interface see "Plugin Interface"
 	Interface PluginInterface `json:"interface"`
+	// Settings see "PluginManifestSetting"
+	Settings []*PluginManifestSetting `json:"settings,omitempty"`
 }
 
 // PluginServices should the plugin provide backend services, like an API, that configuration is done in this section.
@@ -58,6 +89,20 @@ type PluginServices struct {
 	Bin         string                      `json:"bin"`
 	Docs        string                      `json:"docs"`
 	HealthCheck bool                        `json:"healthCheck"`
+	// UserData see "PluginUserDataHooks"
+	UserData PluginUserDataHooks `json:"userData"`
+	// SettingsChanged path called with POST after an admin updates the plugin settings. The body is a "PluginSettingsChanged".
+	SettingsChanged string `json:"settingsChanged,omitempty"`
+}
+
+// PluginUserDataHooks let a plugin take part in user data exports and account deletion. Hooks are called on the plugin with the
+// microservice secret and user context headers.
+type PluginUserDataHooks struct {
+	// Export path called with GET when a user exports their data. The response body is added to the export as plugins/<plugin id>.json
+	Export string `json:"export,omitempty"`
+	// Delete path called with DELETE when a user account is permanently deleted. The plugin must respond with 200 for the deletion to continue.
+	// Failed deletions are retried.
+	Delete string `json:"delete,omitempty"`
 }
 
 // PluginManifestRoute routes for the api services are defined here. Currently only HTTP Request are supported through a reverse proxy provided by the GoCMS Parent Service
@@ -79,6 +124,9 @@ type PluginManifestRoute struct {
 	// look here:
 	// github.com/gocms-io/gocms/tree/alpha-release/domain/acl/permissions/permissions.go
 	Permissions []string `json:"permissions,omitempty"`
+	// RateLimit optional quota for this endpoint. Ex {"requests": 10, "window": 60, "by": "user"}. By may be "ip" or "user" and defaults to "ip".
+	// Requests are counted per route in addition to any limit applied to the route group.
+	RateLimit *rate_limit_model.RateLimit `json:"rateLimit,omitempty"`
 }
 
 // PluginManifestRoute manifest for the api services are defined here. Currently only HTTP Request are supported through a reverse proxy provided by the GoCMS Parent Service
@@ -98,6 +146,8 @@ type PluginManifestMiddleware struct {
 	HeadersToReceive []string `json:"headersToReceive"`
 	// If the middleware should modify the body of the original request
 	CopyBody bool `json:"copyBody"`
+	// MaxBodySize largest request body in bytes sent to the middleware. It can only lower PLUGIN_MAX_BODY_SIZE. 0 uses the setting.
+	MaxBodySize int64 `json:"maxBodySize"`
 	// Continue executing request on error
 	ContinueOnError bool `json:"continueOnError"`
 	// If the middleware error response should be passed along
@@ -105,6 +155,50 @@ type PluginManifestMiddleware struct {
 	// DisableNamespace GoCMS will utilize the plugin id and prepend it to the URL to guarantee that all plugins play nice. This can cause for ugly api endpoints.
 	// this functionality can be disabled. When disabled the URL will be only what is specified. If there is a conflict with another plugin GoCMS will crash.
 	DisableNamespace bool `json:"disableNamespace"`
+	// Paths the middleware is called for. ie. /api/contact, /api/users/:userId or /api/contact/*. Empty is every path.
+	Paths []string `json:"paths"`
+	// Methods the middleware is called for. ie. POST. Empty is every method.
+	Methods []string `json:"methods"`
+	// Groups route groups the middleware is called for: Root, Public, PreTwoFactor or Auth. Empty is every request.
+	// Groups are only known from rank 2000 so they are ignored for lower ranks.
+	Groups []string `json:"groups"`
+}
+
+/**
+* @apiDefine MiddlewareChain
+* @apiSuccess (Response) {string} method
+* @apiSuccess (Response) {string} path
+* @apiSuccess (Response) {string} [group] Route group of the route the request reaches.
+* @apiSuccess (Response) {string} route None, Core or Plugin. Rank 3000-3999 doesn't reach core routes and 4000+ only reaches requests without a route.
+* @apiSuccess (Response) {Object[]} middleware Every plugin middleware in the order it runs.
+* @apiSuccess (Response) {string} middleware.pluginId
+* @apiSuccess (Response) {string} middleware.name
+* @apiSuccess (Response) {number} middleware.executionRank
+* @apiSuccess (Response) {string} middleware.stage 1-999, 1000-1999, 2000-2999, 3000-3999 or 4000+.
+* @apiSuccess (Response) {string[]} [middleware.paths]
+* @apiSuccess (Response) {string[]} [middleware.methods]
+* @apiSuccess (Response) {string[]} [middleware.groups]
+* @apiSuccess (Response) {bool} middleware.called If the middleware would be called for the request.
+* @apiSuccess (Response) {string} [middleware.skipped] method, path, group or route when it isn't called.
+ */
+type MiddlewareChain struct {
+	Method     string                `json:"method"`
+	Path       string                `json:"path"`
+	Group      string                `json:"group,omitempty"`
+	Route      string                `json:"route"`
+	Middleware []MiddlewareChainLink `json:"middleware"`
+}
+
+type MiddlewareChainLink struct {
+	PluginId      string   `json:"pluginId"`
+	Name          string   `json:"name"`
+	ExecutionRank int64    `json:"executionRank"`
+	Stage         string   `json:"stage"`
+	Paths         []string `json:"paths,omitempty"`
+	Methods       []string `json:"methods,omitempty"`
+	Groups        []string `json:"groups,omitempty"`
+	Called        bool     `json:"called"`
+	Skipped       string   `json:"skipped,omitempty"`
 }
 
 // PluginInterface when plugins provide front-end functionality they must serve specific files. More details on this later. For now see the Contact Form Plugin Example:
@@ -129,8 +223,39 @@ type PluginDatabaseRecord struct {
 	ExternalSchema sql.NullString `db:"externalSchema"`
 	ExternalHost   sql.NullString `db:"externalHost"`
 	ExternalPort   sql.NullInt64 `db:"externalPort"`
+	PluginTls
+	PluginProxySettings
 	ManifestData   sql.NullString `db:"manifest"`
 	Manifest       *PluginManifest `db:"-"`
 	Created        time.Time      `db:"created"`
 	LastModified   time.Time      `db:"lastModified"`
 }
+
+// PluginTls is how GoCMS connects to an external plugin over https. Empty fields use the system defaults.
+type PluginTls struct {
+	// CA is a PEM bundle. When set only these CAs are trusted for the plugin.
+	CA sql.NullString `db:"externalTlsCA"`
+	// Cert and Key are the PEM client certificate presented to the plugin. The key is encrypted with the master key.
+	Cert       sql.NullString `db:"externalTlsCert"`
+	Key        sql.NullString `db:"externalTlsKey"`
+	ServerName sql.NullString `db:"externalTlsServerName"`
+	// SkipVerify turns off certificate verification. Only for development.
+	SkipVerify bool `db:"externalTlsSkipVerify"`
+}
+
+// IsSet is false when the plugin uses the default transport
+func (pt *PluginTls) IsSet() bool {
+	return pt.CA.String != "" || pt.Cert.String != "" || pt.Key.String != "" || pt.ServerName.String != "" || pt.SkipVerify
+}
+
+// PluginProxySettings tune calls to a plugin. NULL columns use the defaults.
+type PluginProxySettings struct {
+	// ConnectTimeout and ResponseTimeout are in milliseconds. The response timeout is the wait for headers.
+	ConnectTimeout  sql.NullInt64 `db:"proxyConnectTimeout"`
+	ResponseTimeout sql.NullInt64 `db:"proxyResponseTimeout"`
+	// Retries for idempotent requests without a body
+	Retries sql.NullInt64 `db:"proxyRetries"`
+	// BreakerThreshold consecutive failures open the breaker for BreakerCooldown seconds. 0 turns the breaker off.
+	BreakerThreshold sql.NullInt64 `db:"breakerThreshold"`
+	BreakerCooldown  sql.NullInt64 `db:"breakerCooldown"`
+}
@@ -0,0 +1,63 @@
+package plugin_model
+
+import (
+	"github.com/gocms-io/gocms/domain/setting/setting_model"
+	"time"
+)
+
+// PluginManifestSetting declares a setting the plugin can be configured with. Values are stored by GoCMS, edited by admins, and
+// served to the plugin over the internal api at /internal/api/plugins/<plugin id>/settings.
+type PluginManifestSetting struct {
+	// Name unique within the plugin. Ex API_KEY
+	Name string `json:"name"`
+	// Type string, int, bool or enum.
+	Type string `json:"type"`
+	// Default value used until an admin sets one.
+	Default string `json:"default"`
+	// Min smallest int value or shortest string.
+	Min *int64 `json:"min,omitempty"`
+	// Max largest int value or longest string.
+	Max *int64 `json:"max,omitempty"`
+	// Options allowed values for enum settings.
+	Options []string `json:"options,omitempty"`
+	// Secret values are never returned by the admin api.
+	Secret bool `json:"secret"`
+	// RequiresRestart tells admins the plugin must restart before a change takes effect.
+	RequiresRestart bool `json:"requiresRestart"`
+	// Description displayed in the GoCMS settings.
+	Description string `json:"description"`
+}
+
+// PluginSetting is a setting value stored by GoCMS. This is NOT part of the manifest.
+type PluginSetting struct {
+	PluginId     string    `db:"pluginId"`
+	Name         string    `db:"name"`
+	Value        string    `db:"value"`
+	Created      time.Time `db:"created"`
+	LastModified time.Time `db:"lastModified"`
+}
+
+// PluginSettingsChanged is posted to the plugin's settingsChanged hook after an admin updates its settings.
+type PluginSettingsChanged struct {
+	// Settings every setting value including defaults.
+	Settings map[string]string `json:"settings"`
+	// Changed names of the settings that were updated.
+	Changed []string `json:"changed"`
+}
+
+func (pms *PluginManifestSetting) GetSettingSchema() *setting_model.SettingSchema {
+	schema := setting_model.SettingSchema{
+		Name:            pms.Name,
+		Type:            pms.Type,
+		Default:         pms.Default,
+		Min:             pms.Min,
+		Max:             pms.Max,
+		Options:         pms.Options,
+		Secret:          pms.Secret,
+		RequiresRestart: pms.RequiresRestart,
+	}
+	if schema.Type == "" {
+		schema.Type = setting_model.SETTING_TYPE_STRING
+	}
+	return &schema
+}
@@ -0,0 +1,201 @@
+package plugin_circuit_breaker
+
+import (
+	"errors"
+	"fmt"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/metrics"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	STATE_CLOSED    = "closed"
+	STATE_OPEN      = "open"
+	STATE_HALF_OPEN = "half-open"
+)
+
+var breakerTransitions = metrics.NewCounterVec("gocms_plugin_circuit_breaker_transitions_total",
+	"Plugin circuit breaker state changes by the state entered.",
+	"plugin", "state")
+
+// OpenError is returned instead of calling a plugin while its breaker is open
+type OpenError struct {
+	PluginId string
+	RetryAt  time.Time
+}
+
+func (oe *OpenError) Error() string {
+	return fmt.Sprintf("circuit breaker for plugin %v is open until %v", oe.PluginId, oe.RetryAt.Format(time.RFC3339))
+}
+
+// IsOpen is true when err, or an error it wraps, came from an open breaker
+func IsOpen(err error) bool {
+	var openErr *OpenError
+	return errors.As(err, &openErr)
+}
+
+// Breaker stops calls to a plugin after Threshold consecutive failures. After Cooldown one trial call is let
+// through. If it succeeds the breaker closes, otherwise it opens again.
+type Breaker struct {
+	PluginId string
+
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	state     string
+	failures  int
+	openedAt  time.Time
+	trial     bool
+}
+
+// Status is a snapshot of a breaker for reports
+type Status struct {
+	State    string     `json:"state"`
+	Failures int        `json:"failures"`
+	OpenedAt *time.Time `json:"openedAt,omitempty"`
+}
+
+var (
+	breakers   = make(map[string]*Breaker)
+	breakersMu sync.Mutex
+)
+
+// ForPlugin returns the breaker shared by every proxy to the plugin, creating it if needed. A threshold of 0 never opens.
+func ForPlugin(pluginId string, threshold int, cooldown time.Duration) *Breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+
+	breaker, ok := breakers[pluginId]
+	if !ok {
+		breaker = &Breaker{PluginId: pluginId, state: STATE_CLOSED}
+		breakers[pluginId] = breaker
+	}
+
+	breaker.mu.Lock()
+	breaker.threshold = threshold
+	breaker.cooldown = cooldown
+	breaker.mu.Unlock()
+
+	return breaker
+}
+
+// Get returns the plugin's breaker if it has one
+func Get(pluginId string) (*Breaker, bool) {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	breaker, ok := breakers[pluginId]
+	return breaker, ok
+}
+
+// PluginIds lists plugins with a breaker
+func PluginIds() []string {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	ids := make([]string, 0, len(breakers))
+	for id := range breakers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Allow returns an OpenError when the call shouldn't be made. Every allowed call must be followed by Success or Failure.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case STATE_OPEN:
+		retryAt := b.openedAt.Add(b.cooldown)
+		if time.Now().Before(retryAt) {
+			return &OpenError{PluginId: b.PluginId, RetryAt: retryAt}
+		}
+		b.setState(STATE_HALF_OPEN)
+		b.trial = true
+		return nil
+	case STATE_HALF_OPEN:
+		// only the trial call goes through
+		if b.trial {
+			return &OpenError{PluginId: b.PluginId, RetryAt: time.Now().Add(b.cooldown)}
+		}
+		b.trial = true
+		return nil
+	}
+	return nil
+}
+
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+	if b.state != STATE_CLOSED {
+		b.setState(STATE_CLOSED)
+	}
+}
+
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.state == STATE_HALF_OPEN || (b.threshold > 0 && b.failures >= b.threshold && b.state == STATE_CLOSED) {
+		b.openedAt = time.Now()
+		b.setState(STATE_OPEN)
+	}
+}
+
+// Release ends an allowed call without counting it either way, ie. when the caller cancelled it
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	b.trial = false
+	b.mu.Unlock()
+}
+
+// Reset closes the breaker, ie. after a local plugin was restarted
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+	if b.state != STATE_CLOSED {
+		b.setState(STATE_CLOSED)
+	}
+}
+
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	status := Status{
+		State:    b.state,
+		Failures: b.failures,
+	}
+	if b.state != STATE_CLOSED {
+		openedAt := b.openedAt
+		status.OpenedAt = &openedAt
+	}
+	return status
+}
+
+// setState must be called with the lock held
+func (b *Breaker) setState(state string) {
+	previous := b.state
+	b.state = state
+	breakerTransitions.Inc(b.PluginId, state)
+
+	pluginLog := log.With(log.PLUGIN_ID, b.PluginId)
+	switch state {
+	case STATE_OPEN:
+		pluginLog.Warningf("Circuit breaker opened for plugin %v after %d failures, retrying in %v\n", b.PluginId, b.failures, b.cooldown)
+	case STATE_CLOSED:
+		pluginLog.Infof("Circuit breaker closed for plugin %v\n", b.PluginId)
+	default:
+		pluginLog.Debugf("Circuit breaker for plugin %v went from %v to %v\n", b.PluginId, previous, state)
+	}
+}
@@ -0,0 +1,80 @@
+package plugin_circuit_breaker
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// wait between retries, multiplied by the attempt
+const retryBackoff = 100 * time.Millisecond
+
+// Transport sends requests to a plugin through its breaker and retries idempotent requests without a body when the plugin
+// can't be reached. Any response, even a 502, 503 or 504, means the plugin answered so it is handed back as is and doesn't
+// count against the breaker.
+type Transport struct {
+	Base    http.RoundTripper
+	Breaker *Breaker
+	Retries int
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	attempts := 1
+	if canRetry(req) {
+		attempts += t.Retries
+	}
+
+	var res *http.Response
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-req.Context().Done():
+				closeBody(req)
+				return nil, req.Context().Err()
+			case <-time.After(time.Duration(attempt) * retryBackoff):
+			}
+		}
+
+		if allowErr := t.Breaker.Allow(); allowErr != nil {
+			closeBody(req)
+			// a retry stopped by the breaker reports why the previous attempt failed, not that the breaker is open
+			if attempt > 0 {
+				break
+			}
+			return nil, allowErr
+		}
+
+		res, err = t.Base.RoundTrip(req)
+
+		// the caller went away, that says nothing about the plugin
+		if req.Context().Err() != nil {
+			t.Breaker.Release()
+			return res, err
+		}
+
+		if err == nil {
+			t.Breaker.Success()
+			return res, nil
+		}
+		t.Breaker.Failure()
+	}
+
+	return nil, fmt.Errorf("plugin %v: %w", t.Breaker.PluginId, err)
+}
+
+// closeBody closes the request body when the base transport never sees the request, as RoundTrip must
+func closeBody(req *http.Request) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+}
+
+// canRetry is true for idempotent methods that don't have a body to replay
+func canRetry(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return req.Body == nil || req.Body == http.NoBody
+	}
+	return false
+}
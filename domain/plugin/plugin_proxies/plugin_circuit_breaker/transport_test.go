@@ -0,0 +1,135 @@
+package plugin_circuit_breaker
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func respond(statusCode int) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: statusCode, Body: ioutil.NopCloser(strings.NewReader(""))}, nil
+	}
+}
+
+var errRefused = errors.New("connection refused")
+
+func refuse(calls *int) roundTripFunc {
+	return func(req *http.Request) (*http.Response, error) {
+		*calls++
+		return nil, errRefused
+	}
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{PluginId: "test", state: STATE_CLOSED, threshold: threshold, cooldown: cooldown}
+}
+
+func get(t *testing.T, transport *Transport) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, "http://plugin.test/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return transport.RoundTrip(req)
+}
+
+func TestPluginErrorResponsesDontCountAsFailures(t *testing.T) {
+	for _, statusCode := range []int{http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout} {
+		calls := 0
+		base := respond(statusCode)
+		transport := &Transport{
+			Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				calls++
+				return base(req)
+			}),
+			Breaker: newBreaker(1, time.Minute),
+			Retries: 2,
+		}
+
+		res, err := get(t, transport)
+		if err != nil {
+			t.Fatalf("%v: %v", statusCode, err)
+		}
+		if res.StatusCode != statusCode {
+			t.Errorf("expected the plugin's %v to be passed through, got %v", statusCode, res.StatusCode)
+		}
+		if calls != 1 {
+			t.Errorf("%v: a response shouldn't be retried, called %v times", statusCode, calls)
+		}
+		if state := transport.Breaker.Status().State; state != STATE_CLOSED {
+			t.Errorf("%v: breaker should stay closed, is %v", statusCode, state)
+		}
+	}
+}
+
+func TestUnreachablePluginOpensBreaker(t *testing.T) {
+	calls := 0
+	transport := &Transport{Base: refuse(&calls), Breaker: newBreaker(3, time.Minute), Retries: 2}
+
+	_, err := get(t, transport)
+	if !errors.Is(err, errRefused) {
+		t.Fatalf("expected the transport error, got %v", err)
+	}
+	if calls != 3 {
+		t.Errorf("expected 3 attempts, got %v", calls)
+	}
+	if state := transport.Breaker.Status().State; state != STATE_OPEN {
+		t.Fatalf("breaker should be open, is %v", state)
+	}
+
+	_, err = get(t, transport)
+	if !IsOpen(err) {
+		t.Errorf("calls while open should get an OpenError, got %v", err)
+	}
+	if calls != 3 {
+		t.Error("an open breaker shouldn't call the plugin")
+	}
+}
+
+func TestFailedHalfOpenRetryReturnsRealError(t *testing.T) {
+	calls := 0
+	breaker := newBreaker(1, time.Minute)
+	breaker.openedAt = time.Now().Add(-2 * time.Minute)
+	breaker.state = STATE_OPEN
+	transport := &Transport{Base: refuse(&calls), Breaker: breaker, Retries: 2}
+
+	_, err := get(t, transport)
+	if IsOpen(err) || !errors.Is(err, errRefused) {
+		t.Errorf("expected the trial's error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("only the trial call should reach the plugin, got %v", calls)
+	}
+}
+
+func TestCancelledRequestIsNotAFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	transport := &Transport{
+		Base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			cancel()
+			return nil, req.Context().Err()
+		}),
+		Breaker: newBreaker(1, time.Minute),
+		Retries: 2,
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://plugin.test/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.RoundTrip(req)
+
+	if status := transport.Breaker.Status(); status.State != STATE_CLOSED || status.Failures != 0 {
+		t.Errorf("a cancelled request shouldn't count, breaker is %+v", status)
+	}
+}
@@ -0,0 +1,198 @@
+package plugin_middleware_proxy
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+var errBodyTooLarge = errors.New("request body is larger than the plugin middleware allows")
+
+// spool keeps a copy of everything written to it. The first memoryLimit bytes are kept in memory and
+// anything larger is moved to a temp file. Write never fails so a bad disk doesn't break the stream to the plugin,
+// the error is kept and reported by Err.
+type spool struct {
+	memoryLimit int64
+	buf         bytes.Buffer
+	file        *os.File
+	size        int64
+	err         error
+}
+
+func newSpool(memoryLimit int64) *spool {
+	return &spool{memoryLimit: memoryLimit}
+}
+
+func (s *spool) Write(p []byte) (int, error) {
+	if s.err != nil {
+		return len(p), nil
+	}
+	if s.file == nil && s.size+int64(len(p)) > s.memoryLimit {
+		s.file, s.err = ioutil.TempFile("", "gocms-body-")
+		if s.err != nil {
+			return len(p), nil
+		}
+		if _, s.err = s.file.Write(s.buf.Bytes()); s.err != nil {
+			return len(p), nil
+		}
+		s.buf = bytes.Buffer{}
+	}
+	if s.file != nil {
+		if _, s.err = s.file.Write(p); s.err != nil {
+			return len(p), nil
+		}
+	} else {
+		s.buf.Write(p)
+	}
+	s.size += int64(len(p))
+	return len(p), nil
+}
+
+func (s *spool) Err() error {
+	return s.err
+}
+
+func (s *spool) Size() int64 {
+	return s.size
+}
+
+// Reader reads the spool from the start
+func (s *spool) Reader() (io.ReadCloser, error) {
+	if s.file == nil {
+		return ioutil.NopCloser(bytes.NewReader(s.buf.Bytes())), nil
+	}
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(s.file), nil
+}
+
+// Close removes the temp file if one was needed
+func (s *spool) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	s.file.Close()
+	return os.Remove(s.file.Name())
+}
+
+// limitedReader ends the body with io.EOF once max bytes have been read and notes if there was more.
+// The plugin gets a short body instead of an error so a client can't make the proxy look like it failed.
+// max 0 is unlimited.
+type limitedReader struct {
+	r        io.Reader
+	max      int64
+	read     int64
+	exceeded bool
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.max <= 0 {
+		return l.r.Read(p)
+	}
+	if l.exceeded {
+		return 0, io.EOF
+	}
+	if l.read >= l.max {
+		// look for one more byte to tell a body of exactly max bytes from a larger one
+		var b [1]byte
+		n, err := l.r.Read(b[:])
+		if n > 0 {
+			l.exceeded = true
+			return 0, io.EOF
+		}
+		return 0, err
+	}
+	if remaining := l.max - l.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	return n, err
+}
+
+// requestBody streams the client's body to the plugin and keeps a copy so it can be read again by the handlers that
+// come after the middleware.
+type requestBody struct {
+	mu       sync.Mutex
+	src      *limitedReader
+	spool    *spool
+	detached bool
+}
+
+func newRequestBody(body io.Reader, max int64, memoryLimit int64) *requestBody {
+	return &requestBody{
+		src:   &limitedReader{r: body, max: max},
+		spool: newSpool(memoryLimit),
+	}
+}
+
+// Read is used by the transport sending the plugin request
+func (b *requestBody) Read(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.detached {
+		return 0, io.EOF
+	}
+	n, err := b.src.Read(p)
+	if n > 0 {
+		b.spool.Write(p[:n])
+	}
+	return n, err
+}
+
+// Close is called by the transport. The client's body belongs to the server so it is left open.
+func (b *requestBody) Close() error {
+	return nil
+}
+
+// Finish stops the transport from reading any more, in case the plugin answered before reading the whole body,
+// and copies what is left of the body into the spool.
+func (b *requestBody) Finish() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.detached = true
+	if _, err := io.Copy(b.spool, b.src); err != nil {
+		return err
+	}
+	if b.src.exceeded {
+		return errBodyTooLarge
+	}
+	return b.spool.Err()
+}
+
+// Reader reads the whole body from the start
+func (b *requestBody) Reader() (io.ReadCloser, error) {
+	return b.spool.Reader()
+}
+
+func (b *requestBody) Size() int64 {
+	return b.spool.Size()
+}
+
+// Release removes any temp file. It is called once the rest of the chain is done with the body.
+func (b *requestBody) Release() error {
+	return b.spool.Close()
+}
+
+// readLimited copies r into a new spool. errBodyTooLarge is returned if r is larger than max.
+func readLimited(r io.Reader, max int64, memoryLimit int64) (*spool, error) {
+	s := newSpool(memoryLimit)
+	src := &limitedReader{r: r, max: max}
+	if _, err := io.Copy(s, src); err != nil {
+		s.Close()
+		return nil, err
+	}
+	if src.exceeded {
+		s.Close()
+		return nil, errBodyTooLarge
+	}
+	if err := s.Err(); err != nil {
+		s.Close()
+		return nil, err
+	}
+	return s, nil
+}
@@ -3,14 +3,20 @@ package plugin_middleware_proxy
 import (
 	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/context"
 	"github.com/gocms-io/gocms/context/consts"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_circuit_breaker"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_proxy_metrics"
 	"github.com/gocms-io/gocms/domain/user/user_middleware"
 	"github.com/gocms-io/gocms/utility/api_utility"
 	"github.com/gocms-io/gocms/utility/errors"
 	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/trace"
 	"net/http"
+	"strconv"
 	"strings"
 	"io"
+	"time"
 )
 
 type PluginMiddlewareProxy struct {
@@ -18,14 +24,20 @@ type PluginMiddlewareProxy struct {
 	Port            int
 	Host            string
 	PluginId        string
+	Name            string
 	ExecutionRank   int64
+	Scope           Scope
 	UpdateProxyChan chan (*PluginMiddlewareProxy)
 	Disabled        bool
+	// Transport carries the plugin's tls settings. nil uses the default transport.
+	Transport http.RoundTripper
 
 	HeadersToReceive []string
 	PassAlongError     bool
 	ContinueOnError  bool
 	CopyBody bool
+	// MaxBodySize from the manifest. It can only lower PLUGIN_MAX_BODY_SIZE.
+	MaxBodySize int64
 }
 
 func (ppm *PluginMiddlewareProxy) MiddlewareProxy() gin.HandlerFunc {
@@ -38,9 +50,15 @@ func (ppm *PluginMiddlewareProxy) middlewareProxy(c *gin.Context) {
 	// check if proxy is disabled and skip with error if it is
 	ppm.handleProxyUpdate(c)
 
+	// leave requests the middleware isn't scoped to alone
+	if ppm.Skipped(c) != "" {
+		return
+	}
+
 	// if disabled then return error and skip
 	if ppm.Disabled {
-		log.Errorf("Plugin proxy is currently disabled for %v\n", ppm.PluginId)
+		ppm.logger(c).Errorf("Plugin proxy is currently disabled for %v\n", ppm.PluginId)
+		plugin_proxy_metrics.Error(ppm.PluginId, plugin_proxy_metrics.PROXY_MIDDLEWARE)
 		errors.Response(c, http.StatusInternalServerError, errors.ApiError_Server, errors.ApiError_Server)
 		return
 	}
@@ -54,9 +72,26 @@ func (ppm *PluginMiddlewareProxy) middlewareProxy(c *gin.Context) {
 
 	// create a new url from the raw RequestURI sent by the client
 	url := fmt.Sprintf("%v://%v:%v/%v/%v%v", ppm.Schema, ppm.Host, ppm.Port, "middleware", ppm.ExecutionRank, nonNamespacedRequestUrl)
-	proxyReq, err := http.NewRequest(c.Request.Method, url, c.Request.Body)
+
+	// the body is streamed to the plugin and kept so the handlers after the middleware can still read it
+	maxBodySize := ppm.maxBodySize()
+	var body *requestBody
+	var proxyBody io.Reader
+	if c.Request.Body != nil && c.Request.Body != http.NoBody {
+		if maxBodySize > 0 && c.Request.ContentLength > maxBodySize {
+			ppm.logger(c).Warningf("Request body of %v bytes is larger than middleware %v allows\n", c.Request.ContentLength, ppm.PluginId)
+			errors.Response(c, http.StatusRequestEntityTooLarge, errors.ApiError_BodyTooLarge, errBodyTooLarge)
+			return
+		}
+		body = newRequestBody(c.Request.Body, maxBodySize, context.Config.DbVars.PluginBodyMemoryLimit)
+		defer body.Release()
+		proxyBody = body
+	}
+
+	proxyReq, err := http.NewRequestWithContext(c.Request.Context(), c.Request.Method, url, proxyBody)
 	if err != nil {
 		log.Debugf("Error creating plugin middleware proxy request %v: %v\n", url, err.Error())
+		plugin_proxy_metrics.Error(ppm.PluginId, plugin_proxy_metrics.PROXY_MIDDLEWARE)
 		if ppm.ContinueOnError {
 			c.Next()
 			return
@@ -65,6 +100,9 @@ func (ppm *PluginMiddlewareProxy) middlewareProxy(c *gin.Context) {
 			return
 		}
 	}
+	if body != nil {
+		proxyReq.ContentLength = c.Request.ContentLength
+	}
 	proxyReq.Header.Set("Host", c.Request.Host)
 	proxyReq.Header.Add("X-Forwarded-For", c.Request.RemoteAddr)
 
@@ -74,15 +112,50 @@ func (ppm *PluginMiddlewareProxy) middlewareProxy(c *gin.Context) {
 		}
 	}
 
-	client := &http.Client{}
+	// each middleware hop is its own span and the plugin continues the trace from it
+	_, span := trace.Start(c.Request.Context(), fmt.Sprintf("plugin middleware %v rank %v", ppm.PluginId, ppm.ExecutionRank), trace.KIND_CLIENT)
+	span.SetAttribute("gocms.plugin_id", ppm.PluginId)
+	span.SetAttribute("gocms.middleware_rank", ppm.ExecutionRank)
+	span.Inject(proxyReq.Header)
+
+	client := &http.Client{Transport: ppm.Transport}
+	start := time.Now()
 	proxyRes, err := client.Do(proxyReq)
+	plugin_proxy_metrics.Observe(ppm.PluginId, plugin_proxy_metrics.PROXY_MIDDLEWARE, start, err != nil || proxyRes.StatusCode >= http.StatusInternalServerError)
 	if err != nil {
-		log.Errorf("Error proxying request %v, to middleware %v: %v\n", nonNamespacedRequestUrl, ppm.PluginId, err.Error())
+		span.SetError(err)
+	} else {
+		span.SetAttribute("http.status_code", proxyRes.StatusCode)
+		if proxyRes.StatusCode >= http.StatusInternalServerError {
+			span.SetError(fmt.Errorf("plugin responded %v", proxyRes.StatusCode))
+		}
+	}
+	span.End()
+	if err == nil {
+		defer proxyRes.Body.Close()
+	}
+
+	// put the whole body back before anything else runs
+	if body != nil {
+		if !ppm.restoreBody(c, body) {
+			return
+		}
+	}
+
+	if err != nil {
+		// an open breaker is expected while the plugin is down, don't log every request as an error
+		code := http.StatusBadRequest
+		if plugin_circuit_breaker.IsOpen(err) {
+			ppm.logger(c).Warningf("Skipping middleware %v for %v: %v\n", ppm.PluginId, nonNamespacedRequestUrl, err.Error())
+			code = http.StatusServiceUnavailable
+		} else {
+			ppm.logger(c).Errorf("Error proxying request %v, to middleware %v: %v\n", nonNamespacedRequestUrl, ppm.PluginId, err.Error())
+		}
 		if ppm.ContinueOnError {
 			c.Next()
 			return
 		} else {
-			errors.Response(c, http.StatusBadRequest, errors.ApiError_Server, err)
+			errors.Response(c, code, errors.ApiError_Server, err)
 			return
 		}
 	}
@@ -98,7 +171,7 @@ func (ppm *PluginMiddlewareProxy) middlewareProxy(c *gin.Context) {
 			_, err = io.Copy(c.Writer, proxyRes.Body)
 			// error with copying body
 			if err != nil {
-				log.Errorf("Error writing proxied response body into response: %v\n", err.Error())
+				ppm.logger(c).Errorf("Error writing proxied response body into response: %v\n", err.Error())
 			}
 			c.Abort()
 			return
@@ -114,22 +187,30 @@ func (ppm *PluginMiddlewareProxy) middlewareProxy(c *gin.Context) {
 
 	// check the body next
 	if ppm.CopyBody {
-		c.Request.Body = proxyRes.Body
+		copied, err := readLimited(proxyRes.Body, maxBodySize, context.Config.DbVars.PluginBodyMemoryLimit)
 		if err != nil {
-			log.Errorf("Error writing proxied response body into response: %v\n", err.Error())
-			// if we continue on error then do so
-			if !ppm.ContinueOnError {
+			ppm.logger(c).Errorf("Error reading body from middleware %v: %v\n", ppm.PluginId, err.Error())
+			// if we continue on error then do so with the original body
+			if ppm.ContinueOnError {
 				c.Next()
 				return
 			}
 			// otherwise respond with error
-			errors.Response(c, http.StatusBadRequest, errors.ApiError_Server, err)
+			errors.Response(c, http.StatusBadGateway, errors.ApiError_Server, err)
+			return
+		}
+		defer copied.Close()
+		c.Request.Body, err = copied.Reader()
+		if err != nil {
+			ppm.logger(c).Errorf("Error rewinding body from middleware %v: %v\n", ppm.PluginId, err.Error())
+			errors.Response(c, http.StatusInternalServerError, errors.ApiError_Server, err)
 			return
 		}
+		c.Request.ContentLength = copied.Size()
 
 		// add headers that relate to body
 		c.Request.Header["Content-Type"] = proxyRes.Header["Content-Type"]
-		c.Request.Header["Content-Length"] = proxyRes.Header["Content-Length"]
+		c.Request.Header.Set("Content-Length", strconv.FormatInt(copied.Size(), 10))
 	}
 
 	c.Next()
@@ -146,9 +227,13 @@ func (ppm *PluginMiddlewareProxy) handleProxyUpdate(c *gin.Context) {
 			ppm.Host = newppm.Host
 			ppm.Schema = newppm.Schema
 			ppm.PluginId = newppm.PluginId
+			ppm.Name = newppm.Name
+			ppm.Scope = newppm.Scope
 			ppm.UpdateProxyChan = newppm.UpdateProxyChan
+			ppm.Transport = newppm.Transport
 
 			ppm.CopyBody = newppm.CopyBody
+			ppm.MaxBodySize = newppm.MaxBodySize
 			ppm.ContinueOnError = newppm.ContinueOnError
 			ppm.PassAlongError = newppm.PassAlongError
 			ppm.HeadersToReceive = newppm.HeadersToReceive
@@ -157,6 +242,49 @@ func (ppm *PluginMiddlewareProxy) handleProxyUpdate(c *gin.Context) {
 	}
 }
 
+// Skipped returns why the request is outside the middleware's scope or an empty string when it should be called
+func (ppm *PluginMiddlewareProxy) Skipped(c *gin.Context) string {
+	group := ""
+	if g, exists := c.Get(consts.ROUTE_GROUP_KEY_FOR_GIN_CONTEXT); exists {
+		group = g.(string)
+	}
+	return ppm.Scope.Skipped(c.Request.Method, c.Request.URL.Path, group, ppm.ExecutionRank >= GROUP_SCOPE_MIN_RANK)
+}
+
+// restoreBody reads whatever the plugin didn't and hands the whole body to the rest of the chain.
+// false means a response has been written.
+func (ppm *PluginMiddlewareProxy) restoreBody(c *gin.Context, body *requestBody) bool {
+	err := body.Finish()
+	if err == errBodyTooLarge {
+		ppm.logger(c).Warningf("Request body is larger than middleware %v allows\n", ppm.PluginId)
+		errors.Response(c, http.StatusRequestEntityTooLarge, errors.ApiError_BodyTooLarge, err)
+		return false
+	}
+	if err != nil {
+		ppm.logger(c).Errorf("Error reading request body for middleware %v: %v\n", ppm.PluginId, err.Error())
+		errors.Response(c, http.StatusBadRequest, errors.ApiError_Server, err)
+		return false
+	}
+	reader, err := body.Reader()
+	if err != nil {
+		ppm.logger(c).Errorf("Error rewinding request body for middleware %v: %v\n", ppm.PluginId, err.Error())
+		errors.Response(c, http.StatusInternalServerError, errors.ApiError_Server, err)
+		return false
+	}
+	c.Request.Body = reader
+	c.Request.ContentLength = body.Size()
+	return true
+}
+
+// maxBodySize is PLUGIN_MAX_BODY_SIZE unless the manifest asks for less. 0 is unlimited.
+func (ppm *PluginMiddlewareProxy) maxBodySize() int64 {
+	max := context.Config.DbVars.PluginMaxBodySize
+	if ppm.MaxBodySize > 0 && (max <= 0 || ppm.MaxBodySize < max) {
+		return ppm.MaxBodySize
+	}
+	return max
+}
+
 func (ppm *PluginMiddlewareProxy) handleHeadersAndUserContext(c *gin.Context) {
 	authUser, _ := api_utility.GetUserFromContext(c)
 	timezone, _ := user_middleware.GetTimezoneFromContext(c)
@@ -165,6 +293,14 @@ func (ppm *PluginMiddlewareProxy) handleHeadersAndUserContext(c *gin.Context) {
 		c.Request.Header.Set(consts.GOCMS_HEADER_USER_CONTEXT_KEY, userHeaderContext)
 	}
 	c.Request.Header.Set(consts.GOCMS_HEADER_TIMEZONE_KEY, timezone.String())
+	if requestId, ok := api_utility.GetRequestIdFromContext(c); ok {
+		c.Request.Header.Set(consts.GOCMS_HEADER_REQUEST_ID, requestId)
+	}
+}
+
+// logger tags lines with the request and this plugin
+func (ppm *PluginMiddlewareProxy) logger(c *gin.Context) *log.Logger {
+	return api_utility.GetLoggerFromContext(c).With(log.PLUGIN_ID, ppm.PluginId)
 }
 
 func singleJoiningSlash(a, b string) string {
@@ -0,0 +1,90 @@
+package plugin_middleware_proxy
+
+import "strings"
+
+// route groups are only known once the request reaches a group, so they are ignored for earlier ranks
+const GROUP_SCOPE_MIN_RANK = 2000
+
+// reasons a middleware was skipped
+const (
+	SKIPPED_METHOD = "method"
+	SKIPPED_PATH   = "path"
+	SKIPPED_GROUP  = "group"
+	// the rank isn't applied to the route the request reaches
+	SKIPPED_ROUTE = "route"
+)
+
+// Scope limits which requests a middleware is called for. Empty lists match everything.
+type Scope struct {
+	// Paths are matched against the request path. :name matches one segment and a final * matches the rest of the path.
+	Paths []string
+	// Methods are upper case http methods
+	Methods []string
+	// Groups are route group names, ie. Public or Auth
+	Groups []string
+}
+
+// Skipped returns why the request doesn't match or an empty string when it does.
+// group is only checked when checkGroup is set. An empty group never matches a group scope.
+func (s *Scope) Skipped(method string, path string, group string, checkGroup bool) string {
+	if len(s.Methods) > 0 && !containsFold(s.Methods, method) {
+		return SKIPPED_METHOD
+	}
+	if len(s.Paths) > 0 && !matchAnyPath(s.Paths, path) {
+		return SKIPPED_PATH
+	}
+	if checkGroup && len(s.Groups) > 0 && (group == "" || !containsFold(s.Groups, group)) {
+		return SKIPPED_GROUP
+	}
+	return ""
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyPath(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if MatchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPath matches a path against a pattern like /api/contact, /api/users/:userId or /themes/*
+func MatchPath(pattern string, path string) bool {
+	patternSegments := splitPath(pattern)
+	pathSegments := splitPath(path)
+
+	for i, segment := range patternSegments {
+		// a trailing wildcard matches the rest, including nothing
+		if strings.HasPrefix(segment, "*") && i == len(patternSegments)-1 {
+			return true
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		if strings.HasPrefix(segment, ":") {
+			continue
+		}
+		if segment != pathSegments[i] {
+			return false
+		}
+	}
+
+	return len(patternSegments) == len(pathSegments)
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
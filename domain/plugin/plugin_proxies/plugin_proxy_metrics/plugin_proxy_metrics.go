@@ -0,0 +1,34 @@
+package plugin_proxy_metrics
+
+import (
+	"github.com/gocms-io/gocms/utility/metrics"
+	"time"
+)
+
+const (
+	PROXY_ROUTES     = "routes"
+	PROXY_MIDDLEWARE = "middleware"
+)
+
+var (
+	proxyDuration = metrics.NewHistogramVec("gocms_plugin_proxy_duration_seconds",
+		"Latency of requests proxied to plugins.",
+		metrics.DefaultBuckets,
+		"plugin", "proxy")
+	proxyErrors = metrics.NewCounterVec("gocms_plugin_proxy_errors_total",
+		"Requests to plugins that failed or returned a server error.",
+		"plugin", "proxy")
+)
+
+// Observe records one proxied request. Failed is true when the plugin couldn't be reached or answered with a 5xx.
+func Observe(pluginId string, proxy string, start time.Time, failed bool) {
+	proxyDuration.Observe(time.Since(start).Seconds(), pluginId, proxy)
+	if failed {
+		proxyErrors.Inc(pluginId, proxy)
+	}
+}
+
+// Error records a request that was rejected before reaching the plugin, ie. a disabled proxy
+func Error(pluginId string, proxy string) {
+	proxyErrors.Inc(pluginId, proxy)
+}
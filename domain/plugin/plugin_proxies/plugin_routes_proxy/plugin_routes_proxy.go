@@ -4,13 +4,17 @@ import (
 	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gocms-io/gocms/context/consts"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_circuit_breaker"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_proxy_metrics"
 	"github.com/gocms-io/gocms/domain/user/user_middleware"
 	"github.com/gocms-io/gocms/utility/api_utility"
 	"github.com/gocms-io/gocms/utility/errors"
 	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/trace"
 	"net/http"
 	"net/http/httputil"
 	"strings"
+	"time"
 )
 
 type PluginRoutesProxy struct {
@@ -21,6 +25,8 @@ type PluginRoutesProxy struct {
 	UpdateProxyChan chan (*PluginRoutesProxy)
 	Disabled        bool
 	IsExternal bool
+	// Transport carries the plugin's tls settings. nil uses the default transport.
+	Transport http.RoundTripper
 }
 
 func (ppm *PluginRoutesProxy) ReverseProxy() gin.HandlerFunc {
@@ -35,7 +41,8 @@ func (ppm *PluginRoutesProxy) reverseProxy(c *gin.Context) {
 
 	// if disabled then return error and skip
 	if ppm.Disabled {
-		log.Errorf("Plugin proxy is currently disabled for %v\n", ppm.PluginId)
+		ppm.logger(c).Errorf("Plugin proxy is currently disabled for %v\n", ppm.PluginId)
+		plugin_proxy_metrics.Error(ppm.PluginId, plugin_proxy_metrics.PROXY_ROUTES)
 		errors.Response(c, http.StatusInternalServerError, errors.ApiError_Server, errors.ApiError_Server)
 		return
 	}
@@ -43,8 +50,13 @@ func (ppm *PluginRoutesProxy) reverseProxy(c *gin.Context) {
 	// transfer headers and user context as needed
 	ppm.handleHeadersAndUserContext(c)
 
+	// the hop to the plugin is its own span and the plugin continues the trace from it
+	_, span := trace.Start(c.Request.Context(), "plugin route "+ppm.PluginId, trace.KIND_CLIENT)
+	span.SetAttribute("gocms.plugin_id", ppm.PluginId)
+
 	// do actual request directing
 	director := func(req *http.Request) {
+		span.Inject(req.Header)
 		// check new port channel in case the plugin has moved ports
 		req.URL.Scheme = ppm.Schema
 		req.URL.Host = fmt.Sprintf("%v:%v", ppm.Host, ppm.Port)
@@ -59,8 +71,35 @@ func (ppm *PluginRoutesProxy) reverseProxy(c *gin.Context) {
 		}
 	}
 
-	proxy := &httputil.ReverseProxy{Director: director}
+	start := time.Now()
+	proxy := &httputil.ReverseProxy{
+		Director:     director,
+		Transport:    ppm.Transport,
+		ErrorHandler: ppm.proxyError(c),
+	}
 	proxy.ServeHTTP(c.Writer, c.Request)
+
+	// the reverse proxy answers 502 itself when the plugin can't be reached
+	plugin_proxy_metrics.Observe(ppm.PluginId, plugin_proxy_metrics.PROXY_ROUTES, start, c.Writer.Status() >= http.StatusInternalServerError)
+
+	span.SetAttribute("http.status_code", c.Writer.Status())
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		span.SetError(fmt.Errorf("plugin responded %v", c.Writer.Status()))
+	}
+	span.End()
+}
+
+// proxyError answers 503 while the plugin's breaker is open and 502 when it couldn't be reached or timed out
+func (ppm *PluginRoutesProxy) proxyError(c *gin.Context) func(http.ResponseWriter, *http.Request, error) {
+	return func(w http.ResponseWriter, req *http.Request, err error) {
+		if plugin_circuit_breaker.IsOpen(err) {
+			ppm.logger(c).Warningf("Not proxying %v to plugin %v: %v\n", req.URL.Path, ppm.PluginId, err.Error())
+			errors.Response(c, http.StatusServiceUnavailable, errors.ApiError_Server, nil)
+			return
+		}
+		ppm.logger(c).Errorf("Error proxying %v to plugin %v: %v\n", req.URL.Path, ppm.PluginId, err.Error())
+		errors.Response(c, http.StatusBadGateway, errors.ApiError_Server, nil)
+	}
 }
 
 func (ppm *PluginRoutesProxy) handleProxyUpdate(c *gin.Context) {
@@ -74,6 +113,7 @@ func (ppm *PluginRoutesProxy) handleProxyUpdate(c *gin.Context) {
 			ppm.Schema = newppm.Schema
 			ppm.PluginId = newppm.PluginId
 			ppm.UpdateProxyChan = newppm.UpdateProxyChan
+			ppm.Transport = newppm.Transport
 		}
 	default:
 	}
@@ -87,6 +127,14 @@ func (ppm *PluginRoutesProxy) handleHeadersAndUserContext(c *gin.Context) {
 		c.Request.Header.Set(consts.GOCMS_HEADER_USER_CONTEXT_KEY, userHeaderContext)
 	}
 	c.Request.Header.Set(consts.GOCMS_HEADER_TIMEZONE_KEY, timezone.String())
+	if requestId, ok := api_utility.GetRequestIdFromContext(c); ok {
+		c.Request.Header.Set(consts.GOCMS_HEADER_REQUEST_ID, requestId)
+	}
+}
+
+// logger tags lines with the request and this plugin
+func (ppm *PluginRoutesProxy) logger(c *gin.Context) *log.Logger {
+	return api_utility.GetLoggerFromContext(c).With(log.PLUGIN_ID, ppm.PluginId)
 }
 
 func singleJoiningSlash(a, b string) string {
@@ -9,6 +9,8 @@ import (
 
 type IPluginRepository interface {
 	GetDatabasePlugins() ([]*plugin_model.PluginDatabaseRecord, error)
+	GetSettings(pluginId string) ([]*plugin_model.PluginSetting, error)
+	SetSetting(*plugin_model.PluginSetting) error
 }
 
 type PluginRepository struct {
@@ -48,3 +50,31 @@ func (pr *PluginRepository) GetDatabasePlugins() ([]*plugin_model.PluginDatabase
 
 	return pluginRecords, nil
 }
+
+// get all stored settings for a plugin
+func (pr *PluginRepository) GetSettings(pluginId string) ([]*plugin_model.PluginSetting, error) {
+	var settings []*plugin_model.PluginSetting
+	err := pr.database.Select(&settings, `
+	SELECT * FROM gocms_plugin_settings WHERE pluginId=?
+	`, pluginId)
+	if err != nil {
+		log.Errorf("Error getting plugin %v settings from database: %s", pluginId, err.Error())
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// add or update a plugin setting
+func (pr *PluginRepository) SetSetting(setting *plugin_model.PluginSetting) error {
+	_, err := pr.database.NamedExec(`
+	INSERT INTO gocms_plugin_settings (pluginId, name, value) VALUES (:pluginId, :name, :value)
+	ON DUPLICATE KEY UPDATE value=VALUES(value)
+	`, setting)
+	if err != nil {
+		log.Errorf("Error setting plugin %v setting %v in database: %s", setting.PluginId, setting.Name, err.Error())
+		return err
+	}
+
+	return nil
+}
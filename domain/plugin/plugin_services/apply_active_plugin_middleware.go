@@ -1,7 +1,13 @@
 package plugin_services
 
 import (
+	"fmt"
+	"net/http"
+	"path"
 	"sort"
+	"strings"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_model"
+	"github.com/gocms-io/gocms/routes"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_middleware_proxy"
 	"github.com/gin-gonic/gin"
 	"github.com/gocms-io/gocms/utility/log"
@@ -84,30 +90,168 @@ func sortByRank(a []*plugin_middleware_proxy.PluginMiddlewareProxy) {
 func (pmpr *PluginMiddlewareProxyByRank) ApplyForRank(rank MiddlewareRank) []gin.HandlerFunc {
 	log.Debugf("Adding Plugin Middleware Rank: %v\n", rank)
 
-	var proxies []*plugin_middleware_proxy.PluginMiddlewareProxy
+	var handlers []gin.HandlerFunc
+	// apply proxies in group
+	for _, proxy := range pmpr.proxiesForRank(rank) {
+		log.Debugf("\t  [%v]:%v", proxy.ExecutionRank, proxy.PluginId)
+		handlers = append(handlers, proxy.MiddlewareProxy())
+	}
+
+	return handlers
+}
 
-	// get correct proxy group to apply
+// proxiesForRank gets the correct proxy group to apply
+func (pmpr *PluginMiddlewareProxyByRank) proxiesForRank(rank MiddlewareRank) []*plugin_middleware_proxy.PluginMiddlewareProxy {
 	switch rank {
 	case MIDDLEWARE_RANK_0:
-		proxies = pmpr.Middleware0
+		return pmpr.Middleware0
 	case MIDDLEWARE_RANK_1:
-		proxies = pmpr.Middleware1
+		return pmpr.Middleware1
 	case MIDDLEWARE_RANK_1000:
-		proxies = pmpr.Middleware1000
+		return pmpr.Middleware1000
 	case MIDDLEWARE_RANK_2000:
-		proxies = pmpr.Middleware2000
+		return pmpr.Middleware2000
 	case MIDDLEWARE_RANK_3000:
-		proxies = pmpr.Middleware3000
+		return pmpr.Middleware3000
 	case MIDDLEWARE_RANK_4000:
-		proxies = pmpr.Middleware4000
+		return pmpr.Middleware4000
 	}
+	return nil
+}
 
-	var handlers []gin.HandlerFunc
-	// apply proxies in group
-	for _, proxy := range proxies {
-		log.Debugf("\t  [%v]:%v", proxy.ExecutionRank, proxy.PluginId)
-		handlers = append(handlers, proxy.MiddlewareProxy())
+// the kind of route a request reaches decides which ranks are applied to it
+const (
+	CHAIN_ROUTE_NONE   = "None"
+	CHAIN_ROUTE_CORE   = "Core"
+	CHAIN_ROUTE_PLUGIN = "Plugin"
+)
+
+// GetMiddlewareChain lists the plugin middleware in the order it runs and whether each would be called for the request.
+// route and group are the registered route the request reaches and its group, both empty when it doesn't match a route.
+func (ps *PluginsService) GetMiddlewareChain(method string, path string, route string, group string) *plugin_model.MiddlewareChain {
+	pmpr := ps.NewPluginMiddlewareProxyByRank()
+
+	chain := plugin_model.MiddlewareChain{
+		Method:     strings.ToUpper(method),
+		Path:       path,
+		Group:      group,
+		Route:      ps.chainRoute(method, route, group),
+		Middleware: []plugin_model.MiddlewareChainLink{},
 	}
 
-	return handlers
+	// rank 0 never runs
+	for _, rank := range []MiddlewareRank{MIDDLEWARE_RANK_1, MIDDLEWARE_RANK_1000, MIDDLEWARE_RANK_2000, MIDDLEWARE_RANK_3000, MIDDLEWARE_RANK_4000} {
+		for _, proxy := range pmpr.proxiesForRank(rank) {
+			skipped := plugin_middleware_proxy.SKIPPED_ROUTE
+			if rankReachesRoute(rank, chain.Route) {
+				skipped = proxy.Scope.Skipped(chain.Method, path, group, proxy.ExecutionRank >= plugin_middleware_proxy.GROUP_SCOPE_MIN_RANK)
+			}
+			chain.Middleware = append(chain.Middleware, plugin_model.MiddlewareChainLink{
+				PluginId:      proxy.PluginId,
+				Name:          proxy.Name,
+				ExecutionRank: proxy.ExecutionRank,
+				Stage:         string(rank),
+				Paths:         proxy.Scope.Paths,
+				Methods:       proxy.Scope.Methods,
+				Groups:        proxy.Scope.Groups,
+				Called:        skipped == "",
+				Skipped:       skipped,
+			})
+		}
+	}
+
+	return &chain
+}
+
+// rankReachesRoute mirrors where the controllers apply each rank. 3000 is applied after the core routes are registered and
+// 4000 after the plugin routes, so both only reach the routes registered later and requests that don't match a route.
+func rankReachesRoute(rank MiddlewareRank, route string) bool {
+	switch rank {
+	case MIDDLEWARE_RANK_3000:
+		return route != CHAIN_ROUTE_CORE
+	case MIDDLEWARE_RANK_4000:
+		return route == CHAIN_ROUTE_NONE
+	}
+	return true
+}
+
+// chainRoute works out if the registered route is a plugin route, a core route or no route at all
+func (ps *PluginsService) chainRoute(method string, route string, group string) string {
+	if route == "" {
+		return CHAIN_ROUTE_NONE
+	}
+
+	for _, plugin := range ps.GetActivePlugins() {
+		if plugin.Manifest == nil {
+			continue
+		}
+		for _, routeManifest := range plugin.Manifest.Services.Routes {
+			url := routeManifest.Url
+			if !routeManifest.DisableNamespace {
+				url = fmt.Sprintf("%v/%v", plugin.Manifest.Id, routeManifest.Url)
+			}
+			if strings.EqualFold(routeManifest.Route, group) && strings.EqualFold(routeManifest.Method, method) &&
+				registeredPath(routeGroupPrefix(routeManifest.Route), url) == route {
+				return CHAIN_ROUTE_PLUGIN
+			}
+		}
+
+		// content and docs served by the plugin
+		if group == routes.ROOT && strings.EqualFold(method, http.MethodGet) {
+			if (plugin.Manifest.Interface.Public != "" && route == fmt.Sprintf("/content/%v/*filepath", plugin.Manifest.Id)) ||
+				(plugin.Manifest.Services.Docs != "" && route == fmt.Sprintf("/docs/%v/*filepath", plugin.Manifest.Id)) {
+				return CHAIN_ROUTE_PLUGIN
+			}
+		}
+	}
+
+	return CHAIN_ROUTE_CORE
+}
+
+// registeredPath joins the paths the way gin does when a route is added to a group
+func registeredPath(prefix string, url string) string {
+	registered := path.Join("/", prefix, url)
+	if strings.HasSuffix(url, "/") && !strings.HasSuffix(registered, "/") {
+		registered += "/"
+	}
+	return registered
+}
+
+// routeGroupPrefix is the path the route group was created with
+func routeGroupPrefix(group string) string {
+	if group == routes.ROOT {
+		return ""
+	}
+	return "/api"
+}
+
+// checkMiddlewareScopes rejects middleware that scopes itself to route groups before the group is known
+func checkMiddlewareScopes(manifest *plugin_model.PluginManifest) error {
+	for _, middleware := range manifest.Services.Middleware {
+		if len(middleware.Groups) > 0 && middleware.ExecutionRank < plugin_middleware_proxy.GROUP_SCOPE_MIN_RANK {
+			return fmt.Errorf("middleware %v has route groups at rank %v, groups need a rank of %v or more", middleware.Name, middleware.ExecutionRank, plugin_middleware_proxy.GROUP_SCOPE_MIN_RANK)
+		}
+	}
+	return nil
+}
+
+// middlewareScope reads the scope from the manifest and warns about unknown route groups
+func middlewareScope(pluginId string, middleware *plugin_model.PluginManifestMiddleware) plugin_middleware_proxy.Scope {
+	scope := plugin_middleware_proxy.Scope{
+		Paths:  middleware.Paths,
+		Groups: middleware.Groups,
+	}
+	for _, method := range middleware.Methods {
+		scope.Methods = append(scope.Methods, strings.ToUpper(method))
+	}
+
+	for _, group := range middleware.Groups {
+		switch strings.ToLower(group) {
+		case strings.ToLower(routes.ROOT), strings.ToLower(routes.PUBLIC), strings.ToLower(routes.PRE_TWO_FACTOR), strings.ToLower(routes.AUTH):
+		default:
+			log.With(log.PLUGIN_ID, pluginId).Warningf("Middleware %v has unknown route group %v\n", middleware.Name, group)
+		}
+	}
+
+	return scope
 }
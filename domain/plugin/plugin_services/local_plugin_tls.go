@@ -0,0 +1,74 @@
+package plugin_services
+
+import (
+	"fmt"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/context/consts"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_model"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/tlsUtil"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// local plugins get a new client certificate every time they start
+const localPluginCertificateLifetime = 365 * 24 * time.Hour
+
+// localPluginTlsEnv tells a local plugin how to reach the internal api over https. The plugin is given the CA to trust and,
+// when INTERNAL_TLS_CLIENT_CA_KEY_FILE is set, a client certificate for its id. The returned func removes the certificate
+// once the plugin exits.
+func localPluginTlsEnv(plugin *plugin_model.Plugin) ([]string, func(), error) {
+	envVars := context.Config.EnvVars
+	if !envVars.InternalTlsEnabled() {
+		return nil, func() {}, nil
+	}
+
+	// without a CA the internal certificate itself is trusted
+	caFile := envVars.InternalTlsCAFile
+	if caFile == "" {
+		caFile = envVars.InternalTlsCertFile
+	}
+	caFile, err := filepath.Abs(caFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	env := []string{fmt.Sprintf("%v=%v", consts.GOCMS_ENV_INTERNAL_CA_FILE, caFile)}
+
+	if envVars.InternalTlsClientCAFile == "" || envVars.InternalTlsClientCAKeyFile == "" {
+		return env, func() {}, nil
+	}
+
+	certPem, keyPem, err := tlsUtil.IssueClientCertificate(envVars.InternalTlsClientCAFile, envVars.InternalTlsClientCAKeyFile, plugin.Manifest.Id, localPluginCertificateLifetime)
+	if err != nil {
+		return nil, nil, fmt.Errorf("issuing client certificate for plugin %v: %v", plugin.Manifest.Id, err)
+	}
+
+	dir, err := ioutil.TempDir("", "gocms-plugin-"+plugin.Manifest.Id+"-")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		if err := os.RemoveAll(dir); err != nil {
+			log.With(log.PLUGIN_ID, plugin.Manifest.Id).Errorf("Error removing plugin client certificate: %v\n", err.Error())
+		}
+	}
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := ioutil.WriteFile(certFile, certPem, 0600); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	if err := ioutil.WriteFile(keyFile, keyPem, 0600); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	env = append(env,
+		fmt.Sprintf("%v=%v", consts.GOCMS_ENV_CLIENT_CERT_FILE, certFile),
+		fmt.Sprintf("%v=%v", consts.GOCMS_ENV_CLIENT_KEY_FILE, keyFile),
+	)
+	return env, cleanup, nil
+}
@@ -0,0 +1,252 @@
+package plugin_services
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_model"
+	"github.com/gocms-io/gocms/domain/setting/setting_model"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/security"
+)
+
+// GetPluginSettingDisplays returns every setting declared in the plugin manifest with secret values removed.
+func (ps *PluginsService) GetPluginSettingDisplays(pluginId string) ([]setting_model.SettingDisplay, error) {
+	manifest, err := ps.getPluginManifest(pluginId)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := ps.getStoredPluginSettings(pluginId)
+	if err != nil {
+		return nil, err
+	}
+
+	displays := make([]setting_model.SettingDisplay, len(manifest.Settings))
+	for i, manifestSetting := range manifest.Settings {
+		setting := &setting_model.Setting{
+			Name:        manifestSetting.Name,
+			Description: manifestSetting.Description,
+		}
+		if s, ok := stored[manifestSetting.Name]; ok {
+			setting.Value = s.Value
+		}
+		displays[i] = *manifestSetting.GetSettingSchema().GetSettingDisplay(setting)
+	}
+
+	return displays, nil
+}
+
+// GetPluginSettings returns the value of every setting declared in the plugin manifest. Unset or invalid values use the default.
+func (ps *PluginsService) GetPluginSettings(pluginId string) (map[string]string, error) {
+	manifest, err := ps.getPluginManifest(pluginId)
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := ps.getStoredPluginSettings(pluginId)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]string, len(manifest.Settings))
+	for _, manifestSetting := range manifest.Settings {
+		schema := manifestSetting.GetSettingSchema()
+		values[schema.Name] = schema.Default
+
+		s, ok := stored[schema.Name]
+		if !ok {
+			continue
+		}
+		if err := schema.Validate(s.Value); err != nil {
+			log.Errorf("Invalid plugin %v setting: %v. Using default: %v\n", pluginId, err.Error(), schema.Default)
+			continue
+		}
+		values[schema.Name] = s.Value
+	}
+
+	return values, nil
+}
+
+// UpdatePluginSettings validates and saves the values then notifies the plugin of the change.
+func (ps *PluginsService) UpdatePluginSettings(pluginId string, values map[string]string) (*setting_model.SettingsUpdateResult, error) {
+	if len(values) == 0 {
+		return nil, errors.NewToUser("No settings to update.")
+	}
+
+	manifest, err := ps.getPluginManifest(pluginId)
+	if err != nil {
+		return nil, err
+	}
+
+	schemas := make(map[string]*setting_model.SettingSchema, len(manifest.Settings))
+	for _, manifestSetting := range manifest.Settings {
+		schemas[manifestSetting.Name] = manifestSetting.GetSettingSchema()
+	}
+
+	// validate everything before writing anything
+	for name, value := range values {
+		schema, ok := schemas[name]
+		if !ok {
+			return nil, errors.NewToUser(fmt.Sprintf("Unknown setting: %v.", name))
+		}
+		if err := schema.Validate(value); err != nil {
+			return nil, errors.NewToUser(err.Error() + ".")
+		}
+	}
+
+	stored, err := ps.getStoredPluginSettings(pluginId)
+	if err != nil {
+		return nil, err
+	}
+
+	changed := []string{}
+	restartRequired := []string{}
+	for _, manifestSetting := range manifest.Settings {
+		value, ok := values[manifestSetting.Name]
+		if !ok {
+			continue
+		}
+		if s, exists := stored[manifestSetting.Name]; exists && s.Value == value {
+			continue
+		}
+
+		if manifestSetting.Secret {
+			value, err = security.EncryptSecret(security.PluginSettingSecretName(pluginId, manifestSetting.Name), value)
+			if err != nil {
+				log.Errorf("Error encrypting plugin %v setting %v: %s\n", pluginId, manifestSetting.Name, err.Error())
+				return nil, err
+			}
+		}
+
+		err := ps.repositoriesGroup.PluginRepository.SetSetting(&plugin_model.PluginSetting{
+			PluginId: pluginId,
+			Name:     manifestSetting.Name,
+			Value:    value,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		changed = append(changed, manifestSetting.Name)
+		if manifestSetting.RequiresRestart {
+			restartRequired = append(restartRequired, manifestSetting.Name)
+		}
+	}
+
+	if len(changed) > 0 {
+		ps.notifySettingsChanged(pluginId, changed)
+	}
+
+	displays, err := ps.GetPluginSettingDisplays(pluginId)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &setting_model.SettingsUpdateResult{
+		Settings:        displays,
+		RestartRequired: restartRequired,
+	}
+	return result, nil
+}
+
+// notifySettingsChanged posts the new settings to the plugin's settingsChanged hook if it is running and has one.
+func (ps *PluginsService) notifySettingsChanged(pluginId string, changed []string) {
+	plugin, ok := ps.GetActivePlugins()[pluginId]
+	if !ok || plugin.RoutesProxy == nil || plugin.Manifest.Services.SettingsChanged == "" {
+		return
+	}
+
+	settings, err := ps.GetPluginSettings(pluginId)
+	if err != nil {
+		return
+	}
+
+	body, err := json.Marshal(&plugin_model.PluginSettingsChanged{
+		Settings: settings,
+		Changed:  changed,
+	})
+	if err != nil {
+		log.Errorf("Error marshaling plugin %v settings: %s\n", pluginId, err.Error())
+		return
+	}
+
+	req := pluginHookRequest(plugin, plugin.Manifest.Services.SettingsChanged)
+	req.Body = body
+	_, err = req.Post()
+	if err != nil {
+		log.Errorf("Error notifying plugin %v of settings change: %s\n", pluginId, err.Error())
+	}
+}
+
+// getPluginManifest finds the manifest of a running, installed, or database plugin.
+func (ps *PluginsService) getPluginManifest(pluginId string) (*plugin_model.PluginManifest, error) {
+	if plugin, ok := ps.GetActivePlugins()[pluginId]; ok && plugin.Manifest != nil {
+		return plugin.Manifest, nil
+	}
+	if plugin, ok := ps.installedPlugins[pluginId]; ok && plugin.Manifest != nil {
+		return plugin.Manifest, nil
+	}
+
+	databasePlugins, err := ps.GetDatabasePlugins()
+	if err == nil {
+		if plugin, ok := databasePlugins[pluginId]; ok && plugin.Manifest != nil {
+			return plugin.Manifest, nil
+		}
+	}
+
+	return nil, errors.NewToUser("Plugin doesn't exist.")
+}
+
+// getStoredPluginSettings returns the stored settings of a plugin with secret values decrypted.
+func (ps *PluginsService) getStoredPluginSettings(pluginId string) (map[string]*plugin_model.PluginSetting, error) {
+	settings, err := ps.repositoriesGroup.PluginRepository.GetSettings(pluginId)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]*plugin_model.PluginSetting, len(settings))
+	for _, setting := range settings {
+		setting.Value, err = security.DecryptSecret(security.PluginSettingSecretName(pluginId, setting.Name), setting.Value)
+		if err != nil {
+			log.Errorf("Error decrypting plugin %v setting %v: %s\n", pluginId, setting.Name, err.Error())
+			return nil, err
+		}
+		stored[setting.Name] = setting
+	}
+	return stored, nil
+}
+
+// encryptPluginSecretSettings encrypts secret plugin settings stored before a master key was configured.
+func (ps *PluginsService) encryptPluginSecretSettings(plugin *plugin_model.Plugin) error {
+	if security.GetMasterKey() == nil || plugin.Manifest == nil {
+		return nil
+	}
+
+	settings, err := ps.repositoriesGroup.PluginRepository.GetSettings(plugin.Manifest.Id)
+	if err != nil {
+		return err
+	}
+
+	secrets := make(map[string]bool, len(plugin.Manifest.Settings))
+	for _, manifestSetting := range plugin.Manifest.Settings {
+		secrets[manifestSetting.Name] = manifestSetting.Secret
+	}
+
+	for _, setting := range settings {
+		if !secrets[setting.Name] || setting.Value == "" || security.IsEncrypted(setting.Value) {
+			continue
+		}
+		setting.Value, err = security.EncryptSecret(security.PluginSettingSecretName(setting.PluginId, setting.Name), setting.Value)
+		if err != nil {
+			return err
+		}
+		err = ps.repositoriesGroup.PluginRepository.SetSetting(setting)
+		if err != nil {
+			return err
+		}
+		log.Infof("Encrypted plugin %v setting %v\n", setting.PluginId, setting.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package plugin_services
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_model"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_circuit_breaker"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/security"
+	"net"
+	"net/http"
+	"time"
+)
+
+// defaults for plugins without proxy settings
+const (
+	DEFAULT_PROXY_CONNECT_TIMEOUT  = 5 * time.Second
+	DEFAULT_PROXY_RESPONSE_TIMEOUT = 30 * time.Second
+	DEFAULT_PROXY_RETRIES          = 2
+	DEFAULT_BREAKER_THRESHOLD      = 5
+	DEFAULT_BREAKER_COOLDOWN       = 30 * time.Second
+)
+
+// pluginTransports sets plugin.Transport and returns the round tripper for its proxies.
+// Both use the plugin's timeouts and, for external plugins, its tls settings.
+func pluginTransports(plugin *plugin_model.Plugin) (http.RoundTripper, error) {
+	settings := plugin.ProxySettings
+	if settings == nil {
+		settings = &plugin_model.PluginProxySettings{}
+	}
+
+	transport, err := pluginTransport(plugin, settings)
+	if err != nil {
+		return nil, err
+	}
+	plugin.Transport = transport
+
+	retries := DEFAULT_PROXY_RETRIES
+	if settings.Retries.Valid {
+		retries = int(settings.Retries.Int64)
+	}
+	threshold := DEFAULT_BREAKER_THRESHOLD
+	if settings.BreakerThreshold.Valid {
+		threshold = int(settings.BreakerThreshold.Int64)
+	}
+	cooldown := DEFAULT_BREAKER_COOLDOWN
+	if settings.BreakerCooldown.Valid {
+		cooldown = time.Duration(settings.BreakerCooldown.Int64) * time.Second
+	}
+
+	return &plugin_circuit_breaker.Transport{
+		Base:    transport,
+		Breaker: plugin_circuit_breaker.ForPlugin(plugin.Manifest.Id, threshold, cooldown),
+		Retries: retries,
+	}, nil
+}
+
+// pluginTransport builds the base transport for a plugin. It returns the interface so a failure is a literal nil and never
+// a typed nil *http.Transport that would pass a nil check.
+func pluginTransport(plugin *plugin_model.Plugin, settings *plugin_model.PluginProxySettings) (http.RoundTripper, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{
+		Timeout:   millisecondsOr(settings.ConnectTimeout.Int64, settings.ConnectTimeout.Valid, DEFAULT_PROXY_CONNECT_TIMEOUT),
+		KeepAlive: 30 * time.Second,
+	}).DialContext
+	transport.ResponseHeaderTimeout = millisecondsOr(settings.ResponseTimeout.Int64, settings.ResponseTimeout.Valid, DEFAULT_PROXY_RESPONSE_TIMEOUT)
+
+	if plugin.IsExternal {
+		tlsConfig, err := externalPluginTlsConfig(plugin.Manifest.Id, plugin.ExternalTls)
+		if err != nil {
+			return nil, err
+		}
+		if tlsConfig != nil && plugin.ExternalSchema.String != "https" {
+			log.Warningf("Plugin %v has tls settings but its schema is %v. They won't be used.\n", plugin.Manifest.Id, plugin.ExternalSchema.String)
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	return transport, nil
+}
+
+func millisecondsOr(value int64, valid bool, fallback time.Duration) time.Duration {
+	if !valid {
+		return fallback
+	}
+	return time.Duration(value) * time.Millisecond
+}
+
+// externalPluginTlsConfig returns nil when the plugin has no tls settings so the system defaults are used
+func externalPluginTlsConfig(pluginId string, pluginTls *plugin_model.PluginTls) (*tls.Config, error) {
+	if pluginTls == nil || !pluginTls.IsSet() {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		ServerName:         pluginTls.ServerName.String,
+		InsecureSkipVerify: pluginTls.SkipVerify,
+	}
+	if pluginTls.SkipVerify {
+		log.Warningf("Certificate verification is off for plugin %v. Only use externalTlsSkipVerify in development.\n", pluginId)
+	}
+
+	if pluginTls.CA.String != "" {
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM([]byte(pluginTls.CA.String)) {
+			return nil, fmt.Errorf("no certificates found in externalTlsCA")
+		}
+	}
+
+	if pluginTls.Cert.String != "" || pluginTls.Key.String != "" {
+		key, err := security.DecryptSecret(security.PluginTlsKeySecretName(pluginId), pluginTls.Key.String)
+		if err != nil {
+			return nil, err
+		}
+		cert, err := tls.X509KeyPair([]byte(pluginTls.Cert.String), []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("client certificate: %v", err.Error())
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
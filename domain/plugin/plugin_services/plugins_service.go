@@ -3,36 +3,52 @@ package plugin_services
 import (
 	"database/sql"
 	"github.com/gocms-io/gocms/domain/acl/access_control/access_control_service"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_service"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_model"
+	"github.com/gocms-io/gocms/domain/setting/setting_model"
+	"github.com/gocms-io/gocms/domain/user/user_model"
 	"github.com/gocms-io/gocms/init/repository"
 	"github.com/gocms-io/gocms/routes"
 	"github.com/gocms-io/gocms/utility/log"
+	"sync"
+	"time"
 )
 
 type IPluginsService interface {
 	StartPluginsService() error
+	StopPlugins(timeout time.Duration) error
 	RegisterActivePluginRoutes(routes *routes.Routes) error
 	GetDatabasePlugins() (map[string]*plugin_model.PluginDatabaseRecord, error)
 	RefreshInstalledPlugins() error
 	GetActivePlugins() map[string]*plugin_model.Plugin
 	NewPluginMiddlewareProxyByRank() *PluginMiddlewareProxyByRank
+	GetMiddlewareChain(method string, path string, route string, group string) *plugin_model.MiddlewareChain
+	ExportUserData(user *user_model.User) (map[string][]byte, map[string]error)
+	DeleteUserData(user *user_model.User) error
+	GetPluginSettingDisplays(pluginId string) ([]setting_model.SettingDisplay, error)
+	GetPluginSettings(pluginId string) (map[string]string, error)
+	UpdatePluginSettings(pluginId string, values map[string]string) (*setting_model.SettingsUpdateResult, error)
 }
 
 
 type PluginsService struct {
 	repositoriesGroup *repository.RepositoriesGroup
 	installedPlugins  map[string]*plugin_model.Plugin
+	// activePlugins is written when plugins start or restart and read by requests, health checks and jobs
 	activePlugins     map[string]*plugin_model.Plugin
+	activePluginsMu   sync.RWMutex
 	aclService        access_control_service.IAclService
+	rateLimitService  rate_limit_service.IRateLimitService
 }
 
-func DefaultPluginsService(rg *repository.RepositoriesGroup, aclService access_control_service.IAclService) *PluginsService {
+func DefaultPluginsService(rg *repository.RepositoriesGroup, aclService access_control_service.IAclService, rateLimitService rate_limit_service.IRateLimitService) *PluginsService {
 
 	pluginsService := &PluginsService{
 		repositoriesGroup: rg,
 		installedPlugins:  make(map[string]*plugin_model.Plugin),
 		activePlugins:     make(map[string]*plugin_model.Plugin),
 		aclService:        aclService,
+		rateLimitService:  rateLimitService,
 	}
 
 	return pluginsService
@@ -58,6 +74,20 @@ func (ps *PluginsService) GetDatabasePlugins() (map[string]*plugin_model.PluginD
 	return databasePluginsMap, nil
 }
 
+// GetActivePlugins returns a copy of the active plugins so callers can range over it while plugins restart
 func (ps *PluginsService) GetActivePlugins() map[string]*plugin_model.Plugin {
-	return ps.activePlugins
+	ps.activePluginsMu.RLock()
+	defer ps.activePluginsMu.RUnlock()
+
+	activePlugins := make(map[string]*plugin_model.Plugin, len(ps.activePlugins))
+	for id, plugin := range ps.activePlugins {
+		activePlugins[id] = plugin
+	}
+	return activePlugins
+}
+
+func (ps *PluginsService) setActivePlugin(plugin *plugin_model.Plugin) {
+	ps.activePluginsMu.Lock()
+	ps.activePlugins[plugin.Manifest.Id] = plugin
+	ps.activePluginsMu.Unlock()
 }
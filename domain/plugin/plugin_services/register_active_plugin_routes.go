@@ -9,6 +9,7 @@ import (
 	"github.com/gocms-io/gocms/utility/log"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_model"
 	"github.com/gocms-io/gocms/domain/acl/access_control/access_control_middleware"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_middleware"
 )
 
 type ProxyRoute struct {
@@ -25,7 +26,7 @@ func (ps *PluginsService) RegisterActivePluginRoutes(routes *routes.Routes) erro
 			routerGroup, err := ps.getRouteGroup(routeManifest.Route, routes)
 			if err != nil {
 				es := fmt.Sprintf("Plugin %s -> Route %s -> Method %s, Url %s, Error: %s\n", plugin.Manifest.Id, routeManifest.Route, routeManifest.Method, routeManifest.Url, err.Error())
-				log.Errorf(es)
+				log.Errorf("%s", es)
 				return err
 			}
 
@@ -65,6 +66,13 @@ func (ps *PluginsService) registerPluginProxyOnRoute(route *gin.RouterGroup, plu
 		url = fmt.Sprintf("%v/%v", plugin.Manifest.Id, routeManifest.Url)
 	}
 
+	// add rate limit middleware if needed
+	if routeManifest.RateLimit.Enabled() {
+		log.Debugf("Adding Rate Limit Middleware for %v\n", routeManifest.Url)
+		name := fmt.Sprintf("plugin:%v:%v:%v", plugin.Manifest.Id, routeManifest.Method, url)
+		handlers = append(handlers, rate_limit_middleware.RateLimit(ps.rateLimitService, name, routeManifest.RateLimit))
+	}
+
 	// add reverse proxy handler
 	handlers = append(handlers, plugin.RoutesProxy.ReverseProxy())
 
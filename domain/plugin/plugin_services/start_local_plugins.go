@@ -4,17 +4,28 @@ import (
 	"bufio"
 	"fmt"
 	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/context/consts"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_model"
 	"github.com/gocms-io/gocms/utility"
 	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/metrics"
+	"github.com/gocms-io/gocms/utility/security"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_routes_proxy"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_middleware_proxy"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_proxies/plugin_circuit_breaker"
 	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/routes"
 )
 
+const LOG_SUBSYSTEM_PLUGIN = "plugin"
+
+var pluginRestarts = metrics.NewCounterVec("gocms_plugin_restarts_total",
+	"Attempts to restart local plugins that stopped unexpectedly.",
+	"plugin", "result")
+
 func (ps *PluginsService) StartPluginsService() (err error) {
 
 	// get plugins that are both active in the database and installed on disk
@@ -25,6 +36,11 @@ func (ps *PluginsService) StartPluginsService() (err error) {
 	}
 
 	for _, plugin := range activePlugins {
+		// encrypt secret settings saved before a master key was configured
+		if encryptErr := ps.encryptPluginSecretSettings(plugin); encryptErr != nil {
+			log.Errorf("Error encrypting plugin %v secret settings: %v\n", plugin.Manifest.Id, encryptErr.Error())
+		}
+
 		// handle external plugins
 		if plugin.IsExternal {
 			newErr := ps.registerExternalPlugin(plugin)
@@ -51,31 +67,46 @@ func (ps *PluginsService) registerExternalPlugin(plugin *plugin_model.Plugin) er
 
 	// check for errors
 	if plugin.ExternalPort.Int64 == 0 {
-		log.Errorf("Plugin %v has nil port\n")
+		log.Errorf("Plugin %v has nil port\n", plugin.Manifest.Id)
 		return errors.New("plugin has a nil port")
 	}
 	if plugin.ExternalHost.String == "" {
-		log.Errorf("Plugin %v has nil host\n")
+		log.Errorf("Plugin %v has nil host\n", plugin.Manifest.Id)
 		return errors.New("plugin has a nil host")
 	}
 	if plugin.ExternalSchema.String == "" {
-		log.Errorf("Plugin %v has nil schema\n")
+		log.Errorf("Plugin %v has nil schema\n", plugin.Manifest.Id)
 		return errors.New("plugin has a nil schema")
 	}
+	if err := checkMiddlewareScopes(plugin.Manifest); err != nil {
+		log.Errorf("Plugin %v has a bad manifest: %v\n", plugin.Manifest.Id, err.Error())
+		return err
+	}
+
+	// the same tls settings and timeouts are used for routes, middleware and health checks
+	transport, err := pluginTransports(plugin)
+	if err != nil {
+		log.Errorf("Plugin %v has bad tls settings: %v\n", plugin.Manifest.Id, err.Error())
+		return err
+	}
 
 	plugin.RoutesProxy = &plugin_routes_proxy.PluginRoutesProxy{
-		Port:     int(plugin.ExternalPort.Int64),
-		Schema:   plugin.ExternalSchema.String,
-		Host:     plugin.ExternalHost.String,
-		PluginId: plugin.Manifest.Id,
-		Disabled: false,
+		Port:      int(plugin.ExternalPort.Int64),
+		Schema:    plugin.ExternalSchema.String,
+		Host:      plugin.ExternalHost.String,
+		PluginId:  plugin.Manifest.Id,
+		Disabled:  false,
+		Transport: transport,
 	}
 
 	// create proxies for middleware use
 	for _, middleware := range plugin.Manifest.Services.Middleware {
 		middleProxy := plugin_middleware_proxy.PluginMiddlewareProxy{
+			Name:             middleware.Name,
+			Scope:            middlewareScope(plugin.Manifest.Id, middleware),
 			ExecutionRank:    middleware.ExecutionRank,
 			CopyBody:         middleware.CopyBody,
+			MaxBodySize:      middleware.MaxBodySize,
 			HeadersToReceive: middleware.HeadersToReceive,
 			PassAlongError:   middleware.PassAlongError,
 			ContinueOnError:  middleware.ContinueOnError,
@@ -84,6 +115,7 @@ func (ps *PluginsService) registerExternalPlugin(plugin *plugin_model.Plugin) er
 			Schema:           plugin.ExternalSchema.String,
 			Host:             plugin.ExternalHost.String,
 			Disabled:         false,
+			Transport:        transport,
 		}
 
 		// add middleware to slice
@@ -94,13 +126,19 @@ func (ps *PluginsService) registerExternalPlugin(plugin *plugin_model.Plugin) er
 	log.Infof("Microservice External: %v\n", plugin.Manifest.Id)
 
 	// add plugin to active list for monitoring and other things
-	ps.activePlugins[plugin.Manifest.Id] = plugin
+	ps.setActivePlugin(plugin)
 
 	return nil
 }
 
 func (ps *PluginsService) startLocalPlugin(plugin *plugin_model.Plugin) error {
 
+	// refuse the manifest before starting anything
+	if err := checkMiddlewareScopes(plugin.Manifest); err != nil {
+		log.Errorf("Couldn't start plugin %v, error: %v", plugin.Manifest.Name, err.Error())
+		return err
+	}
+
 	// find port to run on
 	pluginPort, err := utility.FindPort()
 	if err != nil {
@@ -108,37 +146,60 @@ func (ps *PluginsService) startLocalPlugin(plugin *plugin_model.Plugin) error {
 		return err
 	}
 
+	internalSchema := "http"
+	if context.Config.EnvVars.InternalTlsEnabled() {
+		internalSchema = "https"
+	}
+	tlsEnv, removeTlsFiles, err := localPluginTlsEnv(plugin)
+	if err != nil {
+		log.Errorf("Couldn't start plugin %v, error: %v", plugin.Manifest.Name, err.Error())
+		return err
+	}
+
 	// build command
 	cmd := exec.Command(filepath.FromSlash("./"+plugin.BinaryFile), fmt.Sprintf("-port=%d", pluginPort))
 	cmd.Dir = plugin.PluginRoot
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%v=%v", consts.GOCMS_ENV_PLUGIN_ID, plugin.Manifest.Id),
+		fmt.Sprintf("%v=%v://localhost:%v%v", consts.GOCMS_ENV_INTERNAL_API_URL, internalSchema, context.Config.DbVars.MsPort, routes.INTERNAL_PREFIX),
+		fmt.Sprintf("%v=%v", consts.GOCMS_ENV_PLUGIN_SECRET, security.PluginSecret(context.Config.DbVars.MicroserviceSecret, plugin.Manifest.Id)),
+	)
+	cmd.Env = append(cmd.Env, tlsEnv...)
 
 	// set stdout to pipe
 	cmdStdoutReader, err := cmd.StdoutPipe()
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error creating StdoutPipe for Cmd", err)
+		log.With(log.PLUGIN_ID, plugin.Manifest.Id).Errorf("Error creating StdoutPipe for Cmd: %v\n", err)
+		removeTlsFiles()
 		return err
 	}
 
+	// forward plugin output into the cms log tagged with the plugin
+	pluginLog := log.Subsystem(LOG_SUBSYSTEM_PLUGIN).With(log.PLUGIN_ID, plugin.Manifest.Id)
+
 	// setup stdout to scan continuously
 	stdOutScanner := bufio.NewScanner(cmdStdoutReader)
 	go func() {
+		stdOutLog := pluginLog.With("stream", "stdout")
 		for stdOutScanner.Scan() {
-			fmt.Printf("\t > %s - %s\n", plugin.Manifest.Name, stdOutScanner.Text())
+			stdOutLog.Infof("%s", stdOutScanner.Text())
 		}
 	}()
 
 	// set stderr to pipe
 	cmdStderrReader, err := cmd.StderrPipe()
 	if err != nil {
-		fmt.Fprintln(os.Stderr, "Error creating StdoutPipe for Cmd", err)
+		log.With(log.PLUGIN_ID, plugin.Manifest.Id).Errorf("Error creating StderrPipe for Cmd: %v\n", err)
+		removeTlsFiles()
 		return err
 	}
 
 	// setup stderr to scan continuously
 	stdErrScanner := bufio.NewScanner(cmdStderrReader)
 	go func() {
+		stdErrLog := pluginLog.With("stream", "stderr")
 		for stdErrScanner.Scan() {
-			fmt.Printf("\t > %s - %s\n", plugin.Manifest.Name, stdErrScanner.Text())
+			stdErrLog.Infof("%s", stdErrScanner.Text())
 		}
 	}()
 
@@ -153,7 +214,9 @@ func (ps *PluginsService) startLocalPlugin(plugin *plugin_model.Plugin) error {
 	// kick off the command in a none blocking way
 	go func() {
 		started <- cmd.Start()
-		done <- cmd.Wait()
+		waitErr := cmd.Wait()
+		removeTlsFiles()
+		done <- waitErr
 	}()
 
 	// check to see if there is an error starting plugin
@@ -163,13 +226,24 @@ func (ps *PluginsService) startLocalPlugin(plugin *plugin_model.Plugin) error {
 		return err
 	} else {
 		// no error
-		plugin.Running = true
+		plugin.SetRunning(true)
 	}
 
 	// add handle to command
 	plugin.Cmd = cmd
+	exited := make(chan struct{})
+	plugin.Exited = exited
 
 	// do plugin proxies
+	transport, err := pluginTransports(plugin)
+	if err != nil {
+		return err
+	}
+
+	// a restarted plugin starts with a closed breaker
+	if breaker, ok := plugin_circuit_breaker.Get(plugin.Manifest.Id); ok {
+		breaker.Reset()
+	}
 
 	// create proxy for use during registration
 	plugin.RoutesProxy = &plugin_routes_proxy.PluginRoutesProxy{
@@ -179,14 +253,18 @@ func (ps *PluginsService) startLocalPlugin(plugin *plugin_model.Plugin) error {
 		PluginId:        plugin.Manifest.Id,
 		UpdateProxyChan: newPpmRouteChan,
 		Disabled:        false,
+		Transport:       transport,
 	}
 
 	// create proxies for middleware use
 	for _, middleware := range plugin.Manifest.Services.Middleware {
 		middleProxy := plugin_middleware_proxy.PluginMiddlewareProxy{
+			Name:             middleware.Name,
+			Scope:            middlewareScope(plugin.Manifest.Id, middleware),
 			UpdateProxyChan:  newPpmMiddlewareChan,
 			ExecutionRank:    middleware.ExecutionRank,
 			CopyBody:         middleware.CopyBody,
+			MaxBodySize:      middleware.MaxBodySize,
 			HeadersToReceive: middleware.HeadersToReceive,
 			PassAlongError:   middleware.PassAlongError,
 			ContinueOnError:  middleware.ContinueOnError,
@@ -195,6 +273,7 @@ func (ps *PluginsService) startLocalPlugin(plugin *plugin_model.Plugin) error {
 			Host:             "localhost",
 			Schema:           "http",
 			Disabled:         false,
+			Transport:        transport,
 		}
 
 		// add middleware to slice
@@ -202,11 +281,16 @@ func (ps *PluginsService) startLocalPlugin(plugin *plugin_model.Plugin) error {
 	}
 
 	// add plugin to active list for monitoring and other things
-	ps.activePlugins[plugin.Manifest.Id] = plugin
+	ps.setActivePlugin(plugin)
 
 	go func() {
 		err := <-done
-		plugin.Running = false
+		plugin.SetRunning(false)
+		defer close(exited)
+		if plugin.IsStopping() {
+			log.Infof("Microservice, %v, stopped\n", plugin.Manifest.Id)
+			return
+		}
 		if err != nil {
 			log.Errorf("Microservice, %v, stopped unexpectedly: %v\n", plugin.Manifest.Id, err.Error())
 			// do not restart plugins in dev mode
@@ -215,10 +299,12 @@ func (ps *PluginsService) startLocalPlugin(plugin *plugin_model.Plugin) error {
 				err = ps.startLocalPlugin(plugin)
 			}
 			if err != nil {
+				pluginRestarts.Inc(plugin.Manifest.Id, "failed")
 				plugin.RoutesProxy.Disabled = true
 				newPpmRouteChan <- plugin.RoutesProxy
 				log.Errorf("Microservice, %v, failed to restart: %v\n", plugin.Manifest.Id, err.Error())
 			} else {
+				pluginRestarts.Inc(plugin.Manifest.Id, "restarted")
 				newPpmRouteChan <- plugin.RoutesProxy
 				log.Infof("Hot swapped new plugin. Running on port %v\n", plugin.RoutesProxy.Port)
 			}
@@ -248,6 +334,7 @@ func (ps *PluginsService) getActivePlugins() (map[string]*plugin_model.Plugin, e
 			// if plugin is installed and not flagged as external
 			if ps.installedPlugins[dbPluginId] != nil && !dbPlugin.IsExternal {
 				pluginsToStart[dbPluginId] = ps.installedPlugins[dbPluginId]
+				pluginsToStart[dbPluginId].ProxySettings = &dbPlugin.PluginProxySettings
 			} else if dbPlugin.IsExternal { // if external plugin
 				// add external info
 				pluginsToStart[dbPluginId] = &plugin_model.Plugin{
@@ -256,6 +343,8 @@ func (ps *PluginsService) getActivePlugins() (map[string]*plugin_model.Plugin, e
 					ExternalSchema: dbPlugin.ExternalSchema,
 					ExternalHost:   dbPlugin.ExternalHost,
 					ExternalPort:   dbPlugin.ExternalPort,
+					ExternalTls:    &dbPlugin.PluginTls,
+					ProxySettings:  &dbPlugin.PluginProxySettings,
 				}
 			} else { // plugin is not installed locally, but it is active in the database, and its set to internal. WARN!
 				log.Debugf("Skipping %v, plugin active in database but not installed locally. Should plugin be set to run in 'External Mode'?\n", dbPlugin.PluginId)
@@ -0,0 +1,63 @@
+package plugin_services
+
+import (
+	"github.com/gocms-io/gocms/domain/plugin/plugin_model"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StopPlugins asks every running local plugin to exit and kills the ones still running after timeout.
+// External plugins are left alone.
+func (ps *PluginsService) StopPlugins(timeout time.Duration) error {
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var killed []string
+
+	for _, plugin := range ps.GetActivePlugins() {
+		if plugin.IsExternal || plugin.Cmd == nil || plugin.Cmd.Process == nil || !plugin.IsRunning() {
+			continue
+		}
+
+		wg.Add(1)
+		go func(plugin *plugin_model.Plugin) {
+			defer wg.Done()
+			if !stopLocalPlugin(plugin, timeout) {
+				mu.Lock()
+				killed = append(killed, plugin.Manifest.Id)
+				mu.Unlock()
+			}
+		}(plugin)
+	}
+	wg.Wait()
+
+	if len(killed) > 0 {
+		return errors.New("plugins killed after shutdown timeout: " + strings.Join(killed, ", "))
+	}
+	return nil
+}
+
+// stopLocalPlugin returns false when the plugin had to be killed
+func stopLocalPlugin(plugin *plugin_model.Plugin, timeout time.Duration) bool {
+	plugin.SetStopping(true)
+
+	log.Infof("Microservice Stopping: %v\n", plugin.Manifest.Id)
+	if err := terminate(plugin.Cmd.Process); err != nil {
+		log.Warningf("Error asking plugin %v to stop, killing it: %v\n", plugin.Manifest.Id, err.Error())
+		plugin.Cmd.Process.Kill()
+		<-plugin.Exited
+		return false
+	}
+
+	select {
+	case <-plugin.Exited:
+		return true
+	case <-time.After(timeout):
+		log.Errorf("Plugin %v didn't stop within %v, killing it\n", plugin.Manifest.Id, timeout)
+		plugin.Cmd.Process.Kill()
+		<-plugin.Exited
+		return false
+	}
+}
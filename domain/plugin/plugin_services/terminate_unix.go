@@ -0,0 +1,14 @@
+//go:build !windows
+// +build !windows
+
+package plugin_services
+
+import (
+	"os"
+	"syscall"
+)
+
+// terminate asks the plugin process to exit so it can finish in-flight requests
+func terminate(process *os.Process) error {
+	return process.Signal(syscall.SIGTERM)
+}
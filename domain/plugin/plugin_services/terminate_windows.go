@@ -0,0 +1,13 @@
+//go:build windows
+// +build windows
+
+package plugin_services
+
+import (
+	"os"
+)
+
+// terminate can't signal on windows so the plugin is killed right away
+func terminate(process *os.Process) error {
+	return process.Kill()
+}
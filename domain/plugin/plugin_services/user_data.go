@@ -0,0 +1,72 @@
+package plugin_services
+
+import (
+	"fmt"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/context/consts"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_model"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/rest"
+	"github.com/gocms-io/gocms/utility/security"
+	"strings"
+)
+
+// ExportUserData calls the export hook of each active plugin. Results and errors are keyed by plugin id.
+func (ps *PluginsService) ExportUserData(user *user_model.User) (map[string][]byte, map[string]error) {
+	exports := make(map[string][]byte)
+	exportErrors := make(map[string]error)
+
+	for pluginId, plugin := range ps.GetActivePlugins() {
+		if plugin.Manifest.Services.UserData.Export == "" {
+			continue
+		}
+
+		req := userDataHookRequest(plugin, plugin.Manifest.Services.UserData.Export, user)
+		res, err := req.Get()
+		if err != nil {
+			log.Errorf("Error exporting user %v data from plugin %v: %s\n", user.Id, pluginId, err.Error())
+			exportErrors[pluginId] = err
+			continue
+		}
+		exports[pluginId] = res.Body
+	}
+
+	return exports, exportErrors
+}
+
+// DeleteUserData calls the delete hook of each active plugin. An error is returned if any plugin fails so the deletion can be retried.
+func (ps *PluginsService) DeleteUserData(user *user_model.User) error {
+	var deleteErr error
+
+	for pluginId, plugin := range ps.GetActivePlugins() {
+		if plugin.Manifest.Services.UserData.Delete == "" {
+			continue
+		}
+
+		req := userDataHookRequest(plugin, plugin.Manifest.Services.UserData.Delete, user)
+		_, err := req.Delete()
+		if err != nil {
+			log.Errorf("Error deleting user %v data from plugin %v: %s\n", user.Id, pluginId, err.Error())
+			deleteErr = fmt.Errorf("plugin %v: %v", pluginId, err.Error())
+		}
+	}
+
+	return deleteErr
+}
+
+func userDataHookRequest(plugin *plugin_model.Plugin, path string, user *user_model.User) *rest.Request {
+	req := pluginHookRequest(plugin, path)
+	req.Headers[consts.GOCMS_HEADER_USER_CONTEXT_KEY] = user.GetUserContextHeader().Marshal()
+	return req
+}
+
+// pluginHookRequest creates a request to a path on the plugin authenticated with the plugin's own secret.
+func pluginHookRequest(plugin *plugin_model.Plugin, path string) *rest.Request {
+	return &rest.Request{
+		Url: fmt.Sprintf("%v://%v:%v/%v", plugin.RoutesProxy.Schema, plugin.RoutesProxy.Host, plugin.RoutesProxy.Port, strings.TrimLeft(path, "/")),
+		Headers: map[string]string{
+			consts.GOCMS_HEADER_PLUGIN_SECRET: security.PluginSecret(context.Config.DbVars.MicroserviceSecret, plugin.Manifest.Id),
+		},
+	}
+}
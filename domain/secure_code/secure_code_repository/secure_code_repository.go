@@ -10,7 +10,11 @@ import (
 type ISecureCodeRepository interface {
 	Add(*security_code_model.SecureCode) error
 	Delete(int64) error
+	DeleteForUserByType(int64, security_code_model.SecureCodeType) error
+	DeleteExpiredByType(security_code_model.SecureCodeType, time.Time) error
+	Consume(int64) (bool, error)
 	GetLatestForUserByType(int64, security_code_model.SecureCodeType) (*security_code_model.SecureCode, error)
+	GetAllForUser(int64) ([]security_code_model.SecureCode, error)
 }
 
 type SecureCodeRepository struct {
@@ -56,6 +60,49 @@ func (scr *SecureCodeRepository) Delete(id int64) error {
 	return nil
 }
 
+// delete every code of a type issued to a user
+func (scr *SecureCodeRepository) DeleteForUserByType(id int64, codeType security_code_model.SecureCodeType) error {
+	_, err := scr.database.Exec(`
+	DELETE FROM gocms_secure_codes WHERE userId=? AND type=?
+	`, id, codeType)
+	if err != nil {
+		log.Errorf("Error deleting security codes for user from database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// delete every code of a type created before the given time
+func (scr *SecureCodeRepository) DeleteExpiredByType(codeType security_code_model.SecureCodeType, createdBefore time.Time) error {
+	_, err := scr.database.Exec(`
+	DELETE FROM gocms_secure_codes WHERE type=? AND created<?
+	`, codeType, createdBefore)
+	if err != nil {
+		log.Errorf("Error deleting expired security codes from database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+// Consume deletes a code and reports whether this call deleted it. Only one caller can consume a code.
+func (scr *SecureCodeRepository) Consume(id int64) (bool, error) {
+	result, err := scr.database.Exec(`
+	DELETE FROM gocms_secure_codes WHERE id=?
+	`, id)
+	if err != nil {
+		log.Errorf("Error consuming security code in database: %s", err.Error())
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
 // get all events
 func (scr *SecureCodeRepository) GetLatestForUserByType(id int64, codeType security_code_model.SecureCodeType) (*security_code_model.SecureCode, error) {
 	var secureCode security_code_model.SecureCode
@@ -68,3 +115,16 @@ func (scr *SecureCodeRepository) GetLatestForUserByType(id int64, codeType secur
 	}
 	return &secureCode, nil
 }
+
+// get all codes issued to a user
+func (scr *SecureCodeRepository) GetAllForUser(id int64) ([]security_code_model.SecureCode, error) {
+	var secureCodes []security_code_model.SecureCode
+	err := scr.database.Select(&secureCodes, `
+	SELECT * from gocms_secure_codes WHERE userId=? ORDER BY created DESC
+	`, id)
+	if err != nil {
+		log.Errorf("Error getting security codes for user from database: %s", err.Error())
+		return nil, err
+	}
+	return secureCodes, nil
+}
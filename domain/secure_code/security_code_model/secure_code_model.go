@@ -8,6 +8,8 @@ const (
 	Code_VerifyEmail   SecureCodeType = 1
 	Code_VerifyDevice  SecureCodeType = 2
 	Code_ResetPassword SecureCodeType = 3
+	Code_Invitation    SecureCodeType = 4
+	Code_MagicLink     SecureCodeType = 5
 )
 
 type SecureCode struct {
@@ -0,0 +1,78 @@
+package setting_admin_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/acl/access_control/access_control_middleware"
+	"github.com/gocms-io/gocms/domain/acl/permissions"
+	"github.com/gocms-io/gocms/init/service"
+	"github.com/gocms-io/gocms/routes"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+)
+
+type SettingAdminController struct {
+	routes        *routes.Routes
+	ServicesGroup *service.ServicesGroup
+	adminRoutes   *gin.RouterGroup
+}
+
+func DefaultSettingAdminController(routes *routes.Routes, sg *service.ServicesGroup) *SettingAdminController {
+	settingAdminController := &SettingAdminController{
+		routes:        routes,
+		ServicesGroup: sg,
+	}
+
+	// add acl rules to route
+	settingAdminController.adminRoutes = routes.Auth.Group("/admin", access_control_middleware.RequirePermission(sg.AclService, permissions.SUPER_ADMIN))
+
+	settingAdminController.Default()
+	return settingAdminController
+}
+
+func (sac *SettingAdminController) Default() {
+	sac.adminRoutes.GET("/settings", sac.getAll)
+	sac.adminRoutes.PUT("/settings", sac.update)
+}
+
+/**
+* @api {get} /admin/settings Get Settings
+* @apiDescription Get every core setting with its schema. Secret values are never returned.
+* @apiName GetSettings
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiUse SettingDisplay
+* @apiPermission Admin
+ */
+func (sac *SettingAdminController) getAll(c *gin.Context) {
+	c.JSON(http.StatusOK, sac.ServicesGroup.SettingsService.GetSettingDisplays())
+}
+
+/**
+* @api {put} /admin/settings Update Settings
+* @apiDescription Update one or more settings. All values are validated before any are saved and changes apply immediately
+* unless the setting requires a restart.
+* @apiName UpdateSettings
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiParam {Object} body Setting names mapped to their new values. e.g. {"SMTP_PORT": "587"}
+* @apiUse SettingsUpdateResult
+* @apiPermission Admin
+ */
+func (sac *SettingAdminController) update(c *gin.Context) {
+	var values map[string]string
+	err := c.BindJSON(&values)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, errors.ApiError_Json, err)
+		return
+	}
+
+	result, err := sac.ServicesGroup.SettingsService.UpdateSettings(values)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't update settings.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
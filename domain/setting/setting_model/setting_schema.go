@@ -0,0 +1,229 @@
+package setting_model
+
+import (
+	"fmt"
+	"strconv"
+)
+
+const (
+	SETTING_TYPE_STRING = "string"
+	SETTING_TYPE_INT    = "int"
+	SETTING_TYPE_BOOL   = "bool"
+	SETTING_TYPE_ENUM   = "enum"
+)
+
+// SettingSchema describes a setting stored in gocms_settings.
+// Min and Max bound int values or, for strings, the value length.
+type SettingSchema struct {
+	Name            string
+	Type            string
+	Default         string
+	Min             *int64
+	Max             *int64
+	Options         []string
+	Secret          bool
+	RequiresRestart bool
+	ReadOnly        bool
+}
+
+/**
+* @apiDefine SettingDisplay
+* @apiSuccess (Response) {string} name
+* @apiSuccess (Response) {string} value Empty for secret settings.
+* @apiSuccess (Response) {string} type string, int, bool or enum.
+* @apiSuccess (Response) {string} default
+* @apiSuccess (Response) {number} [min] Smallest int value or shortest string.
+* @apiSuccess (Response) {number} [max] Largest int value or longest string.
+* @apiSuccess (Response) {string[]} [options] Allowed values for enum settings.
+* @apiSuccess (Response) {bool} secret
+* @apiSuccess (Response) {bool} isSet False when the setting is empty.
+* @apiSuccess (Response) {bool} requiresRestart Changes take effect after the server restarts.
+* @apiSuccess (Response) {bool} readOnly
+* @apiSuccess (Response) {string} description
+ */
+type SettingDisplay struct {
+	Name            string   `json:"name"`
+	Value           string   `json:"value"`
+	Type            string   `json:"type"`
+	Default         string   `json:"default"`
+	Min             *int64   `json:"min,omitempty"`
+	Max             *int64   `json:"max,omitempty"`
+	Options         []string `json:"options,omitempty"`
+	Secret          bool     `json:"secret"`
+	IsSet           bool     `json:"isSet"`
+	RequiresRestart bool     `json:"requiresRestart"`
+	ReadOnly        bool     `json:"readOnly"`
+	Description     string   `json:"description"`
+}
+
+/**
+* @apiDefine SettingsUpdateResult
+* @apiSuccess (Response) {Object[]} settings All settings after the update. See Get Settings.
+* @apiSuccess (Response) {string[]} restartRequired Updated settings that take effect after the server restarts.
+ */
+type SettingsUpdateResult struct {
+	Settings        []SettingDisplay `json:"settings"`
+	RestartRequired []string         `json:"restartRequired"`
+}
+
+func bound(i int64) *int64 {
+	return &i
+}
+
+// Schema lists every core setting in display order.
+var Schema = []SettingSchema{
+	// Debug
+	{Name: "DEBUG", Type: SETTING_TYPE_BOOL, Default: "false"},
+	{Name: "DEBUG_SECURITY", Type: SETTING_TYPE_BOOL, Default: "false"},
+
+	// App Config
+	{Name: "PORT", Type: SETTING_TYPE_INT, Default: "9090", Min: bound(1), Max: bound(65535), RequiresRestart: true},
+	{Name: "MS_PORT", Type: SETTING_TYPE_INT, Default: "9091", Min: bound(1), Max: bound(65535), RequiresRestart: true},
+	{Name: "PUBLIC_API_URL", Type: SETTING_TYPE_STRING, Default: "http://localhost:9090/api", Min: bound(1)},
+	{Name: "REDIRECT_ROOT_URL", Type: SETTING_TYPE_STRING, Default: "http://localhost:9090/api/healthy", Min: bound(1)},
+	{Name: "CORS_HOST", Type: SETTING_TYPE_STRING, Default: "*", Min: bound(1)},
+	{Name: "OPEN_REGISTRATION", Type: SETTING_TYPE_BOOL, Default: "true"},
+	{Name: "SETTINGS_REFRESH_RATE", Type: SETTING_TYPE_INT, Default: "60", Min: bound(1), RequiresRestart: true},
+
+	// Authentication
+	{Name: "USER_AUTHENTICATION_TIMEOUT", Type: SETTING_TYPE_INT, Default: "43200", Min: bound(1)},
+	{Name: "PASSWORD_RESET_TIMEOUT", Type: SETTING_TYPE_INT, Default: "10", Min: bound(1)},
+	{Name: "DEVICE_AUTHENTICATION_TIMEOUT", Type: SETTING_TYPE_INT, Default: "43200", Min: bound(1)},
+	{Name: "TWO_FACTOR_CODE_TIMEOUT", Type: SETTING_TYPE_INT, Default: "10", Min: bound(1)},
+	{Name: "EMAIL_ACTIVATION_TIMEOUT", Type: SETTING_TYPE_INT, Default: "10", Min: bound(1)},
+	{Name: "INVITATION_TIMEOUT", Type: SETTING_TYPE_INT, Default: "10080", Min: bound(1)},
+	{Name: "USE_TWO_FACTOR", Type: SETTING_TYPE_BOOL, Default: "false", RequiresRestart: true},
+	{Name: "ACCEPT_LEGACY_TOKENS", Type: SETTING_TYPE_BOOL, Default: "true"},
+	{Name: "TOKEN_ISSUER", Type: SETTING_TYPE_STRING, Default: "gocms", Min: bound(1)},
+	{Name: "PASSWORD_COMPLEXITY", Type: SETTING_TYPE_INT, Default: "1", Min: bound(0), Max: bound(5)},
+	{Name: "PASSWORD_MIN_LENGTH", Type: SETTING_TYPE_INT, Default: "8", Min: bound(1), Max: bound(72)},
+	{Name: "PASSWORD_HISTORY", Type: SETTING_TYPE_INT, Default: "0", Min: bound(0), Max: bound(24)},
+	{Name: "PASSWORD_MAX_AGE", Type: SETTING_TYPE_INT, Default: "0", Min: bound(0)},
+	{Name: "PASSWORD_BREACH_LIST", Type: SETTING_TYPE_STRING},
+	{Name: "PERMISSIONS_CACHE_LIFE", Type: SETTING_TYPE_INT, Default: "3600", Min: bound(0)},
+	{Name: "MS_SECRET_KEY", Type: SETTING_TYPE_STRING, Secret: true, ReadOnly: true},
+	{Name: "SIGNING_KEY_ROTATION", Type: SETTING_TYPE_INT, Default: "90", Min: bound(0)},
+	{Name: "SIGNING_KEY_GRACE_PERIOD", Type: SETTING_TYPE_INT, Default: "31", Min: bound(0)},
+
+	// Magic Link
+	{Name: "MAGIC_LINK_ENABLED", Type: SETTING_TYPE_BOOL, Default: "false"},
+	{Name: "MAGIC_LINK_TIMEOUT", Type: SETTING_TYPE_INT, Default: "10", Min: bound(1)},
+	{Name: "MAGIC_LINK_URL", Type: SETTING_TYPE_STRING, Default: "http://localhost:9090/login/magic-link", Min: bound(1)},
+
+	// WebAuthn
+	{Name: "WEBAUTHN_RP_ID", Type: SETTING_TYPE_STRING, Default: "localhost", Min: bound(1)},
+	{Name: "WEBAUTHN_RP_NAME", Type: SETTING_TYPE_STRING, Default: "GoCMS", Min: bound(1)},
+	{Name: "WEBAUTHN_ORIGINS", Type: SETTING_TYPE_STRING, Default: "http://localhost:9090", Min: bound(1)},
+	{Name: "WEBAUTHN_TIMEOUT", Type: SETTING_TYPE_INT, Default: "5", Min: bound(1)},
+	{Name: "WEBAUTHN_USER_VERIFICATION", Type: SETTING_TYPE_ENUM, Default: "preferred", Options: []string{"required", "preferred", "discouraged"}},
+	{Name: "WEBAUTHN_LOGIN_ENABLED", Type: SETTING_TYPE_BOOL, Default: "true"},
+
+	// RSA
+	{Name: "RSA_PRIV", Type: SETTING_TYPE_STRING, Secret: true, ReadOnly: true},
+	{Name: "RSA_PUB", Type: SETTING_TYPE_STRING, ReadOnly: true},
+
+	// SMTP
+	{Name: "SMTP_SERVER", Type: SETTING_TYPE_STRING, Default: "localhost", Min: bound(1)},
+	{Name: "SMTP_PORT", Type: SETTING_TYPE_INT, Default: "465", Min: bound(1), Max: bound(65535)},
+	{Name: "SMTP_USER", Type: SETTING_TYPE_STRING},
+	{Name: "SMTP_PASSWORD", Type: SETTING_TYPE_STRING, Secret: true},
+	{Name: "SMTP_FROM_ADDRESS", Type: SETTING_TYPE_STRING, Min: bound(1)},
+	{Name: "SMTP_SIMULATE", Type: SETTING_TYPE_BOOL, Default: "true"},
+
+	// GoCMS
+	{Name: "ACTIVE_THEME", Type: SETTING_TYPE_STRING, Default: "default", Min: bound(1), RequiresRestart: true},
+	{Name: "ACTIVE_THEME_ASSETS_BASE", Type: SETTING_TYPE_STRING, Default: "http://localhost:9090/themes/default/", Min: bound(1)},
+	{Name: "GOCMS_LOGIN_TITLE", Type: SETTING_TYPE_STRING, Default: "GoCMS", Min: bound(1)},
+	{Name: "GOCMS_LOGIN_SUCCESS_REDIRECT", Type: SETTING_TYPE_STRING, Default: "/admin/dashboard", Min: bound(1)},
+	{Name: "DISABLE_DOCUMENTATION_DISPLAY", Type: SETTING_TYPE_BOOL, Default: "false", RequiresRestart: true},
+
+	// User Data
+	{Name: "USER_DELETION_GRACE_PERIOD", Type: SETTING_TYPE_INT, Default: "14", Min: bound(0)},
+
+	// Rate Limiting
+	{Name: "RATE_LIMIT_STORE", Type: SETTING_TYPE_ENUM, Default: "memory", Options: []string{"memory", "database"}, RequiresRestart: true},
+	{Name: "RATE_LIMIT_PUBLIC_REQUESTS", Type: SETTING_TYPE_INT, Default: "0", Min: bound(0)},
+	{Name: "RATE_LIMIT_PUBLIC_WINDOW", Type: SETTING_TYPE_INT, Default: "60", Min: bound(1)},
+	{Name: "RATE_LIMIT_AUTH_REQUESTS", Type: SETTING_TYPE_INT, Default: "0", Min: bound(0)},
+	{Name: "RATE_LIMIT_AUTH_WINDOW", Type: SETTING_TYPE_INT, Default: "60", Min: bound(1)},
+
+	// Plugin Middleware
+	{Name: "PLUGIN_MAX_BODY_SIZE", Type: SETTING_TYPE_INT, Default: "10485760", Min: bound(0)},
+	{Name: "PLUGIN_BODY_MEMORY_LIMIT", Type: SETTING_TYPE_INT, Default: "1048576", Min: bound(0)},
+}
+
+var schemaByName map[string]*SettingSchema
+
+func init() {
+	schemaByName = make(map[string]*SettingSchema, len(Schema))
+	for i := range Schema {
+		schemaByName[Schema[i].Name] = &Schema[i]
+	}
+}
+
+// GetSchema returns the schema for the named setting.
+func GetSchema(name string) (*SettingSchema, bool) {
+	schema, ok := schemaByName[name]
+	return schema, ok
+}
+
+// Validate checks that value is allowed for the setting.
+func (s *SettingSchema) Validate(value string) error {
+	switch s.Type {
+	case SETTING_TYPE_INT:
+		i, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%v must be a whole number", s.Name)
+		}
+		if s.Min != nil && i < *s.Min {
+			return fmt.Errorf("%v must be at least %v", s.Name, *s.Min)
+		}
+		if s.Max != nil && i > *s.Max {
+			return fmt.Errorf("%v must be at most %v", s.Name, *s.Max)
+		}
+	case SETTING_TYPE_BOOL:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%v must be true or false", s.Name)
+		}
+	case SETTING_TYPE_ENUM:
+		for _, option := range s.Options {
+			if value == option {
+				return nil
+			}
+		}
+		return fmt.Errorf("%v must be one of %v", s.Name, s.Options)
+	default:
+		if s.Min != nil && int64(len(value)) < *s.Min {
+			if *s.Min == 1 {
+				return fmt.Errorf("%v can't be empty", s.Name)
+			}
+			return fmt.Errorf("%v must be at least %v characters", s.Name, *s.Min)
+		}
+		if s.Max != nil && int64(len(value)) > *s.Max {
+			return fmt.Errorf("%v must be at most %v characters", s.Name, *s.Max)
+		}
+	}
+	return nil
+}
+
+func (s *SettingSchema) GetSettingDisplay(setting *Setting) *SettingDisplay {
+	display := SettingDisplay{
+		Name:            s.Name,
+		Type:            s.Type,
+		Default:         s.Default,
+		Min:             s.Min,
+		Max:             s.Max,
+		Options:         s.Options,
+		Secret:          s.Secret,
+		RequiresRestart: s.RequiresRestart,
+		ReadOnly:        s.ReadOnly,
+	}
+	if setting != nil {
+		display.IsSet = setting.Value != ""
+		display.Description = setting.Description
+		if !s.Secret {
+			display.Value = setting.Value
+		}
+	}
+	return &display
+}
@@ -4,6 +4,7 @@ import (
 	"github.com/gocms-io/gocms/domain/setting/setting_model"
 	"github.com/jmoiron/sqlx"
 	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/security"
 )
 
 type ISettingsRepository interface {
@@ -33,6 +34,11 @@ func (ur *SettingsRepository) GetAll() (*[]setting_model.Setting, error) {
 		log.Errorf("Error getting settings from database: %s", err.Error())
 		return nil, err
 	}
+	for i := range settings {
+		if err := decryptSetting(&settings[i]); err != nil {
+			return nil, err
+		}
+	}
 	return &settings, nil
 }
 
@@ -44,12 +50,26 @@ func (ur *SettingsRepository) GetByName(name string) (*setting_model.Setting, er
 		log.Errorf("Error getting runtime from database: %s", err.Error())
 		return nil, err
 	}
+	if err := decryptSetting(&runtime); err != nil {
+		return nil, err
+	}
 	return &runtime, nil
 }
 
 // get all settings
 func (ur *SettingsRepository) UpdateValueById(id int, value string) error {
-	_, err := ur.database.NamedExec("UPDATE gocms_settings SET value=:value WHERE id=:id", map[string]interface{}{"value": value, "id": id})
+	var name string
+	err := ur.database.Get(&name, "SELECT name FROM gocms_settings WHERE id = ?", id)
+	if err != nil {
+		log.Errorf("Error getting runtime from database: %s", err.Error())
+		return err
+	}
+	value, err = security.EncryptSetting(name, value)
+	if err != nil {
+		log.Errorf("Error encrypting %v: %s", name, err.Error())
+		return err
+	}
+	_, err = ur.database.NamedExec("UPDATE gocms_settings SET value=:value WHERE id=:id", map[string]interface{}{"value": value, "id": id})
 	if err != nil {
 		log.Errorf("Error updating value of runtime from database: %s", err.Error())
 		return err
@@ -59,10 +79,27 @@ func (ur *SettingsRepository) UpdateValueById(id int, value string) error {
 
 // get all settings
 func (ur *SettingsRepository) UpdateValueByName(name string, value string) error {
-	_, err := ur.database.NamedExec("UPDATE gocms_settings SET value=:value WHERE name=:name", map[string]interface{}{"value": value, "name": name})
+	value, err := security.EncryptSetting(name, value)
+	if err != nil {
+		log.Errorf("Error encrypting %v: %s", name, err.Error())
+		return err
+	}
+	_, err = ur.database.NamedExec("UPDATE gocms_settings SET value=:value WHERE name=:name", map[string]interface{}{"value": value, "name": name})
 	if err != nil {
 		log.Errorf("Error updating value of runtime from database: %s", err.Error())
 		return err
 	}
 	return nil
 }
+
+// secret settings are decrypted as they are read. a value that can't be decrypted fails the whole read rather than leaving
+// things like MS_SECRET_KEY blank.
+func decryptSetting(setting *setting_model.Setting) error {
+	value, err := security.DecryptSetting(setting.Name, setting.Value)
+	if err != nil {
+		log.Errorf("Error decrypting setting: %s\n", err.Error())
+		return err
+	}
+	setting.Value = value
+	return nil
+}
@@ -1,16 +1,25 @@
 package setting_service
 
 import (
+	"fmt"
 	"github.com/gocms-io/gocms/domain/setting/setting_model"
 	"github.com/gocms-io/gocms/init/repository"
+	"github.com/gocms-io/gocms/utility/errors"
 	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/metrics"
+	"sync"
 	"time"
 )
 
+var settingsRefreshFailures = metrics.NewCounterVec("gocms_settings_refresh_failures_total",
+	"Times the settings cache failed to reload from the database.")
+
 type ISettingsService interface {
 	RefreshSettingsCache() error
 	GetSettings() map[string]setting_model.Setting
 	RegisterRefreshCallback(func(map[string]setting_model.Setting))
+	GetSettingDisplays() []setting_model.SettingDisplay
+	UpdateSettings(map[string]string) (*setting_model.SettingsUpdateResult, error)
 }
 
 type SettingsService struct {
@@ -18,6 +27,10 @@ type SettingsService struct {
 	SettingsCache      map[string]setting_model.Setting
 	RepositoriesGroup  *repository.RepositoriesGroup
 	OnRefreshCallbacks []func(map[string]setting_model.Setting)
+	// mu guards the cache and callbacks. refreshMu serializes refreshes and updates so callbacks run in order
+	// without mu held, which lets them read settings.
+	mu        sync.RWMutex
+	refreshMu sync.Mutex
 }
 
 func DefaultSettingsService(repositoriesGroup *repository.RepositoriesGroup) *SettingsService {
@@ -30,23 +43,44 @@ func DefaultSettingsService(repositoriesGroup *repository.RepositoriesGroup) *Se
 
 }
 
+// RegisterRefreshCallback adds the callback and refreshes the settings. The server can't run without settings so a failed
+// first load is fatal. Later failures keep the cached settings and hand them to the new callback.
 func (ss *SettingsService) RegisterRefreshCallback(cb func(map[string]setting_model.Setting)) {
 
+	ss.mu.Lock()
 	cbs := append(ss.OnRefreshCallbacks, cb)
 	ss.OnRefreshCallbacks = cbs
-
-	if err := ss.RefreshSettingsCache(); err != nil {
-		log.Warningf("Error getting db settings: %s\n", err.Error())
+	ss.mu.Unlock()
+
+	ss.refreshMu.Lock()
+	defer ss.refreshMu.Unlock()
+	if err := ss.refreshSettingsCache(); err != nil {
+		settings := ss.GetSettings()
+		if settings == nil {
+			log.Fatalf("Error loading db settings: %s\n", err.Error())
+		}
+		log.Warningf("Error getting db settings, using cached settings: %s\n", err.Error())
+		cb(settings)
 	}
 
 }
 
 func (ss *SettingsService) RefreshSettingsCache() error {
+	ss.refreshMu.Lock()
+	defer ss.refreshMu.Unlock()
+
+	return ss.refreshSettingsCache()
+}
+
+// refreshSettingsCache reloads settings and runs the refresh callbacks. Callers must hold refreshMu.
+// Callbacks may read settings but must not refresh or update them.
+func (ss *SettingsService) refreshSettingsCache() error {
 
 	// get all permissions
 	settings, err := ss.RepositoriesGroup.SettingsRepository.GetAll()
 	if err != nil {
 		log.Warningf("Error caching permissions: %s\n", err.Error())
+		settingsRefreshFailures.Inc()
 		return err
 	}
 
@@ -56,14 +90,93 @@ func (ss *SettingsService) RefreshSettingsCache() error {
 		settingsCache[setting.Name] = setting
 	}
 
+	ss.mu.Lock()
 	ss.SettingsCache = settingsCache
+	ss.LastRefresh = time.Now()
+	refreshCallbacks := append([]func(map[string]setting_model.Setting){}, ss.OnRefreshCallbacks...)
+	ss.mu.Unlock()
 
-	for _, refreshCallback := range ss.OnRefreshCallbacks {
-		refreshCallback(ss.SettingsCache)
+	for _, refreshCallback := range refreshCallbacks {
+		refreshCallback(settingsCache)
 	}
 	return nil
 }
 
 func (ss *SettingsService) GetSettings() map[string]setting_model.Setting {
+	ss.mu.RLock()
+	defer ss.mu.RUnlock()
+
 	return ss.SettingsCache
-}
\ No newline at end of file
+}
+
+// get every setting in the schema with secret values removed
+func (ss *SettingsService) GetSettingDisplays() []setting_model.SettingDisplay {
+	settings := ss.GetSettings()
+
+	displays := make([]setting_model.SettingDisplay, len(setting_model.Schema))
+	for i, schema := range setting_model.Schema {
+		var setting *setting_model.Setting
+		if s, ok := settings[schema.Name]; ok {
+			setting = &s
+		}
+		displays[i] = *schema.GetSettingDisplay(setting)
+	}
+
+	return displays
+}
+
+// validate every value against the schema then save them all and apply them immediately
+func (ss *SettingsService) UpdateSettings(values map[string]string) (*setting_model.SettingsUpdateResult, error) {
+	if len(values) == 0 {
+		return nil, errors.NewToUser("No settings to update.")
+	}
+
+	// validate everything before writing anything
+	for name, value := range values {
+		schema, ok := setting_model.GetSchema(name)
+		if !ok {
+			return nil, errors.NewToUser(fmt.Sprintf("Unknown setting: %v.", name))
+		}
+		if schema.ReadOnly {
+			return nil, errors.NewToUser(fmt.Sprintf("%v can't be changed.", name))
+		}
+		if err := schema.Validate(value); err != nil {
+			return nil, errors.NewToUser(err.Error() + ".")
+		}
+	}
+
+	ss.refreshMu.Lock()
+	currentSettings := ss.GetSettings()
+	restartRequired := []string{}
+	for _, schema := range setting_model.Schema {
+		value, ok := values[schema.Name]
+		if !ok {
+			continue
+		}
+		if current, exists := currentSettings[schema.Name]; exists && current.Value == value {
+			continue
+		}
+
+		err := ss.RepositoriesGroup.SettingsRepository.UpdateValueByName(schema.Name, value)
+		if err != nil {
+			ss.refreshSettingsCache()
+			ss.refreshMu.Unlock()
+			return nil, err
+		}
+		if schema.RequiresRestart {
+			restartRequired = append(restartRequired, schema.Name)
+		}
+	}
+
+	err := ss.refreshSettingsCache()
+	ss.refreshMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &setting_model.SettingsUpdateResult{
+		Settings:        ss.GetSettingDisplays(),
+		RestartRequired: restartRequired,
+	}
+	return result, nil
+}
@@ -0,0 +1,66 @@
+package tracing_middleware
+
+import (
+	"context"
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/context/consts"
+	"github.com/gocms-io/gocms/utility/api_utility"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/trace"
+	"net/http"
+)
+
+// Trace starts a server span for every request, continuing the caller's trace when a traceparent header is sent.
+// The span is put on the request context so proxies, queries run with that context and anything else can start child
+// spans from it.
+// Public callers can't choose whether their request is sampled.
+func Trace() gin.HandlerFunc {
+	log.Debugf("Adding Tracing Middleware\n")
+	return func(c *gin.Context) {
+		traceRequest(c, trace.StartWithUntrustedParent)
+	}
+}
+
+// TraceInternal is Trace for the internal api. Plugins are trusted to pass on the sampling decision gocms sent them.
+func TraceInternal() gin.HandlerFunc {
+	log.Debugf("Adding Internal Tracing Middleware\n")
+	return func(c *gin.Context) {
+		traceRequest(c, trace.StartWithParent)
+	}
+}
+
+type startFunc func(ctx context.Context, parent trace.SpanContext, name string, kind trace.SpanKind) (context.Context, *trace.Span)
+
+func traceRequest(c *gin.Context, start startFunc) {
+	parent, _ := trace.Extract(c.Request.Header)
+	ctx, span := start(c.Request.Context(), parent, c.Request.Method, trace.KIND_SERVER)
+	c.Request = c.Request.WithContext(ctx)
+
+	c.Next()
+
+	// the route is only known once a group has matched
+	route := "unmatched"
+	if _, exists := c.Get(consts.ROUTE_GROUP_KEY_FOR_GIN_CONTEXT); exists {
+		if r, ok := api_utility.GetRouteFromContext(c); ok {
+			route = r
+		}
+	}
+	span.SetName(c.Request.Method + " " + route)
+	span.SetAttribute("http.method", c.Request.Method)
+	span.SetAttribute("http.route", route)
+	span.SetAttribute("http.target", c.Request.URL.Path)
+	span.SetAttribute("http.status_code", c.Writer.Status())
+	if requestId, ok := api_utility.GetRequestIdFromContext(c); ok {
+		span.SetAttribute("gocms.request_id", requestId)
+	}
+	if c.Writer.Status() >= http.StatusInternalServerError {
+		span.SetError(errorStatus(c.Writer.Status()))
+	}
+	span.End()
+}
+
+type errorStatus int
+
+func (e errorStatus) Error() string {
+	return http.StatusText(int(e))
+}
@@ -0,0 +1,150 @@
+package profile_admin_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/acl/access_control/access_control_middleware"
+	"github.com/gocms-io/gocms/domain/acl/permissions"
+	"github.com/gocms-io/gocms/domain/user/profile/profile_model"
+	"github.com/gocms-io/gocms/init/service"
+	"github.com/gocms-io/gocms/routes"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+	"strconv"
+)
+
+type ProfileAdminController struct {
+	routes        *routes.Routes
+	ServicesGroup *service.ServicesGroup
+	adminRoutes   *gin.RouterGroup
+}
+
+func DefaultProfileAdminController(routes *routes.Routes, sg *service.ServicesGroup) *ProfileAdminController {
+	profileAdminController := &ProfileAdminController{
+		routes:        routes,
+		ServicesGroup: sg,
+	}
+
+	// add acl rules to route
+	profileAdminController.adminRoutes = routes.Auth.Group("/admin", access_control_middleware.RequirePermission(sg.AclService, permissions.SUPER_ADMIN))
+
+	profileAdminController.Default()
+	return profileAdminController
+}
+
+func (pac *ProfileAdminController) Default() {
+	pac.adminRoutes.GET("/profile/field", pac.getAll)
+	pac.adminRoutes.POST("/profile/field", pac.add)
+	pac.adminRoutes.PUT("/profile/field/:fieldId", pac.update)
+	pac.adminRoutes.DELETE("/profile/field/:fieldId", pac.delete)
+}
+
+/**
+* @api {get} /admin/profile/field Get All Profile Fields
+* @apiName GetAllProfileFields
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiUse ProfileFieldDisplay
+* @apiPermission Admin
+ */
+func (pac *ProfileAdminController) getAll(c *gin.Context) {
+	fields, err := pac.ServicesGroup.ProfileService.GetFields()
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, "Couldn't get profile fields.", err)
+		return
+	}
+
+	fieldDisplays := make([]profile_model.ProfileFieldDisplay, len(fields))
+	for i, field := range fields {
+		fieldDisplays[i] = *field.GetProfileFieldDisplay()
+	}
+
+	c.JSON(http.StatusOK, fieldDisplays)
+}
+
+/**
+* @api {post} /admin/profile/field Add Profile Field
+* @apiName AddProfileField
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiUse ProfileFieldInput
+* @apiUse ProfileFieldDisplay
+* @apiPermission Admin
+ */
+func (pac *ProfileAdminController) add(c *gin.Context) {
+	var fieldInput profile_model.ProfileFieldInput
+	err := c.BindJSON(&fieldInput)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, errors.ApiError_Json, err)
+		return
+	}
+
+	field := fieldInput.GetProfileField()
+	err = pac.ServicesGroup.ProfileService.AddField(field)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't add profile field.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, field.GetProfileFieldDisplay())
+}
+
+/**
+* @api {put} /admin/profile/field/:fieldId Update Profile Field
+* @apiName UpdateProfileField
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiUse ProfileFieldInput
+* @apiUse ProfileFieldDisplay
+* @apiPermission Admin
+ */
+func (pac *ProfileAdminController) update(c *gin.Context) {
+	fieldId, err := strconv.ParseInt(c.Param("fieldId"), 10, 64)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Missing Id Field", err)
+		return
+	}
+
+	var fieldInput profile_model.ProfileFieldInput
+	err = c.BindJSON(&fieldInput)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, errors.ApiError_Json, err)
+		return
+	}
+
+	field := fieldInput.GetProfileField()
+	err = pac.ServicesGroup.ProfileService.UpdateField(fieldId, field)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't update profile field.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, field.GetProfileFieldDisplay())
+}
+
+/**
+* @api {delete} /admin/profile/field/:fieldId Delete Profile Field
+* @apiDescription Deletes the field and every users value for it.
+* @apiName DeleteProfileField
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiPermission Admin
+ */
+func (pac *ProfileAdminController) delete(c *gin.Context) {
+	fieldId, err := strconv.ParseInt(c.Param("fieldId"), 10, 64)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Missing Id Field", err)
+		return
+	}
+
+	err = pac.ServicesGroup.ProfileService.DeleteField(fieldId)
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, "Couldn't delete profile field.", err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
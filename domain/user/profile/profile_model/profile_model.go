@@ -0,0 +1,202 @@
+package profile_model
+
+import (
+	"database/sql"
+	"encoding/json"
+	"time"
+)
+
+const (
+	FIELD_TYPE_TEXT    = "text"
+	FIELD_TYPE_NUMBER  = "number"
+	FIELD_TYPE_BOOLEAN = "boolean"
+	FIELD_TYPE_DATE    = "date"
+	FIELD_TYPE_SELECT  = "select"
+	FIELD_TYPE_EMAIL   = "email"
+	FIELD_TYPE_URL     = "url"
+
+	// DATE_FORMAT used to store and validate date fields
+	DATE_FORMAT = "2006-01-02"
+)
+
+// Visibility controls who can see and edit a profile field. Each level includes the ones above it.
+type Visibility int
+
+const (
+	// VISIBILITY_PUBLIC visible to everyone. Editable by the user.
+	VISIBILITY_PUBLIC Visibility = iota
+	// VISIBILITY_SELF visible to the user and admins. Editable by the user.
+	VISIBILITY_SELF
+	// VISIBILITY_ADMIN visible to and editable by admins only.
+	VISIBILITY_ADMIN
+)
+
+var visibilityNames = map[string]Visibility{
+	"public": VISIBILITY_PUBLIC,
+	"self":   VISIBILITY_SELF,
+	"admin":  VISIBILITY_ADMIN,
+}
+
+func ParseVisibility(s string) (Visibility, bool) {
+	v, ok := visibilityNames[s]
+	return v, ok
+}
+
+type ProfileField struct {
+	Id           int64           `db:"id"`
+	Name         string          `db:"name"`
+	Label        string          `db:"label"`
+	Description  string          `db:"description"`
+	Type         string          `db:"type"`
+	Required     bool            `db:"required"`
+	Visibility   string          `db:"visibility"`
+	Pattern      string          `db:"pattern"`
+	Min          sql.NullFloat64 `db:"min"`
+	Max          sql.NullFloat64 `db:"max"`
+	Options      sql.NullString  `db:"options"`
+	SortOrder    int64           `db:"sortOrder"`
+	Created      time.Time       `db:"created"`
+	LastModified time.Time       `db:"lastModified"`
+}
+
+// GetOptions returns the allowed values of a select field.
+func (field *ProfileField) GetOptions() []string {
+	var options []string
+	if field.Options.Valid {
+		json.Unmarshal([]byte(field.Options.String), &options)
+	}
+	return options
+}
+
+// VisibleAt reports if the field can be seen by a viewer with the level provided.
+func (field *ProfileField) VisibleAt(level Visibility) bool {
+	v, ok := ParseVisibility(field.Visibility)
+	return ok && v <= level
+}
+
+type ProfileValue struct {
+	UserId       int64     `db:"userId"`
+	FieldId      int64     `db:"fieldId"`
+	Value        string    `db:"value"`
+	LastModified time.Time `db:"lastModified"`
+}
+
+/**
+* @apiDefine ProfileFieldInput
+* @apiParam (Request) {string} name Key used for the field in user profiles. Letters, numbers, and underscores.
+* @apiParam (Request) {string} label
+* @apiParam (Request) {string} [description]
+* @apiParam (Request) {string} type text, number, boolean, date, select, email, or url
+* @apiParam (Request) {boolean} [required]
+* @apiParam (Request) {string} [visibility] public, self, or admin. Default=self
+* @apiParam (Request) {string} [pattern] Regular expression text values must match.
+* @apiParam (Request) {number} [min] Minimum length for text or minimum value for numbers.
+* @apiParam (Request) {number} [max] Maximum length for text or maximum value for numbers.
+* @apiParam (Request) {string[]} [options] Allowed values for select fields.
+* @apiParam (Request) {number} [sortOrder]
+ */
+type ProfileFieldInput struct {
+	Name        string   `json:"name" binding:"required"`
+	Label       string   `json:"label" binding:"required"`
+	Description string   `json:"description"`
+	Type        string   `json:"type" binding:"required"`
+	Required    bool     `json:"required"`
+	Visibility  string   `json:"visibility"`
+	Pattern     string   `json:"pattern"`
+	Min         *float64 `json:"min"`
+	Max         *float64 `json:"max"`
+	Options     []string `json:"options"`
+	SortOrder   int64    `json:"sortOrder"`
+}
+
+/**
+* @apiDefine ProfileFieldDisplay
+* @apiSuccess (Response) {number} id
+* @apiSuccess (Response) {string} name
+* @apiSuccess (Response) {string} label
+* @apiSuccess (Response) {string} description
+* @apiSuccess (Response) {string} type
+* @apiSuccess (Response) {boolean} required
+* @apiSuccess (Response) {string} visibility
+* @apiSuccess (Response) {string} pattern
+* @apiSuccess (Response) {number} min
+* @apiSuccess (Response) {number} max
+* @apiSuccess (Response) {string[]} options
+* @apiSuccess (Response) {number} sortOrder
+ */
+type ProfileFieldDisplay struct {
+	Id          int64    `json:"id"`
+	Name        string   `json:"name"`
+	Label       string   `json:"label"`
+	Description string   `json:"description,omitempty"`
+	Type        string   `json:"type"`
+	Required    bool     `json:"required"`
+	Visibility  string   `json:"visibility"`
+	Pattern     string   `json:"pattern,omitempty"`
+	Min         *float64 `json:"min,omitempty"`
+	Max         *float64 `json:"max,omitempty"`
+	Options     []string `json:"options,omitempty"`
+	SortOrder   int64    `json:"sortOrder"`
+}
+
+func (field *ProfileField) GetProfileFieldDisplay() *ProfileFieldDisplay {
+	display := ProfileFieldDisplay{
+		Id:          field.Id,
+		Name:        field.Name,
+		Label:       field.Label,
+		Description: field.Description,
+		Type:        field.Type,
+		Required:    field.Required,
+		Visibility:  field.Visibility,
+		Pattern:     field.Pattern,
+		Options:     field.GetOptions(),
+		SortOrder:   field.SortOrder,
+	}
+	if field.Min.Valid {
+		min := field.Min.Float64
+		display.Min = &min
+	}
+	if field.Max.Valid {
+		max := field.Max.Float64
+		display.Max = &max
+	}
+	return &display
+}
+
+// helper function to get a profile field from input
+func (input *ProfileFieldInput) GetProfileField() *ProfileField {
+	field := ProfileField{
+		Name:        input.Name,
+		Label:       input.Label,
+		Description: input.Description,
+		Type:        input.Type,
+		Required:    input.Required,
+		Visibility:  input.Visibility,
+		Pattern:     input.Pattern,
+		SortOrder:   input.SortOrder,
+	}
+	if field.Visibility == "" {
+		field.Visibility = "self"
+	}
+	if input.Min != nil {
+		field.Min = sql.NullFloat64{Float64: *input.Min, Valid: true}
+	}
+	if input.Max != nil {
+		field.Max = sql.NullFloat64{Float64: *input.Max, Valid: true}
+	}
+	if len(input.Options) > 0 {
+		options, _ := json.Marshal(input.Options)
+		field.Options = sql.NullString{String: string(options), Valid: true}
+	}
+	return &field
+}
+
+/**
+* @apiDefine PublicProfileDisplay
+* @apiSuccess (Response) {number} id
+* @apiSuccess (Response) {object} profile Public profile fields keyed by field name.
+ */
+type PublicProfileDisplay struct {
+	Id      int64                  `json:"id"`
+	Profile map[string]interface{} `json:"profile"`
+}
@@ -0,0 +1,132 @@
+package profile_repository
+
+import (
+	"github.com/gocms-io/gocms/domain/user/profile/profile_model"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/jmoiron/sqlx"
+)
+
+type IProfileRepository interface {
+	GetAllFields() ([]profile_model.ProfileField, error)
+	GetField(id int64) (*profile_model.ProfileField, error)
+	AddField(*profile_model.ProfileField) error
+	UpdateField(*profile_model.ProfileField) error
+	DeleteField(id int64) error
+
+	GetUserValues(userId int64) ([]profile_model.ProfileValue, error)
+	SetUserValue(userId int64, fieldId int64, value string) error
+	DeleteUserValue(userId int64, fieldId int64) error
+}
+
+type ProfileRepository struct {
+	database *sqlx.DB
+}
+
+func DefaultProfileRepository(dbx *sqlx.DB) *ProfileRepository {
+	profileRepository := &ProfileRepository{
+		database: dbx,
+	}
+
+	return profileRepository
+}
+
+// get all fields in display order
+func (pr *ProfileRepository) GetAllFields() ([]profile_model.ProfileField, error) {
+	var fields []profile_model.ProfileField
+	err := pr.database.Select(&fields, `
+	SELECT * FROM gocms_profile_fields ORDER BY sortOrder, id
+	`)
+	if err != nil {
+		log.Errorf("Error getting profile fields from database: %s", err.Error())
+		return nil, err
+	}
+	return fields, nil
+}
+
+func (pr *ProfileRepository) GetField(id int64) (*profile_model.ProfileField, error) {
+	var field profile_model.ProfileField
+	err := pr.database.Get(&field, `
+	SELECT * FROM gocms_profile_fields WHERE id=?
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &field, nil
+}
+
+func (pr *ProfileRepository) AddField(field *profile_model.ProfileField) error {
+	result, err := pr.database.NamedExec(`
+	INSERT INTO gocms_profile_fields (name, label, description, type, required, visibility, pattern, min, max, options, sortOrder)
+	VALUES (:name, :label, :description, :type, :required, :visibility, :pattern, :min, :max, :options, :sortOrder)
+	`, field)
+	if err != nil {
+		log.Errorf("Error adding profile field to database: %s", err.Error())
+		return err
+	}
+	id, _ := result.LastInsertId()
+	field.Id = id
+
+	return nil
+}
+
+func (pr *ProfileRepository) UpdateField(field *profile_model.ProfileField) error {
+	_, err := pr.database.NamedExec(`
+	UPDATE gocms_profile_fields SET name=:name, label=:label, description=:description, type=:type, required=:required,
+	visibility=:visibility, pattern=:pattern, min=:min, max=:max, options=:options, sortOrder=:sortOrder WHERE id=:id
+	`, field)
+	if err != nil {
+		log.Errorf("Error updating profile field in database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (pr *ProfileRepository) DeleteField(id int64) error {
+	_, err := pr.database.Exec(`
+	DELETE FROM gocms_profile_fields WHERE id=?
+	`, id)
+	if err != nil {
+		log.Errorf("Error deleting profile field from database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (pr *ProfileRepository) GetUserValues(userId int64) ([]profile_model.ProfileValue, error) {
+	var values []profile_model.ProfileValue
+	err := pr.database.Select(&values, `
+	SELECT * FROM gocms_user_profile_values WHERE userId=?
+	`, userId)
+	if err != nil {
+		log.Errorf("Error getting user profile values from database: %s", err.Error())
+		return nil, err
+	}
+	return values, nil
+}
+
+func (pr *ProfileRepository) SetUserValue(userId int64, fieldId int64, value string) error {
+	_, err := pr.database.Exec(`
+	INSERT INTO gocms_user_profile_values (userId, fieldId, value) VALUES (?, ?, ?)
+	ON DUPLICATE KEY UPDATE value=VALUES(value)
+	`, userId, fieldId, value)
+	if err != nil {
+		log.Errorf("Error setting user profile value in database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (pr *ProfileRepository) DeleteUserValue(userId int64, fieldId int64) error {
+	_, err := pr.database.Exec(`
+	DELETE FROM gocms_user_profile_values WHERE userId=? AND fieldId=?
+	`, userId, fieldId)
+	if err != nil {
+		log.Errorf("Error deleting user profile value from database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,327 @@
+package profile_service
+
+import (
+	"fmt"
+	"github.com/gocms-io/gocms/domain/user/profile/profile_model"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"github.com/gocms-io/gocms/init/repository"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var fieldNameRegex = regexp.MustCompile(`^[a-zA-Z0-9_]{1,50}$`)
+
+type IProfileService interface {
+	GetFields() ([]profile_model.ProfileField, error)
+	GetVisibleFields(level profile_model.Visibility) ([]profile_model.ProfileField, error)
+	AddField(*profile_model.ProfileField) error
+	UpdateField(id int64, field *profile_model.ProfileField) error
+	DeleteField(id int64) error
+
+	GetUserProfile(userId int64, level profile_model.Visibility) (map[string]interface{}, error)
+	UpdateUserProfile(userId int64, values map[string]interface{}, level profile_model.Visibility) error
+	ExportUserProfile(user *user_model.User) (interface{}, error)
+}
+
+type ProfileService struct {
+	RepositoriesGroup *repository.RepositoriesGroup
+}
+
+func DefaultProfileService(rg *repository.RepositoriesGroup) *ProfileService {
+	profileService := &ProfileService{
+		RepositoriesGroup: rg,
+	}
+
+	return profileService
+}
+
+func (ps *ProfileService) GetFields() ([]profile_model.ProfileField, error) {
+	return ps.RepositoriesGroup.ProfileRepository.GetAllFields()
+}
+
+// get the fields a viewer with the level provided can see
+func (ps *ProfileService) GetVisibleFields(level profile_model.Visibility) ([]profile_model.ProfileField, error) {
+	fields, err := ps.GetFields()
+	if err != nil {
+		return nil, err
+	}
+
+	var visibleFields []profile_model.ProfileField
+	for _, field := range fields {
+		if field.VisibleAt(level) {
+			visibleFields = append(visibleFields, field)
+		}
+	}
+	return visibleFields, nil
+}
+
+func (ps *ProfileService) AddField(field *profile_model.ProfileField) error {
+	err := validateField(field)
+	if err != nil {
+		return err
+	}
+
+	return ps.RepositoriesGroup.ProfileRepository.AddField(field)
+}
+
+func (ps *ProfileService) UpdateField(id int64, field *profile_model.ProfileField) error {
+	_, err := ps.RepositoriesGroup.ProfileRepository.GetField(id)
+	if err != nil {
+		return err
+	}
+
+	err = validateField(field)
+	if err != nil {
+		return err
+	}
+
+	field.Id = id
+	return ps.RepositoriesGroup.ProfileRepository.UpdateField(field)
+}
+
+func (ps *ProfileService) DeleteField(id int64) error {
+	return ps.RepositoriesGroup.ProfileRepository.DeleteField(id)
+}
+
+// get the users profile values a viewer with the level provided can see, keyed by field name
+func (ps *ProfileService) GetUserProfile(userId int64, level profile_model.Visibility) (map[string]interface{}, error) {
+	fields, err := ps.GetVisibleFields(level)
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := ps.RepositoriesGroup.ProfileRepository.GetUserValues(userId)
+	if err != nil {
+		return nil, err
+	}
+	valuesByFieldId := make(map[int64]string)
+	for _, value := range values {
+		valuesByFieldId[value.FieldId] = value.Value
+	}
+
+	profile := make(map[string]interface{})
+	for _, field := range fields {
+		if value, ok := valuesByFieldId[field.Id]; ok {
+			profile[field.Name] = typedValue(&field, value)
+		}
+	}
+	return profile, nil
+}
+
+// UpdateUserProfile validates and saves the values provided. A null value removes the value.
+// Only fields an editor with the level provided can see may be changed.
+func (ps *ProfileService) UpdateUserProfile(userId int64, values map[string]interface{}, level profile_model.Visibility) error {
+	fields, err := ps.GetVisibleFields(level)
+	if err != nil {
+		return err
+	}
+	fieldsByName := make(map[string]profile_model.ProfileField)
+	for _, field := range fields {
+		fieldsByName[field.Name] = field
+	}
+
+	existingValues, err := ps.RepositoriesGroup.ProfileRepository.GetUserValues(userId)
+	if err != nil {
+		return err
+	}
+	finalValues := make(map[int64]string)
+	for _, value := range existingValues {
+		finalValues[value.FieldId] = value.Value
+	}
+
+	// validate all values before saving any
+	var validationErrors []string
+	updates := make(map[int64]string)
+	for name, rawValue := range values {
+		field, ok := fieldsByName[name]
+		if !ok {
+			validationErrors = append(validationErrors, fmt.Sprintf("%v is not a profile field.", name))
+			continue
+		}
+
+		value, err := normalizeValue(&field, rawValue)
+		if err != nil {
+			validationErrors = append(validationErrors, fmt.Sprintf("%v %v", field.Label, err.Error()))
+			continue
+		}
+		updates[field.Id] = value
+		finalValues[field.Id] = value
+	}
+
+	for _, field := range fields {
+		if field.Required && finalValues[field.Id] == "" {
+			validationErrors = append(validationErrors, fmt.Sprintf("%v is required.", field.Label))
+		}
+	}
+
+	if len(validationErrors) > 0 {
+		return errors.NewToUser(strings.Join(validationErrors, " "))
+	}
+
+	for fieldId, value := range updates {
+		if value == "" {
+			err = ps.RepositoriesGroup.ProfileRepository.DeleteUserValue(userId, fieldId)
+		} else {
+			err = ps.RepositoriesGroup.ProfileRepository.SetUserValue(userId, fieldId, value)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// exporter for user data exports
+func (ps *ProfileService) ExportUserProfile(user *user_model.User) (interface{}, error) {
+	return ps.GetUserProfile(user.Id, profile_model.VISIBILITY_ADMIN)
+}
+
+func validateField(field *profile_model.ProfileField) error {
+	if !fieldNameRegex.MatchString(field.Name) {
+		return errors.NewToUser("Field name may only contain letters, numbers, and underscores.")
+	}
+
+	switch field.Type {
+	case profile_model.FIELD_TYPE_TEXT, profile_model.FIELD_TYPE_NUMBER, profile_model.FIELD_TYPE_BOOLEAN,
+		profile_model.FIELD_TYPE_DATE, profile_model.FIELD_TYPE_EMAIL, profile_model.FIELD_TYPE_URL:
+	case profile_model.FIELD_TYPE_SELECT:
+		if len(field.GetOptions()) == 0 {
+			return errors.NewToUser("Select fields require options.")
+		}
+	default:
+		return errors.NewToUser(fmt.Sprintf("Unknown field type %v.", field.Type))
+	}
+
+	if _, ok := profile_model.ParseVisibility(field.Visibility); !ok {
+		return errors.NewToUser(fmt.Sprintf("Unknown visibility %v.", field.Visibility))
+	}
+
+	if field.Pattern != "" {
+		if _, err := regexp.Compile(field.Pattern); err != nil {
+			return errors.NewToUser(fmt.Sprintf("Pattern is not a valid regular expression: %v", err.Error()))
+		}
+	}
+
+	if field.Min.Valid && field.Max.Valid && field.Min.Float64 > field.Max.Float64 {
+		return errors.NewToUser("Min must not be greater than max.")
+	}
+
+	return nil
+}
+
+// normalizeValue validates a json value against the field and returns it as it is stored. An empty string means no value.
+func normalizeValue(field *profile_model.ProfileField, rawValue interface{}) (string, error) {
+	if rawValue == nil {
+		return "", nil
+	}
+
+	switch field.Type {
+	case profile_model.FIELD_TYPE_NUMBER:
+		var number float64
+		switch v := rawValue.(type) {
+		case float64:
+			number = v
+		case string:
+			if v == "" {
+				return "", nil
+			}
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				return "", errors.New("must be a number.")
+			}
+			number = parsed
+		default:
+			return "", errors.New("must be a number.")
+		}
+		if field.Min.Valid && number < field.Min.Float64 {
+			return "", fmt.Errorf("must be at least %v.", field.Min.Float64)
+		}
+		if field.Max.Valid && number > field.Max.Float64 {
+			return "", fmt.Errorf("must be at most %v.", field.Max.Float64)
+		}
+		return strconv.FormatFloat(number, 'f', -1, 64), nil
+
+	case profile_model.FIELD_TYPE_BOOLEAN:
+		b, ok := rawValue.(bool)
+		if !ok {
+			return "", errors.New("must be true or false.")
+		}
+		return strconv.FormatBool(b), nil
+	}
+
+	// all other types are strings
+	s, ok := rawValue.(string)
+	if !ok {
+		return "", errors.New("must be text.")
+	}
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", nil
+	}
+
+	switch field.Type {
+	case profile_model.FIELD_TYPE_DATE:
+		if _, err := time.Parse(profile_model.DATE_FORMAT, s); err != nil {
+			return "", errors.New("must be a date formatted YYYY-MM-DD.")
+		}
+	case profile_model.FIELD_TYPE_SELECT:
+		found := false
+		for _, option := range field.GetOptions() {
+			if option == s {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "", errors.New("is not an available option.")
+		}
+	case profile_model.FIELD_TYPE_EMAIL:
+		if _, err := mail.ParseAddress(s); err != nil {
+			return "", errors.New("must be an email address.")
+		}
+	case profile_model.FIELD_TYPE_URL:
+		u, err := url.ParseRequestURI(s)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return "", errors.New("must be a http or https url.")
+		}
+	}
+
+	// length and pattern apply to text values
+	if field.Type == profile_model.FIELD_TYPE_TEXT || field.Type == profile_model.FIELD_TYPE_EMAIL || field.Type == profile_model.FIELD_TYPE_URL {
+		length := float64(len([]rune(s)))
+		if field.Min.Valid && length < field.Min.Float64 {
+			return "", fmt.Errorf("must be at least %v characters.", field.Min.Float64)
+		}
+		if field.Max.Valid && length > field.Max.Float64 {
+			return "", fmt.Errorf("must be at most %v characters.", field.Max.Float64)
+		}
+		if field.Pattern != "" {
+			if matched, _ := regexp.MatchString(field.Pattern, s); !matched {
+				return "", errors.New("is not in a valid format.")
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// typedValue converts a stored value back to its json type
+func typedValue(field *profile_model.ProfileField, value string) interface{} {
+	switch field.Type {
+	case profile_model.FIELD_TYPE_NUMBER:
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			return number
+		}
+	case profile_model.FIELD_TYPE_BOOLEAN:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return value
+}
@@ -1,15 +1,21 @@
 package user_admin_controller
 
 import (
+	"database/sql"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gocms-io/gocms/domain/acl/access_control/access_control_middleware"
 	"github.com/gocms-io/gocms/domain/acl/permissions"
+	"github.com/gocms-io/gocms/domain/user/profile/profile_model"
 	"github.com/gocms-io/gocms/domain/user/user_model"
 	"github.com/gocms-io/gocms/init/service"
 	"github.com/gocms-io/gocms/routes"
+	"github.com/gocms-io/gocms/utility/api_utility"
 	"github.com/gocms-io/gocms/utility/errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type UserAdminController struct {
@@ -41,7 +47,13 @@ func (auc *UserAdminController) Default() {
 	auc.adminRoutes.GET("/user", auc.getAll)
 	auc.adminRoutes.GET("/user/:userId", auc.get)
 	auc.adminRoutes.PUT("/user/:userId", auc.update)
+	auc.adminRoutes.PUT("/user/:userId/profile", auc.updateProfile)
 	auc.adminRoutes.POST("/user", auc.add)
+	auc.adminRoutes.POST("/user/invite", auc.invite)
+	auc.adminRoutes.POST("/user/invite/:userId", auc.resendInvite)
+	auc.adminRoutes.POST("/user/bulk", auc.bulk)
+	auc.adminRoutes.POST("/users/import", auc.importUsers)
+	auc.adminRoutes.GET("/users/export", auc.exportUsers)
 	auc.adminRoutes.DELETE("/user/:userId", auc.delete)
 }
 
@@ -71,6 +83,68 @@ func (auc *UserAdminController) add(c *gin.Context) {
 	c.JSON(http.StatusOK, user)
 }
 
+/**
+* @api {post} /admin/user/invite Invite User
+* @apiDescription Create a disabled user and email them an invitation. The user sets their password and verifies their email when accepting the invitation.
+* @apiName InviteUser
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiUse UserInviteInput
+* @apiUse UserAdminDisplay
+* @apiPermission Admin
+ */
+func (auc *UserAdminController) invite(c *gin.Context) {
+
+	var userInviteInput user_model.UserInviteInput
+	err := c.BindJSON(&userInviteInput)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, errors.ApiError_Json, err)
+		return
+	}
+
+	user := &user_model.User{
+		Email:    userInviteInput.Email,
+		FullName: userInviteInput.FullName,
+	}
+
+	// invite user
+	err = auc.ServicesGroup.UserService.Invite(user, userInviteInput.Groups)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't invite user.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user.GetUserAdminDisplay())
+}
+
+/**
+* @api {post} /admin/user/invite/:userId Resend Invitation
+* @apiDescription Email a new invitation to an invited user that hasn't accepted theirs. Earlier invitations stop working. Accounts that were never invited can't be sent one.
+* @apiName ResendInvitation
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiParam {number} userId
+* @apiUse UserAdminDisplay
+* @apiPermission Admin
+ */
+func (auc *UserAdminController) resendInvite(c *gin.Context) {
+	userId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Missing or invalid user id.", err)
+		return
+	}
+
+	user, err := auc.ServicesGroup.UserService.ResendInvitation(userId)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't resend invitation.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user.GetUserAdminDisplay())
+}
+
 /**
 * @api {get} /admin/user/:userId Get User By Id
 * @apiDescription Get a user by their Id.
@@ -94,33 +168,170 @@ func (auc *UserAdminController) get(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user.GetUserAdminDisplay())
+	userAdminDisplay := user.GetUserAdminDisplay()
+
+	// add profile fields
+	profile, err := auc.ServicesGroup.ProfileService.GetUserProfile(userId, profile_model.VISIBILITY_ADMIN)
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, errors.ApiError_Server, err)
+		return
+	}
+	userAdminDisplay.Profile = profile
+
+	c.JSON(http.StatusOK, userAdminDisplay)
+}
+
+/**
+* @api {put} /admin/user/:userId/profile Update User Profile Fields
+* @apiDescription Update any of a users custom profile fields. Set a field to null to remove it.
+* @apiName UpdateUserProfile
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiParam (Request) {object} profile Custom profile fields keyed by field name.
+* @apiPermission Admin
+ */
+func (auc *UserAdminController) updateProfile(c *gin.Context) {
+	userId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Missing Id Field", err)
+		return
+	}
+
+	var userUpdateInput user_model.UserUpdateInput
+	err = c.BindJSON(&userUpdateInput)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, errors.ApiError_Json, err)
+		return
+	}
+
+	err = auc.ServicesGroup.ProfileService.UpdateUserProfile(userId, userUpdateInput.Profile, profile_model.VISIBILITY_ADMIN)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't update profile.", err)
+		return
+	}
+
+	c.Status(http.StatusOK)
 }
 
 /**
-* @api {get} /admin/user Get All Users
-* @apiDescription Used to get a list of all users.
+* @api {get} /admin/user Search Users
+* @apiDescription Used to search, filter, and page through users. Results are paged with a cursor. Pass the nextCursor from one page as the cursor
+* of the next request with the same filters and sort.
 * @apiName GetAllUsers
 * @apiGroup Admin
 *
 * @apiUse UserAuthHeader
-* @apiUse UserDisplay
+* @apiParam (Query String) {string} [q] Text to search for in the users name and email addresses.
+* @apiParam (Query String) {bool} [enabled]
+* @apiParam (Query String) {bool} [verified]
+* @apiParam (Query String) {string} [group] Group name.
+* @apiParam (Query String) {string} [createdAfter] Date (2006-01-02) or RFC3339 time.
+* @apiParam (Query String) {string} [createdBefore] Date (2006-01-02) or RFC3339 time.
+* @apiParam (Query String) {string} [sort] id, fullName, email, created, or lastModified. Prefix with - for descending. Default=id
+* @apiParam (Query String) {number} [limit] Default=50, Max=500
+* @apiParam (Query String) {string} [cursor]
+* @apiUse UserSearchResult
 * @apiPermission Admin
  */
 func (auc *UserAdminController) getAll(c *gin.Context) {
-	users, err := auc.ServicesGroup.UserService.GetAll()
+	query, err := parseUserSearchQuery(c)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	result, err := auc.ServicesGroup.UserService.Search(query)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't get users.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+/**
+* @api {post} /admin/user/bulk Bulk Update Users
+* @apiDescription Enable, disable, add to group, or delete many users at once. Each user is reported separately.
+* @apiName BulkUpdateUsers
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiUse UserBulkInput
+* @apiUse UserBulkResult
+* @apiPermission Admin
+ */
+func (auc *UserAdminController) bulk(c *gin.Context) {
+	authUser, _ := api_utility.GetUserFromContext(c)
+
+	var userBulkInput user_model.UserBulkInput
+	err := c.BindJSON(&userBulkInput)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, errors.ApiError_Json, err)
+		return
+	}
+
+	results, err := auc.ServicesGroup.UserService.BulkAction(&userBulkInput, authUser.Id)
 	if err != nil {
-		errors.Response(c, http.StatusInternalServerError, "Couldn't get users", err)
+		errors.Response(c, http.StatusBadRequest, "Couldn't update users.", err)
 		return
 	}
 
-	// create list of users to sent out
-	usersAdminDisplays := make([]user_model.UserAdminDisplay, len(*users))
-	for i, user := range *users {
-		usersAdminDisplays[i] = *user.GetUserAdminDisplay()
+	c.JSON(http.StatusOK, results)
+}
+
+func parseUserSearchQuery(c *gin.Context) (*user_model.UserSearchQuery, error) {
+	query := &user_model.UserSearchQuery{
+		Query: strings.TrimSpace(c.Query("q")),
+		Group: c.Query("group"),
+	}
+
+	for param, target := range map[string]**bool{"enabled": &query.Enabled, "verified": &query.Verified} {
+		if value := c.Query(param); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("%v must be true or false.", param))
+			}
+			*target = &b
+		}
+	}
+
+	for param, target := range map[string]**time.Time{"createdAfter": &query.CreatedAfter, "createdBefore": &query.CreatedBefore} {
+		if value := c.Query(param); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				t, err = time.Parse("2006-01-02", value)
+			}
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("%v must be a date (2006-01-02) or RFC3339 time.", param))
+			}
+			*target = &t
+		}
+	}
+
+	query.Sort = c.Query("sort")
+	if strings.HasPrefix(query.Sort, "-") {
+		query.Sort = query.Sort[1:]
+		query.Descending = true
 	}
 
-	c.JSON(http.StatusOK, usersAdminDisplays)
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, errors.New("limit must be a number.")
+		}
+		query.Limit = limit
+	}
+
+	if value := c.Query("cursor"); value != "" {
+		cursor, err := user_model.DecodeUserSearchCursor(value)
+		if err != nil {
+			return nil, errors.New("cursor is not valid.")
+		}
+		query.Cursor = cursor
+	}
+
+	return query, nil
 }
 
 func (auc *UserAdminController) update(c *gin.Context) {
@@ -159,6 +370,10 @@ func (auc *UserAdminController) delete(c *gin.Context) {
 
 	// delete user
 	err = auc.ServicesGroup.UserService.Delete(userId)
+	if err == sql.ErrNoRows {
+		errors.Response(c, http.StatusNotFound, errors.ApiError_UserDoesntExist, err)
+		return
+	}
 	if err != nil {
 		errors.Response(c, http.StatusInternalServerError, "Couldn't delete user.", err)
 		return
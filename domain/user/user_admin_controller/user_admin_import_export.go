@@ -0,0 +1,210 @@
+package user_admin_controller
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// largest import body accepted
+const maxImportSize = 10 << 20
+
+/**
+* @api {post} /admin/users/import Import Users
+* @apiDescription Create users from a csv or json file. Csv files require a header row using the field names below. Json files are an array of users.
+* Every row is validated and reported on. Rows with errors are skipped.
+* @apiName ImportUsers
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiParam (Query String) {string} [format] csv or json. Default=json
+* @apiParam (Query String) {bool} [dryRun] Validate only. Default=false
+* @apiParam (Query String) {string} [notify] none, invite, or activate. Default=none
+* @apiUse UserImportRecord
+* @apiUse UserImportResult
+* @apiPermission Admin
+ */
+func (auc *UserAdminController) importUsers(c *gin.Context) {
+	options := &user_model.UserImportOptions{
+		Notify: c.DefaultQuery("notify", user_model.IMPORT_NOTIFY_NONE),
+	}
+	if dryRun := c.Query("dryRun"); dryRun != "" {
+		b, err := strconv.ParseBool(dryRun)
+		if err != nil {
+			errors.Response(c, http.StatusBadRequest, "dryRun must be true or false.", err)
+			return
+		}
+		options.DryRun = b
+	}
+
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+
+	var records []user_model.UserImportRecord
+	var err error
+	switch c.DefaultQuery("format", user_model.IMPORT_FORMAT_JSON) {
+	case user_model.IMPORT_FORMAT_JSON:
+		err = json.NewDecoder(body).Decode(&records)
+	case user_model.IMPORT_FORMAT_CSV:
+		records, err = readCsvRecords(body)
+	default:
+		errors.Response(c, http.StatusBadRequest, "format must be csv or json.", nil)
+		return
+	}
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't read users.", errors.NewToUser(err.Error()))
+		return
+	}
+
+	result, err := auc.ServicesGroup.UserService.Import(records, options)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't import users.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+/**
+* @api {get} /admin/users/export Export Users
+* @apiDescription Download every user in the format accepted by Import Users.
+* @apiName ExportUsers
+* @apiGroup Admin
+*
+* @apiUse UserAuthHeader
+* @apiParam (Query String) {string} [format] csv or json. Default=json
+* @apiPermission Admin
+ */
+func (auc *UserAdminController) exportUsers(c *gin.Context) {
+	format := c.DefaultQuery("format", user_model.IMPORT_FORMAT_JSON)
+	if format != user_model.IMPORT_FORMAT_CSV && format != user_model.IMPORT_FORMAT_JSON {
+		errors.Response(c, http.StatusBadRequest, "format must be csv or json.", nil)
+		return
+	}
+
+	fileName := fmt.Sprintf("users-%v.%v", time.Now().Format("20060102"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%v\"", fileName))
+
+	var err error
+	if format == user_model.IMPORT_FORMAT_CSV {
+		c.Header("Content-Type", "text/csv")
+		c.Status(http.StatusOK)
+		w := csv.NewWriter(c.Writer)
+		w.Write(user_model.UserImportCsvHeader)
+		err = auc.ServicesGroup.UserService.Export(func(record *user_model.UserImportRecord) error {
+			return w.Write([]string{
+				record.Email,
+				record.FullName,
+				strconv.FormatBool(record.Enabled == nil || *record.Enabled),
+				strconv.FormatBool(record.Verified),
+				strings.Join(record.Groups, ";"),
+				strings.Join(record.AltEmails, ";"),
+			})
+		})
+		w.Flush()
+	} else {
+		c.Header("Content-Type", "application/json")
+		c.Status(http.StatusOK)
+		first := true
+		io.WriteString(c.Writer, "[")
+		err = auc.ServicesGroup.UserService.Export(func(record *user_model.UserImportRecord) error {
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if !first {
+				io.WriteString(c.Writer, ",")
+			}
+			first = false
+			_, err = c.Writer.Write(data)
+			return err
+		})
+		io.WriteString(c.Writer, "]")
+	}
+
+	// the response has already started so the error can only be logged
+	if err != nil {
+		log.Errorf("Error exporting users: %s\n", err.Error())
+	}
+}
+
+func readCsvRecords(r io.Reader) ([]user_model.UserImportRecord, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("missing csv header: %v", err.Error())
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["email"]; !ok {
+		return nil, fmt.Errorf("csv header must include an email column")
+	}
+
+	value := func(row []string, name string) string {
+		if i, ok := columns[strings.ToLower(name)]; ok && i < len(row) {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	list := func(row []string, name string) []string {
+		var values []string
+		for _, v := range strings.Split(value(row, name), ";") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+		return values
+	}
+
+	var records []user_model.UserImportRecord
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		record := user_model.UserImportRecord{
+			Email:     value(row, "email"),
+			FullName:  value(row, "fullName"),
+			Groups:    list(row, "groups"),
+			AltEmails: list(row, "altEmails"),
+		}
+		if enabled := value(row, "enabled"); enabled != "" {
+			b, err := strconv.ParseBool(enabled)
+			if err != nil {
+				record.ParseErrors = append(record.ParseErrors, "enabled must be true or false.")
+			} else {
+				record.Enabled = &b
+			}
+		}
+		if verified := value(row, "verified"); verified != "" {
+			b, err := strconv.ParseBool(verified)
+			if err != nil {
+				record.ParseErrors = append(record.ParseErrors, "verified must be true or false.")
+			} else {
+				record.Verified = b
+			}
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
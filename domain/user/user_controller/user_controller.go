@@ -3,6 +3,7 @@ package user_controller
 import (
 	"github.com/gin-gonic/gin"
 	"github.com/gocms-io/gocms/utility/api_utility"
+	"github.com/gocms-io/gocms/domain/user/profile/profile_model"
 	"github.com/gocms-io/gocms/domain/user/user_model"
 	"github.com/gocms-io/gocms/init/service"
 	"github.com/gocms-io/gocms/routes"
@@ -30,6 +31,17 @@ func (uc *UserController) Default() {
 	uc.routes.Auth.PUT("/user", uc.update)
 	uc.routes.Auth.PUT("/user/deactivate", uc.deactivateUser)
 	uc.routes.Auth.PUT("/user/changePassword", uc.changePassword)
+	uc.routes.Auth.GET("/user/profile/fields", uc.getProfileFields)
+	uc.routes.Public.GET("/profile/:userId", uc.getPublicProfile)
+	uc.routes.Auth.GET("/user/export", uc.exportData)
+	uc.routes.Auth.GET("/user/delete", uc.getDeletion)
+	uc.routes.Auth.PUT("/user/delete", uc.scheduleDeletion)
+	uc.routes.Auth.DELETE("/user/delete", uc.cancelDeletion)
+	uc.routes.Auth.GET("/user/devices", uc.getDevices)
+	uc.routes.Auth.DELETE("/user/devices/:deviceId", uc.revokeDevice)
+	uc.routes.Auth.GET("/user/webauthn", uc.getWebauthnCredentials)
+	uc.routes.Auth.PUT("/user/webauthn/:id", uc.updateWebauthnCredential)
+	uc.routes.Auth.DELETE("/user/webauthn/:id", uc.deleteWebauthnCredential)
 
 }
 
@@ -46,7 +58,17 @@ func (uc *UserController) get(c *gin.Context) {
 
 	authUser, _ := api_utility.GetUserFromContext(c)
 
-	c.JSON(http.StatusOK, authUser.GetUserDisplay())
+	userDisplay := authUser.GetUserDisplay()
+
+	// add profile fields
+	profile, err := uc.ServicesGroup.ProfileService.GetUserProfile(authUser.Id, profile_model.VISIBILITY_SELF)
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, errors.ApiError_Server, err)
+		return
+	}
+	userDisplay.Profile = profile
+
+	c.JSON(http.StatusOK, userDisplay)
 }
 
 /**
@@ -86,6 +108,15 @@ func (uc *UserController) update(c *gin.Context) {
 		break
 	}
 
+	// validate and update profile fields first so a bad value doesn't leave a partial update
+	if userForUpdate.Profile != nil {
+		err = uc.ServicesGroup.ProfileService.UpdateUserProfile(authUser.Id, userForUpdate.Profile, profile_model.VISIBILITY_SELF)
+		if err != nil {
+			errors.Response(c, http.StatusBadRequest, "Couldn't update profile.", err)
+			return
+		}
+	}
+
 	// do update
 	err = uc.ServicesGroup.UserService.Update(authUser.Id, authUser)
 	if err != nil {
@@ -124,10 +155,10 @@ func (uc *UserController) changePassword(c *gin.Context) {
 		return
 	}
 
-	// do update
+	// do update. the password policy is checked by the service.
 	err = uc.ServicesGroup.UserService.UpdatePassword(authUser.Id, changePasswordInput.NewPassword)
 	if err != nil {
-		errors.Response(c, http.StatusInternalServerError, "Couldn't update user.", err)
+		errors.Response(c, http.StatusBadRequest, "Couldn't update password.", err)
 		return
 	}
 
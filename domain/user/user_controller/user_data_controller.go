@@ -0,0 +1,122 @@
+package user_controller
+
+import (
+	"fmt"
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"github.com/gocms-io/gocms/utility/api_utility"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+	"time"
+)
+
+/**
+* @api {get} /user/export Export Data
+* @apiDescription Download a zip archive of all data held about the user, including data held by plugins.
+* @apiName ExportUserData
+* @apiGroup User
+*
+* @apiUse AuthHeader
+* @apiSuccess (Response) {file} archive application/zip
+* @apiPermission Authenticated
+ */
+func (uc *UserController) exportData(c *gin.Context) {
+
+	// get logged in user
+	authUser, _ := api_utility.GetUserFromContext(c)
+
+	archive, err := uc.ServicesGroup.UserDataService.Export(authUser)
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, "Couldn't export user data.", err)
+		return
+	}
+
+	fileName := fmt.Sprintf("user-%v-%v.zip", authUser.Id, time.Now().Format("20060102"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"%v\"", fileName))
+	c.Data(http.StatusOK, "application/zip", archive)
+}
+
+/**
+* @api {get} /user/delete Get Account Deletion
+* @apiDescription Get the scheduled deletion of the account if one exists.
+* @apiName GetUserDeletion
+* @apiGroup User
+*
+* @apiUse AuthHeader
+* @apiUse UserDeletionDisplay
+* @apiPermission Authenticated
+ */
+func (uc *UserController) getDeletion(c *gin.Context) {
+
+	// get logged in user
+	authUser, _ := api_utility.GetUserFromContext(c)
+
+	userDeletion, err := uc.ServicesGroup.UserDataService.GetDeletion(authUser.Id)
+	if err != nil {
+		errors.Response(c, http.StatusNotFound, "Account is not scheduled for deletion.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userDeletion.GetUserDeletionDisplay())
+}
+
+/**
+* @api {put} /user/delete Delete Account
+* @apiDescription Schedule the account and all of its data, including data held by plugins, for permanent deletion.
+* The account is deleted once the grace period is over. Until then the deletion can be cancelled.
+* @apiName DeleteUser
+* @apiGroup User
+*
+* @apiUse AuthHeader
+* @apiUse UserPasswordInput
+* @apiUse UserDeletionDisplay
+* @apiPermission Authenticated
+ */
+func (uc *UserController) scheduleDeletion(c *gin.Context) {
+
+	// get logged in user
+	authUser, _ := api_utility.GetUserFromContext(c)
+
+	var userPasswordInput user_model.UserPasswordInput
+	err := c.BindJSON(&userPasswordInput)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	// verify password
+	if ok := uc.ServicesGroup.AuthService.VerifyPassword(authUser.Password, userPasswordInput.Password); !ok {
+		errors.Response(c, http.StatusUnauthorized, "Bad Password.", err)
+		return
+	}
+
+	userDeletion, err := uc.ServicesGroup.UserDataService.ScheduleDeletion(authUser)
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, "Couldn't schedule account deletion.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, userDeletion.GetUserDeletionDisplay())
+}
+
+/**
+* @api {delete} /user/delete Cancel Account Deletion
+* @apiName CancelUserDeletion
+* @apiGroup User
+*
+* @apiUse AuthHeader
+* @apiPermission Authenticated
+ */
+func (uc *UserController) cancelDeletion(c *gin.Context) {
+
+	// get logged in user
+	authUser, _ := api_utility.GetUserFromContext(c)
+
+	err := uc.ServicesGroup.UserDataService.CancelDeletion(authUser)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Couldn't cancel account deletion.", err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,57 @@
+package user_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/utility/api_utility"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+)
+
+/**
+* @api {get} /user/devices Get Trusted Devices
+* @apiDescription List the devices that passed two-factor verification for the user.
+* @apiName GetTrustedDevices
+* @apiGroup User
+*
+* @apiUse AuthHeader
+* @apiUse TrustedDeviceDisplay
+* @apiPermission Authenticated
+ */
+func (uc *UserController) getDevices(c *gin.Context) {
+
+	// get logged in user
+	authUser, _ := api_utility.GetUserFromContext(c)
+	currentDeviceId, _ := api_utility.GetDeviceIdFromContext(c)
+
+	trustedDevices, err := uc.ServicesGroup.TrustedDeviceService.GetDevices(authUser.Id, currentDeviceId)
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, "Couldn't get devices.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, trustedDevices)
+}
+
+/**
+* @api {delete} /user/devices/:deviceId Revoke Trusted Device
+* @apiDescription Stop trusting a device. The device must pass two-factor verification again before it can be used.
+* @apiName RevokeTrustedDevice
+* @apiGroup User
+*
+* @apiUse AuthHeader
+* @apiParam {string} deviceId
+* @apiPermission Authenticated
+ */
+func (uc *UserController) revokeDevice(c *gin.Context) {
+
+	// get logged in user
+	authUser, _ := api_utility.GetUserFromContext(c)
+
+	err := uc.ServicesGroup.TrustedDeviceService.Revoke(authUser.Id, c.Param("deviceId"))
+	if err != nil {
+		errors.Response(c, http.StatusNotFound, "Couldn't revoke device.", err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,67 @@
+package user_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/user/profile/profile_model"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+	"strconv"
+)
+
+/**
+* @api {get} /user/profile/fields Get Profile Fields
+* @apiDescription Get the custom profile fields the user can view and edit.
+* @apiName GetProfileFields
+* @apiGroup User
+*
+* @apiUse AuthHeader
+* @apiUse ProfileFieldDisplay
+* @apiPermission Authenticated
+ */
+func (uc *UserController) getProfileFields(c *gin.Context) {
+	fields, err := uc.ServicesGroup.ProfileService.GetVisibleFields(profile_model.VISIBILITY_SELF)
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, "Couldn't get profile fields.", err)
+		return
+	}
+
+	fieldDisplays := make([]profile_model.ProfileFieldDisplay, len(fields))
+	for i, field := range fields {
+		fieldDisplays[i] = *field.GetProfileFieldDisplay()
+	}
+
+	c.JSON(http.StatusOK, fieldDisplays)
+}
+
+/**
+* @api {get} /profile/:userId Get Public Profile
+* @apiDescription Get the public profile fields of a user.
+* @apiName GetPublicProfile
+* @apiGroup User
+*
+* @apiUse PublicProfileDisplay
+ */
+func (uc *UserController) getPublicProfile(c *gin.Context) {
+	userId, err := strconv.ParseInt(c.Param("userId"), 10, 64)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Missing Id Field", err)
+		return
+	}
+
+	user, err := uc.ServicesGroup.UserService.Get(userId)
+	if err != nil || !user.Enabled {
+		errors.Response(c, http.StatusNotFound, errors.ApiError_UserDoesntExist, err)
+		return
+	}
+
+	profile, err := uc.ServicesGroup.ProfileService.GetUserProfile(userId, profile_model.VISIBILITY_PUBLIC)
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, errors.ApiError_Server, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, profile_model.PublicProfileDisplay{
+		Id:      userId,
+		Profile: profile,
+	})
+}
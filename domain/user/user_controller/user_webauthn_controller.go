@@ -0,0 +1,101 @@
+package user_controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/domain/acl/webauthn/webauthn_model"
+	"github.com/gocms-io/gocms/utility/api_utility"
+	"github.com/gocms-io/gocms/utility/errors"
+	"net/http"
+	"strconv"
+)
+
+/**
+* @api {get} /user/webauthn Get Passkeys
+* @apiDescription List the passkeys and security keys registered to the user.
+* @apiName GetWebauthnCredentials
+* @apiGroup User
+*
+* @apiUse AuthHeader
+* @apiUse WebauthnCredentialDisplay
+* @apiPermission Authenticated
+ */
+func (uc *UserController) getWebauthnCredentials(c *gin.Context) {
+
+	// get logged in user
+	authUser, _ := api_utility.GetUserFromContext(c)
+
+	credentials, err := uc.ServicesGroup.WebauthnService.GetCredentials(authUser.Id)
+	if err != nil {
+		errors.Response(c, http.StatusInternalServerError, "Couldn't get passkeys.", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, credentials)
+}
+
+/**
+* @api {put} /user/webauthn/:id Rename Passkey
+* @apiName UpdateWebauthnCredential
+* @apiGroup User
+*
+* @apiUse AuthHeader
+* @apiParam {number} id
+* @apiUse WebauthnCredentialUpdateInput
+* @apiPermission Authenticated
+ */
+func (uc *UserController) updateWebauthnCredential(c *gin.Context) {
+
+	// get logged in user
+	authUser, _ := api_utility.GetUserFromContext(c)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Invalid passkey id.", err)
+		return
+	}
+
+	var credentialUpdateInput webauthn_model.CredentialUpdateInput
+	err = c.BindJSON(&credentialUpdateInput)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, err.Error(), err)
+		return
+	}
+
+	err = uc.ServicesGroup.WebauthnService.UpdateCredentialName(authUser.Id, id, credentialUpdateInput.Name)
+	if err != nil {
+		errors.Response(c, http.StatusNotFound, "Couldn't rename passkey.", err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+/**
+* @api {delete} /user/webauthn/:id Delete Passkey
+* @apiDescription Remove a passkey. It can no longer be used to log in or verify devices.
+* @apiName DeleteWebauthnCredential
+* @apiGroup User
+*
+* @apiUse AuthHeader
+* @apiParam {number} id
+* @apiPermission Authenticated
+ */
+func (uc *UserController) deleteWebauthnCredential(c *gin.Context) {
+
+	// get logged in user
+	authUser, _ := api_utility.GetUserFromContext(c)
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		errors.Response(c, http.StatusBadRequest, "Invalid passkey id.", err)
+		return
+	}
+
+	err = uc.ServicesGroup.WebauthnService.DeleteCredential(authUser.Id, id)
+	if err != nil {
+		errors.Response(c, http.StatusNotFound, "Couldn't delete passkey.", err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -0,0 +1,58 @@
+package user_data_model
+
+import (
+	"database/sql"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"time"
+)
+
+// Exporter returns data held about a user. The result is marshaled to json and added to the users export.
+type Exporter func(user *user_model.User) (interface{}, error)
+
+type UserDeletion struct {
+	UserId       int64          `db:"userId"`
+	Requested    time.Time      `db:"requested"`
+	ScheduledFor time.Time      `db:"scheduledFor"`
+	Attempts     int64          `db:"attempts"`
+	LastError    sql.NullString `db:"lastError"`
+}
+
+/**
+* @apiDefine UserDeletionDisplay
+* @apiSuccess (Response) {string} requested
+* @apiSuccess (Response) {string} scheduledFor The time the account will be permanently deleted.
+ */
+type UserDeletionDisplay struct {
+	Requested    time.Time `json:"requested"`
+	ScheduledFor time.Time `json:"scheduledFor"`
+}
+
+func (userDeletion *UserDeletion) GetUserDeletionDisplay() *UserDeletionDisplay {
+	return &UserDeletionDisplay{
+		Requested:    userDeletion.Requested,
+		ScheduledFor: userDeletion.ScheduledFor,
+	}
+}
+
+// ExportSummary is written to the root of every export.
+type ExportSummary struct {
+	UserId         int64             `json:"userId"`
+	Generated      time.Time         `json:"generated"`
+	Files          []string          `json:"files"`
+	PluginErrors   map[string]string `json:"pluginErrors,omitempty"`
+	ExporterErrors map[string]string `json:"exporterErrors,omitempty"`
+}
+
+// SecureCodeExport excludes the hashed code.
+type SecureCodeExport struct {
+	Id      int64     `json:"id"`
+	Type    string    `json:"type"`
+	Created time.Time `json:"created"`
+}
+
+// AuditEntryExport is an account event rebuilt from the records GoCMS keeps.
+type AuditEntryExport struct {
+	Time   time.Time `json:"time"`
+	Event  string    `json:"event"`
+	Detail string    `json:"detail,omitempty"`
+}
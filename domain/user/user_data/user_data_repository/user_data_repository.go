@@ -0,0 +1,91 @@
+package user_data_repository
+
+import (
+	"github.com/gocms-io/gocms/domain/user/user_data/user_data_model"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type IUserDataRepository interface {
+	AddDeletion(*user_data_model.UserDeletion) error
+	GetDeletion(userId int64) (*user_data_model.UserDeletion, error)
+	GetDueDeletions(time.Time) ([]user_data_model.UserDeletion, error)
+	UpdateDeletionAttempt(userId int64, lastError string) error
+	DeleteDeletion(userId int64) error
+}
+
+type UserDataRepository struct {
+	database *sqlx.DB
+}
+
+func DefaultUserDataRepository(dbx *sqlx.DB) *UserDataRepository {
+	userDataRepository := &UserDataRepository{
+		database: dbx,
+	}
+
+	return userDataRepository
+}
+
+func (udr *UserDataRepository) AddDeletion(userDeletion *user_data_model.UserDeletion) error {
+	userDeletion.Requested = time.Now()
+	_, err := udr.database.NamedExec(`
+	INSERT INTO gocms_user_deletions (userId, requested, scheduledFor) VALUES (:userId, :requested, :scheduledFor)
+	`, userDeletion)
+	if err != nil {
+		log.Errorf("Error adding user deletion to database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (udr *UserDataRepository) GetDeletion(userId int64) (*user_data_model.UserDeletion, error) {
+	var userDeletion user_data_model.UserDeletion
+	err := udr.database.Get(&userDeletion, `
+	SELECT * FROM gocms_user_deletions WHERE userId=?
+	`, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userDeletion, nil
+}
+
+// get all deletions scheduled before the time provided
+func (udr *UserDataRepository) GetDueDeletions(before time.Time) ([]user_data_model.UserDeletion, error) {
+	var userDeletions []user_data_model.UserDeletion
+	err := udr.database.Select(&userDeletions, `
+	SELECT * FROM gocms_user_deletions WHERE scheduledFor<=?
+	`, before)
+	if err != nil {
+		log.Errorf("Error getting due user deletions from database: %s", err.Error())
+		return nil, err
+	}
+
+	return userDeletions, nil
+}
+
+func (udr *UserDataRepository) UpdateDeletionAttempt(userId int64, lastError string) error {
+	_, err := udr.database.Exec(`
+	UPDATE gocms_user_deletions SET attempts=attempts+1, lastError=? WHERE userId=?
+	`, lastError, userId)
+	if err != nil {
+		log.Errorf("Error updating user deletion attempt in database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
+
+func (udr *UserDataRepository) DeleteDeletion(userId int64) error {
+	_, err := udr.database.Exec(`
+	DELETE FROM gocms_user_deletions WHERE userId=?
+	`, userId)
+	if err != nil {
+		log.Errorf("Error deleting user deletion from database: %s", err.Error())
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,358 @@
+package user_data_service
+
+import (
+	"archive/zip"
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/mail/mail_service"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_services"
+	"github.com/gocms-io/gocms/domain/secure_code/security_code_model"
+	"github.com/gocms-io/gocms/domain/user/user_data/user_data_model"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"github.com/gocms-io/gocms/domain/user/user_service"
+	"github.com/gocms-io/gocms/init/repository"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"sort"
+	"sync"
+	"time"
+)
+
+type IUserDataService interface {
+	RegisterExporter(name string, exporter user_data_model.Exporter)
+	Export(user *user_model.User) ([]byte, error)
+	ScheduleDeletion(user *user_model.User) (*user_data_model.UserDeletion, error)
+	GetDeletion(userId int64) (*user_data_model.UserDeletion, error)
+	CancelDeletion(user *user_model.User) error
+	ProcessDeletions()
+}
+
+type UserDataService struct {
+	RepositoriesGroup *repository.RepositoriesGroup
+	PluginsService    plugin_services.IPluginsService
+	UserService       user_service.IUserService
+	MailService       mail_service.IMailService
+	exporters         map[string]user_data_model.Exporter
+	exportersMu       sync.RWMutex
+	deletionsMu       sync.Mutex
+}
+
+func DefaultUserDataService(rg *repository.RepositoriesGroup, pluginsService plugin_services.IPluginsService, userService user_service.IUserService, mailService mail_service.IMailService) *UserDataService {
+	userDataService := &UserDataService{
+		RepositoriesGroup: rg,
+		PluginsService:    pluginsService,
+		UserService:       userService,
+		MailService:       mailService,
+		exporters:         make(map[string]user_data_model.Exporter),
+	}
+
+	// core exporters
+	userDataService.RegisterExporter("profile", userDataService.exportProfile)
+	userDataService.RegisterExporter("emails", userDataService.exportEmails)
+	userDataService.RegisterExporter("groups", userDataService.exportGroups)
+	userDataService.RegisterExporter("permissions", userDataService.exportPermissions)
+	userDataService.RegisterExporter("security_codes", userDataService.exportSecureCodes)
+	userDataService.RegisterExporter("audit", userDataService.exportAudit)
+
+	// permanently delete accounts once their grace period is over
+	context.Schedule.AddTicker(time.Hour, userDataService.ProcessDeletions)
+
+	return userDataService
+}
+
+// RegisterExporter adds data to every user export as <name>.json
+func (uds *UserDataService) RegisterExporter(name string, exporter user_data_model.Exporter) {
+	uds.exportersMu.Lock()
+	defer uds.exportersMu.Unlock()
+	uds.exporters[name] = exporter
+}
+
+// Export builds a zip archive of everything held about the user, including data from plugins.
+func (uds *UserDataService) Export(user *user_model.User) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	archive := zip.NewWriter(buf)
+
+	summary := user_data_model.ExportSummary{
+		UserId:         user.Id,
+		Generated:      time.Now(),
+		PluginErrors:   make(map[string]string),
+		ExporterErrors: make(map[string]string),
+	}
+
+	// core and registered exporters
+	uds.exportersMu.RLock()
+	names := make([]string, 0, len(uds.exporters))
+	for name := range uds.exporters {
+		names = append(names, name)
+	}
+	uds.exportersMu.RUnlock()
+	sort.Strings(names)
+
+	for _, name := range names {
+		uds.exportersMu.RLock()
+		exporter := uds.exporters[name]
+		uds.exportersMu.RUnlock()
+
+		data, err := exporter(user)
+		if err != nil {
+			log.Errorf("Error exporting %v for user %v: %s\n", name, user.Id, err.Error())
+			summary.ExporterErrors[name] = errors.ApiError_Server
+			continue
+		}
+
+		fileName := fmt.Sprintf("%v.json", name)
+		err = writeJsonFile(archive, fileName, data)
+		if err != nil {
+			return nil, err
+		}
+		summary.Files = append(summary.Files, fileName)
+	}
+
+	// plugin exports
+	pluginExports, pluginErrors := uds.PluginsService.ExportUserData(user)
+	for pluginId := range pluginErrors {
+		summary.PluginErrors[pluginId] = "Plugin data could not be exported."
+	}
+	pluginIds := make([]string, 0, len(pluginExports))
+	for pluginId := range pluginExports {
+		pluginIds = append(pluginIds, pluginId)
+	}
+	sort.Strings(pluginIds)
+	for _, pluginId := range pluginIds {
+		fileName := fmt.Sprintf("plugins/%v.json", pluginId)
+		w, err := archive.Create(fileName)
+		if err != nil {
+			return nil, err
+		}
+		_, err = w.Write(pluginExports[pluginId])
+		if err != nil {
+			return nil, err
+		}
+		summary.Files = append(summary.Files, fileName)
+	}
+
+	err := writeJsonFile(archive, "export.json", summary)
+	if err != nil {
+		return nil, err
+	}
+
+	err = archive.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// ScheduleDeletion marks the account for permanent deletion once the grace period is over.
+func (uds *UserDataService) ScheduleDeletion(user *user_model.User) (*user_data_model.UserDeletion, error) {
+
+	// already scheduled
+	if existing, err := uds.GetDeletion(user.Id); err == nil {
+		return existing, nil
+	}
+
+	userDeletion := &user_data_model.UserDeletion{
+		UserId:       user.Id,
+		ScheduledFor: time.Now().Add(time.Hour * 24 * time.Duration(context.Config.DbVars.UserDeletionGracePeriod)),
+	}
+	err := uds.RepositoriesGroup.UserDataRepository.AddDeletion(userDeletion)
+	if err != nil {
+		return nil, err
+	}
+
+	scheduledForStr := userDeletion.ScheduledFor.Format("01/02/2006 03:04 pm")
+	uds.MailService.Send(&mail_service.Mail{
+		To:      user.Email,
+		Subject: "Account Deletion Scheduled",
+		Body: "Your account and all of its data will be permanently deleted at: " + scheduledForStr +
+			".\n\nTo keep your account sign in and cancel the deletion before then. If you believe this to be a mistake please contact support.",
+		BodyHTML: fmt.Sprintf("<h1>Account Deletion Scheduled</h1><p>Your account and all of its data will be permanently deleted at: <b>%v</b></p><p>To keep your account sign in and cancel the deletion before then. If you believe this to be a mistake please contact support.</p>", scheduledForStr),
+	})
+
+	return userDeletion, nil
+}
+
+func (uds *UserDataService) GetDeletion(userId int64) (*user_data_model.UserDeletion, error) {
+	return uds.RepositoriesGroup.UserDataRepository.GetDeletion(userId)
+}
+
+func (uds *UserDataService) CancelDeletion(user *user_model.User) error {
+	_, err := uds.GetDeletion(user.Id)
+	if err != nil {
+		return errors.NewToUser("Account is not scheduled for deletion.")
+	}
+
+	err = uds.RepositoriesGroup.UserDataRepository.DeleteDeletion(user.Id)
+	if err != nil {
+		return err
+	}
+
+	uds.MailService.Send(&mail_service.Mail{
+		To:       user.Email,
+		Subject:  "Account Deletion Cancelled",
+		Body:     "The scheduled deletion of your account has been cancelled.\n\n If you believe this to be a mistake please contact support.",
+		BodyHTML: "<h1>Account Deletion Cancelled</h1><p>The scheduled deletion of your account has been cancelled.</p><p>If you believe this to be a mistake please contact support.</p>",
+	})
+
+	return nil
+}
+
+// ProcessDeletions permanently deletes every account whose grace period is over. Plugins are asked to delete their data first.
+// If a plugin fails the account is kept and the deletion is retried on the next run.
+func (uds *UserDataService) ProcessDeletions() {
+	uds.deletionsMu.Lock()
+	defer uds.deletionsMu.Unlock()
+
+	dueDeletions, err := uds.RepositoriesGroup.UserDataRepository.GetDueDeletions(time.Now())
+	if err != nil {
+		return
+	}
+
+	for _, userDeletion := range dueDeletions {
+		user, err := uds.RepositoriesGroup.UsersRepository.Get(userDeletion.UserId)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				uds.RepositoriesGroup.UserDataRepository.DeleteDeletion(userDeletion.UserId)
+			}
+			continue
+		}
+
+		err = uds.UserService.Delete(user.Id)
+		if err != nil {
+			log.Errorf("Error deleting user %v, will retry: %s\n", user.Id, err.Error())
+			uds.RepositoriesGroup.UserDataRepository.UpdateDeletionAttempt(user.Id, err.Error())
+			continue
+		}
+
+		uds.MailService.Send(&mail_service.Mail{
+			To:       user.Email,
+			Subject:  "Account Deleted",
+			Body:     "Your account and all of its data have been permanently deleted.",
+			BodyHTML: "<h1>Account Deleted</h1><p>Your account and all of its data have been permanently deleted.</p>",
+		})
+	}
+}
+
+func (uds *UserDataService) exportProfile(user *user_model.User) (interface{}, error) {
+	dbUser, err := uds.RepositoriesGroup.UsersRepository.Get(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	return dbUser.GetUserAdminDisplay(), nil
+}
+
+func (uds *UserDataService) exportEmails(user *user_model.User) (interface{}, error) {
+	emails, err := uds.RepositoriesGroup.EmailRepository.GetByUserId(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	return emails, nil
+}
+
+func (uds *UserDataService) exportGroups(user *user_model.User) (interface{}, error) {
+	return uds.RepositoriesGroup.GroupsRepository.GetUserGroups(user.Id)
+}
+
+func (uds *UserDataService) exportPermissions(user *user_model.User) (interface{}, error) {
+	return uds.RepositoriesGroup.PermissionsRepository.GetUserPermissions(user.Id)
+}
+
+func (uds *UserDataService) exportSecureCodes(user *user_model.User) (interface{}, error) {
+	secureCodes, err := uds.RepositoriesGroup.SecureCodeRepository.GetAllForUser(user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	codeTypes := map[security_code_model.SecureCodeType]string{
+		security_code_model.Code_VerifyEmail:   "verifyEmail",
+		security_code_model.Code_VerifyDevice:  "verifyDevice",
+		security_code_model.Code_ResetPassword: "resetPassword",
+		security_code_model.Code_Invitation:    "invitation",
+		security_code_model.Code_MagicLink:     "magicLink",
+	}
+
+	exports := make([]user_data_model.SecureCodeExport, len(secureCodes))
+	for i, secureCode := range secureCodes {
+		exports[i] = user_data_model.SecureCodeExport{
+			Id:      secureCode.Id,
+			Type:    codeTypes[secureCode.Type],
+			Created: secureCode.Created,
+		}
+	}
+	return exports, nil
+}
+
+// exportAudit rebuilds the account's history from the timestamps GoCMS keeps, oldest first
+func (uds *UserDataService) exportAudit(user *user_model.User) (interface{}, error) {
+	dbUser, err := uds.RepositoriesGroup.UsersRepository.Get(user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []user_data_model.AuditEntryExport{
+		{Time: dbUser.Created, Event: "accountCreated"},
+		{Time: dbUser.LastModified, Event: "accountModified"},
+	}
+	if dbUser.PasswordChanged != nil {
+		entries = append(entries, user_data_model.AuditEntryExport{Time: *dbUser.PasswordChanged, Event: "passwordChanged"})
+	}
+
+	trustedDevices, err := uds.RepositoriesGroup.TrustedDeviceRepository.GetByUser(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, trustedDevice := range trustedDevices {
+		entries = append(entries,
+			user_data_model.AuditEntryExport{Time: trustedDevice.FirstSeen, Event: "deviceTrusted", Detail: trustedDevice.Name},
+			user_data_model.AuditEntryExport{Time: trustedDevice.LastSeen, Event: "deviceLastSeen", Detail: fmt.Sprintf("%v from %v", trustedDevice.Name, trustedDevice.Ip)},
+		)
+	}
+
+	credentials, err := uds.RepositoriesGroup.WebauthnRepository.GetCredentials(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, credential := range credentials {
+		entries = append(entries, user_data_model.AuditEntryExport{Time: credential.Created, Event: "passkeyAdded", Detail: credential.Name})
+		if credential.LastUsed != nil {
+			entries = append(entries, user_data_model.AuditEntryExport{Time: *credential.LastUsed, Event: "passkeyUsed", Detail: credential.Name})
+		}
+	}
+
+	secureCodes, err := uds.exportSecureCodes(user)
+	if err != nil {
+		return nil, err
+	}
+	for _, secureCode := range secureCodes.([]user_data_model.SecureCodeExport) {
+		entries = append(entries, user_data_model.AuditEntryExport{Time: secureCode.Created, Event: "codeIssued", Detail: secureCode.Type})
+	}
+
+	if userDeletion, err := uds.GetDeletion(user.Id); err == nil {
+		entries = append(entries, user_data_model.AuditEntryExport{Time: userDeletion.Requested, Event: "deletionRequested"})
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Time.Before(entries[j].Time)
+	})
+
+	return entries, nil
+}
+
+func writeJsonFile(archive *zip.Writer, fileName string, data interface{}) error {
+	raw, err := json.MarshalIndent(data, "", "  ")
+	if err != nil {
+		log.Errorf("Error marshaling %v for user export: %s\n", fileName, err.Error())
+		return err
+	}
+
+	w, err := archive.Create(fileName)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(raw)
+	return err
+}
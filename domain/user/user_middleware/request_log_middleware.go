@@ -0,0 +1,32 @@
+package user_middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/utility/api_utility"
+	"github.com/gocms-io/gocms/utility/log"
+	"time"
+)
+
+const LOG_SUBSYSTEM_HTTP = "http"
+
+// RequestLog replaces the gin logger so access lines share the structured output and carry the request id
+func RequestLog() gin.HandlerFunc {
+	return requestLogMiddleware
+}
+
+func requestLogMiddleware(c *gin.Context) {
+	start := time.Now()
+
+	c.Next()
+
+	logger := api_utility.WithRequestContext(log.Subsystem(LOG_SUBSYSTEM_HTTP), c).With(
+		"status", c.Writer.Status(),
+		"latency", time.Since(start).String(),
+		"ip", c.ClientIP(),
+	)
+	if len(c.Errors) > 0 {
+		logger.Errorf("%v %v %v", c.Request.Method, c.Request.URL.Path, c.Errors.String())
+		return
+	}
+	logger.Infof("%v %v %d", c.Request.Method, c.Request.URL.Path, c.Writer.Status())
+}
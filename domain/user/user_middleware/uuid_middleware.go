@@ -2,8 +2,9 @@ package user_middleware
 
 import (
 	"github.com/gin-gonic/gin"
-	"github.com/nu7hatch/gouuid"
+	"github.com/gocms-io/gocms/context/consts"
 	"github.com/gocms-io/gocms/utility/log"
+	"github.com/nu7hatch/gouuid"
 )
 
 func UUID() gin.HandlerFunc {
@@ -11,8 +12,10 @@ func UUID() gin.HandlerFunc {
 	return uuidMiddleware
 }
 
+// uuidMiddleware ids the request for log correlation and returns the id so clients can report it
 func uuidMiddleware(c *gin.Context) {
 	id, _ := uuid.NewV4()
-	c.Set("uuid", id)
+	c.Set(consts.REQUEST_ID_KEY_FOR_GIN_CONTEXT, id.String())
+	c.Header(consts.GOCMS_HEADER_REQUEST_ID, id.String())
 	c.Next()
 }
@@ -13,21 +13,22 @@ var GENDER_FEMALE int64 = 2
 
 // TODO remove user json binding and create a user input.
 type User struct {
-	Id           int64  `json:"id" db:"id"`
-	FullName     string `json:"fullName" db:"fullName"`
-	Email        string `json:"email" db:"email"`
-	Verified     bool   `json:"isVerified" db:"isVerified"`
-	AltEmails    []email_model.Email
-	Password     string    `json:"password" db:"password"`
-	Gender       int64     `json:"gender" db:"gender"`
-	Photo        string    `json:"photo" db:"photo"`
-	MinAge       int64     `json:"minAge" db:"minAge"`
-	MaxAge       int64     `json:"maxAge" db:"maxAge"`
-	Created      time.Time `json:"created" db:"created"`
-	Enabled      bool      `json:"enabled" db:"enabled"`
-	LastModified time.Time `json:"lastModified" db:"lastModified"`
-	Permissions  []*permission_model.Permission
-	Groups       []*group_model.Group
+	Id              int64  `json:"id" db:"id"`
+	FullName        string `json:"fullName" db:"fullName"`
+	Email           string `json:"email" db:"email"`
+	Verified        bool   `json:"isVerified" db:"isVerified"`
+	AltEmails       []email_model.Email
+	Password        string     `json:"password" db:"password"`
+	PasswordChanged *time.Time `json:"passwordChanged" db:"passwordChanged"`
+	Gender          int64      `json:"gender" db:"gender"`
+	Photo           string     `json:"photo" db:"photo"`
+	MinAge          int64      `json:"minAge" db:"minAge"`
+	MaxAge          int64      `json:"maxAge" db:"maxAge"`
+	Created         time.Time  `json:"created" db:"created"`
+	Enabled         bool       `json:"enabled" db:"enabled"`
+	LastModified    time.Time  `json:"lastModified" db:"lastModified"`
+	Permissions     []*permission_model.Permission
+	Groups          []*group_model.Group
 }
 
 /**
@@ -38,24 +39,28 @@ type User struct {
 * @apiSuccess (Response) {number} gender 1=male, 2=female
 * @apiSuccess (Response) {string} photo url string
 * @apiSuccess (Response) {string} lastModified
+* @apiSuccess (Response) {object} profile Custom profile fields keyed by field name.
  */
 type UserDisplay struct {
-	Id           int64     `json:"id,omitempty"`
-	FullName     string    `json:"fullName,omitempty"`
-	Email        string    `json:"email,omitempty"`
-	Gender       int64     `json:"gender,omitempty"`
-	Photo        string    `json:"photo,string,omitempty"`
-	LastModified time.Time `json:"lastModified,omitempty"`
+	Id           int64                  `json:"id,omitempty"`
+	FullName     string                 `json:"fullName,omitempty"`
+	Email        string                 `json:"email,omitempty"`
+	Gender       int64                  `json:"gender,omitempty"`
+	Photo        string                 `json:"photo,string,omitempty"`
+	LastModified time.Time              `json:"lastModified,omitempty"`
+	Profile      map[string]interface{} `json:"profile,omitempty"`
 }
 
 /**
 * @apiDefine UserUpdateInput
 * @apiParam (Request) {string} fullName
 * @apiParam (Request) {number} gender 1=male, 2=female
+* @apiParam (Request) {object} [profile] Custom profile fields keyed by field name. Set a field to null to remove it.
  */
 type UserUpdateInput struct {
-	FullName string `json:"fullName,omitempty"`
-	Gender   int64  `json:"gender"`
+	FullName string                 `json:"fullName,omitempty"`
+	Gender   int64                  `json:"gender"`
+	Profile  map[string]interface{} `json:"profile,omitempty"`
 }
 
 /**
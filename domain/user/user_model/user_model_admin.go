@@ -16,19 +16,33 @@ import (
 * @apiSuccess (Response) {number} maxAge
 * @apiSuccess (Response) {string} created
 * @apiSuccess (Response) {string} lastModified
+* @apiSuccess (Response) {object} profile All custom profile fields keyed by field name.
  */
 type UserAdminDisplay struct {
-	Id           int64     `json:"id,omitempty"`
-	FullName     string    `json:"fullName,omitempty"`
-	Email        string    `json:"email,omitempty"`
-	Verified     bool      `json:"verified,omitempty"`
-	Gender       int64     `json:"gender,omitempty"`
-	Photo        string    `json:"photo,string,omitempty"`
-	Enabled      bool      `json:"enabled,omitempty"`
-	MinAge       int64     `json:"minAge,omitempty"`
-	MaxAge       int64     `json:"maxAge,omitempty"`
-	Created      time.Time `json:"created,omitempty"`
-	LastModified time.Time `json:"lastModified,omitempty"`
+	Id           int64                  `json:"id,omitempty"`
+	FullName     string                 `json:"fullName,omitempty"`
+	Email        string                 `json:"email,omitempty"`
+	Verified     bool                   `json:"verified,omitempty"`
+	Gender       int64                  `json:"gender,omitempty"`
+	Photo        string                 `json:"photo,string,omitempty"`
+	Enabled      bool                   `json:"enabled,omitempty"`
+	MinAge       int64                  `json:"minAge,omitempty"`
+	MaxAge       int64                  `json:"maxAge,omitempty"`
+	Created      time.Time              `json:"created,omitempty"`
+	LastModified time.Time              `json:"lastModified,omitempty"`
+	Profile      map[string]interface{} `json:"profile,omitempty"`
+}
+
+/**
+* @apiDefine UserInviteInput
+* @apiParam (Request) {string} email
+* @apiParam (Request) {string} [fullName]
+* @apiParam (Request) {string[]} [groups] Names of the groups the user will be added to.
+ */
+type UserInviteInput struct {
+	Email    string   `json:"email" binding:"required"`
+	FullName string   `json:"fullName,omitempty"`
+	Groups   []string `json:"groups,omitempty"`
 }
 
 // helper function to get userAdminDisplay from user object
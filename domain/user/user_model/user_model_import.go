@@ -0,0 +1,63 @@
+package user_model
+
+const (
+	IMPORT_FORMAT_CSV  = "csv"
+	IMPORT_FORMAT_JSON = "json"
+
+	// IMPORT_NOTIFY_NONE users are created without being contacted
+	IMPORT_NOTIFY_NONE = "none"
+	// IMPORT_NOTIFY_INVITE users are sent an invitation to set their password. They are enabled once it is accepted.
+	IMPORT_NOTIFY_INVITE = "invite"
+	// IMPORT_NOTIFY_ACTIVATE users are sent an email activation link
+	IMPORT_NOTIFY_ACTIVATE = "activate"
+)
+
+// UserImportCsvHeader column order used for csv exports. Imports accept the columns in any order.
+var UserImportCsvHeader = []string{"email", "fullName", "enabled", "verified", "groups", "altEmails"}
+
+/**
+* @apiDefine UserImportRecord
+* @apiParam (Request) {string} email Primary email.
+* @apiParam (Request) {string} [fullName]
+* @apiParam (Request) {boolean} [enabled] Default=true
+* @apiParam (Request) {boolean} [verified] Default=false
+* @apiParam (Request) {string[]} [groups] Group names. In csv separate names with ;
+* @apiParam (Request) {string[]} [altEmails] Alternative emails. In csv separate emails with ;
+ */
+type UserImportRecord struct {
+	Email     string   `json:"email"`
+	FullName  string   `json:"fullName"`
+	Enabled   *bool    `json:"enabled,omitempty"`
+	Verified  bool     `json:"verified"`
+	Groups    []string `json:"groups,omitempty"`
+	AltEmails []string `json:"altEmails,omitempty"`
+	// ParseErrors are problems found reading the row. They are reported with the row's other errors.
+	ParseErrors []string `json:"-"`
+}
+
+type UserImportOptions struct {
+	DryRun bool
+	Notify string
+}
+
+/**
+* @apiDefine UserImportResult
+* @apiSuccess (Response) {boolean} dryRun
+* @apiSuccess (Response) {number} created
+* @apiSuccess (Response) {number} failed
+* @apiSuccess (Response) {object[]} rows Result for each row. Rows are numbered from 1 not counting a csv header.
+ */
+type UserImportResult struct {
+	DryRun  bool                   `json:"dryRun"`
+	Created int64                  `json:"created"`
+	Failed  int64                  `json:"failed"`
+	Rows    []*UserImportRowResult `json:"rows"`
+}
+
+type UserImportRowResult struct {
+	Row    int64    `json:"row"`
+	Email  string   `json:"email"`
+	Ok     bool     `json:"ok"`
+	UserId int64    `json:"userId,omitempty"`
+	Errors []string `json:"errors,omitempty"`
+}
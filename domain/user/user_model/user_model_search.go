@@ -0,0 +1,104 @@
+package user_model
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"time"
+)
+
+const (
+	USER_SEARCH_DEFAULT_LIMIT = 50
+	USER_SEARCH_MAX_LIMIT     = 500
+
+	BULK_ACTION_ENABLE       = "enable"
+	BULK_ACTION_DISABLE      = "disable"
+	BULK_ACTION_ADD_TO_GROUP = "addToGroup"
+	BULK_ACTION_DELETE       = "delete"
+
+	// keep in step with the max on UserBulkInput.UserIds
+	BULK_ACTION_MAX_USERS = 100
+)
+
+// UserSearchSortFields maps sort names accepted by the api to columns.
+var UserSearchSortFields = map[string]string{
+	"id":           "gocms_users.id",
+	"fullName":     "gocms_users.fullName",
+	"email":        "gocms_emails.email",
+	"created":      "gocms_users.created",
+	"lastModified": "gocms_users.lastModified",
+}
+
+type UserSearchQuery struct {
+	Query         string
+	Enabled       *bool
+	Verified      *bool
+	Group         string
+	CreatedAfter  *time.Time
+	CreatedBefore *time.Time
+	Sort          string
+	Descending    bool
+	Limit         int64
+	Cursor        *UserSearchCursor
+}
+
+// UserSearchCursor is the position of the last user returned. It is passed to clients as an opaque string.
+type UserSearchCursor struct {
+	Sort       string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	Id         int64  `json:"i"`
+}
+
+func (cursor *UserSearchCursor) Encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func DecodeUserSearchCursor(s string) (*UserSearchCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var cursor UserSearchCursor
+	err = json.Unmarshal(data, &cursor)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+/**
+* @apiDefine UserSearchResult
+* @apiSuccess (Response) {object[]} users See UserAdminDisplay
+* @apiSuccess (Response) {number} total Number of users matching the filters.
+* @apiSuccess (Response) {string} [nextCursor] Pass as cursor to get the next page. Missing on the last page.
+ */
+type UserSearchResult struct {
+	Users      []UserAdminDisplay `json:"users"`
+	Total      int64              `json:"total"`
+	NextCursor string             `json:"nextCursor,omitempty"`
+}
+
+/**
+* @apiDefine UserBulkInput
+* @apiParam (Request) {string} action enable, disable, addToGroup, or delete
+* @apiParam (Request) {number[]} userIds At most 100.
+* @apiParam (Request) {string} [group] Group name. Required for addToGroup.
+ */
+type UserBulkInput struct {
+	Action  string  `json:"action" binding:"required"`
+	UserIds []int64 `json:"userIds" binding:"required,max=100"`
+	Group   string  `json:"group,omitempty"`
+}
+
+/**
+* @apiDefine UserBulkResult
+* @apiSuccess (Response) {number} id
+* @apiSuccess (Response) {boolean} ok
+* @apiSuccess (Response) {string} [error]
+ */
+type UserBulkResult struct {
+	Id    int64  `json:"id"`
+	Ok    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
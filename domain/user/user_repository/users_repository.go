@@ -2,10 +2,12 @@ package user_repository
 
 import (
 	"database/sql"
+	"fmt"
 	"github.com/gocms-io/gocms/domain/user/user_model"
 	"github.com/gocms-io/gocms/utility/errors"
 	"github.com/gocms-io/gocms/utility/log"
 	"github.com/jmoiron/sqlx"
+	"strings"
 	"time"
 )
 
@@ -13,6 +15,7 @@ type IUserRepository interface {
 	Get(int64) (*user_model.User, error)
 	GetByEmail(string) (*user_model.User, error)
 	GetAll() (*[]user_model.User, error)
+	Search(*user_model.UserSearchQuery) ([]user_model.User, int64, error)
 	Add(*user_model.User) error
 	Update(int64, *user_model.User) error
 	UpdatePassword(int64, string) error
@@ -97,6 +100,99 @@ func (ur *UserRepository) GetAll() (*[]user_model.User, error) {
 	return &users, nil
 }
 
+// search users. returns up to limit users after the cursor and the total number of users matching the filters.
+func (ur *UserRepository) Search(query *user_model.UserSearchQuery) ([]user_model.User, int64, error) {
+	var where []string
+	var args []interface{}
+
+	if query.Query != "" {
+		like := "%" + likeEscaper.Replace(query.Query) + "%"
+		where = append(where, `(gocms_users.fullName LIKE ? OR EXISTS (
+		SELECT 1 FROM gocms_emails AS e WHERE e.userId=gocms_users.id AND e.email LIKE ?
+		))`)
+		args = append(args, like, like)
+	}
+	if query.Enabled != nil {
+		where = append(where, "gocms_users.enabled=?")
+		args = append(args, *query.Enabled)
+	}
+	if query.Verified != nil {
+		where = append(where, "gocms_emails.isVerified=?")
+		args = append(args, *query.Verified)
+	}
+	if query.Group != "" {
+		where = append(where, `EXISTS (
+		SELECT 1 FROM gocms_users_to_groups AS ug INNER JOIN gocms_groups AS g ON g.id=ug.groupId
+		WHERE ug.userId=gocms_users.id AND g.name=?
+		)`)
+		args = append(args, query.Group)
+	}
+	if query.CreatedAfter != nil {
+		where = append(where, "gocms_users.created>=?")
+		args = append(args, *query.CreatedAfter)
+	}
+	if query.CreatedBefore != nil {
+		where = append(where, "gocms_users.created<?")
+		args = append(args, *query.CreatedBefore)
+	}
+
+	from := `
+	FROM gocms_users
+	INNER JOIN gocms_emails
+	ON gocms_users.id=gocms_emails.userId AND gocms_emails.isPrimary=1
+	`
+
+	// total ignores the cursor
+	var total int64
+	countSql := "SELECT COUNT(*)" + from + whereClause(where)
+	err := ur.database.Get(&total, countSql, args...)
+	if err != nil {
+		log.Errorf("Error counting users in database: %s", err.Error())
+		return nil, 0, err
+	}
+
+	sortColumn := user_model.UserSearchSortFields[query.Sort]
+	direction, comparison := "ASC", ">"
+	if query.Descending {
+		direction, comparison = "DESC", "<"
+	}
+
+	// keyset pagination on the sort column with id as the tie breaker
+	if query.Cursor != nil {
+		if sortColumn == "gocms_users.id" {
+			where = append(where, fmt.Sprintf("gocms_users.id%v?", comparison))
+			args = append(args, query.Cursor.Id)
+		} else {
+			where = append(where, fmt.Sprintf("(%v%v? OR (%v=? AND gocms_users.id%v?))", sortColumn, comparison, sortColumn, comparison))
+			args = append(args, query.Cursor.Value, query.Cursor.Value, query.Cursor.Id)
+		}
+	}
+
+	orderBy := fmt.Sprintf(" ORDER BY %v %v", sortColumn, direction)
+	if sortColumn != "gocms_users.id" {
+		orderBy += fmt.Sprintf(", gocms_users.id %v", direction)
+	}
+
+	var users []user_model.User
+	selectSql := "SELECT gocms_users.*, gocms_emails.email, gocms_emails.isVerified" + from + whereClause(where) + orderBy + " LIMIT ?"
+	err = ur.database.Select(&users, selectSql, append(args, query.Limit)...)
+	if err != nil {
+		log.Errorf("Error searching users in database: %s", err.Error())
+		return nil, 0, err
+	}
+
+	return users, total, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+func whereClause(where []string) string {
+	if len(where) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(where, " AND ")
+}
+
 func (ur *UserRepository) Add(user *user_model.User) error {
 
 	// check if user exists
@@ -108,7 +204,7 @@ func (ur *UserRepository) Add(user *user_model.User) error {
 
 	// insert user
 	result, err := ur.database.NamedExec(`
-	INSERT INTO gocms_users (fullName, gender, photo, minAge, maxAge, password, enabled, created) VALUES (:fullName, :gender, :photo, :minAge, :maxAge, :password, :enabled, :created)
+	INSERT INTO gocms_users (fullName, gender, photo, minAge, maxAge, password, passwordChanged, enabled, created) VALUES (:fullName, :gender, :photo, :minAge, :maxAge, :password, :passwordChanged, :enabled, :created)
 	`, user)
 	if err != nil {
 		log.Errorf("Error adding user to db: %s", err.Error())
@@ -149,12 +245,14 @@ func (ur *UserRepository) SetEnabled(id int64, enabled bool) error {
 
 func (ur *UserRepository) UpdatePassword(id int64, hash string) error {
 	// insert row
+	now := time.Now()
 	user := user_model.User{
-		Id:       id,
-		Password: hash,
+		Id:              id,
+		Password:        hash,
+		PasswordChanged: &now,
 	}
 	_, err := ur.database.NamedExec(`
-	UPDATE gocms_users SET password=:password WHERE id=:id
+	UPDATE gocms_users SET password=:password, passwordChanged=:passwordChanged WHERE id=:id
 	`, user)
 	if err != nil {
 		log.Errorf("Error getting updating password for user in database: %s", err.Error())
@@ -0,0 +1,246 @@
+package user_service
+
+import (
+	"fmt"
+	"github.com/gocms-io/gocms/domain/email/email_model"
+	"github.com/gocms-io/gocms/domain/user/user_model"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"net/mail"
+	"strings"
+)
+
+// Import validates every record and, unless it is a dry run, creates a user for each valid record.
+// Invalid records are reported and skipped.
+func (us *UserService) Import(records []user_model.UserImportRecord, options *user_model.UserImportOptions) (*user_model.UserImportResult, error) {
+
+	switch options.Notify {
+	case "":
+		options.Notify = user_model.IMPORT_NOTIFY_NONE
+	case user_model.IMPORT_NOTIFY_NONE, user_model.IMPORT_NOTIFY_INVITE, user_model.IMPORT_NOTIFY_ACTIVATE:
+	default:
+		return nil, errors.NewToUser(fmt.Sprintf("Unknown notify option %v.", options.Notify))
+	}
+
+	groups, err := us.RepositoriesGroup.GroupsRepository.GetAll()
+	if err != nil {
+		return nil, err
+	}
+	groupNames := make(map[string]bool)
+	for _, group := range *groups {
+		groupNames[group.Name] = true
+	}
+
+	result := &user_model.UserImportResult{
+		DryRun: options.DryRun,
+		Rows:   make([]*user_model.UserImportRowResult, len(records)),
+	}
+	seenEmails := make(map[string]int)
+
+	for i := range records {
+		record := &records[i]
+		row := &user_model.UserImportRowResult{
+			Row:   int64(i + 1),
+			Email: record.Email,
+		}
+		result.Rows[i] = row
+
+		row.Errors = us.validateImportRecord(record, groupNames, seenEmails, i+1)
+		if len(row.Errors) == 0 && !options.DryRun {
+			userId, err := us.importRecord(record, options)
+			if err != nil {
+				log.Errorf("Error importing user %v: %s\n", record.Email, err.Error())
+				row.Errors = append(row.Errors, "User could not be created.")
+			}
+			row.UserId = userId
+		}
+
+		row.Ok = len(row.Errors) == 0
+		if row.Ok {
+			result.Created++
+		} else {
+			result.Failed++
+		}
+	}
+
+	// nothing is created in a dry run
+	if options.DryRun {
+		result.Created = 0
+	}
+
+	return result, nil
+}
+
+func (us *UserService) validateImportRecord(record *user_model.UserImportRecord, groupNames map[string]bool, seenEmails map[string]int, rowNumber int) []string {
+	rowErrors := append([]string{}, record.ParseErrors...)
+
+	record.Email = strings.TrimSpace(record.Email)
+	record.FullName = strings.TrimSpace(record.FullName)
+
+	emails := append([]string{record.Email}, record.AltEmails...)
+	for i, email := range emails {
+		email = strings.TrimSpace(email)
+		if i > 0 {
+			record.AltEmails[i-1] = email
+		}
+
+		if email == "" {
+			if i == 0 {
+				rowErrors = append(rowErrors, "Email is required.")
+			}
+			continue
+		}
+		if _, err := mail.ParseAddress(email); err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("%v is not a valid email address.", email))
+			continue
+		}
+		key := strings.ToLower(email)
+		if firstRow, ok := seenEmails[key]; ok {
+			rowErrors = append(rowErrors, fmt.Sprintf("%v is also used on row %v.", email, firstRow))
+			continue
+		}
+		seenEmails[key] = rowNumber
+		if existing, _ := us.RepositoriesGroup.EmailRepository.GetByAddress(email); existing != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("%v already belongs to a user.", email))
+		}
+	}
+
+	for _, groupName := range record.Groups {
+		if !groupNames[groupName] {
+			rowErrors = append(rowErrors, fmt.Sprintf("Group %v doesn't exist.", groupName))
+		}
+	}
+
+	return rowErrors
+}
+
+func (us *UserService) importRecord(record *user_model.UserImportRecord, options *user_model.UserImportOptions) (int64, error) {
+	user := &user_model.User{
+		Email:    record.Email,
+		FullName: record.FullName,
+		Enabled:  record.Enabled == nil || *record.Enabled,
+	}
+
+	// invited users are enabled when they accept
+	if options.Notify == user_model.IMPORT_NOTIFY_INVITE {
+		user.Enabled = false
+	}
+
+	err := us.Add(user)
+	if err != nil {
+		return 0, err
+	}
+
+	// remove the partially imported user on failure
+	err = us.completeImportRecord(user, record, options)
+	if err != nil {
+		us.RepositoriesGroup.UsersRepository.Delete(user.Id)
+		return 0, err
+	}
+
+	return user.Id, nil
+}
+
+func (us *UserService) completeImportRecord(user *user_model.User, record *user_model.UserImportRecord, options *user_model.UserImportOptions) error {
+	for _, groupName := range record.Groups {
+		err := us.RepositoriesGroup.GroupsRepository.AddUserToGroupByName(user.Id, groupName)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, altEmail := range record.AltEmails {
+		if altEmail == "" {
+			continue
+		}
+		err := us.RepositoriesGroup.EmailRepository.Add(&email_model.Email{
+			Email:      altEmail,
+			UserId:     user.Id,
+			IsVerified: record.Verified,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	// activation verifies the email itself
+	if record.Verified && options.Notify != user_model.IMPORT_NOTIFY_ACTIVATE {
+		err := us.EmailService.SetVerified(user.Email)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch options.Notify {
+	case user_model.IMPORT_NOTIFY_INVITE:
+		return us.sendInvitation(user)
+	case user_model.IMPORT_NOTIFY_ACTIVATE:
+		return us.EmailService.SendEmailActivationCode(user.Email)
+	}
+
+	return nil
+}
+
+// Export calls write with a record for every user in id order.
+func (us *UserService) Export(write func(*user_model.UserImportRecord) error) error {
+	query := &user_model.UserSearchQuery{
+		Sort:  "id",
+		Limit: user_model.USER_SEARCH_MAX_LIMIT,
+	}
+
+	for {
+		page, err := us.Search(query)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range page.Users {
+			record, err := us.exportRecord(&user)
+			if err != nil {
+				return err
+			}
+			err = write(record)
+			if err != nil {
+				return err
+			}
+		}
+
+		if page.NextCursor == "" {
+			return nil
+		}
+		query.Cursor, err = user_model.DecodeUserSearchCursor(page.NextCursor)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (us *UserService) exportRecord(user *user_model.UserAdminDisplay) (*user_model.UserImportRecord, error) {
+	enabled := user.Enabled
+	record := &user_model.UserImportRecord{
+		Email:    user.Email,
+		FullName: user.FullName,
+		Enabled:  &enabled,
+		Verified: user.Verified,
+	}
+
+	emails, err := us.RepositoriesGroup.EmailRepository.GetByUserId(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, email := range emails {
+		if !email.IsPrimary {
+			record.AltEmails = append(record.AltEmails, email.Email)
+		}
+	}
+
+	groups, err := us.RepositoriesGroup.GroupsRepository.GetUserGroups(user.Id)
+	if err != nil {
+		return nil, err
+	}
+	for _, group := range groups {
+		record.Groups = append(record.Groups, group.Name)
+	}
+
+	return record, nil
+}
@@ -1,13 +1,23 @@
 package user_service
 
 import (
+	"database/sql"
+	"fmt"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/secure_code/security_code_model"
+	"github.com/gocms-io/gocms/utility/log"
+	"net/url"
+	"time"
 	"github.com/gocms-io/gocms/utility"
 	"github.com/gocms-io/gocms/utility/errors"
 	"github.com/gocms-io/gocms/domain/user/user_model"
 	"github.com/gocms-io/gocms/init/repository"
 	"github.com/gocms-io/gocms/domain/mail/mail_service"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_services"
 	"github.com/gocms-io/gocms/domain/email/email_model"
+	"github.com/gocms-io/gocms/domain/email/email_service"
 	"github.com/gocms-io/gocms/domain/acl/authentication/authentication_service"
+	"github.com/gocms-io/gocms/domain/acl/password_history/password_history_model"
 )
 
 type IUserService interface {
@@ -15,22 +25,37 @@ type IUserService interface {
 	Get(int64) (*user_model.User, error)
 	GetByEmail(string) (*user_model.User, error)
 	GetAll() (*[]user_model.User, error)
+	Search(*user_model.UserSearchQuery) (*user_model.UserSearchResult, error)
+	BulkAction(input *user_model.UserBulkInput, actingUserId int64) ([]user_model.UserBulkResult, error)
+	Import(records []user_model.UserImportRecord, options *user_model.UserImportOptions) (*user_model.UserImportResult, error)
+	Export(func(*user_model.UserImportRecord) error) error
 	Delete(int64) error
 	Update(int64, *user_model.User) error
 	UpdatePassword(int64, string) error
+	ResetPassword(email string, code string, password string) error
 	SetEnabled(int64, bool) error
+	Invite(*user_model.User, []string) error
+	ResendInvitation(int64) (*user_model.User, error)
+	AcceptInvitation(email string, code string, password string) (*user_model.User, error)
 }
 
 type UserService struct {
 	AuthService       authentication_service.IAuthService
+	EmailService      email_service.IEmailService
 	MailService       mail_service.IMailService
+	PluginsService    plugin_services.IPluginsService
 	RepositoriesGroup *repository.RepositoriesGroup
 }
 
-func DefaultUserService(rg *repository.RepositoriesGroup, authService *authentication_service.AuthService, mailService *mail_service.MailService) *UserService {
+// ErrInvalidCode is returned for any problem with a reset or invitation code so callers can't tell which part was wrong.
+var ErrInvalidCode error = errors.New("Code is not valid or has expired.")
+
+func DefaultUserService(rg *repository.RepositoriesGroup, authService *authentication_service.AuthService, emailService *email_service.EmailService, mailService *mail_service.MailService, pluginsService plugin_services.IPluginsService) *UserService {
 	userService := &UserService{
 		AuthService:       authService,
+		EmailService:      emailService,
 		MailService:       mailService,
+		PluginsService:    pluginsService,
 		RepositoriesGroup: rg,
 	}
 
@@ -69,6 +94,121 @@ func (us *UserService) GetAll() (*[]user_model.User, error) {
 	return users, nil
 }
 
+func (us *UserService) Search(query *user_model.UserSearchQuery) (*user_model.UserSearchResult, error) {
+
+	// defaults
+	if query.Sort == "" {
+		query.Sort = "id"
+	}
+	if _, ok := user_model.UserSearchSortFields[query.Sort]; !ok {
+		return nil, errors.NewToUser(fmt.Sprintf("Can't sort by %v.", query.Sort))
+	}
+	if query.Limit <= 0 {
+		query.Limit = user_model.USER_SEARCH_DEFAULT_LIMIT
+	}
+	if query.Limit > user_model.USER_SEARCH_MAX_LIMIT {
+		query.Limit = user_model.USER_SEARCH_MAX_LIMIT
+	}
+	if query.Cursor != nil && (query.Cursor.Sort != query.Sort || query.Cursor.Descending != query.Descending) {
+		return nil, errors.NewToUser("Cursor doesn't match the requested sort.")
+	}
+
+	// get one extra to know if there is another page
+	limit := query.Limit
+	query.Limit = limit + 1
+	users, total, err := us.RepositoriesGroup.UsersRepository.Search(query)
+	query.Limit = limit
+	if err != nil {
+		return nil, err
+	}
+
+	result := &user_model.UserSearchResult{
+		Users: make([]user_model.UserAdminDisplay, 0, len(users)),
+		Total: total,
+	}
+
+	if int64(len(users)) > limit {
+		users = users[:limit]
+		last := users[len(users)-1]
+		cursor := user_model.UserSearchCursor{
+			Sort:       query.Sort,
+			Descending: query.Descending,
+			Id:         last.Id,
+		}
+		switch query.Sort {
+		case "fullName":
+			cursor.Value = last.FullName
+		case "email":
+			cursor.Value = last.Email
+		case "created":
+			cursor.Value = last.Created.UTC().Format("2006-01-02 15:04:05")
+		case "lastModified":
+			cursor.Value = last.LastModified.UTC().Format("2006-01-02 15:04:05")
+		}
+		result.NextCursor = cursor.Encode()
+	}
+
+	for _, user := range users {
+		result.Users = append(result.Users, *user.GetUserAdminDisplay())
+	}
+
+	return result, nil
+}
+
+// BulkAction applies the action to each user. Failures are reported per user and don't stop the others.
+func (us *UserService) BulkAction(input *user_model.UserBulkInput, actingUserId int64) ([]user_model.UserBulkResult, error) {
+
+	switch input.Action {
+	case user_model.BULK_ACTION_ENABLE, user_model.BULK_ACTION_DISABLE, user_model.BULK_ACTION_DELETE:
+	case user_model.BULK_ACTION_ADD_TO_GROUP:
+		if input.Group == "" {
+			return nil, errors.NewToUser("Group is required to add users to a group.")
+		}
+	default:
+		return nil, errors.NewToUser(fmt.Sprintf("Unknown action %v.", input.Action))
+	}
+	if len(input.UserIds) > user_model.BULK_ACTION_MAX_USERS {
+		return nil, errors.NewToUser(fmt.Sprintf("At most %v users can be updated at once.", user_model.BULK_ACTION_MAX_USERS))
+	}
+
+	results := make([]user_model.UserBulkResult, len(input.UserIds))
+	for i, userId := range input.UserIds {
+		results[i] = user_model.UserBulkResult{Id: userId}
+
+		// admins can't lock themselves out
+		if userId == actingUserId && (input.Action == user_model.BULK_ACTION_DISABLE || input.Action == user_model.BULK_ACTION_DELETE) {
+			results[i].Error = "You can't disable or delete your own account."
+			continue
+		}
+
+		_, err := us.RepositoriesGroup.UsersRepository.Get(userId)
+		if err == nil {
+			switch input.Action {
+			case user_model.BULK_ACTION_ENABLE:
+				err = us.SetEnabled(userId, true)
+			case user_model.BULK_ACTION_DISABLE:
+				err = us.SetEnabled(userId, false)
+			case user_model.BULK_ACTION_ADD_TO_GROUP:
+				err = us.RepositoriesGroup.GroupsRepository.AddUserToGroupByName(userId, input.Group)
+			case user_model.BULK_ACTION_DELETE:
+				err = us.Delete(userId)
+			}
+		}
+
+		switch {
+		case err == nil:
+			results[i].Ok = true
+		case err == sql.ErrNoRows:
+			results[i].Error = errors.ApiError_UserDoesntExist
+		default:
+			log.Errorf("Error applying bulk action %v to user %v: %s\n", input.Action, userId, err.Error())
+			results[i].Error = errors.ApiError_Server
+		}
+	}
+
+	return results, nil
+}
+
 func (us *UserService) Add(user *user_model.User) error {
 
 	// email must exist and not be null
@@ -76,14 +216,18 @@ func (us *UserService) Add(user *user_model.User) error {
 		return errors.New("A valid email address was not provided. The user cannot be created.")
 	}
 
-	// hash password
+	// hash password. a random password was never chosen so it doesn't expire.
 	if user.Password == "" {
 		user.Password, _ = utility.GenerateRandomString(32)
+		user.PasswordChanged = nil
 	} else {
-		// password complexity
-		if !us.AuthService.PasswordIsComplex(user.Password) {
-			return errors.NewToUser("Password is not complex enough.")
+		// password policy
+		err := us.AuthService.ValidatePassword(user.Password, user)
+		if err != nil {
+			return err
 		}
+		now := time.Now()
+		user.PasswordChanged = &now
 	}
 
 	hashPassword, err := us.AuthService.HashPassword(user.Password)
@@ -112,12 +256,26 @@ func (us *UserService) Add(user *user_model.User) error {
 	return nil
 }
 
+// Delete permanently deletes a user. Plugins are asked to delete their data first and the user is kept if any of them fail.
 func (us *UserService) Delete(id int64) error {
 
-	err := us.RepositoriesGroup.UsersRepository.Delete(id)
+	user, err := us.RepositoriesGroup.UsersRepository.Get(id)
 	if err != nil {
 		return err
 	}
+
+	err = us.PluginsService.DeleteUserData(user)
+	if err != nil {
+		return err
+	}
+
+	// emails, codes, groups, devices and the deletion record cascade
+	err = us.RepositoriesGroup.UsersRepository.Delete(id)
+	if err != nil {
+		return err
+	}
+	log.Infof("User %v permanently deleted\n", id)
+
 	return nil
 }
 
@@ -129,13 +287,54 @@ func (us *UserService) Update(id int64, userForUpdate *user_model.User) error {
 
 	return nil
 }
+// UpdatePassword checks the password policy and sets the password.
 func (us *UserService) UpdatePassword(id int64, password string) error {
 
-	//check complexity
-	if !us.AuthService.PasswordIsComplex(password) {
-		return errors.NewToUser("Password is not complex enough.")
+	user, err := us.RepositoriesGroup.UsersRepository.Get(id)
+	if err != nil {
+		return err
+	}
+
+	// check policy
+	err = us.AuthService.ValidatePassword(password, user)
+	if err != nil {
+		return err
+	}
+
+	return us.setPassword(user, password)
+}
+
+// ResetPassword sets the password with a reset code. The code is checked before the password policy so the policy can't be probed
+// without one, and it is only used up once the password is accepted. Every code problem returns ErrInvalidCode.
+func (us *UserService) ResetPassword(email string, code string, password string) error {
+
+	user, err := us.RepositoriesGroup.UsersRepository.GetByEmail(email)
+	if err != nil {
+		return ErrInvalidCode
 	}
 
+	if !us.AuthService.CheckPasswordResetCode(user.Id, code) {
+		return ErrInvalidCode
+	}
+
+	// check policy
+	err = us.AuthService.ValidatePassword(password, user)
+	if err != nil {
+		return err
+	}
+
+	// single use
+	if !us.AuthService.VerifyPasswordResetCode(user.Id, code) {
+		return ErrInvalidCode
+	}
+
+	return us.setPassword(user, password)
+}
+
+// setPassword hashes and saves a password that has already passed the policy.
+func (us *UserService) setPassword(user *user_model.User, password string) error {
+	id := user.Id
+
 	// make hash
 	newHash, err := us.AuthService.HashPassword(password)
 	if err != nil {
@@ -148,9 +347,210 @@ func (us *UserService) UpdatePassword(id int64, password string) error {
 		return err
 	}
 
+	// remember the old password so it can't be reused. the current password is checked separately.
+	passwordHistory := context.Config.DbVars.PasswordHistory
+	if passwordHistory > 1 {
+		err = us.RepositoriesGroup.PasswordHistoryRepository.Add(&password_history_model.PasswordHistory{
+			UserId:   id,
+			Password: user.Password,
+		})
+		if err == nil {
+			err = us.RepositoriesGroup.PasswordHistoryRepository.Prune(id, passwordHistory-1)
+		}
+		if err != nil {
+			log.Errorf("Error updating password history for user %v: %s\n", id, err.Error())
+		}
+	}
+
 	return nil
 }
 
 func (us *UserService) SetEnabled(id int64, enabled bool) error {
 	return us.RepositoriesGroup.UsersRepository.SetEnabled(id, enabled)
 }
+
+// invite creates a disabled user, adds them to the groups provided and emails them a single use invitation code.
+func (us *UserService) Invite(user *user_model.User, groups []string) error {
+
+	// verify groups exist before creating the user
+	if len(groups) > 0 {
+		existingGroups, err := us.RepositoriesGroup.GroupsRepository.GetAll()
+		if err != nil {
+			return err
+		}
+		for _, groupName := range groups {
+			found := false
+			for _, group := range *existingGroups {
+				if group.Name == groupName {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return errors.NewToUser(fmt.Sprintf("Group %v doesn't exist.", groupName))
+			}
+		}
+	}
+
+	// user is enabled once the invitation is accepted
+	user.Password = ""
+	user.Enabled = false
+	err := us.Add(user)
+	if err != nil {
+		return err
+	}
+
+	for _, groupName := range groups {
+		err = us.RepositoriesGroup.GroupsRepository.AddUserToGroupByName(user.Id, groupName)
+		if err != nil {
+			log.Errorf("Error adding invited user %v to group %v: %s\n", user.Id, groupName, err.Error())
+			us.undoInvite(user)
+			return err
+		}
+	}
+
+	err = us.sendInvitation(user)
+	if err != nil {
+		us.undoInvite(user)
+		return err
+	}
+
+	return nil
+}
+
+// undo invite removes a user whose invitation couldn't be sent so the email can be invited again
+func (us *UserService) undoInvite(user *user_model.User) {
+	err := us.RepositoriesGroup.UsersRepository.Delete(user.Id)
+	if err != nil {
+		log.Errorf("Error removing user %v after their invitation failed: %s\n", user.Id, err.Error())
+	}
+}
+
+// resend invitation replaces any outstanding invitation for a user that hasn't accepted one yet
+func (us *UserService) ResendInvitation(id int64) (*user_model.User, error) {
+	user, err := us.RepositoriesGroup.UsersRepository.Get(id)
+	if err != nil {
+		return nil, err
+	}
+
+	// invited users stay disabled and unverified until they accept
+	if user.Enabled || user.Verified {
+		return nil, errors.NewToUser("User has already accepted their invitation.")
+	}
+
+	// a disabled account is only an invitation if it was sent one. accepting consumes it and expired ones are kept.
+	_, err = us.RepositoriesGroup.SecureCodeRepository.GetLatestForUserByType(user.Id, security_code_model.Code_Invitation)
+	if err != nil {
+		return nil, errors.NewToUser("User doesn't have an outstanding invitation.")
+	}
+
+	err = us.RepositoriesGroup.SecureCodeRepository.DeleteForUserByType(user.Id, security_code_model.Code_Invitation)
+	if err != nil {
+		return nil, err
+	}
+
+	err = us.sendInvitation(user)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// send invitation creates a single use invitation code for the user and emails it to them
+func (us *UserService) sendInvitation(user *user_model.User) error {
+
+	// create invitation code
+	code, hashedCode, err := us.AuthService.GetRandomCode(32)
+	if err != nil {
+		return err
+	}
+
+	err = us.RepositoriesGroup.SecureCodeRepository.Add(&security_code_model.SecureCode{
+		UserId: user.Id,
+		Type:   security_code_model.Code_Invitation,
+		Code:   hashedCode,
+	})
+	if err != nil {
+		return err
+	}
+
+	expTimeStr := time.Now().Add(time.Minute * time.Duration(context.Config.DbVars.InvitationTimeout)).Format("01/02/2006 03:04 pm")
+	invitationLink := fmt.Sprintf("%v/invitation/accept.html?code=%v&email=%v", context.Config.DbVars.RedirectRootUrl, url.QueryEscape(code), url.QueryEscape(user.Email))
+
+	// send email
+	err = us.MailService.Send(&mail_service.Mail{
+		To:      user.Email,
+		Subject: "You Have Been Invited",
+		Body: "You have been invited to create an account. Click on the link below to set your password:\n" +
+			invitationLink + "\n\nThe link will expire at: " +
+			expTimeStr + ".",
+		BodyHTML: fmt.Sprintf("<h1>You Have Been Invited</h1><h2>Click on the link below to set your password and activate your account:</h2><p><a href='%v'>Accept Invitation</a></p><p>The link will expire at: <b>%v</b></p>", invitationLink, expTimeStr),
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// accept invitation verifies and consumes the invitation code, sets the password, verifies the email and enables the user.
+func (us *UserService) AcceptInvitation(email string, code string, password string) (*user_model.User, error) {
+
+	user, err := us.RepositoriesGroup.UsersRepository.GetByEmail(email)
+	if err != nil {
+		return nil, ErrInvalidCode
+	}
+
+	// check the code before the policy so the policy can't be probed without an invitation
+	secureCode, err := us.RepositoriesGroup.SecureCodeRepository.GetLatestForUserByType(user.Id, security_code_model.Code_Invitation)
+	if err != nil {
+		return nil, ErrInvalidCode
+	}
+
+	if ok := us.AuthService.VerifyPassword(secureCode.Code, code); !ok {
+		return nil, ErrInvalidCode
+	}
+
+	// check within time
+	if time.Since(secureCode.Created) > (time.Minute * time.Duration(context.Config.DbVars.InvitationTimeout)) {
+		return nil, ErrInvalidCode
+	}
+
+	// check policy before the code is used so the invitee can try another password
+	err = us.AuthService.ValidatePassword(password, user)
+	if err != nil {
+		return nil, err
+	}
+
+	// single use
+	consumed, err := us.RepositoriesGroup.SecureCodeRepository.Consume(secureCode.Id)
+	if err != nil || !consumed {
+		return nil, ErrInvalidCode
+	}
+
+	err = us.setPassword(user, password)
+	if err != nil {
+		return nil, err
+	}
+
+	// the invitation was delivered to this address so it is verified
+	primaryEmail, err := us.RepositoriesGroup.EmailRepository.GetByAddress(email)
+	if err != nil {
+		return nil, err
+	}
+	primaryEmail.IsVerified = true
+	err = us.RepositoriesGroup.EmailRepository.Update(primaryEmail)
+	if err != nil {
+		return nil, err
+	}
+
+	err = us.SetEnabled(user.Id, true)
+	if err != nil {
+		return nil, err
+	}
+
+	user.Enabled = true
+	user.Verified = true
+	return user, nil
+}
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"github.com/gocms-io/gocms/utility/api_utility"
 	"fmt"
 	"github.com/gin-contrib/multitemplate"
 	"github.com/gin-gonic/gin"
@@ -8,12 +9,19 @@ import (
 	"github.com/gocms-io/gocms/domain/acl/authentication/authentication_controller"
 	"github.com/gocms-io/gocms/domain/acl/authentication/authentication_middleware"
 	"github.com/gocms-io/gocms/domain/acl/cors"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_middleware"
+	"github.com/gocms-io/gocms/domain/acl/signing_key/signing_key_controller"
 	"github.com/gocms-io/gocms/domain/content/documentation"
 	"github.com/gocms-io/gocms/domain/content/react"
 	"github.com/gocms-io/gocms/domain/content/template"
 	"github.com/gocms-io/gocms/domain/content/theme"
 	"github.com/gocms-io/gocms/domain/email/email_controller"
 	"github.com/gocms-io/gocms/domain/health/health_controller"
+	"github.com/gocms-io/gocms/domain/metrics/metrics_middleware"
+	"github.com/gocms-io/gocms/domain/tracing/tracing_middleware"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_controller"
+	"github.com/gocms-io/gocms/domain/setting/setting_admin_controller"
+	"github.com/gocms-io/gocms/domain/user/profile/profile_admin_controller"
 	"github.com/gocms-io/gocms/domain/user/user_admin_controller"
 	"github.com/gocms-io/gocms/domain/user/user_controller"
 	"github.com/gocms-io/gocms/domain/user/user_middleware"
@@ -37,11 +45,16 @@ type ContentControllers struct {
 }
 
 type ApiControllers struct {
-	AuthController      *authentication_controller.AuthController
-	HealthyController   *health_controller.HealthController
-	AdminUserController *user_admin_controller.UserAdminController
-	UserController      *user_controller.UserController
-	EmailController     *email_controller.EmailController
+	AuthController         *authentication_controller.AuthController
+	HealthyController      *health_controller.HealthController
+	AdminUserController    *user_admin_controller.UserAdminController
+	UserController         *user_controller.UserController
+	EmailController        *email_controller.EmailController
+	AdminProfileController *profile_admin_controller.ProfileAdminController
+	AdminSettingController *setting_admin_controller.SettingAdminController
+	AdminPluginController  *plugin_controller.PluginAdminController
+	SigningKeyController   *signing_key_controller.SigningKeyController
+	AdminHealthController  *health_controller.HealthAdminController
 }
 
 var (
@@ -50,6 +63,11 @@ var (
 
 func DefaultControllerGroup(r *gin.Engine, sg *service.ServicesGroup) *ControllersGroup {
 
+	// trace and time requests before any other middleware runs
+	r.Use(api_utility.MatchRoute(r))
+	r.Use(tracing_middleware.Trace())
+	r.Use(metrics_middleware.HttpMetrics())
+
 	// create plugin middleware handle
 	pluginMiddlewareProxy := sg.PluginsService.NewPluginMiddlewareProxyByRank()
 	// apply plugin middleware rank 1
@@ -74,21 +92,37 @@ func DefaultControllerGroup(r *gin.Engine, sg *service.ServicesGroup) *Controlle
 		Public:  r.Group(defaultRoutePrefix),
 		Auth:    r.Group(defaultRoutePrefix),
 		NoRoute: r.NoRoute,
+		LookupRoute: func(method string, path string) (string, string, bool) {
+			return api_utility.LookupRoute(r, method, path)
+		},
 	}
 
 	// apply auth middleware
 	am.ApplyAuthToRoutes(routes)
 
+	// label metrics with the route group
+	metrics_middleware.ApplyRouteGroups(routes)
+
+	// apply rate limits
+	routes.Public.Use(rate_limit_middleware.PublicRateLimit(sg.RateLimitService))
+	routes.PreTwofactor.Use(rate_limit_middleware.AuthRateLimit(sg.RateLimitService))
+	routes.Auth.Use(rate_limit_middleware.AuthRateLimit(sg.RateLimitService))
+
 	// apply plugin middleware rank 2000
-	r.Use(pluginMiddlewareProxy.ApplyForRank(plugin_services.MIDDLEWARE_RANK_2000)...)
+	applyPluginMiddleware(r, routes, pluginMiddlewareProxy.ApplyForRank(plugin_services.MIDDLEWARE_RANK_2000))
 
 	// define routes and apply middleware
 	apiControllers := &ApiControllers{
-		AuthController:      authentication_controller.DefaultAuthController(routes, sg),
-		AdminUserController: user_admin_controller.DefaultUserAdminController(routes, sg),
-		HealthyController:   health_controller.DefaultHealthController(routes, sg),
-		UserController:      user_controller.DefaultUserController(routes, sg),
-		EmailController:     email_controller.DefaultEmailController(routes, sg),
+		AuthController:         authentication_controller.DefaultAuthController(routes, sg),
+		AdminUserController:    user_admin_controller.DefaultUserAdminController(routes, sg),
+		HealthyController:      health_controller.DefaultHealthController(routes, sg),
+		UserController:         user_controller.DefaultUserController(routes, sg),
+		EmailController:        email_controller.DefaultEmailController(routes, sg),
+		AdminProfileController: profile_admin_controller.DefaultProfileAdminController(routes, sg),
+		AdminSettingController: setting_admin_controller.DefaultSettingAdminController(routes, sg),
+		AdminPluginController:  plugin_controller.DefaultPluginAdminController(routes, sg),
+		SigningKeyController:   signing_key_controller.DefaultSigningKeyController(routes, sg),
+		AdminHealthController:  health_controller.DefaultHealthAdminController(routes, sg),
 	}
 
 	// define after for 404 catcher
@@ -100,7 +134,7 @@ func DefaultControllerGroup(r *gin.Engine, sg *service.ServicesGroup) *Controlle
 	}
 
 	// apply plugin middleware rank 3000
-	r.Use(pluginMiddlewareProxy.ApplyForRank(plugin_services.MIDDLEWARE_RANK_3000)...)
+	applyPluginMiddleware(r, routes, pluginMiddlewareProxy.ApplyForRank(plugin_services.MIDDLEWARE_RANK_3000))
 
 	// register plugin routes
 	sg.PluginsService.RegisterActivePluginRoutes(routes)
@@ -127,6 +161,19 @@ func DefaultControllerGroup(r *gin.Engine, sg *service.ServicesGroup) *Controlle
 	return controllersGroup
 }
 
+// applyPluginMiddleware adds plugin middleware to the engine, for requests without a route, and to each route group.
+// Groups copy the engine's middleware when they are created so anything added later has to be added to them as well.
+func applyPluginMiddleware(r *gin.Engine, routeGroups *routes.Routes, handlers []gin.HandlerFunc) {
+	if len(handlers) == 0 {
+		return
+	}
+	r.Use(handlers...)
+	routeGroups.Root.Use(handlers...)
+	routeGroups.Public.Use(handlers...)
+	routeGroups.PreTwofactor.Use(handlers...)
+	routeGroups.Auth.Use(handlers...)
+}
+
 func createMyRender() multitemplate.Render {
 	r := multitemplate.New()
 	r.AddFromGlob("docs.tmpl", "./content/templates/docs.tmpl")
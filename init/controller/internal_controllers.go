@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"github.com/gocms-io/gocms/utility/api_utility"
 	"github.com/gin-gonic/gin"
 	"github.com/gocms-io/gocms/init/service"
 	"github.com/gocms-io/gocms/utility/log"
@@ -10,50 +11,98 @@ import (
 	"github.com/gocms-io/gocms/routes"
 	"github.com/gocms-io/gocms/domain/health/health_controller"
 	"github.com/gocms-io/gocms/domain/acl/group/group_controller"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_controller"
+	"github.com/gocms-io/gocms/domain/plugin/plugin_services"
+	"github.com/gocms-io/gocms/domain/metrics/metrics_controller"
+	"github.com/gocms-io/gocms/domain/metrics/metrics_middleware"
+	"github.com/gocms-io/gocms/domain/tracing/tracing_middleware"
+	"github.com/gocms-io/gocms/utility/security"
+	"strings"
 )
 
 type InternalControllersGroup struct {
 	InternalRoutes            *routes.InternalRoutes
 	InternalHealthyController *health_controller.InternalHealthController
-	InternalGroupController *group_controller.InternalGroupController
+	InternalGroupController   *group_controller.InternalGroupController
+	InternalPluginController  *plugin_controller.InternalPluginController
+	InternalMetricsController *metrics_controller.InternalMetricsController
 }
 
-var (
-	defaultInternalRoutePrefix = "/internal/api"
-)
-
 func DefaultInternalControllerGroup(ir *gin.Engine, sg *service.ServicesGroup) *InternalControllersGroup {
 
+	// trace and time requests before anything can reject them
+	ir.Use(api_utility.MatchRoute(ir))
+	ir.Use(tracing_middleware.TraceInternal())
+	ir.Use(metrics_middleware.HttpMetrics())
+
 	// require microservice secret to use internal api
-	ir.Use(RequireMicroserviceSecretMiddleware())
+	ir.Use(RequireMicroserviceSecretMiddleware(sg.PluginsService))
 
 	// setup route groups
 	internalRoutes := &routes.InternalRoutes{
-		InternalRoot:   ir.Group(defaultInternalRoutePrefix),
+		InternalRoot:   ir.Group(routes.INTERNAL_PREFIX),
 	}
+	internalRoutes.InternalRoot.Use(metrics_middleware.RouteGroup(routes.Internal))
 
 	// define after for 404 catcher
 	icg := &InternalControllersGroup{
 		InternalHealthyController: health_controller.DefaultInternalHealthController(internalRoutes, sg),
-		InternalGroupController: group_controller.DefaultInternalGroupController(internalRoutes, sg),
+		InternalGroupController:   group_controller.DefaultInternalGroupController(internalRoutes, sg),
+		InternalPluginController:  plugin_controller.DefaultInternalPluginController(internalRoutes, sg),
+		InternalMetricsController: metrics_controller.DefaultInternalMetricsController(internalRoutes, sg),
 	}
 
 	return icg
 }
 
-func RequireMicroserviceSecretMiddleware() gin.HandlerFunc {
+func RequireMicroserviceSecretMiddleware(pluginsService plugin_services.IPluginsService) gin.HandlerFunc {
 	log.Debugf("Adding Microservice Secret Middleware\n")
-	return msSecretMdl
+	return func(c *gin.Context) {
+		msSecretMdl(c, pluginsService)
+	}
 }
 
-func msSecretMdl(c *gin.Context) {
+func msSecretMdl(c *gin.Context, pluginsService plugin_services.IPluginsService) {
+	// a client certificate signed by INTERNAL_TLS_CLIENT_CA_FILE is as good as the plugin secret when its common name is
+	// the id of an active plugin. other certificates still need a secret.
+	if c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0 {
+		commonName := c.Request.TLS.VerifiedChains[0][0].Subject.CommonName
+		if _, ok := pluginsService.GetActivePlugins()[commonName]; ok && commonName != "" {
+			c.Set(consts.MICROSERVICE_CLIENT_KEY_FOR_GIN_CONTEXT, commonName)
+			c.Next()
+			return
+		}
+	}
+
+	// plugins identify themselves with their own secret
+	pluginId := c.Request.Header.Get(consts.GOCMS_HEADER_PLUGIN_ID)
+	if pluginId != "" {
+		if !security.VerifyPluginSecret(context.Config.DbVars.MicroserviceSecret, pluginId, c.Request.Header.Get(consts.GOCMS_HEADER_PLUGIN_SECRET)) {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set(consts.MICROSERVICE_CLIENT_KEY_FOR_GIN_CONTEXT, pluginId)
+		c.Next()
+		return
+	}
+
 	msSecret := c.Request.Header.Get(consts.GOCMS_HEADER_MICROSERVICE_SECRET)
 
-	// if secret is no good then fail
-	if msSecret != context.Config.DbVars.MicroserviceSecret {
+	// scrapers like prometheus can only send the secret as a bearer token so it's only accepted for metrics
+	if msSecret == "" && isMetricsRequest(c) {
+		msSecret = strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+	}
+
+	// if secret is no good then fail. an empty secret never matches.
+	if msSecret == "" || msSecret != context.Config.DbVars.MicroserviceSecret {
 		c.AbortWithStatus(http.StatusUnauthorized)
 	} else {
 		c.Next()
 	}
 }
 
+func isMetricsRequest(c *gin.Context) bool {
+	route, ok := api_utility.GetRouteFromContext(c)
+	return ok && c.Request.Method == http.MethodGet && route == routes.INTERNAL_PREFIX+metrics_controller.METRICS_ROUTE
+}
+
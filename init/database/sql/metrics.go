@@ -0,0 +1,34 @@
+package sql
+
+import (
+	"database/sql"
+	"github.com/gocms-io/gocms/utility/metrics"
+)
+
+// registerPoolMetrics exposes the connection pool stats. They are read from the pool on every scrape.
+func registerPoolMetrics(db *sql.DB) {
+	metrics.NewGaugeFunc("gocms_db_max_open_connections", "Maximum number of open connections to the database.", func() float64 {
+		return float64(db.Stats().MaxOpenConnections)
+	})
+	metrics.NewGaugeFunc("gocms_db_open_connections", "Number of established connections, in use and idle.", func() float64 {
+		return float64(db.Stats().OpenConnections)
+	})
+	metrics.NewGaugeFunc("gocms_db_in_use_connections", "Number of connections currently in use.", func() float64 {
+		return float64(db.Stats().InUse)
+	})
+	metrics.NewGaugeFunc("gocms_db_idle_connections", "Number of idle connections.", func() float64 {
+		return float64(db.Stats().Idle)
+	})
+	metrics.NewCounterFunc("gocms_db_wait_count_total", "Total number of connections waited for.", func() float64 {
+		return float64(db.Stats().WaitCount)
+	})
+	metrics.NewCounterFunc("gocms_db_wait_duration_seconds_total", "Total time spent waiting for a new connection.", func() float64 {
+		return db.Stats().WaitDuration.Seconds()
+	})
+	metrics.NewCounterFunc("gocms_db_max_idle_closed_total", "Total connections closed due to the idle limit.", func() float64 {
+		return float64(db.Stats().MaxIdleClosed)
+	})
+	metrics.NewCounterFunc("gocms_db_max_lifetime_closed_total", "Total connections closed due to the max lifetime.", func() float64 {
+		return float64(db.Stats().MaxLifetimeClosed)
+	})
+}
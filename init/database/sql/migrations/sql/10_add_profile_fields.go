@@ -0,0 +1,49 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddProfileFields() *migrate.Migration {
+	addProfileFields := migrate.Migration{
+		Id: "10",
+		Up: []string{`
+			CREATE TABLE gocms_profile_fields (
+			id int(11) NOT NULL AUTO_INCREMENT,
+			name varchar(50) NOT NULL UNIQUE,
+			label varchar(255) NOT NULL,
+			description varchar(255) NOT NULL DEFAULT '',
+			type varchar(20) NOT NULL,
+			required int(1) NOT NULL DEFAULT 0,
+			visibility varchar(10) NOT NULL DEFAULT 'self',
+			pattern varchar(255) NOT NULL DEFAULT '',
+			min double,
+			max double,
+			options TEXT,
+			sortOrder int(11) NOT NULL DEFAULT 0,
+			created datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			lastModified DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (id)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+			`, `
+			CREATE TABLE gocms_user_profile_values (
+			userId int(11) NOT NULL,
+			fieldId int(11) NOT NULL,
+			value TEXT NOT NULL,
+			lastModified DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (userId, fieldId),
+			FOREIGN KEY (userId)
+				REFERENCES gocms_users (id)
+				ON DELETE CASCADE,
+			FOREIGN KEY (fieldId)
+				REFERENCES gocms_profile_fields (id)
+				ON DELETE CASCADE
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+			`,
+		},
+		Down: []string{
+			"DROP TABLE gocms_user_profile_values;",
+			"DROP TABLE gocms_profile_fields;",
+		},
+	}
+
+	return &addProfileFields
+}
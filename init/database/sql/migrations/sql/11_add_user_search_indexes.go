@@ -0,0 +1,30 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddUserSearchIndexes() *migrate.Migration {
+	addUserSearchIndexes := migrate.Migration{
+		Id: "11",
+		Up: []string{`
+			CREATE INDEX gocms_users_fullName_id ON gocms_users (fullName, id);
+			`, `
+			CREATE INDEX gocms_users_created_id ON gocms_users (created, id);
+			`, `
+			CREATE INDEX gocms_users_lastModified_id ON gocms_users (lastModified, id);
+			`, `
+			CREATE INDEX gocms_users_enabled ON gocms_users (enabled);
+			`, `
+			CREATE INDEX gocms_emails_userId_isPrimary ON gocms_emails (userId, isPrimary);
+			`,
+		},
+		Down: []string{
+			"DROP INDEX gocms_users_fullName_id ON gocms_users;",
+			"DROP INDEX gocms_users_created_id ON gocms_users;",
+			"DROP INDEX gocms_users_lastModified_id ON gocms_users;",
+			"DROP INDEX gocms_users_enabled ON gocms_users;",
+			"DROP INDEX gocms_emails_userId_isPrimary ON gocms_emails;",
+		},
+	}
+
+	return &addUserSearchIndexes
+}
@@ -0,0 +1,25 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddPluginSettings() *migrate.Migration {
+	addPluginSettings := migrate.Migration{
+		Id: "12",
+		Up: []string{`
+			CREATE TABLE gocms_plugin_settings (
+			pluginId varchar(255) NOT NULL,
+			name varchar(100) NOT NULL,
+			value TEXT NOT NULL,
+			created datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			lastModified DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+			PRIMARY KEY (pluginId, name)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+			`,
+		},
+		Down: []string{
+			"DROP TABLE gocms_plugin_settings;",
+		},
+	}
+
+	return &addPluginSettings
+}
@@ -0,0 +1,32 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddSigningKeys() *migrate.Migration {
+	addSigningKeys := migrate.Migration{
+		Id: "13",
+		Up: []string{`
+			CREATE TABLE gocms_signing_keys (
+			kid varchar(64) NOT NULL,
+			algorithm varchar(10) NOT NULL DEFAULT 'RS256',
+			privateKey TEXT NOT NULL,
+			publicKey TEXT NOT NULL,
+			created datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			retired datetime NULL,
+			expires datetime NULL,
+			PRIMARY KEY (kid)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('SIGNING_KEY_ROTATION', '90', 'Days between automatic token signing key rotations. 0 disables rotation.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('SIGNING_KEY_GRACE_PERIOD', '31', 'Days a rotated signing key is still accepted. Never shorter than the longest token timeout.');
+			`,
+		},
+		Down: []string{
+			"DROP TABLE gocms_signing_keys;",
+			"DELETE FROM gocms_settings WHERE name IN ('SIGNING_KEY_ROTATION', 'SIGNING_KEY_GRACE_PERIOD');",
+		},
+	}
+
+	return &addSigningKeys
+}
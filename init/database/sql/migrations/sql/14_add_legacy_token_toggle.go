@@ -0,0 +1,18 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddLegacyTokenToggle() *migrate.Migration {
+	addLegacyTokenToggle := migrate.Migration{
+		Id: "14",
+		Up: []string{`
+			INSERT INTO gocms_settings (name, value, description) VALUES('ACCEPT_LEGACY_TOKENS', 'true', 'Accept tokens issued without standard claims. Disable once every legacy token has expired.');
+			`,
+		},
+		Down: []string{
+			"DELETE FROM gocms_settings WHERE name = 'ACCEPT_LEGACY_TOKENS';",
+		},
+	}
+
+	return &addLegacyTokenToggle
+}
@@ -0,0 +1,31 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddTrustedDevices() *migrate.Migration {
+	addTrustedDevices := migrate.Migration{
+		Id: "15",
+		Up: []string{`
+			CREATE TABLE gocms_trusted_devices (
+			id varchar(64) NOT NULL,
+			userId int(11) NOT NULL,
+			name varchar(255) NOT NULL DEFAULT '',
+			userAgent varchar(512) NOT NULL DEFAULT '',
+			ip varchar(45) NOT NULL DEFAULT '',
+			firstSeen datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			lastSeen datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			INDEX (userId),
+			FOREIGN KEY (userId)
+				REFERENCES gocms_users (id)
+				ON DELETE CASCADE
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+			`,
+		},
+		Down: []string{
+			"DROP TABLE gocms_trusted_devices;",
+		},
+	}
+
+	return &addTrustedDevices
+}
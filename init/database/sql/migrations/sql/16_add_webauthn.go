@@ -0,0 +1,54 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddWebauthn() *migrate.Migration {
+	addWebauthn := migrate.Migration{
+		Id: "16",
+		Up: []string{`
+			CREATE TABLE gocms_webauthn_credentials (
+			id int(11) NOT NULL AUTO_INCREMENT,
+			userId int(11) NOT NULL,
+			credentialId varchar(255) NOT NULL UNIQUE,
+			publicKey TEXT NOT NULL,
+			algorithm int(11) NOT NULL,
+			signCount bigint(20) NOT NULL DEFAULT 0,
+			aaguid varchar(36) NOT NULL DEFAULT '',
+			name varchar(255) NOT NULL DEFAULT '',
+			created datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			lastUsed datetime NULL,
+			PRIMARY KEY (id),
+			INDEX (userId),
+			FOREIGN KEY (userId)
+				REFERENCES gocms_users (id)
+				ON DELETE CASCADE
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+			`, `
+			CREATE TABLE gocms_webauthn_challenges (
+			challenge varchar(64) NOT NULL,
+			ceremony varchar(20) NOT NULL,
+			userId int(11) NOT NULL DEFAULT 0,
+			expires datetime NOT NULL,
+			PRIMARY KEY (challenge)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('WEBAUTHN_RP_ID', 'localhost', 'Domain passkeys are registered to. Changing it invalidates every registered passkey.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('WEBAUTHN_RP_NAME', 'GoCMS', 'Name shown by browsers when registering a passkey.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('WEBAUTHN_ORIGINS', 'http://localhost:9090', 'Comma separated origins allowed to use passkeys.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('WEBAUTHN_TIMEOUT', '5', 'Minutes to complete a passkey registration or login.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('WEBAUTHN_USER_VERIFICATION', 'preferred', 'Whether authenticators must verify the user with a pin or biometric (required, preferred, discouraged).');
+			`,
+		},
+		Down: []string{
+			"DROP TABLE gocms_webauthn_credentials;",
+			"DROP TABLE gocms_webauthn_challenges;",
+			"DELETE FROM gocms_settings WHERE name IN ('WEBAUTHN_RP_ID', 'WEBAUTHN_RP_NAME', 'WEBAUTHN_ORIGINS', 'WEBAUTHN_TIMEOUT', 'WEBAUTHN_USER_VERIFICATION');",
+		},
+	}
+
+	return &addWebauthn
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddMagicLink() *migrate.Migration {
+	addMagicLink := migrate.Migration{
+		Id: "17",
+		Up: []string{`
+			INSERT INTO gocms_settings (name, value, description) VALUES('MAGIC_LINK_ENABLED', 'false', 'Allow users to login with a link sent to their email.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('MAGIC_LINK_TIMEOUT', '10', 'Minutes a login link stays valid.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('MAGIC_LINK_URL', 'http://localhost:9090/api/login/magic-link', 'Page login links point to. The email and code are added as query parameters.');
+			`,
+		},
+		Down: []string{
+			"DELETE FROM gocms_settings WHERE name IN ('MAGIC_LINK_ENABLED', 'MAGIC_LINK_TIMEOUT', 'MAGIC_LINK_URL');",
+		},
+	}
+
+	return &addMagicLink
+}
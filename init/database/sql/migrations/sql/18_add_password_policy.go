@@ -0,0 +1,43 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddPasswordPolicy() *migrate.Migration {
+	addPasswordPolicy := migrate.Migration{
+		Id: "18",
+		Up: []string{`
+			CREATE TABLE gocms_password_history (
+			id int(11) NOT NULL AUTO_INCREMENT,
+			userId int(11) NOT NULL,
+			password varchar(255) NOT NULL,
+			created datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (id),
+			INDEX (userId, created),
+			FOREIGN KEY (userId)
+				REFERENCES gocms_users (id)
+				ON DELETE CASCADE
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+			`, `
+			ALTER TABLE gocms_users
+			ADD COLUMN passwordChanged DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP AFTER password;
+			`, `
+			UPDATE gocms_users SET passwordChanged=created, lastModified=lastModified;
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('PASSWORD_MIN_LENGTH', '8', 'Minimum number of characters in a password.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('PASSWORD_HISTORY', '0', 'Number of previous passwords users may not reuse. 0 allows reuse.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('PASSWORD_MAX_AGE', '0', 'Days before users must change their password. 0 never expires passwords.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('PASSWORD_BREACH_LIST', '', 'Path to a sorted list of SHA-1 hashes of breached passwords. Blank disables the check.');
+			`,
+		},
+		Down: []string{
+			"DROP TABLE gocms_password_history;",
+			"ALTER TABLE gocms_users DROP COLUMN passwordChanged;",
+			"DELETE FROM gocms_settings WHERE name IN ('PASSWORD_MIN_LENGTH', 'PASSWORD_HISTORY', 'PASSWORD_MAX_AGE', 'PASSWORD_BREACH_LIST');",
+		},
+	}
+
+	return &addPasswordPolicy
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddExternalPluginTls() *migrate.Migration {
+	addExternalPluginTls := migrate.Migration{
+		Id: "19",
+		Up: []string{`
+			ALTER TABLE gocms_plugins
+			ADD COLUMN externalTlsCA TEXT NULL AFTER externalPort,
+			ADD COLUMN externalTlsCert TEXT NULL AFTER externalTlsCA,
+			ADD COLUMN externalTlsKey TEXT NULL AFTER externalTlsCert,
+			ADD COLUMN externalTlsServerName varchar(255) NULL AFTER externalTlsKey,
+			ADD COLUMN externalTlsSkipVerify INT(1) DEFAULT '0' NOT NULL AFTER externalTlsServerName;
+			`,
+		},
+		Down: []string{
+			"ALTER TABLE gocms_plugins DROP COLUMN externalTlsCA, DROP COLUMN externalTlsCert, DROP COLUMN externalTlsKey, DROP COLUMN externalTlsServerName, DROP COLUMN externalTlsSkipVerify;",
+		},
+	}
+
+	return &addExternalPluginTls
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddPluginProxySettings() *migrate.Migration {
+	addPluginProxySettings := migrate.Migration{
+		Id: "20",
+		Up: []string{`
+			ALTER TABLE gocms_plugins
+			ADD COLUMN proxyConnectTimeout INT NULL AFTER externalTlsSkipVerify,
+			ADD COLUMN proxyResponseTimeout INT NULL AFTER proxyConnectTimeout,
+			ADD COLUMN proxyRetries INT NULL AFTER proxyResponseTimeout,
+			ADD COLUMN breakerThreshold INT NULL AFTER proxyRetries,
+			ADD COLUMN breakerCooldown INT NULL AFTER breakerThreshold;
+			`,
+		},
+		Down: []string{
+			"ALTER TABLE gocms_plugins DROP COLUMN proxyConnectTimeout, DROP COLUMN proxyResponseTimeout, DROP COLUMN proxyRetries, DROP COLUMN breakerThreshold, DROP COLUMN breakerCooldown;",
+		},
+	}
+
+	return &addPluginProxySettings
+}
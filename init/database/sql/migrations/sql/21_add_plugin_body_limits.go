@@ -0,0 +1,20 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddPluginBodyLimits() *migrate.Migration {
+	addPluginBodyLimits := migrate.Migration{
+		Id: "21",
+		Up: []string{`
+			INSERT INTO gocms_settings (name, value, description) VALUES('PLUGIN_MAX_BODY_SIZE', '10485760', 'Largest request body in bytes that plugin middleware will accept. 0 is unlimited.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('PLUGIN_BODY_MEMORY_LIMIT', '1048576', 'Bytes of a request body kept in memory for plugin middleware before it is buffered to a temp file.');
+			`,
+		},
+		Down: []string{
+			"DELETE FROM gocms_settings WHERE name IN ('PLUGIN_MAX_BODY_SIZE', 'PLUGIN_BODY_MEMORY_LIMIT');",
+		},
+	}
+
+	return &addPluginBodyLimits
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddTokenIssuer() *migrate.Migration {
+	addTokenIssuer := migrate.Migration{
+		Id: "22",
+		Up: []string{`
+			INSERT INTO gocms_settings (name, value, description) SELECT 'TOKEN_ISSUER', value, 'Issuer of user and device tokens. Changing it invalidates every token.' FROM gocms_settings WHERE name = 'PUBLIC_API_URL';
+			`,
+		},
+		Down: []string{
+			"DELETE FROM gocms_settings WHERE name = 'TOKEN_ISSUER';",
+		},
+	}
+
+	return &addTokenIssuer
+}
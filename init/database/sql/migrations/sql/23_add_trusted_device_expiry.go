@@ -0,0 +1,20 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddTrustedDeviceExpiry() *migrate.Migration {
+	addTrustedDeviceExpiry := migrate.Migration{
+		Id: "23",
+		Up: []string{`
+			ALTER TABLE gocms_trusted_devices ADD COLUMN expires datetime NOT NULL DEFAULT CURRENT_TIMESTAMP, ADD INDEX (expires);
+			`, `
+			UPDATE gocms_trusted_devices SET expires = DATE_ADD(firstSeen, INTERVAL (SELECT CAST(value AS UNSIGNED) FROM gocms_settings WHERE name = 'DEVICE_AUTHENTICATION_TIMEOUT') MINUTE);
+			`,
+		},
+		Down: []string{
+			"ALTER TABLE gocms_trusted_devices DROP INDEX expires, DROP COLUMN expires;",
+		},
+	}
+
+	return &addTrustedDeviceExpiry
+}
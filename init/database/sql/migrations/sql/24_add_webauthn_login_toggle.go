@@ -0,0 +1,18 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddWebauthnLoginToggle() *migrate.Migration {
+	addWebauthnLoginToggle := migrate.Migration{
+		Id: "24",
+		Up: []string{`
+			INSERT INTO gocms_settings (name, value, description) VALUES('WEBAUTHN_LOGIN_ENABLED', 'true', 'Allow passwordless login with a passkey. Passkeys can still be used for two-factor when this is off.');
+			`,
+		},
+		Down: []string{
+			"DELETE FROM gocms_settings WHERE name = 'WEBAUTHN_LOGIN_ENABLED';",
+		},
+	}
+
+	return &addWebauthnLoginToggle
+}
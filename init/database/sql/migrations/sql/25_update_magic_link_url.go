@@ -0,0 +1,18 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func UpdateMagicLinkUrl() *migrate.Migration {
+	updateMagicLinkUrl := migrate.Migration{
+		Id: "25",
+		Up: []string{`
+			UPDATE gocms_settings SET value='http://localhost:9090/login/magic-link', description='Page login links point to. The email and code are added as query parameters. The page should post the code to /api/login/magic-link when the user asks to login.' WHERE name = 'MAGIC_LINK_URL' AND value = 'http://localhost:9090/api/login/magic-link';
+			`,
+		},
+		Down: []string{
+			"UPDATE gocms_settings SET value='http://localhost:9090/api/login/magic-link', description='Page login links point to. The email and code are added as query parameters.' WHERE name = 'MAGIC_LINK_URL' AND value = 'http://localhost:9090/login/magic-link';",
+		},
+	}
+
+	return &updateMagicLinkUrl
+}
@@ -0,0 +1,18 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func UpdatePasswordBreachList() *migrate.Migration {
+	updatePasswordBreachList := migrate.Migration{
+		Id: "26",
+		Up: []string{`
+			UPDATE gocms_settings SET description='Directory of breached password range files named by the first 5 characters of the SHA-1 hash, or a range api url such as https://api.pwnedpasswords.com/range/. Blank disables the check.' WHERE name = 'PASSWORD_BREACH_LIST';
+			`,
+		},
+		Down: []string{
+			"UPDATE gocms_settings SET description='Path to a sorted list of SHA-1 hashes of breached passwords. Blank disables the check.' WHERE name = 'PASSWORD_BREACH_LIST';",
+		},
+	}
+
+	return &updatePasswordBreachList
+}
@@ -0,0 +1,21 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AllowUnsetPasswordChanged() *migrate.Migration {
+	allowUnsetPasswordChanged := migrate.Migration{
+		Id: "27",
+		Up: []string{`
+			ALTER TABLE gocms_users MODIFY passwordChanged DATETIME NULL DEFAULT NULL;
+			`, `
+			UPDATE gocms_users SET passwordChanged=NULL, lastModified=lastModified WHERE enabled=0 AND id IN (SELECT userId FROM gocms_secure_codes WHERE type=4);
+			`,
+		},
+		Down: []string{
+			"UPDATE gocms_users SET passwordChanged=created, lastModified=lastModified WHERE passwordChanged IS NULL;",
+			"ALTER TABLE gocms_users MODIFY passwordChanged DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP;",
+		},
+	}
+
+	return &allowUnsetPasswordChanged
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddRateLimits() *migrate.Migration {
+	addRateLimits := migrate.Migration{
+		Id: "7",
+		Up: []string{`
+			CREATE TABLE gocms_rate_limits (
+			rateKey varchar(255) NOT NULL,
+			windowStart datetime NOT NULL,
+			count int(11) NOT NULL DEFAULT 0,
+			expires datetime NOT NULL,
+			PRIMARY KEY (rateKey, windowStart),
+			INDEX (expires)
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('RATE_LIMIT_STORE', 'memory', 'Counter store used for rate limits. memory or database. Use database when running multiple GoCMS instances.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('RATE_LIMIT_PUBLIC_REQUESTS', '0', 'Requests allowed per client ip on Public routes within each window. 0 disables the limit.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('RATE_LIMIT_PUBLIC_WINDOW', '60', 'Seconds in each Public route rate limit window.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('RATE_LIMIT_AUTH_REQUESTS', '0', 'Requests allowed per user on Auth routes within each window. 0 disables the limit.');
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('RATE_LIMIT_AUTH_WINDOW', '60', 'Seconds in each Auth route rate limit window.');
+			`,
+		},
+		Down: []string{
+			"DROP TABLE gocms_rate_limits;",
+			"DELETE FROM gocms_settings WHERE name LIKE 'RATE_LIMIT_%';",
+		},
+	}
+
+	return &addRateLimits
+}
@@ -0,0 +1,19 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddInvitations() *migrate.Migration {
+	addInvitations := migrate.Migration{
+		Id: "8",
+		Up: []string{`
+			INSERT INTO gocms_settings (name, value, description) VALUES('INVITATION_TIMEOUT', '10080', 'Minutes an invitation to register remains valid.');
+			`,
+		},
+		Down: []string{
+			"DELETE FROM gocms_settings WHERE name='INVITATION_TIMEOUT';",
+			"DELETE FROM gocms_secure_codes WHERE type=4;",
+		},
+	}
+
+	return &addInvitations
+}
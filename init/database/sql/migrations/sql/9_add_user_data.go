@@ -0,0 +1,32 @@
+package migrations
+
+import "github.com/rubenv/sql-migrate"
+
+func AddUserData() *migrate.Migration {
+	addUserData := migrate.Migration{
+		Id: "9",
+		Up: []string{`
+			CREATE TABLE gocms_user_deletions (
+			userId int(11) NOT NULL,
+			requested datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			scheduledFor datetime NOT NULL,
+			attempts int(11) NOT NULL DEFAULT 0,
+			lastError TEXT,
+			PRIMARY KEY (userId),
+			INDEX (scheduledFor),
+			FOREIGN KEY (userId)
+				REFERENCES gocms_users (id)
+				ON DELETE CASCADE
+			) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+			`, `
+			INSERT INTO gocms_settings (name, value, description) VALUES('USER_DELETION_GRACE_PERIOD', '14', 'Days between a user requesting account deletion and the account being permanently deleted.');
+			`,
+		},
+		Down: []string{
+			"DROP TABLE gocms_user_deletions;",
+			"DELETE FROM gocms_settings WHERE name='USER_DELETION_GRACE_PERIOD';",
+		},
+	}
+
+	return &addUserData
+}
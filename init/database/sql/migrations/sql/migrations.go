@@ -14,6 +14,27 @@ func Default() *migrate.MemoryMigrationSource {
 			AddExternalPlugin(),
 			MigrateToRSAKeys(),
 			AddDocumentationToggle(),
+			AddRateLimits(),
+			AddInvitations(),
+			AddUserData(),
+			AddProfileFields(),
+			AddUserSearchIndexes(),
+			AddPluginSettings(),
+			AddSigningKeys(),
+			AddLegacyTokenToggle(),
+			AddTrustedDevices(),
+			AddWebauthn(),
+			AddMagicLink(),
+			AddPasswordPolicy(),
+			AddExternalPluginTls(),
+			AddPluginProxySettings(),
+			AddPluginBodyLimits(),
+			AddTokenIssuer(),
+			AddTrustedDeviceExpiry(),
+			AddWebauthnLoginToggle(),
+			UpdateMagicLinkUrl(),
+			UpdatePasswordBreachList(),
+			AllowUnsetPasswordChanged(),
 		},
 	}
 	return &migrationsList
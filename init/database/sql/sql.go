@@ -2,7 +2,6 @@ package sql
 
 import (
 	"database/sql"
-	_ "github.com/go-sql-driver/mysql"
 	"github.com/gocms-io/gocms/context"
 	"github.com/gocms-io/gocms/init/database/sql/migrations/sql"
 	"github.com/gocms-io/gocms/utility/log"
@@ -19,19 +18,22 @@ type SQL struct {
 func DefaultSQL() *SQL {
 	// create db connection
 	connectionString := context.Config.EnvVars.DbUser + ":" + context.Config.EnvVars.DbPassword + "@" + context.Config.EnvVars.DbServer + "/" + context.Config.EnvVars.DbName + "?parseTime=true"
-	dbHandle, err := sql.Open("mysql", connectionString)
+	dbHandle, err := sql.Open(TRACED_DRIVER, connectionString)
 	if err != nil {
-		log.Criticalf("Database Error opening connection: %v\n", err.Error())
+		log.Fatalf("Database Error opening connection: %v\n", err.Error())
 	}
 
 	// ping to verify connection
 	err = dbHandle.Ping()
 	if err != nil {
-		log.Criticalf("Database Error verifying good connection: %v\n", err.Error())
+		log.Fatalf("Database Error verifying good connection: %v\n", err.Error())
 	}
 
 	dbx := sqlx.NewDb(dbHandle, "mysql")
 
+	// expose pool stats on /metrics
+	registerPoolMetrics(dbHandle)
+
 	mySql := &SQL{
 		Dbx:        dbx,
 		migrations: migrations.Default(),
@@ -41,8 +43,10 @@ func DefaultSQL() *SQL {
 	return mySql
 }
 
+const MIGRATIONS_TABLE = "gocms_migrations"
+
 func (sql *SQL) MigrateSql() error {
-	tableName := "gocms_migrations"
+	tableName := MIGRATIONS_TABLE
 	migrate.SetTable(tableName)
 	n, err := migrate.Exec(sql.Dbx.DB, "mysql", sql.migrations, migrate.Up)
 	if err != nil {
@@ -65,3 +69,17 @@ func (sql *SQL) MigrateSql() error {
 	}
 	return nil
 }
+
+// PendingMigrations lists the ids of migrations that haven't been applied yet
+func (sql *SQL) PendingMigrations() ([]string, error) {
+	migrate.SetTable(MIGRATIONS_TABLE)
+	planned, _, err := migrate.PlanMigration(sql.Dbx.DB, "mysql", sql.migrations, migrate.Up, 0)
+	if err != nil {
+		return nil, err
+	}
+	pending := make([]string, len(planned))
+	for i, migration := range planned {
+		pending[i] = migration.Id
+	}
+	return pending, nil
+}
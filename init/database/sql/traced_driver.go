@@ -0,0 +1,208 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"github.com/go-sql-driver/mysql"
+	"github.com/gocms-io/gocms/utility/trace"
+	"strings"
+)
+
+// TRACED_DRIVER wraps the mysql driver so every query and exec is a span
+const TRACED_DRIVER = "mysql-traced"
+
+func init() {
+	sql.Register(TRACED_DRIVER, &tracedDriver{Driver: &mysql.MySQLDriver{}})
+}
+
+type tracedDriver struct {
+	driver.Driver
+}
+
+func (d *tracedDriver) Open(name string) (driver.Conn, error) {
+	conn, err := d.Driver.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedConn{Conn: conn}, nil
+}
+
+// tracedConn passes through every optional interface database/sql looks for so wrapping doesn't change how connections behave
+type tracedConn struct {
+	driver.Conn
+}
+
+func (c *tracedConn) Prepare(query string) (driver.Stmt, error) {
+	stmt, err := c.Conn.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query}, nil
+}
+
+func (c *tracedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	preparer, ok := c.Conn.(driver.ConnPrepareContext)
+	if !ok {
+		return c.Prepare(query)
+	}
+	stmt, err := preparer.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return &tracedStmt{Stmt: stmt, query: query}, nil
+}
+
+// BeginTx refuses options the driver can't honor the same way database/sql does for drivers without ConnBeginTx
+func (c *tracedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(sql.LevelDefault) {
+		return nil, errors.New("sql: driver does not support non-default isolation level")
+	}
+	if opts.ReadOnly {
+		return nil, errors.New("sql: driver does not support read-only transactions")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *tracedConn) ResetSession(ctx context.Context) error {
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+// CheckNamedValue skips back to the default conversion when the driver doesn't check values itself
+func (c *tracedConn) CheckNamedValue(nv *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+func (c *tracedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *tracedConn) IsValid() bool {
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+// QueryContext runs queries without args directly. Queries with args are skipped back to database/sql which prepares them.
+func (c *tracedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	queryer, ok := c.Conn.(driver.Queryer)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	span := startQuerySpan(ctx, query)
+	rows, err := queryer.Query(query, values)
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	endQuerySpan(span, err)
+	return rows, err
+}
+
+func (c *tracedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	execer, ok := c.Conn.(driver.Execer)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	span := startQuerySpan(ctx, query)
+	result, err := execer.Exec(query, values)
+	if err == driver.ErrSkip {
+		return nil, err
+	}
+	endQuerySpan(span, err)
+	return result, err
+}
+
+type tracedStmt struct {
+	driver.Stmt
+	query string
+}
+
+// ColumnConverter keeps the driver's own argument conversion
+func (s *tracedStmt) ColumnConverter(idx int) driver.ValueConverter {
+	if cc, ok := s.Stmt.(driver.ColumnConverter); ok {
+		return cc.ColumnConverter(idx)
+	}
+	return driver.DefaultParameterConverter
+}
+
+func (s *tracedStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	span := startQuerySpan(ctx, s.query)
+	rows, err := s.Stmt.Query(values)
+	endQuerySpan(span, err)
+	return rows, err
+}
+
+func (s *tracedStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	values, err := namedValues(args)
+	if err != nil {
+		return nil, err
+	}
+
+	span := startQuerySpan(ctx, s.query)
+	result, err := s.Stmt.Exec(values)
+	endQuerySpan(span, err)
+	return result, err
+}
+
+// startQuerySpan is a child of the span in ctx. Queries run without a span in their context, ie. from repositories that
+// don't take a context yet or from scheduled jobs, aren't traced so they don't each start a trace.
+func startQuerySpan(ctx context.Context, query string) *trace.Span {
+	parent := trace.FromContext(ctx)
+	if parent == nil {
+		return nil
+	}
+
+	operation := strings.ToUpper(strings.SplitN(strings.TrimSpace(query), " ", 2)[0])
+	_, span := trace.Start(trace.ContextWithSpan(ctx, parent), "db "+operation, trace.KIND_CLIENT)
+	span.SetAttribute("db.system", "mysql")
+	span.SetAttribute("db.statement", query)
+	return span
+}
+
+func endQuerySpan(span *trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	span.SetError(err)
+	span.End()
+}
+
+func namedValues(args []driver.NamedValue) ([]driver.Value, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, driver.ErrSkip
+		}
+		values[i] = arg.Value
+	}
+	return values, nil
+}
@@ -2,41 +2,62 @@ package repository
 
 import (
 	"github.com/gocms-io/gocms/domain/acl/group/group_repository"
+	"github.com/gocms-io/gocms/domain/acl/password_history/password_history_repository"
 	"github.com/gocms-io/gocms/domain/acl/permissions/permission_repository"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_repository"
+	"github.com/gocms-io/gocms/domain/acl/signing_key/signing_key_repository"
+	"github.com/gocms-io/gocms/domain/acl/trusted_device/trusted_device_repository"
+	"github.com/gocms-io/gocms/domain/acl/webauthn/webauthn_repository"
 	"github.com/gocms-io/gocms/domain/email/email_respository"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_repository"
 	"github.com/gocms-io/gocms/domain/runtime/runtime_repository"
 	"github.com/gocms-io/gocms/domain/secure_code/secure_code_repository"
 	"github.com/gocms-io/gocms/domain/setting/setting_repository"
+	"github.com/gocms-io/gocms/domain/user/profile/profile_repository"
+	"github.com/gocms-io/gocms/domain/user/user_data/user_data_repository"
 	"github.com/gocms-io/gocms/domain/user/user_repository"
 	"github.com/jmoiron/sqlx"
 )
 
 type RepositoriesGroup struct {
-	RuntimeRepository     runtime_repository.IRuntimeRepository
-	SettingsRepository    setting_repository.ISettingsRepository
-	UsersRepository       user_repository.IUserRepository
-	EmailRepository       email_respository.IEmailRepository
-	SecureCodeRepository  secure_code_repository.ISecureCodeRepository
-	PermissionsRepository permission_repository.IPermissionsRepository
-	GroupsRepository      group_repository.IGroupsRepository
-	PluginRepository      plugin_repository.IPluginRepository
-	dbx                   *sqlx.DB
+	RuntimeRepository         runtime_repository.IRuntimeRepository
+	SettingsRepository        setting_repository.ISettingsRepository
+	UsersRepository           user_repository.IUserRepository
+	EmailRepository           email_respository.IEmailRepository
+	SecureCodeRepository      secure_code_repository.ISecureCodeRepository
+	PermissionsRepository     permission_repository.IPermissionsRepository
+	GroupsRepository          group_repository.IGroupsRepository
+	PluginRepository          plugin_repository.IPluginRepository
+	RateLimitRepository       rate_limit_repository.IRateLimitRepository
+	UserDataRepository        user_data_repository.IUserDataRepository
+	ProfileRepository         profile_repository.IProfileRepository
+	SigningKeyRepository      signing_key_repository.ISigningKeyRepository
+	TrustedDeviceRepository   trusted_device_repository.ITrustedDeviceRepository
+	WebauthnRepository        webauthn_repository.IWebauthnRepository
+	PasswordHistoryRepository password_history_repository.IPasswordHistoryRepository
+	dbx                       *sqlx.DB
 }
 
 func DefaultRepositoriesGroup(dbx *sqlx.DB) *RepositoriesGroup {
 
 	// setup repositories
 	rg := &RepositoriesGroup{
-		dbx:                   dbx,
-		SettingsRepository:    setting_repository.DefaultSettingsRepository(dbx),
-		RuntimeRepository:     runtime_repository.DefaultRuntimeRepository(dbx),
-		UsersRepository:       user_repository.DefaultUserRepository(dbx),
-		EmailRepository:       email_respository.DefaultEmailRepository(dbx),
-		SecureCodeRepository:  secure_code_repository.DefaultSecureCodeRepository(dbx),
-		PermissionsRepository: permission_repository.DefaultPermissionsRepository(dbx),
-		GroupsRepository:      group_repository.DefaultGroupsRepository(dbx),
-		PluginRepository:      plugin_repository.DefaultPluginRepository(dbx),
+		dbx:                       dbx,
+		SettingsRepository:        setting_repository.DefaultSettingsRepository(dbx),
+		RuntimeRepository:         runtime_repository.DefaultRuntimeRepository(dbx),
+		UsersRepository:           user_repository.DefaultUserRepository(dbx),
+		EmailRepository:           email_respository.DefaultEmailRepository(dbx),
+		SecureCodeRepository:      secure_code_repository.DefaultSecureCodeRepository(dbx),
+		PermissionsRepository:     permission_repository.DefaultPermissionsRepository(dbx),
+		GroupsRepository:          group_repository.DefaultGroupsRepository(dbx),
+		PluginRepository:          plugin_repository.DefaultPluginRepository(dbx),
+		RateLimitRepository:       rate_limit_repository.DefaultRateLimitRepository(dbx),
+		UserDataRepository:        user_data_repository.DefaultUserDataRepository(dbx),
+		ProfileRepository:         profile_repository.DefaultProfileRepository(dbx),
+		SigningKeyRepository:      signing_key_repository.DefaultSigningKeyRepository(dbx),
+		TrustedDeviceRepository:   trusted_device_repository.DefaultTrustedDeviceRepository(dbx),
+		WebauthnRepository:        webauthn_repository.DefaultWebauthnRepository(dbx),
+		PasswordHistoryRepository: password_history_repository.DefaultPasswordHistoryRepository(dbx),
 	}
 	return rg
 }
@@ -4,31 +4,43 @@ import (
 	"github.com/gocms-io/gocms/context"
 	"github.com/gocms-io/gocms/domain/acl/access_control/access_control_service"
 	"github.com/gocms-io/gocms/domain/acl/authentication/authentication_service"
+	"github.com/gocms-io/gocms/domain/acl/group/group_service"
 	"github.com/gocms-io/gocms/domain/acl/permissions/permissions_service"
+	"github.com/gocms-io/gocms/domain/acl/rate_limit/rate_limit_service"
+	"github.com/gocms-io/gocms/domain/acl/signing_key/signing_key_service"
+	"github.com/gocms-io/gocms/domain/acl/trusted_device/trusted_device_service"
+	"github.com/gocms-io/gocms/domain/acl/webauthn/webauthn_service"
 	"github.com/gocms-io/gocms/domain/email/email_service"
 	"github.com/gocms-io/gocms/domain/health/health_service"
 	"github.com/gocms-io/gocms/domain/mail/mail_service"
 	"github.com/gocms-io/gocms/domain/plugin/plugin_services"
 	"github.com/gocms-io/gocms/domain/setting/setting_service"
+	"github.com/gocms-io/gocms/domain/user/profile/profile_service"
+	"github.com/gocms-io/gocms/domain/user/user_data/user_data_service"
 	"github.com/gocms-io/gocms/domain/user/user_service"
 	"github.com/gocms-io/gocms/init/database"
 	"github.com/gocms-io/gocms/init/repository"
 	"github.com/gocms-io/gocms/utility/log"
 	"time"
-	"github.com/gocms-io/gocms/domain/acl/group/group_service"
 )
 
 type ServicesGroup struct {
-	SettingsService   setting_service.ISettingsService
-	MailService       mail_service.IMailService
-	AuthService       authentication_service.IAuthService
-	PermissionService permission_service.IPermissionService
-	GroupService      group_service.IGroupService
-	UserService       user_service.IUserService
-	AclService        access_control_service.IAclService
-	EmailService      email_service.IEmailService
-	PluginsService    plugin_services.IPluginsService
-	HealthService     health_service.IHealthService
+	SettingsService      setting_service.ISettingsService
+	MailService          mail_service.IMailService
+	AuthService          authentication_service.IAuthService
+	PermissionService    permission_service.IPermissionService
+	GroupService         group_service.IGroupService
+	UserService          user_service.IUserService
+	AclService           access_control_service.IAclService
+	EmailService         email_service.IEmailService
+	PluginsService       plugin_services.IPluginsService
+	HealthService        health_service.IHealthService
+	RateLimitService     rate_limit_service.IRateLimitService
+	UserDataService      user_data_service.IUserDataService
+	ProfileService       profile_service.IProfileService
+	SigningKeyService    signing_key_service.ISigningKeyService
+	TrustedDeviceService trusted_device_service.ITrustedDeviceService
+	WebauthnService      webauthn_service.IWebauthnService
 }
 
 func DefaultServicesGroup(repositoriesGroup *repository.RepositoriesGroup, db *database.Database) *ServicesGroup {
@@ -46,22 +58,31 @@ func DefaultServicesGroup(repositoriesGroup *repository.RepositoriesGroup, db *d
 
 	// mail service
 	mailService := mail_service.DefaultMailService()
+	settingsService.RegisterRefreshCallback(mailService.RefreshSettings)
+
+	// token signing keys
+	signingKeyService := signing_key_service.DefaultSigningKeyService(repositoriesGroup)
 
 	// start permissions cache
 	aclService := access_control_service.DefaultAclService(repositoriesGroup)
-	aclService.RefreshPermissionsCache()
+	err := aclService.RefreshPermissionsCache()
+	if err != nil {
+		log.Fatalf("Error starting permissions cache: %s\n", err.Error())
+	}
 
 	permissionService := permission_service.DefaultPermissionService(repositoriesGroup)
 	groupService := group_service.DefaultGroupService(repositoriesGroup)
 
-	authService := authentication_service.DefaultAuthService(repositoriesGroup, mailService)
-	userService := user_service.DefaultUserService(repositoriesGroup, authService, mailService)
+	authService := authentication_service.DefaultAuthService(repositoriesGroup, mailService, signingKeyService)
 
 	// email service
 	emailService := email_service.DefaultEmailService(repositoriesGroup, mailService, authService)
 
+	// rate limit service
+	rateLimitService := rate_limit_service.DefaultRateLimitService(repositoriesGroup)
+
 	// plugins service
-	pluginsService := plugin_services.DefaultPluginsService(repositoriesGroup, aclService)
+	pluginsService := plugin_services.DefaultPluginsService(repositoriesGroup, aclService, rateLimitService)
 	pluginRelatedErr = pluginsService.RefreshInstalledPlugins()
 	if pluginRelatedErr != nil {
 		log.Errorf("Error finding plugins. Can't start plugin microservice: %s\n", pluginRelatedErr.Error())
@@ -69,20 +90,44 @@ func DefaultServicesGroup(repositoriesGroup *repository.RepositoriesGroup, db *d
 		pluginRelatedErr = pluginsService.StartPluginsService()
 	}
 
+	// user service deletes through plugins
+	userService := user_service.DefaultUserService(repositoriesGroup, authService, emailService, mailService, pluginsService)
+
+	// user data service
+	userDataService := user_data_service.DefaultUserDataService(repositoriesGroup, pluginsService, userService, mailService)
+
+	// profile service
+	profileService := profile_service.DefaultProfileService(repositoriesGroup)
+	userDataService.RegisterExporter("profile_fields", profileService.ExportUserProfile)
+
+	// trusted devices for two-factor
+	trustedDeviceService := trusted_device_service.DefaultTrustedDeviceService(repositoriesGroup, mailService)
+	userDataService.RegisterExporter("trusted_devices", trustedDeviceService.ExportUserDevices)
+
+	// passkeys
+	webauthnService := webauthn_service.DefaultWebauthnService(repositoriesGroup)
+	userDataService.RegisterExporter("webauthn_credentials", webauthnService.ExportUserCredentials)
+
 	// heath service
 	healthService := health_service.DefaultHealthService(db, pluginsService)
 
 	sg := &ServicesGroup{
-		SettingsService:   settingsService,
-		MailService:       mailService,
-		AuthService:       authService,
-		PermissionService: permissionService,
-		GroupService:      groupService,
-		UserService:       userService,
-		AclService:        aclService,
-		EmailService:      emailService,
-		PluginsService:    pluginsService,
-		HealthService:     healthService,
+		SettingsService:      settingsService,
+		MailService:          mailService,
+		AuthService:          authService,
+		PermissionService:    permissionService,
+		GroupService:         groupService,
+		UserService:          userService,
+		AclService:           aclService,
+		EmailService:         emailService,
+		PluginsService:       pluginsService,
+		HealthService:        healthService,
+		RateLimitService:     rateLimitService,
+		UserDataService:      userDataService,
+		ProfileService:       profileService,
+		SigningKeyService:    signingKeyService,
+		TrustedDeviceService: trustedDeviceService,
+		WebauthnService:      webauthnService,
 	}
 
 	return sg
@@ -2,27 +2,46 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/domain/user/user_middleware"
 	"github.com/gocms-io/gocms/init/controller"
 	"github.com/gocms-io/gocms/init/database"
 	"github.com/gocms-io/gocms/init/repository"
 	"github.com/gocms-io/gocms/init/service"
 	"github.com/gocms-io/gocms/utility/log"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"github.com/gocms-io/gocms/utility/graceful"
 	"github.com/gocms-io/gocms/utility/security"
+	"github.com/gocms-io/gocms/utility/tlsUtil"
+	"github.com/gocms-io/gocms/utility/trace"
 	"golang.org/x/sync/errgroup"
+	"sync"
+	"syscall"
+	"time"
+	stdcontext "context"
 )
 
 var (
-	egocms *Engine
-	igocms *InternalEngine
-	g      errgroup.Group
+	egocms         *Engine
+	igocms         *InternalEngine
+	redirectServer *http.Server
+	g              errgroup.Group
+)
+
+// listener names used to hand sockets to a new process
+const (
+	LISTENER_EXTERNAL = "external"
+	LISTENER_INTERNAL = "internal"
 )
 
 type Engine struct {
 	Gin               *gin.Engine
+	Server            *http.Server
 	ControllersGroup  *controller.ControllersGroup
 	ServicesGroup     *service.ServicesGroup
 	RepositoriesGroup *repository.RepositoriesGroup
@@ -31,6 +50,7 @@ type Engine struct {
 
 type InternalEngine struct {
 	Gin               *gin.Engine
+	Server            *http.Server
 	InternalControllersGroup  *controller.InternalControllersGroup
 	ServicesGroup     *service.ServicesGroup
 	RepositoriesGroup *repository.RepositoriesGroup
@@ -38,7 +58,13 @@ type InternalEngine struct {
 }
 
 type gocmsFlags struct {
-
+	port            string
+	msPort          string
+	noExternal      bool
+	runInternal     bool
+	genMasterKey    bool
+	rotateMasterKey bool
+	pluginSecret    string
 }
 
 type gocmsRuntimeSettings struct {
@@ -74,8 +100,12 @@ func Default() (e *Engine, ie *InternalEngine) {
 	case log.LOG_LEVEL_DEBUG:
 		gin.SetMode(gin.DebugMode)
 	}
-	r := gin.Default()
-	ir := gin.Default()
+	// gin's own logger is replaced so access lines use the same structured output
+	httpErrors := log.Subsystem(user_middleware.LOG_SUBSYSTEM_HTTP).ErrorWriter()
+	r := gin.New()
+	r.Use(user_middleware.RequestLog(), gin.RecoveryWithWriter(httpErrors))
+	ir := gin.New()
+	ir.Use(user_middleware.RequestLog(), gin.RecoveryWithWriter(httpErrors))
 
 	// setup repositories
 	rg := repository.DefaultRepositoriesGroup(db.SQL.Dbx)
@@ -108,67 +138,287 @@ func Default() (e *Engine, ie *InternalEngine) {
 	return e, ie
 }
 
-func (engine *Engine) Listen(uri string) error {
+// Listen opens the socket, or takes it over from the previous process, and serves tls when a certificate is configured.
+// Pass the listener to serve to start handling requests.
+func (engine *Engine) Listen(uri string) (net.Listener, error) {
 
-	err := http.ListenAndServe(uri, engine.Gin)
-	if err == nil {
+	ln, err := graceful.Listen(LISTENER_EXTERNAL, uri)
+	if err != nil {
+		return nil, err
+	}
+	engine.Server = &http.Server{Handler: engine.Gin}
+
+	env := context.Config.EnvVars
+	if env.TlsEnabled() {
+		ln, engine.Server.TLSConfig, err = listenTLS(ln, &tlsUtil.Options{
+			CertFile:     env.TlsCertFile,
+			KeyFile:      env.TlsKeyFile,
+			MinVersion:   env.TlsMinVersion,
+			CipherSuites: env.TlsCipherSuites,
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("Listening with TLS on: %v\n", uri)
+	} else {
 		log.Infof("Listening on: %v\n", uri)
 	}
-	return err
+	return ln, nil
 
 }
 
-func (engine *InternalEngine) Listen(uri string) error {
+// Listen is the same as Engine.Listen. When a client CA is configured plugins can authenticate with a client certificate.
+func (engine *InternalEngine) Listen(uri string) (net.Listener, error) {
 
-	err := http.ListenAndServe(uri, engine.Gin)
-	if err == nil {
+	ln, err := graceful.Listen(LISTENER_INTERNAL, uri)
+	if err != nil {
+		return nil, err
+	}
+	engine.Server = &http.Server{Handler: engine.Gin}
+
+	env := context.Config.EnvVars
+	if env.InternalTlsEnabled() {
+		ln, engine.Server.TLSConfig, err = listenTLS(ln, &tlsUtil.Options{
+			CertFile:          env.InternalTlsCertFile,
+			KeyFile:           env.InternalTlsKeyFile,
+			ClientCAFile:      env.InternalTlsClientCAFile,
+			RequireClientCert: env.InternalTlsRequireClientCert,
+			MinVersion:        env.TlsMinVersion,
+			CipherSuites:      env.TlsCipherSuites,
+		})
+		if err != nil {
+			return nil, err
+		}
+		log.Infof("(Internal API) Listening with TLS on: %v\n", uri)
+	} else {
 		log.Infof("(Internal API) Listening on: %v\n", uri)
 	}
-	return err
+	return ln, nil
 
 }
 
+// serve blocks until the server fails or is shut down. A shut down isn't an error.
+func serve(server *http.Server, ln net.Listener) error {
+	err := server.Serve(ln)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
 func main() {
 
+	// parse flags before startup so commands can run without starting the server
+	flags := parseFlags()
+
+	// print a new master key and exit
+	if flags.genMasterKey {
+		key, err := security.GenerateMasterKey()
+		if err != nil {
+			log.Fatalf("Error generating master key: %v\n", err.Error())
+		}
+		fmt.Println(key)
+		return
+	}
+
+	// rotate the master key and exit
+	if flags.rotateMasterKey {
+		db := database.DefaultSQL()
+		db.SQL.MigrateSql()
+		err := security.RotateMasterKey(db.SQL.Dbx)
+		if err != nil {
+			log.Fatalf("Error rotating master key: %v\n", err.Error())
+		}
+		log.Infof("Master key rotated. Set %v to the new key before restarting.\n", security.ENV_MASTER_KEY)
+		return
+	}
+
+	// print the secret an external plugin identifies itself with and exit
+	if flags.pluginSecret != "" {
+		db := database.DefaultSQL()
+		db.SQL.MigrateSql()
+		security.CheckOrGenRSAKeysAndSecrets(db.SQL.Dbx)
+		msSecret, err := security.LoadMicroserviceSecret(db.SQL.Dbx)
+		if err != nil {
+			log.Fatalf("Error getting microservice secret: %v\n", err.Error())
+		}
+		fmt.Println(security.PluginSecret(msSecret, flags.pluginSecret))
+		return
+	}
+
 	// startup defaults
 	egocms, igocms = Default()
 
 	// get ports
-	rs := getRuntimeSettings()
+	rs := getRuntimeSettings(flags)
+
+	// servers report here if they stop unexpectedly
+	failed := make(chan error, 3)
 
 	// skip external if needed
 	if !rs.noExtneralServices {
+		ln, err := egocms.Listen(":" + rs.port)
+		if err != nil {
+			log.Fatalf("Error listening on %v: %v\n", rs.port, err.Error())
+		}
+		g.Go(func() error {
+			return reportFailure(failed, serve(egocms.Server, ln))
+		})
+	}
+
+	// redirect plain http to the public engine
+	if !rs.noExtneralServices && context.Config.EnvVars.TlsEnabled() && context.Config.EnvVars.TlsRedirectPort != "" {
+		var ln net.Listener
+		var err error
+		redirectServer, ln, err = listenRedirect(":"+context.Config.EnvVars.TlsRedirectPort, rs.port)
+		if err != nil {
+			log.Fatalf("Error listening on %v: %v\n", context.Config.EnvVars.TlsRedirectPort, err.Error())
+		}
 		g.Go(func() error {
-			return egocms.Listen(":" + rs.port)
+			return reportFailure(failed, serve(redirectServer, ln))
 		})
 	}
 
 	// run internal if needed
 	if rs.runInternalServices {
+		ln, err := igocms.Listen(":" + rs.msPort)
+		if err != nil {
+			log.Fatalf("Error listening on %v: %v\n", rs.msPort, err.Error())
+		}
 		g.Go(func() error {
-			return igocms.Listen(":" + rs.msPort)
+			return reportFailure(failed, serve(igocms.Server, ln))
 		})
 	}
 
-	if err := g.Wait(); err != nil {
-		log.Criticalf("Error launching services: %v\n", err.Error())
+	// nothing to serve
+	if rs.noExtneralServices && !rs.runInternalServices {
+		log.Infof("No services to run\n")
+		shutdown(context.Config.EnvVars.ShutdownTimeout)
+		return
+	}
+
+	// let the process we took over from know it can stop
+	if err := graceful.Ready(); err != nil {
+		log.Errorf("Error signalling ready to previous process: %v\n", err.Error())
+	}
+
+	// wait for a signal or for a server to fail
+	signals := make(chan os.Signal, 1)
+	notify := []os.Signal{os.Interrupt, syscall.SIGTERM}
+	notify = append(notify, graceful.ReloadSignals...)
+	notify = append(notify, graceful.CertificateSignals...)
+	signal.Notify(signals, notify...)
+	var serveErr error
+	for running := true; running; {
+		select {
+		case sig := <-signals:
+			if sig == os.Interrupt || sig == syscall.SIGTERM {
+				log.Infof("Received %v, shutting down\n", sig)
+				running = false
+				continue
+			}
+			if isSignal(sig, graceful.CertificateSignals) {
+				log.Infof("Received %v, reloading certificates\n", sig)
+				reloadCertificates()
+				continue
+			}
+			// any other signal is a reload
+			log.Infof("Received %v, starting a new process to take over\n", sig)
+			if err := graceful.Handover(); err != nil {
+				log.Errorf("Error handing over to new process, still serving: %v\n", err.Error())
+				continue
+			}
+			log.Infof("New process is serving, shutting down\n")
+			running = false
+		case serveErr = <-failed:
+			running = false
+		}
+	}
+
+	shutdown(context.Config.EnvVars.ShutdownTimeout)
+	g.Wait()
+
+	if serveErr != nil {
+		log.Fatalf("Error launching services: %v\n", serveErr.Error())
 	}
 }
 
+func isSignal(sig os.Signal, signals []os.Signal) bool {
+	for _, s := range signals {
+		if s == sig {
+			return true
+		}
+	}
+	return false
+}
 
-func getRuntimeSettings() *gocmsRuntimeSettings {
+func reportFailure(failed chan<- error, err error) error {
+	if err != nil {
+		failed <- err
+	}
+	return err
+}
+
+// shutdown drains both engines, then stops background work and plugins.
+// Plugins are stopped last so requests being proxied to them can finish.
+func shutdown(timeout time.Duration) {
+	ctx, cancel := stdcontext.WithTimeout(stdcontext.Background(), timeout)
+	defer cancel()
+
+	var drain sync.WaitGroup
+	for _, server := range []*http.Server{egocms.Server, igocms.Server, redirectServer} {
+		if server == nil {
+			continue
+		}
+		drain.Add(1)
+		go func(server *http.Server) {
+			defer drain.Done()
+			if err := server.Shutdown(ctx); err != nil {
+				log.Errorf("Error draining requests, closing remaining connections: %v\n", err.Error())
+				server.Close()
+			}
+		}(server)
+	}
+	drain.Wait()
 
+	context.Schedule.Stop()
+
+	if err := egocms.ServicesGroup.PluginsService.StopPlugins(timeout); err != nil {
+		log.Errorf("Error stopping plugins: %v\n", err.Error())
+	}
+
+	trace.Shutdown()
+	log.Infof("Shutdown complete\n")
+	log.Close()
+}
+
+func parseFlags() *gocmsFlags {
 
 	// define flags
 	portFlag := flag.String("port", "", "port to run on. Overrides all.")
 	msPortFlag := flag.String("msPort", "", "msPort to run on. Overrides all.")
 	noExternalServiceFlag := flag.Bool("noExternal", false, "noExternal when this flag is set gocms will not run external services.")
 	runInternalServiceFlag := flag.Bool("runInternal", false, "runInternal when this flag is set gocms will run internal services.")
+	genMasterKeyFlag := flag.Bool("genMasterKey", false, "genMasterKey print a new master key for encrypting secret settings and exit.")
+	rotateMasterKeyFlag := flag.Bool("rotateMasterKey", false, "rotateMasterKey re-encrypt secret settings with GOCMS_NEW_MASTER_KEY and exit.")
+	pluginSecretFlag := flag.String("pluginSecret", "", "pluginSecret print the secret for the given plugin id and exit. External plugins set it as GOCMS_PLUGIN_SECRET.")
 	flag.Parse()
 
+	return &gocmsFlags{
+		port:            *portFlag,
+		msPort:          *msPortFlag,
+		noExternal:      *noExternalServiceFlag,
+		runInternal:     *runInternalServiceFlag,
+		genMasterKey:    *genMasterKeyFlag,
+		rotateMasterKey: *rotateMasterKeyFlag,
+		pluginSecret:    *pluginSecretFlag,
+	}
+}
+
+func getRuntimeSettings(flags *gocmsFlags) *gocmsRuntimeSettings {
 
-	noExternalService := *noExternalServiceFlag
-	runInternalService := *runInternalServiceFlag
+	noExternalService := flags.noExternal
+	runInternalService := flags.runInternal
 
 	///////// PORT ///////////
 	// get server port in order of importance
@@ -180,8 +430,8 @@ func getRuntimeSettings() *gocmsRuntimeSettings {
 		port = portEnv
 	}
 	// 1. flag
-	if *portFlag != "" {
-		port = *portFlag
+	if flags.port != "" {
+		port = flags.port
 	}
 	// 0. if still unset
 	if port == "" {
@@ -199,8 +449,8 @@ func getRuntimeSettings() *gocmsRuntimeSettings {
 	}
 	// 1. flag
 	// check for msPort flag and override all
-	if *msPortFlag != "" {
-		msPort = *msPortFlag
+	if flags.msPort != "" {
+		msPort = flags.msPort
 	}
 	// 0. if still unset
 	if msPort == "" {
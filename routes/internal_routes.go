@@ -3,6 +3,7 @@ package routes
 import "github.com/gin-gonic/gin"
 
 const Internal = "Internal"
+const INTERNAL_PREFIX = "/internal/api"
 
 // Routes are the most basic organizational option available. They define urls as well as what level authentication is required.
 type InternalRoutes struct {
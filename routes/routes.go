@@ -20,4 +20,6 @@ type Routes struct {
 	Auth *gin.RouterGroup
 	// NoRoute is not currently available to plugins.
 	NoRoute func(...gin.HandlerFunc)
+	// LookupRoute finds the registered route and route group a request would reach. It is only complete once every route is registered.
+	LookupRoute func(method string, path string) (route string, group string, ok bool)
 }
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"crypto/tls"
+	"github.com/gocms-io/gocms/context"
+	"github.com/gocms-io/gocms/utility/graceful"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/tlsUtil"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const LISTENER_REDIRECT = "redirect"
+
+// how often certificate files are checked for changes
+const certificateCheckInterval = 30 * time.Second
+
+var (
+	certificateReloaders   []*tlsUtil.Reloader
+	certificateReloadersMu sync.Mutex
+)
+
+// listenTLS wraps ln so connections are served over tls with certificates that reload when the files change
+func listenTLS(ln net.Listener, options *tlsUtil.Options) (net.Listener, *tls.Config, error) {
+	reloader, err := tlsUtil.NewReloader(options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	certificateReloadersMu.Lock()
+	if len(certificateReloaders) == 0 {
+		context.Schedule.AddTicker(certificateCheckInterval, reloadChangedCertificates)
+	}
+	certificateReloaders = append(certificateReloaders, reloader)
+	certificateReloadersMu.Unlock()
+
+	config := reloader.Config()
+	return tls.NewListener(ln, config), config, nil
+}
+
+// reloadCertificates reads every certificate again, ie. on SIGUSR1
+func reloadCertificates() {
+	certificateReloadersMu.Lock()
+	defer certificateReloadersMu.Unlock()
+	for _, reloader := range certificateReloaders {
+		if err := reloader.Reload(); err != nil {
+			log.Errorf("Error reloading certificate, still serving the previous one: %v\n", err.Error())
+		}
+	}
+	if len(certificateReloaders) > 0 {
+		log.Infof("Reloaded %d certificates\n", len(certificateReloaders))
+	}
+}
+
+func reloadChangedCertificates() {
+	certificateReloadersMu.Lock()
+	defer certificateReloadersMu.Unlock()
+	for _, reloader := range certificateReloaders {
+		if !reloader.Changed() {
+			continue
+		}
+		if err := reloader.Reload(); err != nil {
+			log.Errorf("Error reloading changed certificate, still serving the previous one: %v\n", err.Error())
+		} else {
+			log.Infof("Reloaded changed certificate\n")
+		}
+	}
+}
+
+// listenRedirect sends plain http requests to the same url over https on httpsPort
+func listenRedirect(uri string, httpsPort string) (*http.Server, net.Listener, error) {
+	ln, err := graceful.Listen(LISTENER_REDIRECT, uri)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Infof("Redirecting http on %v to https\n", uri)
+
+	server := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host := strings.Trim(r.Host, "[]")
+			if h, _, err := net.SplitHostPort(r.Host); err == nil {
+				host = h
+			}
+			if httpsPort != "443" {
+				host = net.JoinHostPort(host, httpsPort)
+			} else if strings.Contains(host, ":") {
+				host = "[" + host + "]"
+			}
+
+			// 308 keeps the method and body of posts
+			code := http.StatusPermanentRedirect
+			if r.Method == http.MethodGet || r.Method == http.MethodHead {
+				code = http.StatusMovedPermanently
+			}
+			http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+		}),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	return server, ln, nil
+}
@@ -0,0 +1,16 @@
+package api_utility
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/context/consts"
+)
+
+// GetDeviceIdFromContext returns the id of the trusted device making the request. Only set when two-factor is enabled.
+func GetDeviceIdFromContext(c *gin.Context) (string, bool) {
+	if deviceContext, ok := c.Get(consts.DEVICE_ID_KEY_FOR_GIN_CONTEXT); ok {
+		if deviceId, ok := deviceContext.(string); ok {
+			return deviceId, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,27 @@
+package api_utility
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/utility/log"
+	"github.com/gocms-io/gocms/utility/trace"
+)
+
+// GetLoggerFromContext returns a logger that tags lines with the request id and the authenticated user
+func GetLoggerFromContext(c *gin.Context) *log.Logger {
+	return WithRequestContext(log.With(), c)
+}
+
+// WithRequestContext adds the request id, authenticated user and trace to an existing logger, ie. a subsystem logger
+func WithRequestContext(logger *log.Logger, c *gin.Context) *log.Logger {
+	var keyvals []interface{}
+	if requestId, ok := GetRequestIdFromContext(c); ok {
+		keyvals = append(keyvals, log.REQUEST_ID, requestId)
+	}
+	if user, ok := GetUserFromContext(c); ok {
+		keyvals = append(keyvals, log.USER_ID, user.Id)
+	}
+	if span := trace.FromContext(c.Request.Context()); span != nil && span.SpanContext().Sampled {
+		keyvals = append(keyvals, log.TRACE_ID, span.SpanContext().TraceID.String())
+	}
+	return logger.With(keyvals...)
+}
@@ -0,0 +1,16 @@
+package api_utility
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/context/consts"
+)
+
+// GetRequestIdFromContext returns the uuid assigned to the request by the uuid middleware
+func GetRequestIdFromContext(c *gin.Context) (string, bool) {
+	if requestContext, ok := c.Get(consts.REQUEST_ID_KEY_FOR_GIN_CONTEXT); ok {
+		if requestId, ok := requestContext.(string); ok {
+			return requestId, true
+		}
+	}
+	return "", false
+}
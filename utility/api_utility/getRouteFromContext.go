@@ -0,0 +1,137 @@
+package api_utility
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/gocms-io/gocms/context/consts"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// MatchRoute puts the registered path (ie. /api/user/devices/:deviceId) the request matched on the context. The vendored gin
+// can't report the matched path so it is looked up in the engine's route table, which is read on the first request once every
+// route has been registered. Add it before any middleware that needs the route.
+func MatchRoute(engine *gin.Engine) gin.HandlerFunc {
+	table := routeTableFor(engine)
+	return func(c *gin.Context) {
+		if route, _, ok := table.match(c.Request.Method, c.Request.URL.Path); ok {
+			c.Set(consts.ROUTE_KEY_FOR_GIN_CONTEXT, route)
+		}
+		c.Next()
+	}
+}
+
+// LookupRoute finds the registered path and route group a request would reach without running it. The group is empty for
+// routes registered outside a group added with AddRouteGroupHandler. Only call it once every route has been registered.
+func LookupRoute(engine *gin.Engine, method string, path string) (string, string, bool) {
+	return routeTableFor(engine).match(method, path)
+}
+
+// AddRouteGroupHandler records the handler that labels a route group so the route table can find the group among a route's
+// handlers. Handlers are told apart by their function so each group needs its own function, not a shared closure.
+// Call it before the first request.
+func AddRouteGroupHandler(group string, handler gin.HandlerFunc) {
+	routeGroupHandlers[reflect.ValueOf(handler).Pointer()] = group
+}
+
+// GetRouteFromContext returns the registered path set by MatchRoute. Requests that didn't match a route have none.
+func GetRouteFromContext(c *gin.Context) (string, bool) {
+	if routeContext, ok := c.Get(consts.ROUTE_KEY_FOR_GIN_CONTEXT); ok {
+		if route, ok := routeContext.(string); ok {
+			return route, true
+		}
+	}
+	return "", false
+}
+
+var (
+	routeTables        sync.Map
+	routeGroupHandlers = make(map[uintptr]string)
+)
+
+func routeTableFor(engine *gin.Engine) *routeTable {
+	table, _ := routeTables.LoadOrStore(engine, &routeTable{engine: engine})
+	return table.(*routeTable)
+}
+
+type routeTable struct {
+	engine *gin.Engine
+	once   sync.Once
+	routes map[string][]tableRoute
+}
+
+type tableRoute struct {
+	path  string
+	group string
+}
+
+// match finds the route for the path. The router doesn't allow a static segment and a param in the same place so at most one
+// route can match.
+func (rt *routeTable) match(method string, path string) (string, string, bool) {
+	rt.once.Do(rt.load)
+
+	for _, route := range rt.routes[method] {
+		if routeMatches(route.path, path) {
+			return route.path, route.group, true
+		}
+	}
+	return "", "", false
+}
+
+// load walks the engine's route trees. engine.Routes() only names each route's last handler and the group is found among
+// the others, so the vendored gin's trees are read directly.
+func (rt *routeTable) load() {
+	rt.routes = make(map[string][]tableRoute)
+	trees := reflect.ValueOf(rt.engine).Elem().FieldByName("trees")
+	for i := 0; i < trees.Len(); i++ {
+		tree := trees.Index(i)
+		rt.loadNode(tree.FieldByName("method").String(), "", tree.FieldByName("root"))
+	}
+}
+
+func (rt *routeTable) loadNode(method string, path string, node reflect.Value) {
+	if node.IsNil() {
+		return
+	}
+	node = node.Elem()
+	path += node.FieldByName("path").String()
+
+	if handlers := node.FieldByName("handlers"); handlers.Len() > 0 {
+		route := tableRoute{path: path}
+		for i := 0; i < handlers.Len(); i++ {
+			if group, ok := routeGroupHandlers[handlers.Index(i).Pointer()]; ok {
+				route.group = group
+			}
+		}
+		rt.routes[method] = append(rt.routes[method], route)
+	}
+
+	children := node.FieldByName("children")
+	for i := 0; i < children.Len(); i++ {
+		rt.loadNode(method, path, children.Index(i))
+	}
+}
+
+func routeMatches(route string, path string) bool {
+	routeSegments := strings.Split(route, "/")
+	pathSegments := strings.Split(path, "/")
+	for i, segment := range routeSegments {
+		// catch all params hold the rest of the path
+		if strings.HasPrefix(segment, "*") {
+			return i <= len(pathSegments)
+		}
+		if i >= len(pathSegments) {
+			return false
+		}
+		if strings.HasPrefix(segment, ":") {
+			if pathSegments[i] == "" {
+				return false
+			}
+			continue
+		}
+		if segment != pathSegments[i] {
+			return false
+		}
+	}
+	return len(routeSegments) == len(pathSegments)
+}
@@ -0,0 +1,246 @@
+// Package cbor decodes the subset of CBOR (RFC 7049) used by WebAuthn authenticators.
+// Integers decode to int64, byte strings to []byte, text to string, arrays to []interface{} and maps to map[interface{}]interface{}.
+// Tags are dropped and their content returned. Indefinite lengths aren't supported.
+package cbor
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+const maxDepth = 16
+
+const (
+	majorUnsigned = 0
+	majorNegative = 1
+	majorBytes    = 2
+	majorText     = 3
+	majorArray    = 4
+	majorMap      = 5
+	majorTag      = 6
+	majorSimple   = 7
+)
+
+var ErrUnexpectedEnd = errors.New("cbor: unexpected end of data")
+var ErrIndefiniteLength = errors.New("cbor: indefinite length items aren't supported")
+var ErrTooDeep = errors.New("cbor: items nested too deeply")
+var ErrTrailingData = errors.New("cbor: trailing data after item")
+var ErrIntegerOverflow = errors.New("cbor: integer doesn't fit in int64")
+
+// Decode decodes a single item that must use all of data.
+func Decode(data []byte) (interface{}, error) {
+	value, rest, err := DecodeFirst(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, ErrTrailingData
+	}
+	return value, nil
+}
+
+// DecodeFirst decodes the first item in data and returns the bytes that follow it.
+func DecodeFirst(data []byte) (interface{}, []byte, error) {
+	d := &decoder{data: data}
+	value, err := d.decode(0)
+	if err != nil {
+		return nil, nil, err
+	}
+	return value, d.data[d.pos:], nil
+}
+
+type decoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *decoder) decode(depth int) (interface{}, error) {
+	if depth > maxDepth {
+		return nil, ErrTooDeep
+	}
+
+	major, info, err := d.readHead()
+	if err != nil {
+		return nil, err
+	}
+
+	// simple values and floats use the additional info directly
+	if major == majorSimple {
+		return d.decodeSimple(info)
+	}
+
+	arg, err := d.readArgument(info)
+	if err != nil {
+		return nil, err
+	}
+
+	switch major {
+	case majorUnsigned:
+		if arg > math.MaxInt64 {
+			return nil, ErrIntegerOverflow
+		}
+		return int64(arg), nil
+	case majorNegative:
+		if arg > math.MaxInt64 {
+			return nil, ErrIntegerOverflow
+		}
+		return -1 - int64(arg), nil
+	case majorBytes:
+		b, err := d.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return append(make([]byte, 0, len(b)), b...), nil
+	case majorText:
+		b, err := d.readBytes(arg)
+		if err != nil {
+			return nil, err
+		}
+		return string(b), nil
+	case majorArray:
+		// every item takes at least one byte
+		if arg > uint64(len(d.data)-d.pos) {
+			return nil, ErrUnexpectedEnd
+		}
+		array := make([]interface{}, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			item, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		return array, nil
+	case majorMap:
+		if arg > uint64(len(d.data)-d.pos)/2 {
+			return nil, ErrUnexpectedEnd
+		}
+		m := make(map[interface{}]interface{}, arg)
+		for i := uint64(0); i < arg; i++ {
+			key, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			switch key.(type) {
+			case int64, string, bool:
+			default:
+				return nil, errors.New("cbor: unsupported map key type")
+			}
+			value, err := d.decode(depth + 1)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = value
+		}
+		return m, nil
+	default: // majorTag
+		return d.decode(depth + 1)
+	}
+}
+
+func (d *decoder) readHead() (byte, byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, 0, ErrUnexpectedEnd
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b >> 5, b & 0x1f, nil
+}
+
+func (d *decoder) readArgument(info byte) (uint64, error) {
+	switch {
+	case info < 24:
+		return uint64(info), nil
+	case info == 24:
+		b, err := d.readBytes(1)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(b[0]), nil
+	case info == 25:
+		b, err := d.readBytes(2)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case info == 26:
+		b, err := d.readBytes(4)
+		if err != nil {
+			return 0, err
+		}
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	case info == 27:
+		b, err := d.readBytes(8)
+		if err != nil {
+			return 0, err
+		}
+		return binary.BigEndian.Uint64(b), nil
+	case info == 31:
+		return 0, ErrIndefiniteLength
+	}
+	return 0, errors.New("cbor: malformed item")
+}
+
+func (d *decoder) decodeSimple(info byte) (interface{}, error) {
+	switch info {
+	case 20:
+		return false, nil
+	case 21:
+		return true, nil
+	case 22, 23: // null and undefined
+		return nil, nil
+	case 25:
+		b, err := d.readBytes(2)
+		if err != nil {
+			return nil, err
+		}
+		return halfToFloat64(binary.BigEndian.Uint16(b)), nil
+	case 26:
+		b, err := d.readBytes(4)
+		if err != nil {
+			return nil, err
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), nil
+	case 27:
+		b, err := d.readBytes(8)
+		if err != nil {
+			return nil, err
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), nil
+	case 31:
+		return nil, ErrIndefiniteLength
+	}
+	return nil, errors.New("cbor: unsupported simple value")
+}
+
+func (d *decoder) readBytes(n uint64) ([]byte, error) {
+	if n > uint64(len(d.data)-d.pos) {
+		return nil, ErrUnexpectedEnd
+	}
+	b := d.data[d.pos : d.pos+int(n)]
+	d.pos += int(n)
+	return b, nil
+}
+
+func halfToFloat64(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 31:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		return -f
+	}
+	return f
+}
@@ -0,0 +1,139 @@
+package cbor
+
+import (
+	"encoding/hex"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func mustHex(t *testing.T, s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad hex %v: %v", s, err)
+	}
+	return b
+}
+
+// examples from RFC 7049 appendix A
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		in   string
+		want interface{}
+	}{
+		{"00", int64(0)},
+		{"17", int64(23)},
+		{"1818", int64(24)},
+		{"1903e8", int64(1000)},
+		{"1a000f4240", int64(1000000)},
+		{"1b000000e8d4a51000", int64(1000000000000)},
+		{"20", int64(-1)},
+		{"3903e7", int64(-1000)},
+		{"f4", false},
+		{"f5", true},
+		{"f6", nil},
+		{"f93c00", float64(1)},
+		{"f9c400", float64(-4)},
+		{"fa47c35000", float64(100000)},
+		{"fb3ff199999999999a", 1.1},
+		{"40", []byte{}},
+		{"4401020304", []byte{1, 2, 3, 4}},
+		{"60", ""},
+		{"6449455446", "IETF"},
+		{"80", []interface{}{}},
+		{"83010203", []interface{}{int64(1), int64(2), int64(3)}},
+		{"8301820203820405", []interface{}{int64(1), []interface{}{int64(2), int64(3)}, []interface{}{int64(4), int64(5)}}},
+		{"a201020304", map[interface{}]interface{}{int64(1): int64(2), int64(3): int64(4)}},
+		{"a26161016162820203", map[interface{}]interface{}{"a": int64(1), "b": []interface{}{int64(2), int64(3)}}},
+		{"c074323031332d30332d32315432303a30343a30305a", "2013-03-21T20:04:00Z"},
+	}
+
+	for _, test := range tests {
+		got, err := Decode(mustHex(t, test.in))
+		if err != nil {
+			t.Errorf("Decode(%v) error: %v", test.in, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Decode(%v) = %#v, want %#v", test.in, got, test.want)
+		}
+	}
+}
+
+func TestDecodeHalfFloats(t *testing.T) {
+	got, err := Decode(mustHex(t, "f97c00"))
+	if err != nil || got != math.Inf(1) {
+		t.Errorf("Decode(f97c00) = %v, %v, want +Inf", got, err)
+	}
+	got, err = Decode(mustHex(t, "f97e00"))
+	if f, ok := got.(float64); err != nil || !ok || !math.IsNaN(f) {
+		t.Errorf("Decode(f97e00) = %v, %v, want NaN", got, err)
+	}
+	got, err = Decode(mustHex(t, "f90001"))
+	if err != nil || got != 5.960464477539063e-8 {
+		t.Errorf("Decode(f90001) = %v, %v, want 5.960464477539063e-8", got, err)
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error
+	}{
+		{"", ErrUnexpectedEnd},
+		{"18", ErrUnexpectedEnd},
+		{"1903", ErrUnexpectedEnd},
+		{"4401", ErrUnexpectedEnd},
+		{"6449", ErrUnexpectedEnd},
+		{"8301", ErrUnexpectedEnd},
+		{"a101", ErrUnexpectedEnd},
+		{"9bffffffffffffffff", ErrUnexpectedEnd},
+		{"bbffffffffffffffff", ErrUnexpectedEnd},
+		{"5bffffffffffffffff", ErrUnexpectedEnd},
+		{"9f01ff", ErrIndefiniteLength},
+		{"5f", ErrIndefiniteLength},
+		{"1b8000000000000000", ErrIntegerOverflow},
+		{"3b8000000000000000", ErrIntegerOverflow},
+		{"0000", ErrTrailingData},
+		{"818181818181818181818181818181818181", ErrTooDeep},
+	}
+
+	for _, test := range tests {
+		_, err := Decode(mustHex(t, test.in))
+		if err != test.want {
+			t.Errorf("Decode(%v) error = %v, want %v", test.in, err, test.want)
+		}
+	}
+
+	// malformed heads, unsupported simple values and map keys fail without a sentinel
+	for _, in := range []string{"1c", "f0", "f8", "a1400102", "a1800102"} {
+		if _, err := Decode(mustHex(t, in)); err == nil {
+			t.Errorf("Decode(%v) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestDecodeFirst(t *testing.T) {
+	value, rest, err := DecodeFirst(mustHex(t, "a10102ff00"))
+	if err != nil {
+		t.Fatalf("DecodeFirst error: %v", err)
+	}
+	if !reflect.DeepEqual(value, map[interface{}]interface{}{int64(1): int64(2)}) {
+		t.Errorf("DecodeFirst value = %#v", value)
+	}
+	if hex.EncodeToString(rest) != "ff00" {
+		t.Errorf("DecodeFirst rest = %x, want ff00", rest)
+	}
+}
+
+func TestDecodeCopiesBytes(t *testing.T) {
+	data := mustHex(t, "4401020304")
+	got, err := Decode(data)
+	if err != nil {
+		t.Fatalf("Decode error: %v", err)
+	}
+	data[1] = 9
+	if got.([]byte)[0] != 1 {
+		t.Errorf("decoded bytes share memory with the input")
+	}
+}
@@ -0,0 +1,107 @@
+// Package cose parses the COSE (RFC 8152) public keys registered by WebAuthn authenticators and verifies signatures made with them.
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"github.com/gocms-io/gocms/utility/cbor"
+	"github.com/gocms-io/gocms/utility/errors"
+	"math/big"
+)
+
+// supported algorithm identifiers
+const (
+	ALG_ES256 = -7
+	ALG_EDDSA = -8
+	ALG_RS256 = -257
+)
+
+// key parameters
+const (
+	keyType    = 1
+	keyAlg     = 3
+	keyCrv     = -1
+	keyX       = -2
+	keyY       = -3
+	keyRsaN    = -1
+	keyRsaE    = -2
+	ktyOkp     = 1
+	ktyEc2     = 2
+	ktyRsa     = 3
+	crvP256    = 1
+	crvEd25519 = 6
+)
+
+const minRsaKeyBits = 2048
+
+// ParseKey returns the public key and algorithm of a COSE encoded key.
+func ParseKey(raw []byte) (crypto.PublicKey, int64, error) {
+	decoded, err := cbor.Decode(raw)
+	if err != nil {
+		return nil, 0, errors.New("Credential key isn't valid cbor.")
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, 0, errors.New("Credential key isn't a map.")
+	}
+	kty, _ := key[int64(keyType)].(int64)
+	alg, _ := key[int64(keyAlg)].(int64)
+
+	switch alg {
+	case ALG_ES256:
+		crv, _ := key[int64(keyCrv)].(int64)
+		x, _ := key[int64(keyX)].([]byte)
+		y, _ := key[int64(keyY)].([]byte)
+		if kty != ktyEc2 || crv != crvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, errors.New("Credential key isn't a P-256 key.")
+		}
+		point := append(append([]byte{4}, x...), y...)
+		publicKey, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil, 0, errors.New("Credential key isn't on the P-256 curve.")
+		}
+		return publicKey, alg, nil
+	case ALG_RS256:
+		n, _ := key[int64(keyRsaN)].([]byte)
+		e, _ := key[int64(keyRsaE)].([]byte)
+		if kty != ktyRsa || len(n) == 0 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, errors.New("Credential key isn't an RSA key.")
+		}
+		publicKey := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		if publicKey.N.BitLen() < minRsaKeyBits {
+			return nil, 0, errors.New("Credential RSA key is too small.")
+		}
+		return publicKey, alg, nil
+	case ALG_EDDSA:
+		crv, _ := key[int64(keyCrv)].(int64)
+		x, _ := key[int64(keyX)].([]byte)
+		if kty != ktyOkp || crv != crvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, errors.New("Credential key isn't an Ed25519 key.")
+		}
+		return ed25519.PublicKey(x), alg, nil
+	}
+
+	return nil, 0, errors.New("Credential key algorithm isn't supported.")
+}
+
+// Verify checks a signature made by the private half of a key from ParseKey. ES256 and RS256 signatures are over the SHA-256 of signed.
+func Verify(publicKey crypto.PublicKey, signed []byte, signature []byte) bool {
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		hash := sha256.Sum256(signed)
+		return ecdsa.VerifyASN1(key, hash[:], signature)
+	case *rsa.PublicKey:
+		hash := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, signed, signature)
+	}
+	return false
+}
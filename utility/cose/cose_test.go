@@ -0,0 +1,220 @@
+package cose
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/big"
+	"testing"
+)
+
+// encodeKey encodes a COSE key map with integer labels and integer or byte string values.
+func encodeKey(params map[int64]interface{}) []byte {
+	out := head(5, uint64(len(params)))
+	for label, value := range params {
+		out = append(out, encodeInt(label)...)
+		switch v := value.(type) {
+		case int64:
+			out = append(out, encodeInt(v)...)
+		case []byte:
+			out = append(out, head(2, uint64(len(v)))...)
+			out = append(out, v...)
+		}
+	}
+	return out
+}
+
+func encodeInt(i int64) []byte {
+	if i < 0 {
+		return head(1, uint64(-1-i))
+	}
+	return head(0, uint64(i))
+}
+
+func head(major byte, arg uint64) []byte {
+	switch {
+	case arg < 24:
+		return []byte{major<<5 | byte(arg)}
+	case arg < 1<<8:
+		return []byte{major<<5 | 24, byte(arg)}
+	case arg < 1<<16:
+		b := []byte{major<<5 | 25, 0, 0}
+		binary.BigEndian.PutUint16(b[1:], uint16(arg))
+		return b
+	}
+	b := []byte{major<<5 | 26, 0, 0, 0, 0}
+	binary.BigEndian.PutUint32(b[1:], uint32(arg))
+	return b
+}
+
+func pad32(b []byte) []byte {
+	return append(make([]byte, 32-len(b)), b...)
+}
+
+func ec2Key(key *ecdsa.PublicKey) map[int64]interface{} {
+	return map[int64]interface{}{
+		keyType: int64(ktyEc2),
+		keyAlg:  int64(ALG_ES256),
+		keyCrv:  int64(crvP256),
+		keyX:    pad32(key.X.Bytes()),
+		keyY:    pad32(key.Y.Bytes()),
+	}
+}
+
+func TestParseKeyES256(t *testing.T) {
+	privateKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	publicKey, alg, err := ParseKey(encodeKey(ec2Key(&privateKey.PublicKey)))
+	if err != nil {
+		t.Fatalf("ParseKey error: %v", err)
+	}
+	if alg != ALG_ES256 {
+		t.Errorf("alg = %v, want %v", alg, ALG_ES256)
+	}
+
+	signed := []byte("authenticator data and client data hash")
+	hash := sha256.Sum256(signed)
+	signature, err := ecdsa.SignASN1(rand.Reader, privateKey, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(publicKey, signed, signature) {
+		t.Errorf("valid ES256 signature didn't verify")
+	}
+	if Verify(publicKey, []byte("something else"), signature) {
+		t.Errorf("ES256 signature verified over different data")
+	}
+	if Verify(publicKey, signed, signature[:len(signature)-1]) {
+		t.Errorf("truncated ES256 signature verified")
+	}
+}
+
+func TestParseKeyEdDSA(t *testing.T) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, alg, err := ParseKey(encodeKey(map[int64]interface{}{
+		keyType: int64(ktyOkp),
+		keyAlg:  int64(ALG_EDDSA),
+		keyCrv:  int64(crvEd25519),
+		keyX:    []byte(publicKey),
+	}))
+	if err != nil {
+		t.Fatalf("ParseKey error: %v", err)
+	}
+	if alg != ALG_EDDSA {
+		t.Errorf("alg = %v, want %v", alg, ALG_EDDSA)
+	}
+
+	signed := []byte("signed")
+	if !Verify(parsed, signed, ed25519.Sign(privateKey, signed)) {
+		t.Errorf("valid EdDSA signature didn't verify")
+	}
+	if Verify(parsed, []byte("other"), ed25519.Sign(privateKey, signed)) {
+		t.Errorf("EdDSA signature verified over different data")
+	}
+}
+
+func TestParseKeyRS256(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, alg, err := ParseKey(encodeKey(map[int64]interface{}{
+		keyType: int64(ktyRsa),
+		keyAlg:  int64(ALG_RS256),
+		keyRsaN: privateKey.N.Bytes(),
+		keyRsaE: big.NewInt(int64(privateKey.E)).Bytes(),
+	}))
+	if err != nil {
+		t.Fatalf("ParseKey error: %v", err)
+	}
+	if alg != ALG_RS256 {
+		t.Errorf("alg = %v, want %v", alg, ALG_RS256)
+	}
+
+	signed := []byte("signed")
+	hash := sha256.Sum256(signed)
+	signature, err := rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Verify(parsed, signed, signature) {
+		t.Errorf("valid RS256 signature didn't verify")
+	}
+	signature[0] ^= 0xff
+	if Verify(parsed, signed, signature) {
+		t.Errorf("altered RS256 signature verified")
+	}
+}
+
+func TestParseKeyErrors(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	smallRsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wrongCurve := ec2Key(&ecKey.PublicKey)
+	wrongCurve[keyCrv] = int64(2)
+
+	offCurve := ec2Key(&ecKey.PublicKey)
+	offCurve[keyY] = make([]byte, 32)
+
+	shortX := ec2Key(&ecKey.PublicKey)
+	shortX[keyX] = []byte{1, 2, 3}
+
+	wrongType := ec2Key(&ecKey.PublicKey)
+	wrongType[keyType] = int64(ktyRsa)
+
+	unsupportedAlg := ec2Key(&ecKey.PublicKey)
+	unsupportedAlg[keyAlg] = int64(-35)
+
+	tests := map[string][]byte{
+		"invalid cbor":     {0xa1},
+		"not a map":        {0x80},
+		"wrong curve":      encodeKey(wrongCurve),
+		"point off curve":  encodeKey(offCurve),
+		"short coordinate": encodeKey(shortX),
+		"wrong key type":   encodeKey(wrongType),
+		"unsupported alg":  encodeKey(unsupportedAlg),
+		"small rsa key": encodeKey(map[int64]interface{}{
+			keyType: int64(ktyRsa),
+			keyAlg:  int64(ALG_RS256),
+			keyRsaN: smallRsaKey.N.Bytes(),
+			keyRsaE: big.NewInt(int64(smallRsaKey.E)).Bytes(),
+		}),
+		"short ed25519 key": encodeKey(map[int64]interface{}{
+			keyType: int64(ktyOkp),
+			keyAlg:  int64(ALG_EDDSA),
+			keyCrv:  int64(crvEd25519),
+			keyX:    make([]byte, 31),
+		}),
+	}
+
+	for name, raw := range tests {
+		if _, _, err := ParseKey(raw); err == nil {
+			t.Errorf("%v: ParseKey succeeded, want an error", name)
+		}
+	}
+}
+
+func TestVerifyUnknownKey(t *testing.T) {
+	if Verify("not a key", []byte("signed"), []byte("signature")) {
+		t.Errorf("Verify accepted an unknown key type")
+	}
+}
@@ -15,6 +15,10 @@ const (
 	ApiError_User_Disabled      = "Account is currently deactivated."
 	ApiError_Server             = "Something went wrong. Please try again."
 	ApiError_Activating_Email   = "Email couldn't be activate. The activation code has likely expired. Try requesting a new activation code."
+	ApiError_RateLimit          = "Too many requests. Please try again later."
+	ApiError_Webauthn           = "Passkey couldn't be verified."
+	ApiError_PasswordExpired    = "Your password has expired. Please change it to continue."
+	ApiError_BodyTooLarge       = "Request body is too large."
 )
 
 type appError interface {
@@ -27,7 +27,7 @@ func main() {
 	for _, dir := range dirsToWalk {
 		err := walkFiles(dir)
 		if err != nil {
-			log.Criticalf("Error traversing %s: %s\n", dir, err.Error())
+			log.Fatalf("Error traversing %s: %s\n", dir, err.Error())
 		}
 	}
 
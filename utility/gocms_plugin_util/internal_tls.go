@@ -0,0 +1,43 @@
+package gocms_plugin_util
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"github.com/gocms-io/gocms/context/consts"
+	"io/ioutil"
+	"os"
+)
+
+// InternalTlsConfig returns the tls config for calling the GoCMS internal api. It trusts GOCMS_INTERNAL_CA_FILE and presents
+// GOCMS_CLIENT_CERT_FILE and GOCMS_CLIENT_KEY_FILE when they are set. GoCMS sets them when it starts a local plugin and the
+// internal api uses https. Returns nil when none are set.
+func InternalTlsConfig() (*tls.Config, error) {
+	caFile := os.Getenv(consts.GOCMS_ENV_INTERNAL_CA_FILE)
+	certFile := os.Getenv(consts.GOCMS_ENV_CLIENT_CERT_FILE)
+	keyFile := os.Getenv(consts.GOCMS_ENV_CLIENT_KEY_FILE)
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		caPem, err := ioutil.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(caPem) {
+			return nil, fmt.Errorf("%v has no certificates", caFile)
+		}
+		config.RootCAs = roots
+	}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
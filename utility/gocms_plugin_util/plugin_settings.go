@@ -0,0 +1,46 @@
+package gocms_plugin_util
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gocms-io/gocms/context/consts"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/rest"
+	"os"
+)
+
+// GetSettings fetches the plugin settings from GoCMS. GoCMS sets the environment this needs when it starts a local plugin.
+// External plugins must set GOCMS_PLUGIN_ID, GOCMS_INTERNAL_API_URL and GOCMS_PLUGIN_SECRET themselves.
+func GetSettings() (map[string]string, error) {
+	pluginId := os.Getenv(consts.GOCMS_ENV_PLUGIN_ID)
+	apiUrl := os.Getenv(consts.GOCMS_ENV_INTERNAL_API_URL)
+	if pluginId == "" || apiUrl == "" {
+		return nil, errors.New(fmt.Sprintf("%v and %v must be set to get plugin settings", consts.GOCMS_ENV_PLUGIN_ID, consts.GOCMS_ENV_INTERNAL_API_URL))
+	}
+
+	tlsConfig, err := InternalTlsConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	req := rest.Request{
+		Url: fmt.Sprintf("%v/plugins/%v/settings", apiUrl, pluginId),
+		Headers: map[string]string{
+			consts.GOCMS_HEADER_PLUGIN_ID:     pluginId,
+			consts.GOCMS_HEADER_PLUGIN_SECRET: os.Getenv(consts.GOCMS_ENV_PLUGIN_SECRET),
+		},
+		TLSConfig: tlsConfig,
+	}
+	res, err := req.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var settings map[string]string
+	err = json.Unmarshal(res.Body, &settings)
+	if err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
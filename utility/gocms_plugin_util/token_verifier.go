@@ -0,0 +1,171 @@
+package gocms_plugin_util
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gocms-io/gocms/domain/acl/signing_key/signing_key_model"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/rest"
+	"math/big"
+	"sync"
+	"time"
+)
+
+// minimum time between fetches triggered by an unknown kid
+const jwksRefetchInterval = time.Minute
+
+// fetches are retried after jwksMinBackoff, doubling after each failure up to jwksMaxBackoff
+const jwksMinBackoff = time.Second
+const jwksMaxBackoff = time.Minute
+
+// default time allowed for fetching the keys
+const jwksFetchTimeout = 10 * time.Second
+
+// TokenVerifier verifies GoCMS user and device tokens offline with the keys published at /.well-known/jwks.json.
+// Keys are cached and fetched again when they expire or a token names a key that isn't cached.
+// Only one fetch runs at a time and it runs without the lock held, so a slow or failing jwks endpoint doesn't block verification with
+// cached keys. Failed fetches are retried with a backoff.
+type TokenVerifier struct {
+	JwksUrl  string
+	CacheFor time.Duration
+	Timeout  time.Duration
+
+	mu       sync.Mutex
+	keys     map[string]*rsa.PublicKey
+	fetched  time.Time
+	fetching chan struct{}
+	failures uint
+	retryAt  time.Time
+}
+
+func NewTokenVerifier(jwksUrl string) *TokenVerifier {
+	tokenVerifier := &TokenVerifier{
+		JwksUrl:  jwksUrl,
+		CacheFor: time.Hour,
+		Timeout:  jwksFetchTimeout,
+		keys:     make(map[string]*rsa.PublicKey),
+	}
+
+	return tokenVerifier
+}
+
+// Verify parses the token into claims and checks its signature and expiry. Pass &authentication_model.TokenClaims{} to read the user id
+// from sub and check aud.
+func (tv *TokenVerifier) Verify(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if jwt.SigningMethodRS256 != token.Method {
+			return nil, errors.New("Token signing method does not match.")
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("Token has no kid.")
+		}
+		return tv.getKey(kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if !token.Valid {
+		return nil, errors.New("Token is not valid.")
+	}
+
+	return token, nil
+}
+
+func (tv *TokenVerifier) getKey(kid string) (*rsa.PublicKey, error) {
+	tv.mu.Lock()
+	defer tv.mu.Unlock()
+
+	for {
+		key, ok := tv.keys[kid]
+		expired := time.Since(tv.fetched) > tv.CacheFor
+		if ok && !expired {
+			return key, nil
+		}
+		if !expired && time.Since(tv.fetched) <= jwksRefetchInterval {
+			return nil, errors.New("Token signing key is unknown.")
+		}
+
+		// wait for a fetch another caller started then check again
+		if tv.fetching != nil {
+			fetching := tv.fetching
+			tv.mu.Unlock()
+			<-fetching
+			tv.mu.Lock()
+			if time.Now().Before(tv.retryAt) {
+				return nil, errors.New("Token signing keys couldn't be fetched.")
+			}
+			continue
+		}
+
+		if time.Now().Before(tv.retryAt) {
+			return nil, errors.New("Token signing keys couldn't be fetched.")
+		}
+
+		tv.fetching = make(chan struct{})
+		tv.mu.Unlock()
+		keys, err := tv.fetch()
+		tv.mu.Lock()
+		if err != nil {
+			backoff := jwksMaxBackoff
+			if tv.failures < 6 {
+				backoff = jwksMinBackoff << tv.failures
+			}
+			tv.failures++
+			tv.retryAt = time.Now().Add(backoff)
+		} else {
+			tv.keys = keys
+			tv.fetched = time.Now()
+			tv.failures = 0
+			tv.retryAt = time.Time{}
+		}
+		close(tv.fetching)
+		tv.fetching = nil
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// fetch gets the published keys. It must be called without the lock held.
+func (tv *TokenVerifier) fetch() (map[string]*rsa.PublicKey, error) {
+	req := rest.Request{
+		Url:     tv.JwksUrl,
+		Timeout: tv.Timeout,
+	}
+	res, err := req.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	var jwks signing_key_model.JWKS
+	err = json.Unmarshal(res.Body, &jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwks.Keys))
+	for _, jwk := range jwks.Keys {
+		if jwk.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(jwk.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(jwk.E)
+		if err != nil {
+			continue
+		}
+		keys[jwk.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,104 @@
+package graceful
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+)
+
+// environment used to hand listeners to a new process on restart
+const (
+	ENV_LISTENERS = "GOCMS_INHERITED_LISTENERS"
+	ENV_READY_FD  = "GOCMS_READY_FD"
+)
+
+// file descriptors passed to the new process. 0-2 are stdin, stdout and stderr.
+const (
+	readyFd         = 3
+	firstListenerFd = 4
+)
+
+var (
+	mu        sync.Mutex
+	listeners = make(map[string]*net.TCPListener)
+	names     []string
+
+	inheritOnce sync.Once
+	inherited   = make(map[string]*os.File)
+)
+
+// Listen opens a tcp listener for addr, or reuses the one handed over by the previous process under the same name
+func Listen(name string, addr string) (net.Listener, error) {
+	inheritOnce.Do(readInherited)
+
+	var l net.Listener
+	var err error
+	if f, ok := inherited[name]; ok {
+		delete(inherited, name)
+		l, err = net.FileListener(f)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("inherited listener %v: %v", name, err)
+		}
+		// the port may have been changed for the new process
+		if !samePort(l.Addr().String(), addr) {
+			l.Close()
+			l = nil
+		}
+	}
+	if l == nil {
+		l, err = net.Listen("tcp", addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tcp, ok := l.(*net.TCPListener)
+	if !ok {
+		l.Close()
+		return nil, fmt.Errorf("listener %v is not tcp", name)
+	}
+
+	mu.Lock()
+	if _, exists := listeners[name]; !exists {
+		names = append(names, name)
+	}
+	listeners[name] = tcp
+	mu.Unlock()
+
+	return tcp, nil
+}
+
+// Ready tells the process that started this one that it is serving and the old process can stop.
+// It does nothing when the process wasn't started by a handover.
+func Ready() error {
+	if os.Getenv(ENV_READY_FD) == "" {
+		return nil
+	}
+	os.Unsetenv(ENV_READY_FD)
+
+	f := os.NewFile(uintptr(readyFd), "ready")
+	defer f.Close()
+	_, err := f.Write([]byte{1})
+	return err
+}
+
+func readInherited() {
+	value := os.Getenv(ENV_LISTENERS)
+	if value == "" {
+		return
+	}
+	os.Unsetenv(ENV_LISTENERS)
+
+	for i, name := range strings.Split(value, ",") {
+		inherited[name] = os.NewFile(uintptr(firstListenerFd+i), name)
+	}
+}
+
+func samePort(a string, b string) bool {
+	_, portA, errA := net.SplitHostPort(a)
+	_, portB, errB := net.SplitHostPort(b)
+	return errA == nil && errB == nil && portA == portB
+}
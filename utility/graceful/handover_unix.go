@@ -0,0 +1,113 @@
+//go:build !windows
+// +build !windows
+
+package graceful
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// ReloadSignals start a handover to a new copy of the binary
+var ReloadSignals = []os.Signal{syscall.SIGHUP}
+
+// CertificateSignals reload certificates without restarting
+var CertificateSignals = []os.Signal{syscall.SIGUSR1}
+
+// how long the new process has to migrate, start plugins and begin serving
+const readyTimeout = 2 * time.Minute
+
+// Handover starts the binary on disk again with every listener passed along. It returns once the new process
+// is serving so the caller can drain and exit. Connections are accepted by one process or the other the whole time.
+func Handover() error {
+	executable, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer readyReader.Close()
+
+	files := []*os.File{readyWriter}
+	mu.Lock()
+	for _, name := range names {
+		f, err := listeners[name].File()
+		if err != nil {
+			mu.Unlock()
+			closeAll(files)
+			return fmt.Errorf("listener %v: %v", name, err)
+		}
+		files = append(files, f)
+	}
+	listenerNames := strings.Join(names, ",")
+	mu.Unlock()
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(withoutHandoverEnv(os.Environ()),
+		fmt.Sprintf("%v=%v", ENV_LISTENERS, listenerNames),
+		fmt.Sprintf("%v=%d", ENV_READY_FD, readyFd),
+	)
+
+	err = cmd.Start()
+	// the child has its own copies now
+	closeAll(files)
+	if err != nil {
+		return err
+	}
+
+	// the write end closes without a byte if the new process exits early
+	ready := make(chan error, 1)
+	go func() {
+		b := make([]byte, 1)
+		n, err := readyReader.Read(b)
+		if n == 1 {
+			err = nil
+		} else if err == nil {
+			err = fmt.Errorf("no ready signal")
+		}
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			cmd.Wait()
+			return fmt.Errorf("new process %d exited before it was ready: %v", cmd.Process.Pid, err)
+		}
+		// reap it if it exits before we do
+		go cmd.Wait()
+		return nil
+	case <-time.After(readyTimeout):
+		cmd.Process.Kill()
+		cmd.Wait()
+		return fmt.Errorf("new process %d wasn't ready after %v", cmd.Process.Pid, readyTimeout)
+	}
+}
+
+func closeAll(files []*os.File) {
+	for _, f := range files {
+		f.Close()
+	}
+}
+
+func withoutHandoverEnv(env []string) []string {
+	filtered := make([]string, 0, len(env))
+	for _, kv := range env {
+		if strings.HasPrefix(kv, ENV_LISTENERS+"=") || strings.HasPrefix(kv, ENV_READY_FD+"=") {
+			continue
+		}
+		filtered = append(filtered, kv)
+	}
+	return filtered
+}
@@ -0,0 +1,19 @@
+//go:build windows
+// +build windows
+
+package graceful
+
+import (
+	"fmt"
+	"os"
+)
+
+// ReloadSignals is empty because windows can't pass sockets to a child process this way
+var ReloadSignals = []os.Signal{}
+
+// CertificateSignals is empty because windows has no user signals. Certificates are still reloaded when the files change.
+var CertificateSignals = []os.Signal{}
+
+func Handover() error {
+	return fmt.Errorf("handover is not supported on windows")
+}
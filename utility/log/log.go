@@ -1,68 +1,190 @@
 package log
 
 import (
-	"github.com/fatih/color"
-	"log"
-	"os"
-	"runtime/debug"
 	"encoding/json"
 	"fmt"
+	"os"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
 )
 
 var LogLevel int64 = 3
 
+const LOG_LEVEL_OFF = -1
 const LOG_LEVEL_CRITICAL = 0
 const LOG_LEVEL_ERROR = 1
 const LOG_LEVEL_WARNING = 2
 const LOG_LEVEL_DEBUG = 3
 const LOG_LEVEL_WITH_STACK_TRACE = 4
 
-// Criticalf print always. Print color.
-func Criticalf(msg string, args ...interface{}) {
-	if LogLevel >= LOG_LEVEL_CRITICAL {
-		color.Set(color.FgRed)
-		defer color.Unset()
-		log.Printf("[CRITICAL] - "+msg, args...)
-		if LogLevel >= LOG_LEVEL_WITH_STACK_TRACE {
-			debug.PrintStack()
+// field names shared by everything that logs about a request or plugin
+const (
+	REQUEST_ID = "requestId"
+	USER_ID    = "userId"
+	PLUGIN_ID  = "pluginId"
+	TRACE_ID   = "traceId"
+	SUBSYSTEM  = "subsystem"
+)
+
+type field struct {
+	key   string
+	value interface{}
+}
+
+// Logger carries a subsystem and fields that are added to every line it writes.
+// The zero value logs like the package level functions.
+type Logger struct {
+	subsystem string
+	fields    []field
+}
+
+var (
+	root = &Logger{}
+
+	// subsystemLevels override LogLevel for loggers created with Subsystem
+	subsystemLevels = map[string]int64{}
+	levelsMu        sync.RWMutex
+)
+
+// Subsystem returns a logger whose level can be set on its own with LOG_LEVELS
+func Subsystem(name string) *Logger {
+	return &Logger{subsystem: name}
+}
+
+// With returns a logger that adds the key value pairs to every line
+func With(keyvals ...interface{}) *Logger {
+	return root.With(keyvals...)
+}
+
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]field, len(l.fields), len(l.fields)+len(keyvals)/2)
+	copy(fields, l.fields)
+	for i := 0; i+1 < len(keyvals); i += 2 {
+		fields = append(fields, field{key: fmt.Sprint(keyvals[i]), value: keyvals[i+1]})
+	}
+	return &Logger{subsystem: l.subsystem, fields: fields}
+}
+
+// SetSubsystemLevels replaces the per subsystem levels
+func SetSubsystemLevels(levels map[string]int64) {
+	levelsMu.Lock()
+	subsystemLevels = levels
+	levelsMu.Unlock()
+}
+
+// ParseSubsystemLevels reads levels in the form "plugin=3,mail=1"
+func ParseSubsystemLevels(s string) (map[string]int64, error) {
+	levels := make(map[string]int64)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
 		}
-		os.Exit(1)
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid subsystem level %q, expected name=level", pair)
+		}
+		var level int64
+		if _, err := fmt.Sscanf(strings.TrimSpace(parts[1]), "%d", &level); err != nil {
+			return nil, fmt.Errorf("invalid level for subsystem %v: %v", parts[0], parts[1])
+		}
+		levels[strings.TrimSpace(parts[0])] = level
 	}
+	return levels, nil
 }
 
-// Errorf print if error is enabled. Print color.
-func Errorf(msg string, args ...interface{}) {
-	if LogLevel >= LOG_LEVEL_ERROR {
-		color.Set(color.FgRed)
-		defer color.Unset()
-		log.Printf("[ERROR] - "+msg, args...)
-		if LogLevel >= LOG_LEVEL_WITH_STACK_TRACE {
-			debug.PrintStack()
+func (l *Logger) level() int64 {
+	if l.subsystem != "" {
+		levelsMu.RLock()
+		level, ok := subsystemLevels[l.subsystem]
+		levelsMu.RUnlock()
+		if ok {
+			return level
 		}
 	}
+	return LogLevel
+}
+
+// Criticalf print always. Print color.
+func (l *Logger) Criticalf(msg string, args ...interface{}) {
+	if l.level() >= LOG_LEVEL_CRITICAL {
+		l.write(LOG_LEVEL_CRITICAL, fmt.Sprintf(msg, args...), l.level() >= LOG_LEVEL_WITH_STACK_TRACE)
+	}
+}
+
+// Fatalf prints like Criticalf then exits. Only use it where the cms can't start.
+func (l *Logger) Fatalf(msg string, args ...interface{}) {
+	l.write(LOG_LEVEL_CRITICAL, fmt.Sprintf(msg, args...), l.level() >= LOG_LEVEL_WITH_STACK_TRACE)
+	Close()
+	os.Exit(1)
+}
+
+// Errorf print if error is enabled. Print color.
+func (l *Logger) Errorf(msg string, args ...interface{}) {
+	if l.level() >= LOG_LEVEL_ERROR {
+		l.write(LOG_LEVEL_ERROR, fmt.Sprintf(msg, args...), l.level() >= LOG_LEVEL_WITH_STACK_TRACE)
+	}
 }
 
 // Warningf print if warning is enabled. Print color.
-func Warningf(msg string, args ...interface{}) {
-	if LogLevel >= LOG_LEVEL_WARNING {
-		color.Set(color.FgYellow)
-		defer color.Unset()
-		log.Printf("[WARNING] - "+msg, args...)
+func (l *Logger) Warningf(msg string, args ...interface{}) {
+	if l.level() >= LOG_LEVEL_WARNING {
+		l.write(LOG_LEVEL_WARNING, fmt.Sprintf(msg, args...), false)
 	}
 }
 
 // Debugf print if debug mode is enabled. print no color
-func Debugf(msg string, args ...interface{}) {
-	if LogLevel >= LOG_LEVEL_DEBUG {
-		log.Printf("[DEBUG] - "+msg, args...)
+func (l *Logger) Debugf(msg string, args ...interface{}) {
+	if l.level() >= LOG_LEVEL_DEBUG {
+		l.write(LOG_LEVEL_DEBUG, fmt.Sprintf(msg, args...), false)
+	}
+}
+
+// Infof print unless logging is off. Print no color
+func (l *Logger) Infof(msg string, args ...interface{}) {
+	if l.level() >= LOG_LEVEL_CRITICAL {
+		l.write(levelInfo, fmt.Sprintf(msg, args...), false)
+	}
+}
+
+func (l *Logger) write(level int64, msg string, withStack bool) {
+	e := &entry{
+		time:      time.Now(),
+		level:     level,
+		subsystem: l.subsystem,
+		msg:       strings.TrimRight(msg, "\n"),
+		fields:    l.fields,
+	}
+	if withStack {
+		e.stack = string(debug.Stack())
 	}
+	writeEntry(e)
+}
+
+func Criticalf(msg string, args ...interface{}) {
+	root.Criticalf(msg, args...)
+}
+
+func Fatalf(msg string, args ...interface{}) {
+	root.Fatalf(msg, args...)
+}
+
+func Errorf(msg string, args ...interface{}) {
+	root.Errorf(msg, args...)
+}
+
+func Warningf(msg string, args ...interface{}) {
+	root.Warningf(msg, args...)
+}
+
+func Debugf(msg string, args ...interface{}) {
+	root.Debugf(msg, args...)
 }
 
-// Infof print always. Print no color
 func Infof(msg string, args ...interface{}) {
-	color.Set(color.FgBlue)
-	defer color.Unset()
-	log.Printf("[INFO] - "+msg, args...)
+	root.Infof(msg, args...)
 }
 
 func PrettyPrint(v interface{}) string {
@@ -71,5 +193,5 @@ func PrettyPrint(v interface{}) string {
 		Errorf("Error trying to pretty print output: %v\n", err)
 	}
 	s := fmt.Sprint(string(b))
-	return  s
-}
\ No newline at end of file
+	return s
+}
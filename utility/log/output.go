@@ -0,0 +1,271 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/fatih/color"
+	"io"
+	stdlog "log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	FORMAT_TEXT = "text"
+	FORMAT_JSON = "json"
+)
+
+// info isn't a level that can be filtered on its own so it sits outside the LOG_LEVEL range
+const levelInfo = -100
+
+type Config struct {
+	// Format is text or json
+	Format string
+	// File is written to instead of stderr when set
+	File string
+	// MaxSize in megabytes before the file is rotated. 0 never rotates.
+	MaxSize int64
+	// MaxBackups is how many rotated files are kept
+	MaxBackups int64
+	// Levels override LogLevel per subsystem
+	Levels map[string]int64
+}
+
+type entry struct {
+	time      time.Time
+	level     int64
+	subsystem string
+	msg       string
+	fields    []field
+	stack     string
+}
+
+var (
+	outMu    sync.Mutex
+	out      io.Writer = os.Stderr
+	format             = FORMAT_TEXT
+	colorize           = !color.NoColor
+)
+
+func init() {
+	// send anything using the standard logger through the same stream
+	stdlog.SetFlags(0)
+	stdlog.SetOutput(root.Writer())
+}
+
+// Configure sets the output for every logger. It is safe to call while logging.
+func Configure(config *Config) error {
+	var w io.Writer = os.Stderr
+	if config.File != "" {
+		rf, err := OpenRotatingFile(config.File, config.MaxSize*1024*1024, int(config.MaxBackups))
+		if err != nil {
+			return err
+		}
+		w = rf
+	}
+
+	f := strings.ToLower(config.Format)
+	if f == "" {
+		f = FORMAT_TEXT
+	}
+	if f != FORMAT_TEXT && f != FORMAT_JSON {
+		return fmt.Errorf("unknown log format %q, expected %v or %v", config.Format, FORMAT_TEXT, FORMAT_JSON)
+	}
+
+	outMu.Lock()
+	previous := out
+	out = w
+	format = f
+	colorize = config.File == "" && f == FORMAT_TEXT && !color.NoColor
+	outMu.Unlock()
+
+	if closer, ok := previous.(io.Closer); ok {
+		closer.Close()
+	}
+
+	if config.Levels != nil {
+		SetSubsystemLevels(config.Levels)
+	}
+	return nil
+}
+
+// Close flushes and closes the log file if there is one
+func Close() {
+	outMu.Lock()
+	defer outMu.Unlock()
+	if closer, ok := out.(io.Closer); ok {
+		closer.Close()
+		out = os.Stderr
+	}
+}
+
+func writeEntry(e *entry) {
+	outMu.Lock()
+	defer outMu.Unlock()
+
+	var line []byte
+	if format == FORMAT_JSON {
+		line = formatJSON(e)
+	} else {
+		line = formatText(e, colorize)
+	}
+	out.Write(line)
+}
+
+func levelName(level int64) string {
+	switch level {
+	case LOG_LEVEL_CRITICAL:
+		return "CRITICAL"
+	case LOG_LEVEL_ERROR:
+		return "ERROR"
+	case LOG_LEVEL_WARNING:
+		return "WARNING"
+	case LOG_LEVEL_DEBUG:
+		return "DEBUG"
+	}
+	return "INFO"
+}
+
+func levelColor(level int64) *color.Color {
+	switch level {
+	case LOG_LEVEL_CRITICAL, LOG_LEVEL_ERROR:
+		return color.New(color.FgRed)
+	case LOG_LEVEL_WARNING:
+		return color.New(color.FgYellow)
+	case levelInfo:
+		return color.New(color.FgBlue)
+	}
+	return nil
+}
+
+// formatText keeps the original "2006/01/02 15:04:05 [LEVEL] - msg" layout with fields appended as key=value
+func formatText(e *entry, colorize bool) []byte {
+	var b bytes.Buffer
+	b.WriteString(e.time.Format("2006/01/02 15:04:05"))
+	b.WriteString(" [")
+	b.WriteString(levelName(e.level))
+	b.WriteString("] - ")
+	b.WriteString(e.msg)
+	if e.subsystem != "" {
+		writeTextField(&b, SUBSYSTEM, e.subsystem)
+	}
+	for _, f := range e.fields {
+		writeTextField(&b, f.key, f.value)
+	}
+
+	line := b.String()
+	if c := levelColor(e.level); colorize && c != nil {
+		line = c.Sprint(line)
+	}
+	if e.stack != "" {
+		line += "\n" + strings.TrimRight(e.stack, "\n")
+	}
+	return []byte(line + "\n")
+}
+
+func writeTextField(b *bytes.Buffer, key string, value interface{}) {
+	v := fmt.Sprint(value)
+	if strings.ContainsAny(v, " \t\n\"=") {
+		v = fmt.Sprintf("%q", v)
+	}
+	b.WriteByte(' ')
+	b.WriteString(key)
+	b.WriteByte('=')
+	b.WriteString(v)
+}
+
+// formatJSON writes one object per line with a stable key order
+func formatJSON(e *entry) []byte {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	writeJSONField(&b, "time", e.time.Format(time.RFC3339Nano), true)
+	writeJSONField(&b, "level", strings.ToLower(levelName(e.level)), false)
+	if e.subsystem != "" {
+		writeJSONField(&b, SUBSYSTEM, e.subsystem, false)
+	}
+	writeJSONField(&b, "msg", e.msg, false)
+	for _, f := range e.fields {
+		writeJSONField(&b, f.key, f.value, false)
+	}
+	if e.stack != "" {
+		writeJSONField(&b, "stack", e.stack, false)
+	}
+	b.WriteString("}\n")
+	return b.Bytes()
+}
+
+func writeJSONField(b *bytes.Buffer, key string, value interface{}, first bool) {
+	if !first {
+		b.WriteByte(',')
+	}
+	k, _ := json.Marshal(key)
+	b.Write(k)
+	b.WriteByte(':')
+
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	b.Write(v)
+}
+
+// Writer returns a writer that logs each line written to it at info. Use it to capture output from
+// libraries and child processes.
+func (l *Logger) Writer() io.Writer {
+	return &lineWriter{logger: l}
+}
+
+// lines longer than this are logged in pieces so output without newlines can't grow the buffer forever
+const maxLineLength = 64 * 1024
+
+type lineWriter struct {
+	logger *Logger
+	mu     sync.Mutex
+	buf    []byte
+}
+
+func (lw *lineWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	lw.buf = append(lw.buf, p...)
+	for {
+		i := bytes.IndexByte(lw.buf, '\n')
+		var line string
+		if i >= 0 {
+			line = string(lw.buf[:i])
+			lw.buf = lw.buf[i+1:]
+		} else if len(lw.buf) >= maxLineLength {
+			line = string(lw.buf[:maxLineLength])
+			lw.buf = lw.buf[maxLineLength:]
+		} else {
+			break
+		}
+		if strings.TrimSpace(line) != "" {
+			lw.logger.Infof("%s", line)
+		}
+	}
+	return len(p), nil
+}
+
+// ErrorWriter returns a writer that logs each write as a single error, keeping multi line output like stack traces together
+func (l *Logger) ErrorWriter() io.Writer {
+	return &errorWriter{logger: l}
+}
+
+type errorWriter struct {
+	logger *Logger
+}
+
+func (ew *errorWriter) Write(p []byte) (int, error) {
+	if msg := strings.TrimSpace(string(p)); msg != "" {
+		ew.logger.Errorf("%s", msg)
+	}
+	return len(p), nil
+}
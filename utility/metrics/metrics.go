@@ -0,0 +1,279 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ContentType is the prometheus text exposition format served by Write
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const (
+	TYPE_COUNTER   = "counter"
+	TYPE_GAUGE     = "gauge"
+	TYPE_HISTOGRAM = "histogram"
+)
+
+// DefaultBuckets are latency buckets in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+type collector interface {
+	write(w *bufio.Writer)
+}
+
+type Registry struct {
+	mu         sync.Mutex
+	collectors []collector
+}
+
+// DefaultRegistry holds every metric created with the New functions in this package
+var DefaultRegistry = &Registry{}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	r.collectors = append(r.collectors, c)
+	r.mu.Unlock()
+}
+
+// Write renders every registered metric in registration order
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	collectors := make([]collector, len(r.collectors))
+	copy(collectors, r.collectors)
+	r.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, c := range collectors {
+		c.write(bw)
+	}
+	return bw.Flush()
+}
+
+func Write(w io.Writer) error {
+	return DefaultRegistry.Write(w)
+}
+
+// CounterVec is a monotonically increasing value partitioned by labels
+type CounterVec struct {
+	name       string
+	help       string
+	labelNames []string
+	mu         sync.Mutex
+	series     map[string]*counterSeries
+}
+
+type counterSeries struct {
+	labelValues []string
+	value       float64
+}
+
+func NewCounterVec(name string, help string, labelNames ...string) *CounterVec {
+	cv := &CounterVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		series:     make(map[string]*counterSeries),
+	}
+	DefaultRegistry.register(cv)
+	return cv
+}
+
+func (cv *CounterVec) Inc(labelValues ...string) {
+	cv.Add(1, labelValues...)
+}
+
+func (cv *CounterVec) Add(v float64, labelValues ...string) {
+	key := seriesKey(cv.labelNames, labelValues)
+	cv.mu.Lock()
+	s, ok := cv.series[key]
+	if !ok {
+		s = &counterSeries{labelValues: labelValues}
+		cv.series[key] = s
+	}
+	s.value += v
+	cv.mu.Unlock()
+}
+
+func (cv *CounterVec) write(w *bufio.Writer) {
+	writeHeader(w, cv.name, cv.help, TYPE_COUNTER)
+
+	cv.mu.Lock()
+	defer cv.mu.Unlock()
+	for _, key := range sortedKeys(cv.series) {
+		s := cv.series[key]
+		writeSample(w, cv.name, cv.labelNames, s.labelValues, "", "", s.value)
+	}
+}
+
+// HistogramVec counts observations into cumulative buckets partitioned by labels
+type HistogramVec struct {
+	name       string
+	help       string
+	labelNames []string
+	buckets    []float64
+	mu         sync.Mutex
+	series     map[string]*histogramSeries
+}
+
+type histogramSeries struct {
+	labelValues []string
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+func NewHistogramVec(name string, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	sorted := make([]float64, len(buckets))
+	copy(sorted, buckets)
+	sort.Float64s(sorted)
+
+	hv := &HistogramVec{
+		name:       name,
+		help:       help,
+		labelNames: labelNames,
+		buckets:    sorted,
+		series:     make(map[string]*histogramSeries),
+	}
+	DefaultRegistry.register(hv)
+	return hv
+}
+
+func (hv *HistogramVec) Observe(v float64, labelValues ...string) {
+	key := seriesKey(hv.labelNames, labelValues)
+	hv.mu.Lock()
+	s, ok := hv.series[key]
+	if !ok {
+		s = &histogramSeries{labelValues: labelValues, counts: make([]uint64, len(hv.buckets))}
+		hv.series[key] = s
+	}
+	for i, upper := range hv.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+	hv.mu.Unlock()
+}
+
+func (hv *HistogramVec) write(w *bufio.Writer) {
+	writeHeader(w, hv.name, hv.help, TYPE_HISTOGRAM)
+
+	hv.mu.Lock()
+	defer hv.mu.Unlock()
+	for _, key := range sortedKeys(hv.series) {
+		s := hv.series[key]
+		for i, upper := range hv.buckets {
+			writeSample(w, hv.name+"_bucket", hv.labelNames, s.labelValues, "le", formatFloat(upper), float64(s.counts[i]))
+		}
+		writeSample(w, hv.name+"_bucket", hv.labelNames, s.labelValues, "le", "+Inf", float64(s.count))
+		writeSample(w, hv.name+"_sum", hv.labelNames, s.labelValues, "", "", s.sum)
+		writeSample(w, hv.name+"_count", hv.labelNames, s.labelValues, "", "", float64(s.count))
+	}
+}
+
+// ValueFunc reports a value read at scrape time. Use it for state owned by something else, like pool stats.
+type ValueFunc struct {
+	name      string
+	help      string
+	valueType string
+	fn        func() float64
+}
+
+func NewGaugeFunc(name string, help string, fn func() float64) *ValueFunc {
+	return newValueFunc(name, help, TYPE_GAUGE, fn)
+}
+
+// NewCounterFunc is for totals that are already counted elsewhere
+func NewCounterFunc(name string, help string, fn func() float64) *ValueFunc {
+	return newValueFunc(name, help, TYPE_COUNTER, fn)
+}
+
+func newValueFunc(name string, help string, valueType string, fn func() float64) *ValueFunc {
+	vf := &ValueFunc{
+		name:      name,
+		help:      help,
+		valueType: valueType,
+		fn:        fn,
+	}
+	DefaultRegistry.register(vf)
+	return vf
+}
+
+func (vf *ValueFunc) write(w *bufio.Writer) {
+	writeHeader(w, vf.name, vf.help, vf.valueType)
+	writeSample(w, vf.name, nil, nil, "", "", vf.fn())
+}
+
+func seriesKey(labelNames []string, labelValues []string) string {
+	if len(labelValues) != len(labelNames) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch series := m.(type) {
+	case map[string]*counterSeries:
+		for k := range series {
+			keys = append(keys, k)
+		}
+	case map[string]*histogramSeries:
+		for k := range series {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func writeHeader(w *bufio.Writer, name string, help string, valueType string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, strings.NewReplacer("\\", `\\`, "\n", `\n`).Replace(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, valueType)
+}
+
+func writeSample(w *bufio.Writer, name string, labelNames []string, labelValues []string, extraName string, extraValue string, value float64) {
+	w.WriteString(name)
+	if len(labelNames) > 0 || extraName != "" {
+		w.WriteByte('{')
+		for i, labelName := range labelNames {
+			if i > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", labelName, escapeLabel(labelValues[i]))
+		}
+		if extraName != "" {
+			if len(labelNames) > 0 {
+				w.WriteByte(',')
+			}
+			fmt.Fprintf(w, "%s=\"%s\"", extraName, extraValue)
+		}
+		w.WriteByte('}')
+	}
+	w.WriteByte(' ')
+	w.WriteString(formatFloat(value))
+	w.WriteByte('\n')
+}
+
+var labelEscaper = strings.NewReplacer("\\", `\\`, "\"", `\"`, "\n", `\n`)
+
+func escapeLabel(v string) string {
+	return labelEscaper.Replace(v)
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,104 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+)
+
+func render(c collector) string {
+	var buf bytes.Buffer
+	w := bufio.NewWriter(&buf)
+	c.write(w)
+	w.Flush()
+	return buf.String()
+}
+
+func TestCounterVec(t *testing.T) {
+	cv := NewCounterVec("test_requests_total", "Requests.", "method", "status")
+	cv.Inc("GET", "200")
+	cv.Inc("GET", "200")
+	cv.Add(3, "POST", "500")
+
+	expected := "# HELP test_requests_total Requests.\n" +
+		"# TYPE test_requests_total counter\n" +
+		"test_requests_total{method=\"GET\",status=\"200\"} 2\n" +
+		"test_requests_total{method=\"POST\",status=\"500\"} 3\n"
+	if got := render(cv); got != expected {
+		t.Errorf("got\n%s\nexpected\n%s", got, expected)
+	}
+}
+
+func TestCounterVecEscapesLabels(t *testing.T) {
+	cv := NewCounterVec("test_escaped_total", "Help with \\ and\nnewline.", "route")
+	cv.Inc("a\"b\\c\nd")
+
+	got := render(cv)
+	if !strings.Contains(got, "# HELP test_escaped_total Help with \\\\ and\\nnewline.\n") {
+		t.Errorf("help not escaped: %s", got)
+	}
+	if !strings.Contains(got, "test_escaped_total{route=\"a\\\"b\\\\c\\nd\"} 1\n") {
+		t.Errorf("label not escaped: %s", got)
+	}
+}
+
+func TestCounterVecLabelCountMismatch(t *testing.T) {
+	cv := NewCounterVec("test_mismatch_total", "Mismatch.", "a", "b")
+	defer func() {
+		if recover() == nil {
+			t.Error("expected a panic for the wrong number of label values")
+		}
+	}()
+	cv.Inc("only one")
+}
+
+func TestHistogramVec(t *testing.T) {
+	hv := NewHistogramVec("test_duration_seconds", "Duration.", []float64{1, 0.5}, "route")
+	hv.Observe(0.25, "/a")
+	hv.Observe(0.75, "/a")
+	hv.Observe(2, "/a")
+
+	expected := "# HELP test_duration_seconds Duration.\n" +
+		"# TYPE test_duration_seconds histogram\n" +
+		"test_duration_seconds_bucket{route=\"/a\",le=\"0.5\"} 1\n" +
+		"test_duration_seconds_bucket{route=\"/a\",le=\"1\"} 2\n" +
+		"test_duration_seconds_bucket{route=\"/a\",le=\"+Inf\"} 3\n" +
+		"test_duration_seconds_sum{route=\"/a\"} 3\n" +
+		"test_duration_seconds_count{route=\"/a\"} 3\n"
+	if got := render(hv); got != expected {
+		t.Errorf("got\n%s\nexpected\n%s", got, expected)
+	}
+}
+
+func TestValueFuncs(t *testing.T) {
+	value := 1.0
+	gauge := NewGaugeFunc("test_open", "Open.", func() float64 { return value })
+	counter := NewCounterFunc("test_total", "Total.", func() float64 { return math.Inf(1) })
+
+	value = 4
+	if got := render(gauge); got != "# HELP test_open Open.\n# TYPE test_open gauge\ntest_open 4\n" {
+		t.Errorf("gauge should be read at scrape time: %s", got)
+	}
+	if got := render(counter); got != "# HELP test_total Total.\n# TYPE test_total counter\ntest_total +Inf\n" {
+		t.Errorf("counter: %s", got)
+	}
+}
+
+func TestRegistryWrite(t *testing.T) {
+	registry := &Registry{}
+	first := &ValueFunc{name: "test_first", help: "First.", valueType: TYPE_GAUGE, fn: func() float64 { return 1 }}
+	second := &ValueFunc{name: "test_second", help: "Second.", valueType: TYPE_GAUGE, fn: func() float64 { return 2 }}
+	registry.register(first)
+	registry.register(second)
+
+	var buf bytes.Buffer
+	if err := registry.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	got := buf.String()
+	if strings.Index(got, "test_first 1\n") > strings.Index(got, "test_second 2\n") || !strings.Contains(got, "test_first 1\n") {
+		t.Errorf("metrics should be written in registration order: %s", got)
+	}
+}
@@ -2,12 +2,17 @@ package rest
 
 import (
 	"bytes"
+	"crypto/tls"
 	"github.com/gocms-io/gocms/utility/errors"
 	"io/ioutil"
 	"net/http"
 	"github.com/gocms-io/gocms/utility/log"
+	"time"
 )
 
+// DEFAULT_TIMEOUT is used when a request doesn't set its own so a hung server can't block the caller forever
+const DEFAULT_TIMEOUT = 30 * time.Second
+
 var GET string = "GET"
 var POST string = "POST"
 var PUT string = "PUT"
@@ -17,6 +22,10 @@ type Request struct {
 	Url     string
 	Headers map[string]string
 	Body    []byte
+	// Timeout for the whole request including reading the body. 0 uses DEFAULT_TIMEOUT.
+	Timeout time.Duration
+	// TLSConfig is used for https urls. nil uses the system defaults.
+	TLSConfig *tls.Config
 	method  string
 }
 
@@ -36,6 +45,11 @@ func (rr *Request) Post() (*RestResponse, error) {
 	return rr.do()
 }
 
+func (rr *Request) Delete() (*RestResponse, error) {
+	rr.method = DELETE
+	return rr.do()
+}
+
 func (rr *Request) do() (*RestResponse, error) {
 	// create request
 	req, err := http.NewRequest(rr.method, rr.Url, bytes.NewBuffer(rr.Body))
@@ -52,7 +66,14 @@ func (rr *Request) do() (*RestResponse, error) {
 		}
 	}
 
-	client := http.DefaultClient
+	timeout := rr.Timeout
+	if timeout <= 0 {
+		timeout = DEFAULT_TIMEOUT
+	}
+	client := &http.Client{Timeout: timeout}
+	if rr.TLSConfig != nil {
+		client.Transport = &http.Transport{Proxy: http.ProxyFromEnvironment, TLSClientConfig: rr.TLSConfig}
+	}
 	res, err := client.Do(req)
 	if err != nil {
 		log.Errorf("Error making request: %s", err.Error())
@@ -0,0 +1,96 @@
+package security
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// only this many leading hex characters of the hash are used to find a range, so the full hash never leaves the server
+const breachPrefixLength = 5
+
+const breachRangeTimeout = 5 * time.Second
+
+// PasswordIsBreached checks a password against a Have I Been Pwned style range list using only the first five characters of its
+// SHA-1 hash. listPath is either a directory holding one file per hash prefix, as written by the official pwned passwords downloader,
+// or the url of a range api such as https://api.pwnedpasswords.com/range/. Each range holds one "SUFFIX:COUNT" line per hash.
+// An error is returned when the range can't be read so callers can refuse the password instead of skipping the check.
+func PasswordIsBreached(listPath string, password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachPrefixLength], hash[breachPrefixLength:]
+
+	var breachRange io.ReadCloser
+	var err error
+	if strings.HasPrefix(listPath, "http://") || strings.HasPrefix(listPath, "https://") {
+		breachRange, err = getBreachRange(listPath, prefix)
+	} else {
+		breachRange, err = openBreachRange(listPath, prefix)
+	}
+	if err != nil {
+		return false, err
+	}
+	defer breachRange.Close()
+
+	scanner := bufio.NewScanner(breachRange)
+	for scanner.Scan() {
+		lineSuffix, count := parseBreachLine(scanner.Text())
+		// padded responses include made up suffixes with a count of 0
+		if lineSuffix == suffix && count != "0" {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+// openBreachRange opens the file for prefix. Downloaders name them PREFIX or PREFIX.txt.
+func openBreachRange(dir string, prefix string) (io.ReadCloser, error) {
+	for _, name := range []string{prefix, prefix + ".txt", strings.ToLower(prefix), strings.ToLower(prefix) + ".txt"} {
+		file, err := os.Open(filepath.Join(dir, name))
+		if err == nil {
+			return file, nil
+		}
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("breached password list %v has no range for %v", dir, prefix)
+}
+
+// getBreachRange requests the range for prefix from a range api with padding so the response size doesn't reveal the range.
+func getBreachRange(url string, prefix string) (io.ReadCloser, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimRight(url, "/")+"/"+prefix, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Add-Padding", "true")
+
+	client := &http.Client{Timeout: breachRangeTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		res.Body.Close()
+		return nil, fmt.Errorf("breached password range %v returned %v", prefix, res.Status)
+	}
+	return res.Body, nil
+}
+
+// parseBreachLine splits a "SUFFIX:COUNT" line into the uppercase suffix and the count
+func parseBreachLine(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	count := ""
+	if i := strings.IndexByte(line, ':'); i >= 0 {
+		line, count = line[:i], strings.TrimSpace(line[i+1:])
+	}
+	return strings.ToUpper(line), count
+}
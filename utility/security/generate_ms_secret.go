@@ -1,6 +1,9 @@
 package security
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"github.com/jmoiron/sqlx"
 	"github.com/gocms-io/gocms/domain/setting/setting_model"
 	"github.com/gocms-io/gocms/utility/log"
@@ -18,25 +21,27 @@ func msSecret(db *sqlx.DB) bool {
 	WHERE name=?
 	`, "MS_SECRET_KEY")
 	if err != nil {
-		log.Criticalf("MS_SECRET_KEY row doesn't exist in gocms_settings\n")
-		return false
+		log.Fatalf("MS_SECRET_KEY row doesn't exist in gocms_settings\n")
 	}
 
 	// if it is nil gen one
 	if msKey.Value == "" {
 		key, err := utility.GenerateRandomString(16)
 		if err != nil {
-			log.Criticalf("Error creating msKey")
+			log.Fatalf("Error creating msKey")
 		}
 
 		// insert msKey
+		key, err = EncryptSetting("MS_SECRET_KEY", key)
+		if err != nil {
+			log.Fatalf("Error encrypting MS_SECRET_KEY: %v\n", err.Error())
+		}
 		_, err = db.Exec(`
 		UPDATE gocms_settings SET value=?
 		WHERE name = ?
 		`, key, "MS_SECRET_KEY")
 		if err != nil {
-			log.Criticalf("Error inserting MS_SECRET_KEY: %v\n", err.Error())
-			return false
+			log.Fatalf("Error inserting MS_SECRET_KEY: %v\n", err.Error())
 		}
 
 		log.Infof("MS Key Created")
@@ -45,3 +50,33 @@ func msSecret(db *sqlx.DB) bool {
 	return true
 }
 
+
+// LoadMicroserviceSecret reads and decrypts MS_SECRET_KEY without loading the rest of the settings.
+func LoadMicroserviceSecret(db *sqlx.DB) (string, error) {
+	var msKey setting_model.Setting
+	err := db.Get(&msKey, `
+	SELECT *
+	FROM gocms_settings
+	WHERE name=?
+	`, "MS_SECRET_KEY")
+	if err != nil {
+		return "", err
+	}
+	return DecryptSetting(msKey.Name, msKey.Value)
+}
+
+// PluginSecret derives the secret a plugin uses to identify itself on the internal api from the microservice secret.
+// A plugin holding its own secret can't compute another plugin's.
+func PluginSecret(msSecret string, pluginId string) string {
+	mac := hmac.New(sha256.New, []byte(msSecret))
+	mac.Write([]byte("plugin:" + pluginId))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyPluginSecret reports whether secret is the plugin secret of pluginId.
+func VerifyPluginSecret(msSecret string, pluginId string, secret string) bool {
+	if msSecret == "" || pluginId == "" || secret == "" {
+		return false
+	}
+	return hmac.Equal([]byte(secret), []byte(PluginSecret(msSecret, pluginId)))
+}
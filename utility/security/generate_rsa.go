@@ -21,8 +21,7 @@ func rsaPrivKey(db *sqlx.DB) bool {
 	WHERE name=?
 	`, "RSA_PRIV")
 	if err != nil {
-		log.Criticalf("RSA_PRIV row doesn't exist in gocms_settings\n")
-		return false
+		log.Fatalf("RSA_PRIV row doesn't exist in gocms_settings\n")
 	}
 
 	// if it is nil gen one
@@ -31,7 +30,7 @@ func rsaPrivKey(db *sqlx.DB) bool {
 		bitSize := 2048
 		key, err := rsa.GenerateKey(reader, bitSize)
 		if err != nil {
-			log.Criticalf("Error creating rsa")
+			log.Fatalf("Error creating rsa")
 		}
 
 		privKeyData := pem.EncodeToMemory(&pem.Block{
@@ -41,8 +40,7 @@ func rsaPrivKey(db *sqlx.DB) bool {
 
 		pubKeyData, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
 		if err != nil {
-			log.Criticalf("Error marshaling RSA_PUB.pem: %v\n", err.Error())
-			return false
+			log.Fatalf("Error marshaling RSA_PUB.pem: %v\n", err.Error())
 		}
 
 		pubKeyData = pem.EncodeToMemory(&pem.Block{
@@ -51,13 +49,16 @@ func rsaPrivKey(db *sqlx.DB) bool {
 		})
 
 		// insert priv key
+		privKeyValue, err := EncryptSetting("RSA_PRIV", string(privKeyData))
+		if err != nil {
+			log.Fatalf("Error encrypting RSA_PRIV: %v\n", err.Error())
+		}
 		_, err = db.Exec(`
 		UPDATE gocms_settings SET value=?
 		WHERE name = ?
-		`, string(privKeyData), "RSA_PRIV")
+		`, privKeyValue, "RSA_PRIV")
 		if err != nil {
-			log.Criticalf("Error inserting RSA_PRIV: %v\n", err.Error())
-			return false
+			log.Fatalf("Error inserting RSA_PRIV: %v\n", err.Error())
 		}
 
 		// insert pub key
@@ -66,8 +67,7 @@ func rsaPrivKey(db *sqlx.DB) bool {
 		WHERE name = ?
 		`, pubKeyData, "RSA_PUB")
 		if err != nil {
-			log.Criticalf("Error inserting RSA_PUB")
-			return false
+			log.Fatalf("Error inserting RSA_PUB")
 		}
 
 		log.Infof("RSA Key Pair Created")
@@ -0,0 +1,252 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/gocms-io/gocms/domain/setting/setting_model"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+)
+
+const (
+	ENV_MASTER_KEY          = "GOCMS_MASTER_KEY"
+	ENV_MASTER_KEY_FILE     = "GOCMS_MASTER_KEY_FILE"
+	ENV_NEW_MASTER_KEY      = "GOCMS_NEW_MASTER_KEY"
+	ENV_NEW_MASTER_KEY_FILE = "GOCMS_NEW_MASTER_KEY_FILE"
+
+	masterKeySize   = 32
+	encryptedPrefix = "enc:v1:"
+)
+
+// MasterKey encrypts the data keys used to encrypt secret settings.
+//
+// Each value is encrypted with its own random data key. The data key is encrypted with the master key and stored next to the value as
+// enc:v1:<master key id>:<encrypted data key>:<encrypted value>
+// so rotating the master key only re-encrypts the data keys.
+type MasterKey struct {
+	Id  string
+	key []byte
+}
+
+var (
+	masterKey     *MasterKey
+	masterKeyOnce sync.Once
+)
+
+// GetMasterKey returns the master key from the environment or nil if one isn't configured.
+// An invalid master key stops the server so secrets are never written with the wrong key.
+func GetMasterKey() *MasterKey {
+	masterKeyOnce.Do(func() {
+		key, err := LoadMasterKey(ENV_MASTER_KEY, ENV_MASTER_KEY_FILE)
+		if err != nil {
+			log.Fatalf("Error loading master key: %v\n", err.Error())
+		}
+		masterKey = key
+	})
+	return masterKey
+}
+
+// LoadMasterKey reads a base64 encoded key from envVar or from the file named by fileEnvVar. Returns nil if neither is set.
+func LoadMasterKey(envVar string, fileEnvVar string) (*MasterKey, error) {
+	encoded := os.Getenv(envVar)
+	if encoded == "" {
+		keyFile := os.Getenv(fileEnvVar)
+		if keyFile == "" {
+			return nil, nil
+		}
+		data, err := ioutil.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		encoded = string(data)
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%v must be base64 encoded: %v", envVar, err.Error())
+	}
+
+	return NewMasterKey(raw)
+}
+
+func NewMasterKey(raw []byte) (*MasterKey, error) {
+	if len(raw) != masterKeySize {
+		return nil, fmt.Errorf("master key must be %v bytes, got %v", masterKeySize, len(raw))
+	}
+
+	sum := sha256.Sum256(raw)
+	mk := &MasterKey{
+		Id:  hex.EncodeToString(sum[:4]),
+		key: raw,
+	}
+	return mk, nil
+}
+
+// GenerateMasterKey returns a new random base64 encoded master key.
+func GenerateMasterKey() (string, error) {
+	raw := make([]byte, masterKeySize)
+	if _, err := io.ReadFull(rand.Reader, raw); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(raw), nil
+}
+
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// Encrypt encrypts value under a new data key. name is bound to the ciphertext so values can't be swapped between settings.
+func (mk *MasterKey) Encrypt(name string, value string) (string, error) {
+	dataKey := make([]byte, masterKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(mk.key, dataKey, []byte(mk.Id))
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dataKey, []byte(value), []byte(name))
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedPrefix + mk.Id + ":" + base64.RawURLEncoding.EncodeToString(wrappedKey) + ":" + base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+func (mk *MasterKey) Decrypt(name string, value string) (string, error) {
+	dataKey, ciphertext, err := mk.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+
+	plaintext, err := open(dataKey, ciphertext, []byte(name))
+	if err != nil {
+		return "", fmt.Errorf("can't decrypt %v: %v", name, err.Error())
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap encrypts the data key of value with newKey. The value itself is not decrypted.
+func (mk *MasterKey) Rewrap(value string, newKey *MasterKey) (string, error) {
+	dataKey, ciphertext, err := mk.unwrap(value)
+	if err != nil {
+		return "", err
+	}
+
+	wrappedKey, err := seal(newKey.key, dataKey, []byte(newKey.Id))
+	if err != nil {
+		return "", err
+	}
+
+	return encryptedPrefix + newKey.Id + ":" + base64.RawURLEncoding.EncodeToString(wrappedKey) + ":" + base64.RawURLEncoding.EncodeToString(ciphertext), nil
+}
+
+func (mk *MasterKey) unwrap(value string) (dataKey []byte, ciphertext []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if !IsEncrypted(value) || len(parts) != 3 {
+		return nil, nil, errors.New("value is not encrypted")
+	}
+	if parts[0] != mk.Id {
+		return nil, nil, fmt.Errorf("value was encrypted with master key %v, not %v", parts[0], mk.Id)
+	}
+
+	wrappedKey, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	ciphertext, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	dataKey, err = open(mk.key, wrappedKey, []byte(mk.Id))
+	if err != nil {
+		return nil, nil, fmt.Errorf("can't decrypt data key: %v", err.Error())
+	}
+	return dataKey, ciphertext, nil
+}
+
+// EncryptSetting encrypts the value of a secret setting when a master key is configured. Other values are returned unchanged.
+func EncryptSetting(name string, value string) (string, error) {
+	schema, ok := setting_model.GetSchema(name)
+	if !ok || !schema.Secret {
+		return value, nil
+	}
+	return EncryptSecret(name, value)
+}
+
+// EncryptSecret encrypts value when a master key is configured. Without one the value is returned unchanged.
+func EncryptSecret(name string, value string) (string, error) {
+	if value == "" || IsEncrypted(value) {
+		return value, nil
+	}
+
+	mk := GetMasterKey()
+	if mk == nil {
+		return value, nil
+	}
+	return mk.Encrypt(name, value)
+}
+
+// DecryptSetting decrypts an encrypted setting value. Plaintext values are returned unchanged.
+func DecryptSetting(name string, value string) (string, error) {
+	return DecryptSecret(name, value)
+}
+
+// DecryptSecret decrypts a value from EncryptSecret. Plaintext values are returned unchanged.
+func DecryptSecret(name string, value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+
+	mk := GetMasterKey()
+	if mk == nil {
+		return "", fmt.Errorf("%v is encrypted but %v or %v isn't set", name, ENV_MASTER_KEY, ENV_MASTER_KEY_FILE)
+	}
+	return mk.Decrypt(name, value)
+}
+
+func seal(key []byte, plaintext []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key []byte, sealed []byte, additionalData []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
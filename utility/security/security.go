@@ -1,12 +1,15 @@
 package security
 
 import (
+	"fmt"
+	"github.com/gocms-io/gocms/domain/setting/setting_model"
+	"github.com/gocms-io/gocms/utility/errors"
+	"github.com/gocms-io/gocms/utility/log"
 	"github.com/jmoiron/sqlx"
 )
 
 func CheckOrGenRSAKeysAndSecrets(db *sqlx.DB) bool {
 
-
 	// check rsaPrivKey
 	if ok := rsaPrivKey(db); !ok {
 		return false
@@ -17,6 +20,260 @@ func CheckOrGenRSAKeysAndSecrets(db *sqlx.DB) bool {
 		return false
 	}
 
+	// encrypt any secrets still stored in plaintext
+	if ok := encryptSecretSettings(db); !ok {
+		return false
+	}
+
+	return true
+}
+
+// encryptSecretSettings encrypts plaintext secret settings once a master key is configured.
+func encryptSecretSettings(db *sqlx.DB) bool {
+	if GetMasterKey() == nil {
+		log.Warningf("%v isn't set. Secret settings are stored in plaintext.\n", ENV_MASTER_KEY)
+		return true
+	}
+
+	var settings []setting_model.Setting
+	err := db.Select(&settings, "SELECT * FROM gocms_settings")
+	if err != nil {
+		log.Fatalf("Error getting settings to encrypt: %v\n", err.Error())
+	}
+
+	for _, setting := range settings {
+		value, err := EncryptSetting(setting.Name, setting.Value)
+		if err != nil {
+			log.Fatalf("Error encrypting %v: %v\n", setting.Name, err.Error())
+		}
+		if value == setting.Value {
+			continue
+		}
+
+		_, err = db.Exec("UPDATE gocms_settings SET value=? WHERE name=?", value, setting.Name)
+		if err != nil {
+			log.Fatalf("Error updating encrypted %v: %v\n", setting.Name, err.Error())
+		}
+		log.Infof("Encrypted %v\n", setting.Name)
+	}
+
+	var signingKeys []signingKeySecret
+	err = db.Select(&signingKeys, "SELECT kid, privateKey FROM gocms_signing_keys")
+	if err != nil {
+		log.Fatalf("Error getting signing keys to encrypt: %v\n", err.Error())
+	}
+
+	for _, signingKey := range signingKeys {
+		if IsEncrypted(signingKey.PrivateKey) {
+			continue
+		}
+		value, err := EncryptSecret(SigningKeySecretName(signingKey.Kid), signingKey.PrivateKey)
+		if err != nil {
+			log.Fatalf("Error encrypting signing key %v: %v\n", signingKey.Kid, err.Error())
+		}
+		_, err = db.Exec("UPDATE gocms_signing_keys SET privateKey=? WHERE kid=?", value, signingKey.Kid)
+		if err != nil {
+			log.Fatalf("Error updating encrypted signing key %v: %v\n", signingKey.Kid, err.Error())
+		}
+		log.Infof("Encrypted signing key %v\n", signingKey.Kid)
+	}
+
+	var pluginKeys []pluginTlsKeySecret
+	err = db.Select(&pluginKeys, "SELECT pluginId, externalTlsKey FROM gocms_plugins WHERE externalTlsKey IS NOT NULL AND externalTlsKey != ''")
+	if err != nil {
+		log.Fatalf("Error getting plugin tls keys to encrypt: %v\n", err.Error())
+	}
+
+	for _, pluginKey := range pluginKeys {
+		if IsEncrypted(pluginKey.Key) {
+			continue
+		}
+		value, err := EncryptSecret(PluginTlsKeySecretName(pluginKey.PluginId), pluginKey.Key)
+		if err != nil {
+			log.Fatalf("Error encrypting plugin %v tls key: %v\n", pluginKey.PluginId, err.Error())
+		}
+		_, err = db.Exec("UPDATE gocms_plugins SET externalTlsKey=? WHERE pluginId=?", value, pluginKey.PluginId)
+		if err != nil {
+			log.Fatalf("Error updating encrypted plugin %v tls key: %v\n", pluginKey.PluginId, err.Error())
+		}
+		log.Infof("Encrypted plugin %v tls key\n", pluginKey.PluginId)
+	}
 
 	return true
 }
+
+// SigningKeySecretName names the private key of a token signing key when it is encrypted.
+func SigningKeySecretName(kid string) string {
+	return fmt.Sprintf("SIGNING_KEY:%v", kid)
+}
+
+type signingKeySecret struct {
+	Kid        string `db:"kid"`
+	PrivateKey string `db:"privateKey"`
+}
+
+// PluginTlsKeySecretName names the client key used to connect to an external plugin when it is encrypted.
+func PluginTlsKeySecretName(pluginId string) string {
+	return fmt.Sprintf("PLUGIN_TLS_KEY:%v", pluginId)
+}
+
+// PluginSettingSecretName names a secret plugin setting when it is encrypted.
+func PluginSettingSecretName(pluginId string, name string) string {
+	return fmt.Sprintf("PLUGIN_SETTING:%v:%v", pluginId, name)
+}
+
+type pluginSettingSecret struct {
+	PluginId string `db:"pluginId"`
+	Name     string `db:"name"`
+	Value    string `db:"value"`
+}
+
+type pluginTlsKeySecret struct {
+	PluginId string `db:"pluginId"`
+	Key      string `db:"externalTlsKey"`
+}
+
+// RotateMasterKey re-encrypts the data key of every encrypted setting, signing key, plugin tls key and plugin setting with the key in GOCMS_NEW_MASTER_KEY or GOCMS_NEW_MASTER_KEY_FILE.
+// Plaintext secrets are encrypted with the new key. All settings are updated in one transaction.
+func RotateMasterKey(db *sqlx.DB) error {
+	currentKey := GetMasterKey()
+	newKey, err := LoadMasterKey(ENV_NEW_MASTER_KEY, ENV_NEW_MASTER_KEY_FILE)
+	if err != nil {
+		return err
+	}
+	if newKey == nil {
+		return errors.New(ENV_NEW_MASTER_KEY + " or " + ENV_NEW_MASTER_KEY_FILE + " must be set to rotate the master key")
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+
+	var settings []setting_model.Setting
+	err = tx.Select(&settings, "SELECT * FROM gocms_settings FOR UPDATE")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, setting := range settings {
+		var value string
+		if IsEncrypted(setting.Value) {
+			if currentKey == nil {
+				tx.Rollback()
+				return errors.New(setting.Name + " is encrypted but " + ENV_MASTER_KEY + " or " + ENV_MASTER_KEY_FILE + " isn't set")
+			}
+			value, err = currentKey.Rewrap(setting.Value, newKey)
+		} else if schema, ok := setting_model.GetSchema(setting.Name); ok && schema.Secret && setting.Value != "" {
+			value, err = newKey.Encrypt(setting.Name, setting.Value)
+		} else {
+			continue
+		}
+		if err != nil {
+			tx.Rollback()
+			return errors.New(setting.Name + ": " + err.Error())
+		}
+
+		_, err = tx.Exec("UPDATE gocms_settings SET value=? WHERE name=?", value, setting.Name)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		log.Infof("Rotated %v\n", setting.Name)
+	}
+
+	var signingKeys []signingKeySecret
+	err = tx.Select(&signingKeys, "SELECT kid, privateKey FROM gocms_signing_keys FOR UPDATE")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, signingKey := range signingKeys {
+		var value string
+		if IsEncrypted(signingKey.PrivateKey) {
+			if currentKey == nil {
+				tx.Rollback()
+				return errors.New("signing key " + signingKey.Kid + " is encrypted but " + ENV_MASTER_KEY + " or " + ENV_MASTER_KEY_FILE + " isn't set")
+			}
+			value, err = currentKey.Rewrap(signingKey.PrivateKey, newKey)
+		} else {
+			value, err = newKey.Encrypt(SigningKeySecretName(signingKey.Kid), signingKey.PrivateKey)
+		}
+		if err != nil {
+			tx.Rollback()
+			return errors.New("signing key " + signingKey.Kid + ": " + err.Error())
+		}
+
+		_, err = tx.Exec("UPDATE gocms_signing_keys SET privateKey=? WHERE kid=?", value, signingKey.Kid)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		log.Infof("Rotated signing key %v\n", signingKey.Kid)
+	}
+
+	var pluginKeys []pluginTlsKeySecret
+	err = tx.Select(&pluginKeys, "SELECT pluginId, externalTlsKey FROM gocms_plugins WHERE externalTlsKey IS NOT NULL AND externalTlsKey != '' FOR UPDATE")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, pluginKey := range pluginKeys {
+		var value string
+		if IsEncrypted(pluginKey.Key) {
+			if currentKey == nil {
+				tx.Rollback()
+				return errors.New("plugin " + pluginKey.PluginId + " tls key is encrypted but " + ENV_MASTER_KEY + " or " + ENV_MASTER_KEY_FILE + " isn't set")
+			}
+			value, err = currentKey.Rewrap(pluginKey.Key, newKey)
+		} else {
+			value, err = newKey.Encrypt(PluginTlsKeySecretName(pluginKey.PluginId), pluginKey.Key)
+		}
+		if err != nil {
+			tx.Rollback()
+			return errors.New("plugin " + pluginKey.PluginId + " tls key: " + err.Error())
+		}
+
+		_, err = tx.Exec("UPDATE gocms_plugins SET externalTlsKey=? WHERE pluginId=?", value, pluginKey.PluginId)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		log.Infof("Rotated plugin %v tls key\n", pluginKey.PluginId)
+	}
+
+	// plaintext plugin secrets are encrypted by the plugins service at startup since only the manifest knows which are secret
+	var pluginSettings []pluginSettingSecret
+	err = tx.Select(&pluginSettings, "SELECT pluginId, name, value FROM gocms_plugin_settings FOR UPDATE")
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	for _, pluginSetting := range pluginSettings {
+		if !IsEncrypted(pluginSetting.Value) {
+			continue
+		}
+		if currentKey == nil {
+			tx.Rollback()
+			return errors.New("plugin " + pluginSetting.PluginId + " setting " + pluginSetting.Name + " is encrypted but " + ENV_MASTER_KEY + " or " + ENV_MASTER_KEY_FILE + " isn't set")
+		}
+		value, err := currentKey.Rewrap(pluginSetting.Value, newKey)
+		if err != nil {
+			tx.Rollback()
+			return errors.New("plugin " + pluginSetting.PluginId + " setting " + pluginSetting.Name + ": " + err.Error())
+		}
+
+		_, err = tx.Exec("UPDATE gocms_plugin_settings SET value=? WHERE pluginId=? AND name=?", value, pluginSetting.PluginId, pluginSetting.Name)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+		log.Infof("Rotated plugin %v setting %v\n", pluginSetting.PluginId, pluginSetting.Name)
+	}
+
+	return tx.Commit()
+}
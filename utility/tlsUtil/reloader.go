@@ -0,0 +1,138 @@
+package tlsUtil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+)
+
+// Options configure a server tls config
+type Options struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile turns on client certificates. Clients that send one must be signed by a CA in this file.
+	ClientCAFile string
+	// RequireClientCert rejects connections without a client certificate
+	RequireClientCert bool
+	MinVersion        uint16
+	CipherSuites      []uint16
+}
+
+// Reloader serves the certificate, key and client CAs from disk and picks up new files without a restart
+type Reloader struct {
+	options *Options
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  map[string]time.Time
+}
+
+// NewReloader loads the files once so a bad certificate fails at startup
+func NewReloader(options *Options) (*Reloader, error) {
+	r := &Reloader{
+		options: options,
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads every file again. On error the current certificate keeps being served.
+func (r *Reloader) Reload() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.options.CertFile, r.options.KeyFile)
+	if err != nil {
+		return fmt.Errorf("loading %v: %v", r.options.CertFile, err)
+	}
+
+	var clientCAs *x509.CertPool
+	if r.options.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.options.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		clientCAs = x509.NewCertPool()
+		if !clientCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in %v", r.options.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = clientCAs
+	r.modTimes = modTimes
+	r.mu.Unlock()
+	return nil
+}
+
+// Changed is true when any of the files have been modified since they were loaded
+func (r *Reloader) Changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			// mid replace, try again next time
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Config returns a tls config that always uses the latest files
+func (r *Reloader) Config() *tls.Config {
+	base := &tls.Config{
+		MinVersion:   r.options.MinVersion,
+		CipherSuites: r.options.CipherSuites,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			return r.cert, nil
+		},
+	}
+	if r.options.ClientCAFile == "" {
+		return base
+	}
+
+	clientAuth := tls.VerifyClientCertIfGiven
+	if r.options.RequireClientCert {
+		clientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	// client CAs can't be swapped on a shared config, so each handshake gets a copy with the current pool
+	config := base.Clone()
+	config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		handshake := base.Clone()
+		handshake.ClientAuth = clientAuth
+		handshake.ClientCAs = r.clientCAs
+		return handshake, nil
+	}
+	return config
+}
+
+func (r *Reloader) files() []string {
+	files := []string{r.options.CertFile, r.options.KeyFile}
+	if r.options.ClientCAFile != "" {
+		files = append(files, r.options.ClientCAFile)
+	}
+	return files
+}
@@ -0,0 +1,57 @@
+package tlsUtil
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+)
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseVersion reads a minimum version like "1.2". Empty is 1.2.
+func ParseVersion(s string) (uint16, error) {
+	s = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(s)), "tls")
+	if s == "" {
+		return tls.VersionTLS12, nil
+	}
+	version, ok := versions[s]
+	if !ok {
+		return 0, fmt.Errorf("unknown tls version %q, expected 1.0, 1.1, 1.2 or 1.3", s)
+	}
+	return version, nil
+}
+
+// ParseCipherSuites reads a comma separated list of cipher suite names, ie. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256.
+// Empty uses go's defaults. Suites can't be chosen for tls 1.3.
+func ParseCipherSuites(s string) ([]uint16, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+	for _, suite := range tls.InsecureCipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	var suites []uint16
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		id, ok := known[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown cipher suite %q", name)
+		}
+		suites = append(suites, id)
+	}
+	return suites, nil
+}
@@ -0,0 +1,222 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// StdoutExporter writes one json object per span for local debugging
+type StdoutExporter struct {
+	Out io.Writer
+	mu  sync.Mutex
+}
+
+type stdoutSpan struct {
+	Service      string                 `json:"service"`
+	Name         string                 `json:"name"`
+	Kind         string                 `json:"kind"`
+	TraceID      string                 `json:"traceId"`
+	SpanID       string                 `json:"spanId"`
+	ParentSpanID string                 `json:"parentSpanId,omitempty"`
+	Start        time.Time              `json:"start"`
+	Duration     string                 `json:"duration"`
+	Attributes   map[string]interface{} `json:"attributes,omitempty"`
+	Error        string                 `json:"error,omitempty"`
+}
+
+func (se *StdoutExporter) Export(serviceName string, spans []*SpanData) error {
+	se.mu.Lock()
+	defer se.mu.Unlock()
+
+	enc := json.NewEncoder(se.Out)
+	for _, span := range spans {
+		out := stdoutSpan{
+			Service:  serviceName,
+			Name:     span.Name,
+			Kind:     kindName(span.Kind),
+			TraceID:  span.SpanContext.TraceID.String(),
+			SpanID:   span.SpanContext.SpanID.String(),
+			Start:    span.Start,
+			Duration: span.End.Sub(span.Start).String(),
+			Error:    span.StatusMessage,
+		}
+		if span.ParentSpanID != (SpanID{}) {
+			out.ParentSpanID = span.ParentSpanID.String()
+		}
+		if len(span.Attributes) > 0 {
+			out.Attributes = make(map[string]interface{}, len(span.Attributes))
+			for _, attribute := range span.Attributes {
+				out.Attributes[attribute.Key] = attribute.Value
+			}
+		}
+		if err := enc.Encode(out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func kindName(kind SpanKind) string {
+	switch kind {
+	case KIND_SERVER:
+		return "server"
+	case KIND_CLIENT:
+		return "client"
+	}
+	return "internal"
+}
+
+// OTLPExporter posts spans to an OpenTelemetry collector using OTLP/HTTP with the json encoding
+type OTLPExporter struct {
+	Endpoint string
+	Headers  map[string]string
+	Client   *http.Client
+}
+
+func NewOTLPExporter(endpoint string, headers map[string]string) *OTLPExporter {
+	if endpoint == "" {
+		endpoint = "http://localhost:4318/v1/traces"
+	}
+	return &OTLPExporter{
+		Endpoint: endpoint,
+		Headers:  headers,
+		Client:   &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano string         `json:"startTimeUnixNano"`
+	EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	Status            otlpStatus     `json:"status"`
+}
+
+type otlpScopeSpans struct {
+	Scope struct {
+		Name string `json:"name"`
+	} `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	} `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpRequest struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+// otlp status codes
+const (
+	otlpStatusUnset = 0
+	otlpStatusError = 2
+)
+
+func (oe *OTLPExporter) Export(serviceName string, spans []*SpanData) error {
+	scope := otlpScopeSpans{Spans: make([]otlpSpan, len(spans))}
+	scope.Scope.Name = "github.com/gocms-io/gocms"
+	for i, span := range spans {
+		s := otlpSpan{
+			TraceID:           span.SpanContext.TraceID.String(),
+			SpanID:            span.SpanContext.SpanID.String(),
+			Name:              span.Name,
+			Kind:              int(span.Kind),
+			StartTimeUnixNano: strconv.FormatInt(span.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(span.End.UnixNano(), 10),
+			Status:            otlpStatus{Code: otlpStatusUnset},
+		}
+		if span.ParentSpanID != (SpanID{}) {
+			s.ParentSpanID = span.ParentSpanID.String()
+		}
+		for _, attribute := range span.Attributes {
+			s.Attributes = append(s.Attributes, otlpKeyValue{Key: attribute.Key, Value: otlpValue(attribute.Value)})
+		}
+		if span.StatusError {
+			s.Status = otlpStatus{Code: otlpStatusError, Message: span.StatusMessage}
+		}
+		scope.Spans[i] = s
+	}
+
+	resource := otlpResourceSpans{ScopeSpans: []otlpScopeSpans{scope}}
+	resource.Resource.Attributes = []otlpKeyValue{{Key: "service.name", Value: otlpValue(serviceName)}}
+
+	body, err := json.Marshal(otlpRequest{ResourceSpans: []otlpResourceSpans{resource}})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, oe.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range oe.Headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := oe.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	io.Copy(ioutil.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("collector responded %v", res.Status)
+	}
+	return nil
+}
+
+func otlpValue(value interface{}) otlpAnyValue {
+	var v otlpAnyValue
+	switch typed := value.(type) {
+	case string:
+		v.StringValue = &typed
+	case bool:
+		v.BoolValue = &typed
+	case int:
+		s := strconv.FormatInt(int64(typed), 10)
+		v.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(typed, 10)
+		v.IntValue = &s
+	case float64:
+		v.DoubleValue = &typed
+	default:
+		s := fmt.Sprint(typed)
+		v.StringValue = &s
+	}
+	return v
+}
@@ -0,0 +1,246 @@
+package trace
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HEADER_TRACEPARENT is the W3C trace context header propagated to plugins
+const HEADER_TRACEPARENT = "traceparent"
+
+// span kinds use the OTLP enum values
+type SpanKind int
+
+const (
+	KIND_INTERNAL SpanKind = 1
+	KIND_SERVER   SpanKind = 2
+	KIND_CLIENT   SpanKind = 3
+)
+
+type TraceID [16]byte
+type SpanID [8]byte
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext is the part of a span that crosses process boundaries
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Sampled bool
+}
+
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent formats the context as a version 00 traceparent header
+func (sc SpanContext) Traceparent() string {
+	flags := "00"
+	if sc.Sampled {
+		flags = "01"
+	}
+	return fmt.Sprintf("00-%v-%v-%v", sc.TraceID, sc.SpanID, flags)
+}
+
+// ParseTraceparent reads a traceparent header. Unknown future versions are read as version 00.
+func ParseTraceparent(header string) (SpanContext, bool) {
+	var sc SpanContext
+	parts := strings.Split(strings.TrimSpace(header), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return sc, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return sc, false
+	}
+	if len(parts[1]) != 32 || len(parts[2]) != 16 || len(parts[3]) != 2 {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.TraceID[:], []byte(parts[1])); err != nil {
+		return sc, false
+	}
+	if _, err := hex.Decode(sc.SpanID[:], []byte(parts[2])); err != nil {
+		return sc, false
+	}
+	flags, err := hex.DecodeString(parts[3])
+	if err != nil {
+		return sc, false
+	}
+	sc.Sampled = flags[0]&1 == 1
+	return sc, sc.IsValid()
+}
+
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span times one unit of work. Spans that aren't sampled still carry ids so the trace continues in plugins.
+type Span struct {
+	mu            sync.Mutex
+	name          string
+	kind          SpanKind
+	spanContext   SpanContext
+	parentSpanID  SpanID
+	start         time.Time
+	end           time.Time
+	attributes    []Attribute
+	statusError   bool
+	statusMessage string
+	ended         bool
+}
+
+type spanKey struct{}
+
+// FromContext returns the active span or nil
+func FromContext(ctx context.Context) *Span {
+	if ctx == nil {
+		return nil
+	}
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// ContextWithSpan makes span the parent of spans started from the returned context
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// Start begins a span that is a child of the span in ctx, or a new trace if there isn't one
+func Start(ctx context.Context, name string, kind SpanKind) (context.Context, *Span) {
+	var parent SpanContext
+	if parentSpan := FromContext(ctx); parentSpan != nil {
+		parent = parentSpan.SpanContext()
+	}
+	return StartWithParent(ctx, parent, name, kind)
+}
+
+// StartRoot begins a new trace for work that isn't given a request context, ie. mail sends
+func StartRoot(name string, kind SpanKind) *Span {
+	_, span := StartWithParent(context.Background(), SpanContext{}, name, kind)
+	return span
+}
+
+// StartWithParent begins a span under a remote parent, ie. one read from a traceparent header
+func StartWithParent(ctx context.Context, parent SpanContext, name string, kind SpanKind) (context.Context, *Span) {
+	return startWithParent(ctx, parent, name, kind, true)
+}
+
+// StartWithUntrustedParent continues the trace of a caller that isn't trusted to make sampling decisions, ie. anyone on the
+// public api. The caller's sampled flag is ignored and the local ratio is applied to a random draw instead of the trace id so
+// a caller can't force its requests to be recorded.
+func StartWithUntrustedParent(ctx context.Context, parent SpanContext, name string, kind SpanKind) (context.Context, *Span) {
+	return startWithParent(ctx, parent, name, kind, false)
+}
+
+func startWithParent(ctx context.Context, parent SpanContext, name string, kind SpanKind, trustSampled bool) (context.Context, *Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	span := &Span{
+		name:  name,
+		kind:  kind,
+		start: time.Now(),
+	}
+	if parent.IsValid() {
+		span.spanContext.TraceID = parent.TraceID
+		span.spanContext.Sampled = parent.Sampled
+		if !trustSampled {
+			span.spanContext.Sampled = tracer.sample(newTraceID())
+		}
+		span.parentSpanID = parent.SpanID
+	} else {
+		span.spanContext.TraceID = newTraceID()
+		span.spanContext.Sampled = tracer.sample(span.spanContext.TraceID)
+	}
+	span.spanContext.SpanID = newSpanID()
+
+	return ContextWithSpan(ctx, span), span
+}
+
+func (s *Span) SpanContext() SpanContext {
+	return s.spanContext
+}
+
+func (s *Span) SetName(name string) {
+	s.mu.Lock()
+	s.name = name
+	s.mu.Unlock()
+}
+
+func (s *Span) SetAttribute(key string, value interface{}) {
+	s.mu.Lock()
+	s.attributes = append(s.attributes, Attribute{Key: key, Value: value})
+	s.mu.Unlock()
+}
+
+// SetError marks the span as failed. A nil error is ignored.
+func (s *Span) SetError(err error) {
+	if err == nil {
+		return
+	}
+	s.mu.Lock()
+	s.statusError = true
+	s.statusMessage = err.Error()
+	s.mu.Unlock()
+}
+
+// End records the span. Calling it again does nothing.
+func (s *Span) End() {
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.end = time.Now()
+	s.mu.Unlock()
+
+	if s.spanContext.Sampled {
+		tracer.record(s)
+	}
+}
+
+// Inject writes the span as the traceparent header
+func (s *Span) Inject(header http.Header) {
+	header.Set(HEADER_TRACEPARENT, s.spanContext.Traceparent())
+}
+
+// Extract reads the traceparent header
+func Extract(header http.Header) (SpanContext, bool) {
+	return ParseTraceparent(header.Get(HEADER_TRACEPARENT))
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	for id == (TraceID{}) {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	for id == (SpanID{}) {
+		rand.Read(id[:])
+	}
+	return id
+}
+
+// traceIDRatio reads the low 8 bytes of the trace id as a fraction. Every service sampling by ratio agrees on the same traces.
+func traceIDRatio(id TraceID) float64 {
+	return float64(binary.BigEndian.Uint64(id[8:])>>11) / float64(uint64(1)<<53)
+}
@@ -0,0 +1,90 @@
+package trace
+
+import (
+	"context"
+	"testing"
+)
+
+type discardExporter struct{}
+
+func (discardExporter) Export(serviceName string, spans []*SpanData) error {
+	return nil
+}
+
+// sampleNothing turns sampling on with a ratio of 0 so only a trusted caller's flag can record a span
+func sampleNothing(t *testing.T) {
+	tracer.mu.Lock()
+	tracer.exporter = discardExporter{}
+	tracer.ratio = 0
+	tracer.mu.Unlock()
+	t.Cleanup(func() {
+		tracer.mu.Lock()
+		tracer.exporter = nil
+		tracer.mu.Unlock()
+	})
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, ok := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if !ok {
+		t.Fatal("expected a valid traceparent")
+	}
+	if sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || sc.SpanID.String() != "00f067aa0ba902b7" || !sc.Sampled {
+		t.Errorf("parsed %+v", sc)
+	}
+	if sc.Traceparent() != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Errorf("round trip gave %v", sc.Traceparent())
+	}
+
+	for _, header := range []string{
+		"",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"00-4bf92f3577b34da6a3ce929d0e0e473z-00f067aa0ba902b7-01",
+	} {
+		if _, ok := ParseTraceparent(header); ok {
+			t.Errorf("%q should be rejected", header)
+		}
+	}
+}
+
+func TestStartWithParentTrustsSampledFlag(t *testing.T) {
+	sampleNothing(t)
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	_, span := StartWithParent(context.Background(), parent, "trusted", KIND_SERVER)
+	if !span.SpanContext().Sampled {
+		t.Error("a trusted caller's sampled flag should be kept")
+	}
+	if span.SpanContext().TraceID != parent.TraceID || span.parentSpanID != parent.SpanID {
+		t.Error("the span should continue the caller's trace")
+	}
+}
+
+func TestStartWithUntrustedParentIgnoresSampledFlag(t *testing.T) {
+	sampleNothing(t)
+	parent, _ := ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+
+	_, span := StartWithUntrustedParent(context.Background(), parent, "untrusted", KIND_SERVER)
+	if span.SpanContext().Sampled {
+		t.Error("an untrusted caller shouldn't be able to force sampling")
+	}
+	if span.SpanContext().TraceID != parent.TraceID {
+		t.Error("the span should still continue the caller's trace")
+	}
+}
+
+func TestStartFromContext(t *testing.T) {
+	ctx, span := StartWithParent(context.Background(), SpanContext{}, "request", KIND_SERVER)
+
+	_, child := Start(ctx, "db", KIND_CLIENT)
+	if child.SpanContext().TraceID != span.SpanContext().TraceID || child.parentSpanID != span.SpanContext().SpanID {
+		t.Error("spans started from the request context should be children of the request span")
+	}
+
+	if root := StartRoot("mail", KIND_CLIENT); root.parentSpanID != (SpanID{}) || root.SpanContext().TraceID == span.SpanContext().TraceID {
+		t.Error("a root span should start a new trace")
+	}
+}
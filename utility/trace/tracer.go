@@ -0,0 +1,198 @@
+package trace
+
+import (
+	"fmt"
+	"github.com/gocms-io/gocms/utility/log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	EXPORTER_NONE   = "none"
+	EXPORTER_OTLP   = "otlp"
+	EXPORTER_STDOUT = "stdout"
+)
+
+const (
+	batchSize     = 512
+	queueSize     = 2048
+	flushInterval = 5 * time.Second
+)
+
+type Config struct {
+	// Exporter is otlp, stdout or none
+	Exporter string
+	// ServiceName is reported as the service.name resource attribute
+	ServiceName string
+	// Endpoint is the OTLP/HTTP traces url, ie. http://localhost:4318/v1/traces
+	Endpoint string
+	// Headers are sent with every OTLP export, ie. for api keys
+	Headers map[string]string
+	// SampleRatio of new traces to record. Traces started by a caller follow the caller's decision.
+	SampleRatio float64
+}
+
+// SpanData is a finished span handed to an exporter
+type SpanData struct {
+	Name          string
+	Kind          SpanKind
+	SpanContext   SpanContext
+	ParentSpanID  SpanID
+	Start         time.Time
+	End           time.Time
+	Attributes    []Attribute
+	StatusError   bool
+	StatusMessage string
+}
+
+type Exporter interface {
+	Export(serviceName string, spans []*SpanData) error
+}
+
+type tracerState struct {
+	mu          sync.RWMutex
+	exporter    Exporter
+	serviceName string
+	ratio       float64
+	queue       chan *SpanData
+	flush       chan chan struct{}
+	done        chan struct{}
+}
+
+var tracer = &tracerState{}
+
+// Configure starts exporting spans. It should be called once at startup.
+func Configure(config *Config) error {
+	var exporter Exporter
+	switch strings.ToLower(config.Exporter) {
+	case "", EXPORTER_NONE:
+		return nil
+	case EXPORTER_STDOUT:
+		exporter = &StdoutExporter{Out: os.Stdout}
+	case EXPORTER_OTLP:
+		exporter = NewOTLPExporter(config.Endpoint, config.Headers)
+	default:
+		return fmt.Errorf("unknown trace exporter %q, expected %v, %v or %v", config.Exporter, EXPORTER_OTLP, EXPORTER_STDOUT, EXPORTER_NONE)
+	}
+
+	serviceName := config.ServiceName
+	if serviceName == "" {
+		serviceName = "gocms"
+	}
+
+	tracer.mu.Lock()
+	tracer.exporter = exporter
+	tracer.serviceName = serviceName
+	tracer.ratio = config.SampleRatio
+	tracer.queue = make(chan *SpanData, queueSize)
+	tracer.flush = make(chan chan struct{})
+	tracer.done = make(chan struct{})
+	tracer.mu.Unlock()
+
+	go tracer.run(exporter, serviceName, tracer.queue, tracer.flush, tracer.done)
+	return nil
+}
+
+// Enabled is false when no exporter is configured
+func Enabled() bool {
+	tracer.mu.RLock()
+	defer tracer.mu.RUnlock()
+	return tracer.exporter != nil
+}
+
+// Shutdown exports anything queued and stops the exporter
+func Shutdown() {
+	tracer.mu.Lock()
+	queue, flush, done := tracer.queue, tracer.flush, tracer.done
+	tracer.exporter = nil
+	tracer.queue = nil
+	tracer.mu.Unlock()
+
+	if queue == nil {
+		return
+	}
+	ack := make(chan struct{})
+	flush <- ack
+	<-ack
+	close(done)
+}
+
+func (t *tracerState) sample(id TraceID) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.exporter == nil {
+		return false
+	}
+	return traceIDRatio(id) < t.ratio
+}
+
+func (t *tracerState) record(s *Span) {
+	s.mu.Lock()
+	data := &SpanData{
+		Name:          s.name,
+		Kind:          s.kind,
+		SpanContext:   s.spanContext,
+		ParentSpanID:  s.parentSpanID,
+		Start:         s.start,
+		End:           s.end,
+		Attributes:    s.attributes,
+		StatusError:   s.statusError,
+		StatusMessage: s.statusMessage,
+	}
+	s.mu.Unlock()
+
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	if t.queue == nil {
+		return
+	}
+
+	// never block a request on the exporter. spans are dropped when the queue is full.
+	select {
+	case t.queue <- data:
+	default:
+	}
+}
+
+func (t *tracerState) run(exporter Exporter, serviceName string, queue chan *SpanData, flush chan chan struct{}, done chan struct{}) {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*SpanData, 0, batchSize)
+	export := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := exporter.Export(serviceName, batch); err != nil {
+			log.Errorf("Error exporting %d spans: %v\n", len(batch), err.Error())
+		}
+		batch = make([]*SpanData, 0, batchSize)
+	}
+
+	for {
+		select {
+		case data := <-queue:
+			batch = append(batch, data)
+			if len(batch) >= batchSize {
+				export()
+			}
+		case <-ticker.C:
+			export()
+		case ack := <-flush:
+			for drained := false; !drained; {
+				select {
+				case data := <-queue:
+					batch = append(batch, data)
+				default:
+					drained = true
+				}
+			}
+			export()
+			close(ack)
+		case <-done:
+			return
+		}
+	}
+}